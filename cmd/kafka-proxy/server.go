@@ -1,6 +1,7 @@
 package server
 
 import (
+	"flag"
 	"fmt"
 	"log/slog"
 	"runtime"
@@ -27,16 +28,26 @@ import (
 	"strings"
 
 	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
+	brokercredentials "github.com/grepplabs/kafka-proxy/plugin/broker-credentials/shared"
 	localauth "github.com/grepplabs/kafka-proxy/plugin/local-auth/shared"
+	scramcredentials "github.com/grepplabs/kafka-proxy/plugin/scram-credentials/shared"
 	tokeninfo "github.com/grepplabs/kafka-proxy/plugin/token-info/shared"
 	tokenprovider "github.com/grepplabs/kafka-proxy/plugin/token-provider/shared"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
+	"github.com/jcmturner/gokrb5/v8/keytab"
 
 	"github.com/grepplabs/kafka-proxy/pkg/registry"
 	// built-in plugins
+	_ "github.com/grepplabs/kafka-proxy/pkg/libs/broker-credentials-file"
 	_ "github.com/grepplabs/kafka-proxy/pkg/libs/googleid-info"
 	_ "github.com/grepplabs/kafka-proxy/pkg/libs/googleid-provider"
+	_ "github.com/grepplabs/kafka-proxy/pkg/libs/http-auth"
+	_ "github.com/grepplabs/kafka-proxy/pkg/libs/introspection-info"
+	_ "github.com/grepplabs/kafka-proxy/pkg/libs/oidc-info"
+	_ "github.com/grepplabs/kafka-proxy/pkg/libs/scram-file"
+	_ "github.com/grepplabs/kafka-proxy/pkg/libs/user-file"
 	"github.com/spf13/viper"
 )
 
@@ -46,6 +57,10 @@ var (
 	bootstrapServersMapping = make([]string, 0)
 	externalServersMapping  = make([]string, 0)
 	dialAddressMapping      = make([]string, 0)
+	advertisedListenerRules = make([]string, 0)
+
+	localAuthMechanismCommands = make([]string, 0)
+	localAuthMechanismParams   = make([]string, 0)
 )
 
 var Server = &cobra.Command{
@@ -66,6 +81,18 @@ var Server = &cobra.Command{
 		if err := c.InitDialAddressMappings(getOrEnvStringSlice(dialAddressMapping, "DIAL_ADDRESS_MAPPING")); err != nil {
 			return err
 		}
+		if err := c.InitAdvertisedListenerRules(getOrEnvStringSlice(advertisedListenerRules, "ADVERTISED_LISTENER_RULE")); err != nil {
+			return err
+		}
+		if err := c.InitLocalAuthMechanisms(localAuthMechanismCommands, localAuthMechanismParams); err != nil {
+			return err
+		}
+		if err := c.InitListenerProfiles(); err != nil {
+			return err
+		}
+		if err := c.InitUpstreamClusters(); err != nil {
+			return err
+		}
 		if err := c.Validate(); err != nil {
 			return err
 		}
@@ -83,6 +110,7 @@ func getOrEnvStringSlice(value []string, envKey string) []string {
 
 func init() {
 	initFlags()
+	c.InitUpstreamClusterDefaults()
 }
 
 func initFlags() {
@@ -92,10 +120,12 @@ func initFlags() {
 	Server.Flags().StringArrayVar(&bootstrapServersMapping, "bootstrap-server-mapping", []string{}, "Mapping of Kafka bootstrap server address to local address (host:port,host:port(,advhost:advport))")
 	Server.Flags().StringArrayVar(&externalServersMapping, "external-server-mapping", []string{}, "Mapping of Kafka server address to external address (host:port,host:port). A listener for the external address is not started")
 	Server.Flags().StringArrayVar(&dialAddressMapping, "dial-address-mapping", []string{}, "Mapping of target broker address to new one (host:port,host:port). The mapping is performed during connection establishment")
+	Server.Flags().StringArrayVar(&advertisedListenerRules, "advertised-listener-rule", []string{}, "Split-horizon rule selecting advertised broker addresses for clients by listener address, local or client network (listener=host:port,local-cidr=cidr,client-cidr=cidr,advertised=host(:port)). Advertised address supports templating with {{.brokerId}}. The first matching rule is used")
 	Server.Flags().BoolVar(&c.Proxy.DeterministicListeners, "deterministic-listeners", false, "Enable deterministic listeners (listener port = min port + broker id).")
 	Server.Flags().BoolVar(&c.Proxy.DisableDynamicListeners, "dynamic-listeners-disable", false, "Disable dynamic listeners.")
 	Server.Flags().Uint16Var(&c.Proxy.DynamicSequentialMinPort, "dynamic-sequential-min-port", 0, "If set to non-zero, makes the dynamic listener use a sequential port starting with this value rather than a random port every time.")
 	Server.Flags().Uint16Var(&c.Proxy.DynamicSequentialMaxPorts, "dynamic-sequential-max-ports", 0, "If set to non-zero, ports are allocated sequentially from the half open interval [dynamic-sequential-min-port, dynamic-sequential-min-port + dynamic-sequential-max-ports)")
+	Server.Flags().StringVar(&c.UpstreamClustersFile, "upstream-clusters-file", "", "YAML file with additional named upstream clusters. A cluster defines server mappings, broker TLS, SASL, forward proxy and dynamic listeners")
 
 	Server.Flags().IntVar(&c.Proxy.RequestBufferSize, "proxy-request-buffer-size", 4096, "Request buffer size pro tcp connection")
 	Server.Flags().IntVar(&c.Proxy.ResponseBufferSize, "proxy-response-buffer-size", 4096, "Response buffer size pro tcp connection")
@@ -113,16 +143,45 @@ func initFlags() {
 	Server.Flags().StringVar(&c.Proxy.TLS.ListenerCRLFile, "proxy-listener-crl-file", "", "PEM encoded X509 CRLs file")
 	Server.Flags().StringSliceVar(&c.Proxy.TLS.ListenerCipherSuites, "proxy-listener-cipher-suites", []string{}, "List of supported cipher suites")
 	Server.Flags().StringSliceVar(&c.Proxy.TLS.ListenerCurvePreferences, "proxy-listener-curve-preferences", []string{}, "List of curve preferences")
+	Server.Flags().StringVar(&c.Proxy.TLS.ListenerMode, "proxy-listener-tls-mode", "", "Listener TLS mode: tls (TLS only), plaintext (plaintext only) or both (TLS and plaintext detected on the same port). If empty, tls is used when TLS listener is enabled")
+	Server.Flags().StringToStringVar(&c.Proxy.TLS.ListenerModeMapping, "proxy-listener-tls-mode-mapping", map[string]string{}, "Mapping of listener address to listener TLS mode (host:port=mode)")
+	Server.Flags().DurationVar(&c.Proxy.TLS.DetectTimeout, "proxy-listener-tls-detect-timeout", 10*time.Second, "How long to wait for the first bytes of a connection when the listener TLS mode is both")
+
+	Server.Flags().StringSliceVar(&c.Proxy.TLS.ClientCert.Subjects, "proxy-listener-tls-required-client-subject", []string{}, "Required client certificate subject common name; example; s:/CN=[value]/C=[state]/C=[DE,PL] or r:/CN=[^val.{2}$]/C=[state]/C=[DE,PL]; SAN, issuer and EKU fields: s:/SPIFFE=[spiffe://example.org/ns/*]/EKU=[clientAuth]; check manual for more details")
+
+	Server.Flags().StringVar(&c.Proxy.ListenerProfilesFile, "listener-profiles-file", "", "YAML file with named listener profiles. A profile defines TLS, local authentication and forbidden api keys of the assigned listeners")
 
-	Server.Flags().StringSliceVar(&c.Proxy.TLS.ClientCert.Subjects, "proxy-listener-tls-required-client-subject", []string{}, "Required client certificate subject common name; example; s:/CN=[value]/C=[state]/C=[DE,PL] or r:/CN=[^val.{2}$]/C=[state]/C=[DE,PL]; check manual for more details")
+	// PROXY protocol
+	Server.Flags().BoolVar(&c.Proxy.ProxyProtocol.Enable, "proxy-protocol-enable", false, "Whether or not to read PROXY protocol v1/v2 header sent by the load balancer")
+	Server.Flags().StringSliceVar(&c.Proxy.ProxyProtocol.TrustedCIDRs, "proxy-protocol-trusted-cidrs", []string{}, "List of CIDRs of load balancers sending the PROXY protocol header, required when PROXY protocol is enabled. Connections from other sources are accepted without the header")
+	Server.Flags().DurationVar(&c.Proxy.ProxyProtocol.HeaderTimeout, "proxy-protocol-header-timeout", 10*time.Second, "How long to wait for the PROXY protocol header")
 
 	// local authentication plugin
 	Server.Flags().BoolVar(&c.Auth.Local.Enable, "auth-local-enable", false, "Enable local SASL/PLAIN authentication performed by listener - SASL handshake will not be passed to kafka brokers")
 	Server.Flags().StringVar(&c.Auth.Local.Command, "auth-local-command", "", "Path to authentication plugin binary")
-	Server.Flags().StringVar(&c.Auth.Local.Mechanism, "auth-local-mechanism", "PLAIN", "SASL mechanism used for local authentication: PLAIN or OAUTHBEARER")
+	Server.Flags().StringVar(&c.Auth.Local.Mechanism, "auth-local-mechanism", "PLAIN", "SASL mechanism used for local authentication: PLAIN, OAUTHBEARER, SCRAM-SHA-256, SCRAM-SHA-512 or GSSAPI")
 	Server.Flags().StringArrayVar(&c.Auth.Local.Parameters, "auth-local-param", []string{}, "Authentication plugin parameter")
+	Server.Flags().StringArrayVar(&localAuthMechanismCommands, "auth-local-mechanism-command", []string{}, "Additional SASL mechanism offered by local authentication and path to its authentication plugin binary or built-in plugin name (mechanism=command)")
+	Server.Flags().StringArrayVar(&localAuthMechanismParams, "auth-local-mechanism-param", []string{}, "Authentication plugin parameter of the additional SASL mechanism (mechanism=param)")
 	Server.Flags().StringVar(&c.Auth.Local.LogLevel, "auth-local-log-level", "trace", "Log level of the auth plugin")
 	Server.Flags().DurationVar(&c.Auth.Local.Timeout, "auth-local-timeout", 10*time.Second, "Authentication timeout")
+	Server.Flags().DurationVar(&c.Auth.Local.MaxSessionLifetime, "auth-local-max-session-lifetime", 0, "Maximum lifetime of the local SASL session, clients must re-authenticate (KIP-368) before it expires. The lifetime is also limited by the credential expiry e.g. OAUTHBEARER token exp or Kerberos ticket end time. 0 disables session expiry")
+
+	// auth cache
+	Server.Flags().DurationVar(&c.Auth.Cache.TTL, "auth-cache-ttl", 0, "Cache successful results of the local PLAIN and OAUTHBEARER and gateway server authentication plugins for this duration. The OAUTHBEARER token exp claim limits the TTL. 0 disables the cache")
+	Server.Flags().DurationVar(&c.Auth.Cache.NegativeTTL, "auth-cache-negative-ttl", 0, "Cache rejected authentications for this duration. Plugin errors are never cached. 0 disables negative caching")
+	Server.Flags().IntVar(&c.Auth.Cache.MaxEntries, "auth-cache-max-entries", 10000, "Maximum number of cached authentication results")
+
+	// local authentication brute-force protection
+	Server.Flags().BoolVar(&c.Auth.Lockout.Enable, "auth-lockout-enable", false, "Enable tracking of failed local authentications per username and client IP with delayed failure responses and temporary lockout")
+	Server.Flags().IntVar(&c.Auth.Lockout.MaxUsernameFailures, "auth-lockout-max-username-failures", 5, "Number of failed authentications of a username before it is locked. 0 disables the username lockout")
+	Server.Flags().IntVar(&c.Auth.Lockout.MaxSourceFailures, "auth-lockout-max-source-failures", 20, "Number of failed authentications from a client IP before it is locked. 0 disables the client IP lockout")
+	Server.Flags().DurationVar(&c.Auth.Lockout.Window, "auth-lockout-window", 15*time.Minute, "Failures are forgotten when there was no further failure within this window")
+	Server.Flags().DurationVar(&c.Auth.Lockout.Duration, "auth-lockout-duration", 15*time.Minute, "Duration of the lockout")
+	Server.Flags().DurationVar(&c.Auth.Lockout.Delay, "auth-lockout-delay", 100*time.Millisecond, "Delay of the failure response after the first failure, doubled with every further failure. 0 disables the delay")
+	Server.Flags().DurationVar(&c.Auth.Lockout.MaxDelay, "auth-lockout-max-delay", 2*time.Second, "Maximum delay of the failure response, it must be shorter than the local authentication timeout")
+	Server.Flags().StringSliceVar(&c.Auth.Lockout.Allowlist, "auth-lockout-allowlist", []string{}, "List of CIDRs of client IPs which are never delayed or locked")
+	Server.Flags().IntVar(&c.Auth.Lockout.MaxEntries, "auth-lockout-max-entries", 100000, "Maximum number of tracked usernames and client IPs")
 
 	Server.Flags().BoolVar(&c.Auth.Gateway.Client.Enable, "auth-gateway-client-enable", false, "Enable gateway client authentication")
 	Server.Flags().StringVar(&c.Auth.Gateway.Client.Command, "auth-gateway-client-command", "", "Path to authentication plugin binary")
@@ -168,12 +227,43 @@ func initFlags() {
 	//Same TLS client cert tls-same-client-cert-enable
 	Server.Flags().BoolVar(&c.Kafka.TLS.SameClientCertEnable, "tls-same-client-cert-enable", false, "Use only when mutual TLS is enabled on proxy and broker. It controls whether a proxy validates if proxy client certificate exactly matches brokers client cert (tls-client-cert-file)")
 
+	// Per-client TLS certificates toward the brokers
+	Server.Flags().BoolVar(&c.Kafka.TLS.ClientCertIssuer.Enable, "tls-client-cert-issuer-enable", false, "Issue a short-lived client certificate per client for the broker connections, signed by the local intermediate CA")
+	Server.Flags().StringVar(&c.Kafka.TLS.ClientCertIssuer.CACertFile, "tls-client-cert-issuer-ca-cert-file", "", "PEM encoded certificate file of the intermediate CA issuing the client certificates")
+	Server.Flags().StringVar(&c.Kafka.TLS.ClientCertIssuer.CAKeyFile, "tls-client-cert-issuer-ca-key-file", "", "PEM encoded private key file of the intermediate CA issuing the client certificates")
+	Server.Flags().StringVar(&c.Kafka.TLS.ClientCertIssuer.SubjectSource, "tls-client-cert-issuer-subject-source", config.ClientCertSubjectSourceTLS, "Subject of the issued client certificates. One of: tls-subject, sasl-principal")
+	Server.Flags().DurationVar(&c.Kafka.TLS.ClientCertIssuer.Validity, "tls-client-cert-issuer-validity", 1*time.Hour, "Validity of the issued client certificates")
+	Server.Flags().IntVar(&c.Kafka.TLS.ClientCertIssuer.CacheMaxEntries, "tls-client-cert-issuer-cache-max-entries", 10000, "Maximum number of issued client certificates cached for reuse. 0 disables the cache")
+
 	// SASL by Proxy
 	Server.Flags().BoolVar(&c.Kafka.SASL.Enable, "sasl-enable", false, "Connect using SASL")
 	Server.Flags().StringVar(&c.Kafka.SASL.Username, "sasl-username", "", "SASL user name")
 	Server.Flags().StringVar(&c.Kafka.SASL.Password, "sasl-password", os.Getenv("SASL_PASSWORD"), "SASL user password")
 	Server.Flags().StringVar(&c.Kafka.SASL.JaasConfigFile, "sasl-jaas-config-file", "", "Location of JAAS config file with SASL username and password")
 	Server.Flags().StringVar(&c.Kafka.SASL.Method, "sasl-method", "PLAIN", "SASL method to use (PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, GSSAPI, AWS_MSK_IAM")
+	Server.Flags().BoolVar(&c.Kafka.SASL.ReauthenticationEnable, "sasl-reauthentication-enable", false, "Re-authenticate broker connections before the SASL session expires (KIP-368). Requires Kafka 2.2+, supported for OAUTHBEARER plugin, AWS_MSK_IAM and token relay")
+
+	// SASL per-client broker credentials
+	Server.Flags().BoolVar(&c.Kafka.SASL.BrokerCredentials.Enable, "sasl-broker-credentials-enable", false, "Authenticate the broker connections with per-client SASL/PLAIN credentials after the local authentication of the client. Requires local authentication and SASL method PLAIN")
+	Server.Flags().StringVar(&c.Kafka.SASL.BrokerCredentials.Source, "sasl-broker-credentials-source", config.BrokerCredentialsSourceProvider, "Source of the broker credentials: provider maps the local principal with the provider command, client reuses the SASL/PLAIN credentials of the client")
+	Server.Flags().StringVar(&c.Kafka.SASL.BrokerCredentials.Command, "sasl-broker-credentials-command", "", "Built-in 'broker-credentials-file' or path to the broker credentials plugin binary")
+	Server.Flags().StringArrayVar(&c.Kafka.SASL.BrokerCredentials.Parameters, "sasl-broker-credentials-param", []string{}, "Broker credentials provider parameter")
+	Server.Flags().StringVar(&c.Kafka.SASL.BrokerCredentials.LogLevel, "sasl-broker-credentials-log-level", "trace", "Log level of the broker credentials plugin")
+	Server.Flags().DurationVar(&c.Kafka.SASL.BrokerCredentials.Timeout, "sasl-broker-credentials-timeout", 10*time.Second, "Broker credentials lookup timeout")
+	Server.Flags().BoolVar(&c.Kafka.SASL.BrokerCredentials.Fallback, "sasl-broker-credentials-fallback", false, "Authenticate unmapped clients with sasl-username and sasl-password instead of closing their connections")
+	Server.Flags().BoolVar(&c.Kafka.SASL.TokenRelay.Enable, "sasl-token-relay-enable", false, "Authenticate the broker connections with the OAUTHBEARER token of the client after its local authentication. Requires local OAUTHBEARER authentication")
+	Server.Flags().StringVar(&c.Kafka.SASL.TokenRelay.Mode, "sasl-token-relay-mode", config.TokenRelayModeRelay, "Token relay mode: relay sends the verified token of the client, exchange sends the token issued by the token exchange (RFC 8693)")
+	Server.Flags().StringVar(&c.Kafka.SASL.TokenRelay.Exchange.TokenURL, "sasl-token-exchange-url", "", "URL of the RFC 8693 token endpoint")
+	Server.Flags().StringVar(&c.Kafka.SASL.TokenRelay.Exchange.ClientID, "sasl-token-exchange-client-id", "", "Client ID used to authenticate at the token endpoint")
+	Server.Flags().StringVar(&c.Kafka.SASL.TokenRelay.Exchange.ClientSecret, "sasl-token-exchange-client-secret", "", "Client secret used to authenticate at the token endpoint")
+	Server.Flags().StringVar(&c.Kafka.SASL.TokenRelay.Exchange.ClientSecretFile, "sasl-token-exchange-client-secret-file", "", "Location of the file with the client secret")
+	Server.Flags().StringArrayVar(&c.Kafka.SASL.TokenRelay.Exchange.Audience, "sasl-token-exchange-audience", []string{}, "Audience of the exchanged token")
+	Server.Flags().StringArrayVar(&c.Kafka.SASL.TokenRelay.Exchange.Resource, "sasl-token-exchange-resource", []string{}, "Resource of the exchanged token")
+	Server.Flags().StringArrayVar(&c.Kafka.SASL.TokenRelay.Exchange.Scopes, "sasl-token-exchange-scope", []string{}, "Scope of the exchanged token")
+	Server.Flags().StringVar(&c.Kafka.SASL.TokenRelay.Exchange.SubjectTokenType, "sasl-token-exchange-subject-token-type", "urn:ietf:params:oauth:token-type:access_token", "Type of the client token sent to the token endpoint")
+	Server.Flags().StringVar(&c.Kafka.SASL.TokenRelay.Exchange.RequestedTokenType, "sasl-token-exchange-requested-token-type", "", "Requested type of the exchanged token. The token endpoint decides if empty")
+	Server.Flags().DurationVar(&c.Kafka.SASL.TokenRelay.Exchange.Timeout, "sasl-token-exchange-timeout", 10*time.Second, "Token exchange timeout")
+	Server.Flags().IntVar(&c.Kafka.SASL.TokenRelay.Exchange.CacheMaxEntries, "sasl-token-exchange-cache-max-entries", 10000, "Maximum number of exchanged tokens cached until they expire. 0 disables the cache")
 
 	// SASL GSSAPI
 	Server.Flags().StringVar(&c.Kafka.SASL.GSSAPI.AuthType, "gssapi-auth-type", config.KRB5_KEYTAB_AUTH, "GSSAPI auth type: KEYTAB or USER")
@@ -206,6 +296,11 @@ func initFlags() {
 	Server.Flags().StringVar(&c.Http.MetricsPath, "http-metrics-path", "/metrics", "Path on which to expose metrics")
 	Server.Flags().StringVar(&c.Http.HealthPath, "http-health-path", "/health", "Path on which to health endpoint")
 
+	// Admin
+	Server.Flags().BoolVar(&c.Admin.Enabled, "admin-enable", false, "Enable admin endpoints. They are not authenticated, the admin listen address must not be reachable by untrusted clients")
+	Server.Flags().StringVar(&c.Admin.ListenAddress, "admin-listen-address", "127.0.0.1:9081", "Admin listen address")
+	Server.Flags().StringVar(&c.Admin.AuthLockoutPath, "admin-auth-lockout-path", "/auth/lockouts", "Path on which to list (GET) and clear (DELETE with username or source query parameter) local authentication lockouts")
+
 	// Debug
 	Server.Flags().BoolVar(&c.Debug.Enabled, "debug-enable", false, "Enable Debug endpoint")
 	Server.Flags().StringVar(&c.Debug.ListenAddress, "debug-listen-address", "0.0.0.0:6060", "Debug listen address")
@@ -227,103 +322,20 @@ func initFlags() {
 func Run(_ *cobra.Command, _ []string) {
 	logrus.Infof("Starting kafka-proxy version %s on platform %s/%s", config.Version, runtime.GOOS, runtime.GOARCH)
 
-	var localPasswordAuthenticator apis.PasswordAuthenticator
-	var localTokenAuthenticator apis.TokenInfo
-	if c.Auth.Local.Enable {
-		switch c.Auth.Local.Mechanism {
-		case "PLAIN":
-			var err error
-			factory, ok := registry.GetComponent(new(apis.PasswordAuthenticatorFactory), c.Auth.Local.Command).(apis.PasswordAuthenticatorFactory)
-			if ok {
-				logrus.Infof("Using built-in '%s' PasswordAuthenticator for local PasswordAuthenticator", c.Auth.Local.Command)
-				localPasswordAuthenticator, err = factory.New(c.Auth.Local.Parameters)
-				if err != nil {
-					logrus.Fatal(err)
-				}
-			} else {
-				client := NewPluginClient(localauth.Handshake, localauth.PluginMap, c.Auth.Local.LogLevel, c.Auth.Local.Command, c.Auth.Local.Parameters)
-				defer client.Kill()
-
-				rpcClient, err := client.Client()
-				if err != nil {
-					logrus.Fatal(err)
-				}
-				raw, err := rpcClient.Dispense("passwordAuthenticator")
-				if err != nil {
-					logrus.Fatal(err)
-				}
-				localPasswordAuthenticator, ok = raw.(apis.PasswordAuthenticator)
-				if !ok {
-					logrus.Fatal(errors.New("unsupported PasswordAuthenticator plugin type"))
-				}
-			}
-		case "OAUTHBEARER":
-			var err error
-			factory, ok := registry.GetComponent(new(apis.TokenInfoFactory), c.Auth.Local.Command).(apis.TokenInfoFactory)
-			if ok {
-				logrus.Infof("Using built-in '%s' TokenInfo for local TokenAuthenticator", c.Auth.Local.Command)
-
-				localTokenAuthenticator, err = factory.New(c.Auth.Local.Parameters)
-				if err != nil {
-					logrus.Fatal(err)
-				}
-			} else {
-				client := NewPluginClient(tokeninfo.Handshake, tokeninfo.PluginMap, c.Auth.Local.LogLevel, c.Auth.Local.Command, c.Auth.Local.Parameters)
-				defer client.Kill()
-
-				rpcClient, err := client.Client()
-				if err != nil {
-					logrus.Fatal(err)
-				}
-				raw, err := rpcClient.Dispense("tokenInfo")
-				if err != nil {
-					logrus.Fatal(err)
-				}
-				localTokenAuthenticator, ok = raw.(apis.TokenInfo)
-				if !ok {
-					logrus.Fatal(errors.New("unsupported TokenInfo plugin type"))
-				}
-			}
-		default:
-			logrus.Fatal(errors.New("unsupported local auth mechanism"))
-		}
-	}
-
-	var saslTokenProvider apis.TokenProvider
-	if c.Kafka.SASL.Plugin.Enable {
-		switch c.Kafka.SASL.Plugin.Mechanism {
-		case "OAUTHBEARER":
-			var err error
-			factory, ok := registry.GetComponent(new(apis.TokenProviderFactory), c.Kafka.SASL.Plugin.Command).(apis.TokenProviderFactory)
-			if ok {
-				logrus.Infof("Using built-in '%s' TokenProvider for sasl authentication", c.Kafka.SASL.Plugin.Command)
-
-				saslTokenProvider, err = factory.New(c.Kafka.SASL.Plugin.Parameters)
-				if err != nil {
-					logrus.Fatal(err)
-				}
-			} else {
-				client := NewPluginClient(tokenprovider.Handshake, tokenprovider.PluginMap, c.Kafka.SASL.Plugin.LogLevel, c.Kafka.SASL.Plugin.Command, c.Kafka.SASL.Plugin.Parameters)
-				defer client.Kill()
-
-				rpcClient, err := client.Client()
-				if err != nil {
-					logrus.Fatal(err)
-				}
-				raw, err := rpcClient.Dispense("tokenProvider")
-				if err != nil {
-					logrus.Fatal(err)
-				}
-				saslTokenProvider, ok = raw.(apis.TokenProvider)
-				if !ok {
-					logrus.Fatal(errors.New("unsupported TokenProvider plugin type"))
-				}
-			}
-		default:
-			logrus.Fatal(errors.New("unsupported sasl auth mechanism"))
+	localAuthenticators, closeLocalAuth := newLocalAuthenticators(c.Auth.Local, c.Auth.Cache)
+	defer closeLocalAuth()
+
+	var authLockout *proxy.AuthLockout
+	if c.Auth.Lockout.Enable {
+		var err error
+		if authLockout, err = proxy.NewAuthLockout(c.Auth.Lockout); err != nil {
+			logrus.Fatal(err)
 		}
 	}
 
+	saslTokenProvider, closeSASLTokenProvider := newSASLTokenProvider(c.Kafka.SASL)
+	defer closeSASLTokenProvider()
+
 	var gatewayTokenProvider apis.TokenProvider
 	if c.Auth.Gateway.Client.Enable {
 		var err error
@@ -354,7 +366,11 @@ func Run(_ *cobra.Command, _ []string) {
 	}
 
 	var gatewayTokenInfo apis.TokenInfo
-	if c.Auth.Gateway.Server.Enable {
+	if c.Auth.Gateway.Server.Enable && c.Auth.Gateway.Server.Command == config.AuthChainCommand {
+		var closeChain func()
+		gatewayTokenInfo, closeChain = newTokenInfoChain("gateway-server", c.Auth.Gateway.Server.Parameters, c.Auth.Gateway.Server.LogLevel)
+		defer closeChain()
+	} else if c.Auth.Gateway.Server.Enable {
 		var err error
 		factory, ok := registry.GetComponent(new(apis.TokenInfoFactory), c.Auth.Gateway.Server.Command).(apis.TokenInfoFactory)
 		if ok {
@@ -382,12 +398,18 @@ func Run(_ *cobra.Command, _ []string) {
 			}
 		}
 	}
+	if c.Auth.Gateway.Server.Enable && c.Auth.Cache.Enabled() {
+		gatewayTokenInfo = proxy.NewCachingTokenInfo(gatewayTokenInfo, "gateway-server", c.Auth.Cache)
+	}
+
+	brokerCredentialsProvider, closeBrokerCredentials := newBrokerCredentialsProvider(c.Kafka.SASL)
+	defer closeBrokerCredentials()
 
 	var g run.Group
+	// All active connections are stored in this variable, one connection set per upstream cluster.
+	connsets := []*proxy.ConnSet{proxy.NewConnSet()}
 	{
-		// All active connections are stored in this variable.
-		connset := proxy.NewConnSet()
-		prometheus.MustRegister(proxy.NewCollector(connset))
+		connset := connsets[0]
 		listeners, err := proxy.NewListeners(c)
 		if err != nil {
 			logrus.Fatal(err)
@@ -396,10 +418,26 @@ func Run(_ *cobra.Command, _ []string) {
 		if err != nil {
 			logrus.Fatal(err)
 		}
-		proxyClient, err := proxy.NewClient(connset, c, listeners.GetNetAddressMapping, localPasswordAuthenticator, localTokenAuthenticator, saslTokenProvider, gatewayTokenProvider, gatewayTokenInfo)
+		proxyClient, err := proxy.NewClient(connset, c, listeners.GetNetAddressMapping, localAuthenticators, saslTokenProvider, gatewayTokenProvider, gatewayTokenInfo, authLockout, brokerCredentialsProvider)
 		if err != nil {
 			logrus.Fatal(err)
 		}
+		for _, profile := range c.Proxy.ListenerProfiles {
+			logrus.Infof("Starting listeners of listener profile '%s'", profile.Name)
+			profileListeners, err := listeners.NewProfileListeners(c, profile)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if _, err = profileListeners.ListenInstances(c.Proxy.BootstrapServers); err != nil {
+				logrus.Fatal(err)
+			}
+			profileLocalAuthenticators, closeProfileLocalAuth := newLocalAuthenticators(profile.LocalAuth, c.Auth.Cache)
+			defer closeProfileLocalAuth()
+
+			if err = proxyClient.AddListenerProfile(profile, profileListeners.GetNetAddressMapping, profileLocalAuthenticators); err != nil {
+				logrus.Fatal(err)
+			}
+		}
 		g.Add(func() error {
 			logrus.Print("Ready for new connections")
 			return proxyClient.Run(connSrc)
@@ -407,6 +445,35 @@ func Run(_ *cobra.Command, _ []string) {
 			proxyClient.Close()
 		})
 	}
+	for _, clusterConfig := range c.UpstreamClusters {
+		logrus.Infof("Starting listeners of upstream cluster '%s'", clusterConfig.Cluster)
+		connset := proxy.NewClusterConnSet(clusterConfig.Cluster)
+		connsets = append(connsets, connset)
+		listeners, err := proxy.NewListeners(clusterConfig)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		connSrc, err := listeners.ListenInstances(clusterConfig.Proxy.BootstrapServers)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		clusterSASLTokenProvider, closeClusterSASLTokenProvider := newSASLTokenProvider(clusterConfig.Kafka.SASL)
+		defer closeClusterSASLTokenProvider()
+		clusterBrokerCredentialsProvider, closeClusterBrokerCredentials := newBrokerCredentialsProvider(clusterConfig.Kafka.SASL)
+		defer closeClusterBrokerCredentials()
+
+		proxyClient, err := proxy.NewClient(connset, clusterConfig, listeners.GetNetAddressMapping, localAuthenticators, clusterSASLTokenProvider, gatewayTokenProvider, gatewayTokenInfo, authLockout, clusterBrokerCredentialsProvider)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		g.Add(func() error {
+			logrus.Printf("Upstream cluster '%s' ready for new connections", clusterConfig.Cluster)
+			return proxyClient.Run(connSrc)
+		}, func(error) {
+			proxyClient.Close()
+		})
+	}
+	prometheus.MustRegister(proxy.NewCollector(connsets...))
 	{
 		cancelInterrupt := make(chan struct{})
 		g.Add(func() error {
@@ -433,6 +500,17 @@ func Run(_ *cobra.Command, _ []string) {
 			httpListener.Close()
 		})
 	}
+	if c.Admin.Enabled {
+		adminListener, err := net.Listen("tcp", c.Admin.ListenAddress)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		g.Add(func() error {
+			return http.Serve(adminListener, NewAdminHTTPHandler(authLockout))
+		}, func(error) {
+			adminListener.Close()
+		})
+	}
 	if c.Debug.Enabled {
 		// https://golang.org/pkg/net/http/pprof/
 		// https://jvns.ca/blog/2017/09/24/profiling-go-with-pprof/
@@ -451,6 +529,294 @@ func Run(_ *cobra.Command, _ []string) {
 	logrus.Info("Exit ", err)
 }
 
+// newLocalAuthenticators creates authenticators of the local SASL mechanisms. The returned function stops the auth plugins.
+func newLocalAuthenticators(localAuth config.LocalAuthConfig, authCache config.AuthCacheConfig) (map[string]proxy.LocalSaslAuth, func()) {
+	localAuthenticators := make(map[string]proxy.LocalSaslAuth)
+	closeFuncs := make([]func(), 0)
+	closeFunc := func() {
+		for _, f := range closeFuncs {
+			f()
+		}
+	}
+	if !localAuth.Enable {
+		return localAuthenticators, closeFunc
+	}
+	for _, mechanism := range localAuth.GetMechanisms() {
+		localAuthenticator, closeMechanism := newLocalAuthenticator(mechanism, authCache)
+		closeFuncs = append(closeFuncs, closeMechanism)
+		localAuthenticators[mechanism.Mechanism] = localAuthenticator
+	}
+	return localAuthenticators, closeFunc
+}
+
+// newLocalAuthenticator creates the authenticator of the local SASL mechanism. PLAIN and OAUTHBEARER results are cached when
+// the auth cache is enabled. The returned function stops the auth plugin.
+func newLocalAuthenticator(localAuth config.LocalAuthMechanism, authCache config.AuthCacheConfig) (localAuthenticator proxy.LocalSaslAuth, closeFunc func()) {
+	closeFunc = func() {}
+	switch localAuth.Mechanism {
+	case "PLAIN":
+		var localPasswordAuthenticator apis.PasswordAuthenticator
+		if localAuth.Command == config.AuthChainCommand {
+			localPasswordAuthenticator, closeFunc = newPasswordAuthenticatorChain("local-plain", localAuth.Parameters, localAuth.LogLevel)
+		} else {
+			localPasswordAuthenticator, closeFunc = newPasswordAuthenticator(localAuth.Command, localAuth.Parameters, localAuth.LogLevel)
+		}
+		if authCache.Enabled() {
+			localPasswordAuthenticator = proxy.NewCachingPasswordAuthenticator(localPasswordAuthenticator, "local-plain", authCache)
+		}
+		localAuthenticator = proxy.NewLocalSaslPlain(localPasswordAuthenticator)
+	case "OAUTHBEARER":
+		var localTokenAuthenticator apis.TokenInfo
+		if localAuth.Command == config.AuthChainCommand {
+			localTokenAuthenticator, closeFunc = newTokenInfoChain("local-oauthbearer", localAuth.Parameters, localAuth.LogLevel)
+		} else {
+			localTokenAuthenticator, closeFunc = newTokenInfo(localAuth.Command, localAuth.Parameters, localAuth.LogLevel)
+		}
+		if authCache.Enabled() {
+			localTokenAuthenticator = proxy.NewCachingTokenInfo(localTokenAuthenticator, "local-oauthbearer", authCache)
+		}
+		localAuthenticator = proxy.NewLocalSaslOauth(localTokenAuthenticator)
+	case "SCRAM-SHA-256", "SCRAM-SHA-512":
+		var err error
+		var localScramCredentialStore apis.ScramCredentialStore
+		factory, ok := registry.GetComponent(new(apis.ScramCredentialStoreFactory), localAuth.Command).(apis.ScramCredentialStoreFactory)
+		if ok {
+			logrus.Infof("Using built-in '%s' ScramCredentialStore for local ScramCredentialStore", localAuth.Command)
+
+			localScramCredentialStore, err = factory.New(localAuth.Parameters)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+		} else {
+			client := NewPluginClient(scramcredentials.Handshake, scramcredentials.PluginMap, localAuth.LogLevel, localAuth.Command, localAuth.Parameters)
+			closeFunc = client.Kill
+
+			rpcClient, err := client.Client()
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			raw, err := rpcClient.Dispense("scramCredentialStore")
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			localScramCredentialStore, ok = raw.(apis.ScramCredentialStore)
+			if !ok {
+				logrus.Fatal(errors.New("unsupported ScramCredentialStore plugin type"))
+			}
+		}
+		localAuthenticator, err = proxy.NewLocalSaslScram(localAuth.Mechanism, localScramCredentialStore)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	case "GSSAPI":
+		var err error
+		logrus.Infof("Using built-in '%s' GSSAPI acceptor for local GSSAPI authentication", localAuth.Command)
+		localAuthenticator, err = newLocalSaslGSSAPI(localAuth.Parameters)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	default:
+		logrus.Fatal(errors.New("unsupported local auth mechanism"))
+	}
+	return localAuthenticator, closeFunc
+}
+
+// newSASLTokenProvider creates the built-in or plugin TokenProvider of the broker SASL authentication. The returned function stops the plugin.
+func newSASLTokenProvider(sasl config.KafkaSASLConfig) (apis.TokenProvider, func()) {
+	if !sasl.Plugin.Enable {
+		return nil, func() {}
+	}
+	if sasl.Plugin.Mechanism != "OAUTHBEARER" {
+		logrus.Fatal(errors.New("unsupported sasl auth mechanism"))
+	}
+	factory, ok := registry.GetComponent(new(apis.TokenProviderFactory), sasl.Plugin.Command).(apis.TokenProviderFactory)
+	if ok {
+		logrus.Infof("Using built-in '%s' TokenProvider for sasl authentication", sasl.Plugin.Command)
+
+		tokenProvider, err := factory.New(sasl.Plugin.Parameters)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		return tokenProvider, func() {}
+	}
+	client := NewPluginClient(tokenprovider.Handshake, tokenprovider.PluginMap, sasl.Plugin.LogLevel, sasl.Plugin.Command, sasl.Plugin.Parameters)
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	raw, err := rpcClient.Dispense("tokenProvider")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	tokenProvider, ok := raw.(apis.TokenProvider)
+	if !ok {
+		logrus.Fatal(errors.New("unsupported TokenProvider plugin type"))
+	}
+	return tokenProvider, client.Kill
+}
+
+// newBrokerCredentialsProvider creates the built-in or plugin BrokerCredentialsProvider of the per-client broker credentials, nil when the provider is not used.
+// The returned function stops the plugin.
+func newBrokerCredentialsProvider(sasl config.KafkaSASLConfig) (apis.BrokerCredentialsProvider, func()) {
+	brokerCredentials := sasl.BrokerCredentials
+	if !sasl.Enable || !brokerCredentials.Enable || brokerCredentials.Source != config.BrokerCredentialsSourceProvider {
+		return nil, func() {}
+	}
+	factory, ok := registry.GetComponent(new(apis.BrokerCredentialsProviderFactory), brokerCredentials.Command).(apis.BrokerCredentialsProviderFactory)
+	if ok {
+		logrus.Infof("Using built-in '%s' BrokerCredentialsProvider for broker credentials", brokerCredentials.Command)
+		provider, err := factory.New(brokerCredentials.Parameters)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		return provider, func() {}
+	}
+	client := NewPluginClient(brokercredentials.Handshake, brokercredentials.PluginMap, brokerCredentials.LogLevel, brokerCredentials.Command, brokerCredentials.Parameters)
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	raw, err := rpcClient.Dispense("brokerCredentialsProvider")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	provider, ok := raw.(apis.BrokerCredentialsProvider)
+	if !ok {
+		logrus.Fatal(errors.New("unsupported BrokerCredentialsProvider plugin type"))
+	}
+	return provider, client.Kill
+}
+
+// newPasswordAuthenticator creates the built-in or plugin PasswordAuthenticator. The returned function stops the auth plugin.
+func newPasswordAuthenticator(command string, parameters []string, logLevel string) (apis.PasswordAuthenticator, func()) {
+	factory, ok := registry.GetComponent(new(apis.PasswordAuthenticatorFactory), command).(apis.PasswordAuthenticatorFactory)
+	if ok {
+		logrus.Infof("Using built-in '%s' PasswordAuthenticator for local PasswordAuthenticator", command)
+		passwordAuthenticator, err := factory.New(parameters)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		return passwordAuthenticator, func() {}
+	}
+	client := NewVersionedPluginClient(localauth.Handshake, localauth.VersionedPlugins, logLevel, command, parameters)
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	raw, err := rpcClient.Dispense("passwordAuthenticator")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	passwordAuthenticator, ok := raw.(apis.PasswordAuthenticator)
+	if !ok {
+		logrus.Fatal(errors.New("unsupported PasswordAuthenticator plugin type"))
+	}
+	return passwordAuthenticator, client.Kill
+}
+
+// newTokenInfo creates the built-in or plugin TokenInfo. The returned function stops the auth plugin.
+func newTokenInfo(command string, parameters []string, logLevel string) (apis.TokenInfo, func()) {
+	factory, ok := registry.GetComponent(new(apis.TokenInfoFactory), command).(apis.TokenInfoFactory)
+	if ok {
+		logrus.Infof("Using built-in '%s' TokenInfo for local TokenAuthenticator", command)
+
+		tokenInfo, err := factory.New(parameters)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		return tokenInfo, func() {}
+	}
+	client := NewVersionedPluginClient(tokeninfo.Handshake, tokeninfo.VersionedPlugins, logLevel, command, parameters)
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	raw, err := rpcClient.Dispense("tokenInfo")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	tokenInfo, ok := raw.(apis.TokenInfo)
+	if !ok {
+		logrus.Fatal(errors.New("unsupported TokenInfo plugin type"))
+	}
+	return tokenInfo, client.Kill
+}
+
+// newPasswordAuthenticatorChain creates the PasswordAuthenticator trying the chain stages given by the parameters in order.
+// The returned function stops the auth plugins of the stages.
+func newPasswordAuthenticatorChain(name string, parameters []string, logLevel string) (apis.PasswordAuthenticator, func()) {
+	chain, err := config.ParseAuthChain(parameters, logLevel)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stages := make([]proxy.PasswordAuthenticatorStage, 0, len(chain.Stages))
+	closeFuncs := make([]func(), 0, len(chain.Stages))
+	for _, stage := range chain.Stages {
+		logrus.Infof("Adding stage '%s' with command '%s' to the '%s' authentication chain", stage.Name, stage.Command, name)
+		passwordAuthenticator, closeStage := newPasswordAuthenticator(stage.Command, stage.Parameters, stage.LogLevel)
+		closeFuncs = append(closeFuncs, closeStage)
+		stages = append(stages, proxy.PasswordAuthenticatorStage{Stage: stage, Authenticator: passwordAuthenticator})
+	}
+	return proxy.NewPasswordAuthenticatorChain(name, stages), func() {
+		for _, f := range closeFuncs {
+			f()
+		}
+	}
+}
+
+// newTokenInfoChain creates the TokenInfo trying the chain stages given by the parameters in order.
+// The returned function stops the auth plugins of the stages.
+func newTokenInfoChain(name string, parameters []string, logLevel string) (apis.TokenInfo, func()) {
+	chain, err := config.ParseAuthChain(parameters, logLevel)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	stages := make([]proxy.TokenInfoStage, 0, len(chain.Stages))
+	closeFuncs := make([]func(), 0, len(chain.Stages))
+	for _, stage := range chain.Stages {
+		logrus.Infof("Adding stage '%s' with command '%s' to the '%s' authentication chain", stage.Name, stage.Command, name)
+		tokenInfo, closeStage := newTokenInfo(stage.Command, stage.Parameters, stage.LogLevel)
+		closeFuncs = append(closeFuncs, closeStage)
+		stages = append(stages, proxy.TokenInfoStage{Stage: stage, TokenInfo: tokenInfo})
+	}
+	return proxy.NewTokenInfoChain(name, stages), func() {
+		for _, f := range closeFuncs {
+			f()
+		}
+	}
+}
+
+// newLocalSaslGSSAPI creates the keytab based GSSAPI acceptor from the parameters of the built-in gssapi-keytab command
+func newLocalSaslGSSAPI(params []string) (*proxy.LocalSaslGSSAPI, error) {
+	var keytabFile string
+	var principalToLocalRules util.ArrayFlags
+	opts := proxy.LocalSaslGSSAPIOptions{}
+
+	fs := flag.NewFlagSet("gssapi-keytab settings", flag.ContinueOnError)
+	fs.StringVar(&keytabFile, "keytab", "", "Path to the keytab file with the keys of the service principal")
+	fs.StringVar(&opts.ServicePrincipal, "service-principal", "", "Accept only tickets for the service principal e.g. kafka/proxy.example.com@EXAMPLE.COM. Any principal of the keytab is accepted when empty")
+	fs.StringVar(&opts.DefaultRealm, "default-realm", "", "Realm of the DEFAULT principal to local rule. The realm of the service principal is used when empty")
+	fs.Var(&principalToLocalRules, "principal-to-local-rule", "Rule mapping the client principal to a local name e.g. RULE:[1:$1@$0](.*@EXAMPLE.COM)s/@.*//. DEFAULT is used when not set")
+	fs.DurationVar(&opts.MaxClockSkew, "max-clock-skew", 5*time.Minute, "Maximum clock skew between the client and the proxy")
+	if err := fs.Parse(params); err != nil {
+		return nil, err
+	}
+	if keytabFile == "" {
+		return nil, errors.New("parameter --keytab is required by the gssapi-keytab command")
+	}
+	kt, err := keytab.Load(keytabFile)
+	if err != nil {
+		return nil, err
+	}
+	opts.Keytab = kt
+	opts.PrincipalToLocalRules = principalToLocalRules
+	return proxy.NewLocalSaslGSSAPI(opts)
+}
+
 func NewHTTPHandler() http.Handler {
 	m := http.NewServeMux()
 	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -473,6 +839,15 @@ func NewHTTPHandler() http.Handler {
 	return m
 }
 
+// NewAdminHTTPHandler serves the admin endpoints, which are not exposed on the metrics and health listener
+func NewAdminHTTPHandler(authLockout *proxy.AuthLockout) http.Handler {
+	m := http.NewServeMux()
+	if authLockout != nil {
+		m.Handle(c.Admin.AuthLockoutPath, authLockout)
+	}
+	return m
+}
+
 func SetLogger() {
 	if c.Log.Format == "json" {
 		formatter := &logrus.JSONFormatter{
@@ -515,6 +890,11 @@ func toSlogLevel(level logrus.Level) slog.Level {
 }
 
 func NewPluginClient(handshakeConfig plugin.HandshakeConfig, plugins map[string]plugin.Plugin, logLevel string, command string, params []string) *plugin.Client {
+	return NewVersionedPluginClient(handshakeConfig, map[int]plugin.PluginSet{int(handshakeConfig.ProtocolVersion): plugins}, logLevel, command, params)
+}
+
+// NewVersionedPluginClient creates the plugin client negotiating the highest protocol version supported by the plugin and the host
+func NewVersionedPluginClient(handshakeConfig plugin.HandshakeConfig, versionedPlugins map[int]plugin.PluginSet, logLevel string, command string, params []string) *plugin.Client {
 	jsonFormat := false
 	if c.Log.Format == "json" {
 		jsonFormat = true
@@ -528,10 +908,10 @@ func NewPluginClient(handshakeConfig plugin.HandshakeConfig, plugins map[string]
 	})
 
 	return plugin.NewClient(&plugin.ClientConfig{
-		HandshakeConfig: handshakeConfig,
-		Plugins:         plugins,
-		Logger:          logger,
-		Cmd:             exec.Command(command, params...),
+		HandshakeConfig:  handshakeConfig,
+		VersionedPlugins: versionedPlugins,
+		Logger:           logger,
+		Cmd:              exec.Command(command, params...),
 		AllowedProtocols: []plugin.Protocol{
 			plugin.ProtocolNetRPC, plugin.ProtocolGRPC},
 	})
@@ -14,6 +14,7 @@ func setupBootstrapServersMappingTest() {
 	_ = os.Setenv("BOOTSTRAP_SERVER_MAPPING", "")
 	_ = os.Setenv("EXTERNAL_SERVER_MAPPING", "")
 	_ = os.Setenv("DIAL_ADDRESS_MAPPING", "")
+	_ = os.Setenv("ADVERTISED_LISTENER_RULE", "")
 }
 
 func TestBootstrapServersMappingFromFlags(t *testing.T) {
@@ -228,6 +229,23 @@ func TestSameClientCertEnabledWithMissingFlags(t *testing.T) {
 	})
 }
 
+func TestProxyProtocolWithoutTrustedCIDRs(t *testing.T) {
+	args := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--proxy-protocol-enable",
+	}
+	serverPreRunFailure(t, args, "Proxy.ProxyProtocol.TrustedCIDRs must not be empty when Proxy.ProxyProtocol.Enable is enabled")
+}
+
+func TestAdminListenAddressOfHTTP(t *testing.T) {
+	args := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--admin-enable",
+		"--admin-listen-address", "0.0.0.0:9080",
+	}
+	serverPreRunFailure(t, args, "Admin.ListenAddress must differ from Http.ListenAddress")
+}
+
 func TestDynamicPortIntervals(t *testing.T) {
 
 	setupBootstrapServersMappingTest()
@@ -300,6 +318,154 @@ func TestDynamicPortIntervals(t *testing.T) {
 	a.Nil(err)
 }
 
+func TestListenerTLSModes(t *testing.T) {
+
+	unsupportedMode := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--proxy-listener-tls-mode", "auto",
+	}
+
+	bothWithoutTLS := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--proxy-listener-tls-mode-mapping", "0.0.0.0:32401=both",
+	}
+
+	t.Run("UnsupportedMode", func(t *testing.T) {
+		serverPreRunFailure(t, unsupportedMode, "Unsupported listener TLS mode 'auto', expected tls, plaintext or both")
+	})
+	t.Run("BothModeWithoutTLS", func(t *testing.T) {
+		serverPreRunFailure(t, bothWithoutTLS, "Proxy TLS must be enabled when listener TLS mode is 'both'")
+	})
+
+	setupBootstrapServersMappingTest()
+	args := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--bootstrap-server-mapping", "192.168.99.100:32402,0.0.0.0:32402",
+		"--proxy-listener-tls-enable", "",
+		"--proxy-listener-key-file", "server.pem",
+		"--proxy-listener-cert-file", "server.crt",
+		"--proxy-listener-tls-mode-mapping", "0.0.0.0:32401=both,0.0.0.0:32402=plaintext",
+	}
+	_ = Server.ParseFlags(args)
+	err := Server.PreRunE(nil, args)
+	a := assert.New(t)
+	a.Nil(err)
+	a.Equal(config.ListenerTLSModeBoth, c.GetListenerTLSMode("0.0.0.0:32401"))
+	a.Equal(config.ListenerTLSModePlaintext, c.GetListenerTLSMode("0.0.0.0:32402"))
+	a.Equal(config.ListenerTLSModeTLS, c.GetListenerTLSMode("0.0.0.0:32403"))
+}
+
+func TestAdvertisedListenerRules(t *testing.T) {
+
+	withoutMatch := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--advertised-listener-rule", "advertised=kafka-{{.brokerId}}.vpn.example.com",
+	}
+	invalidCIDR := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--advertised-listener-rule", "client-cidr=10.8.0.0,advertised=kafka-{{.brokerId}}.vpn.example.com",
+	}
+
+	t.Run("WithoutMatch", func(t *testing.T) {
+		serverPreRunFailure(t, withoutMatch, "Advertised listener rule 'kafka-{{.brokerId}}.vpn.example.com' must match listener, local-cidr or client-cidr")
+	})
+	t.Run("InvalidCIDR", func(t *testing.T) {
+		serverPreRunFailure(t, invalidCIDR, "invalid CIDR '10.8.0.0' in Proxy.AdvertisedListenerRules")
+	})
+
+	setupBootstrapServersMappingTest()
+	args := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--advertised-listener-rule", "client-cidr=10.8.0.0/16,advertised=kafka-{{.brokerId}}.vpn.example.com",
+		"--advertised-listener-rule", "listener=0.0.0.0:32401,local-cidr=172.16.0.0/12,advertised=kafka.peering.example.com:9093",
+	}
+	_ = Server.ParseFlags(args)
+	err := Server.PreRunE(nil, args)
+	a := assert.New(t)
+	a.Nil(err)
+	a.Equal([]config.AdvertisedListenerRule{
+		{ClientCIDR: "10.8.0.0/16", AdvertisedListener: "kafka-{{.brokerId}}.vpn.example.com"},
+		{ListenerAddress: "0.0.0.0:32401", LocalCIDR: "172.16.0.0/12", AdvertisedListener: "kafka.peering.example.com:9093"},
+	}, c.Proxy.AdvertisedListenerRules)
+}
+
+func TestLocalAuthMechanisms(t *testing.T) {
+
+	duplicatedMechanism := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--auth-local-enable",
+		"--auth-local-command", "build/auth-user",
+		"--auth-local-mechanism-command", "PLAIN=build/auth-ldap",
+	}
+	paramWithoutCommand := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--auth-local-enable",
+		"--auth-local-command", "build/auth-user",
+		"--auth-local-mechanism-param", "OAUTHBEARER=--claim-sub=alice",
+	}
+	unsupportedMechanism := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--auth-local-enable",
+		"--auth-local-mechanism-command", "SCRAM-SHA-1=build/auth-scram",
+	}
+	gssapiPluginCommand := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--auth-local-enable",
+		"--auth-local-mechanism-command", "GSSAPI=build/auth-krb5",
+	}
+
+	t.Run("DuplicatedMechanism", func(t *testing.T) {
+		serverPreRunFailure(t, duplicatedMechanism, "Local auth mechanism 'PLAIN' configured twice")
+	})
+	t.Run("ParamWithoutCommand", func(t *testing.T) {
+		serverPreRunFailure(t, paramWithoutCommand, "auth-local-mechanism-param 'OAUTHBEARER=--claim-sub=alice' is given for mechanism without auth-local-mechanism-command")
+	})
+	t.Run("UnsupportedMechanism", func(t *testing.T) {
+		serverPreRunFailure(t, unsupportedMechanism, "Unsupported local auth mechanism 'SCRAM-SHA-1', expected PLAIN, OAUTHBEARER, SCRAM-SHA-256, SCRAM-SHA-512 or GSSAPI")
+	})
+	t.Run("GSSAPIPluginCommand", func(t *testing.T) {
+		serverPreRunFailure(t, gssapiPluginCommand, "Local auth mechanism 'GSSAPI' supports only the built-in 'gssapi-keytab' command")
+	})
+
+	setupBootstrapServersMappingTest()
+	args := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--auth-local-enable",
+		"--auth-local-command", "build/auth-user",
+		"--auth-local-param", "--username=my-test-user",
+		"--auth-local-mechanism-command", "OAUTHBEARER=build/unsecured-jwt-info",
+		"--auth-local-mechanism-param", "OAUTHBEARER=--claim-sub=alice",
+		"--auth-local-mechanism-param", "OAUTHBEARER=--claim-sub=bob",
+		"--auth-local-mechanism-command", "SCRAM-SHA-512=scram-file",
+		"--auth-local-mechanism-command", "GSSAPI=gssapi-keytab",
+		"--auth-local-mechanism-param", "GSSAPI=--keytab=/etc/kafka-proxy/kafka.keytab",
+	}
+	_ = Server.ParseFlags(args)
+	err := Server.PreRunE(nil, args)
+	a := assert.New(t)
+	a.Nil(err)
+	a.Equal([]config.LocalAuthMechanism{
+		{Mechanism: "PLAIN", Command: "build/auth-user", Parameters: []string{"--username=my-test-user"}, LogLevel: "trace"},
+		{Mechanism: "OAUTHBEARER", Command: "build/unsecured-jwt-info", Parameters: []string{"--claim-sub=alice", "--claim-sub=bob"}, LogLevel: "trace"},
+		{Mechanism: "SCRAM-SHA-512", Command: "scram-file", Parameters: []string{}, LogLevel: "trace"},
+		{Mechanism: "GSSAPI", Command: "gssapi-keytab", Parameters: []string{"--keytab=/etc/kafka-proxy/kafka.keytab"}, LogLevel: "trace"},
+	}, c.Auth.Local.GetMechanisms())
+}
+
+func TestClientCertIssuerPrincipalRequiresClientCert(t *testing.T) {
+	args := []string{"cobra.test",
+		"--bootstrap-server-mapping", "192.168.99.100:32401,0.0.0.0:32401",
+		"--auth-local-enable",
+		"--auth-local-command", "build/unsecured-jwt-info",
+		"--tls-enable",
+		"--tls-client-cert-issuer-enable",
+		"--tls-client-cert-issuer-ca-cert-file", "intermediate-ca.crt",
+		"--tls-client-cert-issuer-ca-key-file", "intermediate-ca.pem",
+		"--tls-client-cert-issuer-subject-source", "sasl-principal",
+	}
+	serverPreRunFailure(t, args, "Kafka.TLS.ClientCertIssuer subject source sasl-principal requires Kafka.TLS.ClientCertFile")
+}
+
 func serverPreRunFailure(t *testing.T, cmdLineFlags []string, expectedErrorMsg string) {
 	setupBootstrapServersMappingTest()
 
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+const (
+	UserDNPlaceholder = "%d"
+
+	maxNestedGroupDepth = 10
+)
+
+// groupsEnabled reports whether the group membership is read from the user entry attribute or by the group search
+func (pa *LdapAuthenticator) groupsEnabled() bool {
+	return pa.GroupAttr != "" || pa.GroupFilter != ""
+}
+
+// getUserGroups returns the DNs of the groups of the user. With nested groups, the groups of the groups are resolved as well.
+func (pa *LdapAuthenticator) getUserGroups(conn *ldap.Conn, username, userDN string) ([]string, error) {
+	result := make([]string, 0)
+	visited := map[string]struct{}{strings.ToLower(userDN): {}}
+	members := []string{userDN}
+	for depth := 0; len(members) != 0 && depth < maxNestedGroupDepth; depth++ {
+		next := make([]string, 0)
+		for _, member := range members {
+			groups, err := pa.getMemberGroups(conn, username, member)
+			if err != nil {
+				return nil, err
+			}
+			for _, group := range groups {
+				key := strings.ToLower(group)
+				if _, ok := visited[key]; ok {
+					continue
+				}
+				visited[key] = struct{}{}
+				result = append(result, group)
+				next = append(next, group)
+			}
+		}
+		if !pa.NestedGroups {
+			break
+		}
+		members = next
+	}
+	return result, nil
+}
+
+// getMemberGroups returns the DNs of the groups which the user or group is a direct member of
+func (pa *LdapAuthenticator) getMemberGroups(conn *ldap.Conn, username, memberDN string) ([]string, error) {
+	if pa.GroupAttr != "" {
+		searchRequest := ldap.NewSearchRequest(memberDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false, "(objectClass=*)", []string{pa.GroupAttr}, nil)
+		sr, err := conn.Search(searchRequest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "LDAP read of %s attribute of %s failed", pa.GroupAttr, memberDN)
+		}
+		if len(sr.Entries) != 1 {
+			return nil, errors.Errorf("LDAP read of %s returned %d entries", memberDN, len(sr.Entries))
+		}
+		return sr.Entries[0].GetAttributeValues(pa.GroupAttr), nil
+	}
+	filter := strings.ReplaceAll(pa.GroupFilter, UsernamePlaceholder, ldap.EscapeFilter(username))
+	filter = strings.ReplaceAll(filter, UserDNPlaceholder, ldap.EscapeFilter(memberDN))
+	searchRequest := ldap.NewSearchRequest(pa.GroupSearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, filter, []string{"dn"}, nil)
+	sr, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "LDAP group search with base DN %s and filter %s failed", pa.GroupSearchBase, filter)
+	}
+	groups := make([]string, 0, len(sr.Entries))
+	for _, entry := range sr.Entries {
+		groups = append(groups, entry.DN)
+	}
+	return groups, nil
+}
+
+// groupName returns the value of the first RDN of the group DN e.g. kafka-users for cn=kafka-users,ou=groups,dc=example,dc=org
+func groupName(groupDN string) string {
+	dn, err := ldap.ParseDN(groupDN)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return groupDN
+	}
+	return dn.RDNs[0].Attributes[0].Value
+}
+
+// hasRequiredGroup reports whether one of the required groups matches the group name or DN, all users are accepted without required groups
+func (pa *LdapAuthenticator) hasRequiredGroup(groupDNs []string) bool {
+	if len(pa.RequiredGroups) == 0 {
+		return true
+	}
+	for _, groupDN := range groupDNs {
+		for _, required := range pa.RequiredGroups {
+			if strings.EqualFold(required, groupName(groupDN)) || strings.EqualFold(required, groupDN) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// testLDAPEntry is an entry of the test directory, attribute names are case-insensitive
+type testLDAPEntry struct {
+	dn         string
+	attributes map[string][]string
+}
+
+// testLDAPServer is an in-process LDAP server supporting simple bind and search with and, or, not, equality and presence filters
+type testLDAPServer struct {
+	listener    net.Listener
+	entries     []testLDAPEntry
+	connections int32
+	conns       []net.Conn
+	l           sync.Mutex
+}
+
+func newTestLDAPServer(t *testing.T, entries ...testLDAPEntry) *testLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testLDAPServer{listener: listener, entries: entries}
+	go s.serve()
+	t.Cleanup(s.close)
+	return s
+}
+
+func (s *testLDAPServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testLDAPServer) getConnections() int {
+	return int(atomic.LoadInt32(&s.connections))
+}
+
+// closeConnections closes the server side of the client connections
+func (s *testLDAPServer) closeConnections() {
+	s.l.Lock()
+	defer s.l.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *testLDAPServer) close() {
+	_ = s.listener.Close()
+	s.closeConnections()
+}
+
+func (s *testLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		atomic.AddInt32(&s.connections, 1)
+		s.l.Lock()
+		s.conns = append(s.conns, conn)
+		s.l.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *testLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			responses = append(responses, s.bind(op))
+		case ldap.ApplicationSearchRequest:
+			responses = s.search(op)
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			return
+		}
+		for _, response := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+			envelope.AppendChild(response)
+			if _, err = conn.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *testLDAPServer) bind(op *ber.Packet) *ber.Packet {
+	name := op.Children[1].Data.String()
+	password := op.Children[2].Data.String()
+	if name == "" && password == "" {
+		return ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess)
+	}
+	entry, ok := s.findEntry(name)
+	if !ok || password == "" || !contains(entry.values("userPassword"), password) {
+		return ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials)
+	}
+	return ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess)
+}
+
+func (s *testLDAPServer) search(op *ber.Packet) []*ber.Packet {
+	baseDN := op.Children[0].Data.String()
+	scope, _ := op.Children[1].Value.(int64)
+	filter := op.Children[6]
+	attributes := make([]string, 0)
+	for _, attribute := range op.Children[7].Children {
+		attributes = append(attributes, attribute.Data.String())
+	}
+	if baseDN == "" && scope == ldap.ScopeBaseObject {
+		// root DSE
+		return []*ber.Packet{searchResultEntry(testLDAPEntry{}, nil), ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess)}
+	}
+	if _, ok := s.findEntry(baseDN); !ok {
+		return []*ber.Packet{ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject)}
+	}
+	base := strings.ToLower(baseDN)
+	var responses []*ber.Packet
+	for _, entry := range s.entries {
+		dn := strings.ToLower(entry.dn)
+		var inScope bool
+		switch scope {
+		case ldap.ScopeBaseObject:
+			inScope = dn == base
+		case ldap.ScopeSingleLevel:
+			_, parent, _ := strings.Cut(dn, ",")
+			inScope = parent == base
+		default:
+			inScope = dn == base || strings.HasSuffix(dn, ","+base)
+		}
+		if inScope && entry.matches(filter) {
+			responses = append(responses, searchResultEntry(entry, attributes))
+		}
+	}
+	return append(responses, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+func (s *testLDAPServer) findEntry(dn string) (testLDAPEntry, bool) {
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.dn, dn) {
+			return entry, true
+		}
+	}
+	return testLDAPEntry{}, false
+}
+
+func (e testLDAPEntry) values(name string) []string {
+	for attribute, values := range e.attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func (e testLDAPEntry) matches(filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !e.matches(child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if e.matches(child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !e.matches(filter.Children[0])
+	case ldap.FilterEqualityMatch:
+		value := filter.Children[1].Data.String()
+		for _, v := range e.values(filter.Children[0].Data.String()) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		name := filter.Data.String()
+		return strings.EqualFold(name, "objectClass") || len(e.values(name)) != 0
+	default:
+		return false
+	}
+}
+
+func ldapResult(tag ber.Tag, resultCode int) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), "resultCode"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return response
+}
+
+// searchResultEntry returns the requested attributes, all attributes are returned when none are requested
+func searchResultEntry(entry testLDAPEntry, attributes []string) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+	partialAttributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range entry.attributes {
+		if len(attributes) != 0 && !containsFold(attributes, name) {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(set)
+		partialAttributes.AppendChild(attribute)
+	}
+	response.AppendChild(partialAttributes)
+	return response
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,28 +1,43 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/go-ldap/ldap/v3"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
 	"github.com/grepplabs/kafka-proxy/plugin/local-auth/shared"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-plugin"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"net"
-	"net/url"
-	"os"
-	"strings"
 )
 
 const UsernamePlaceholder = "%u"
 
+const (
+	// StatusOK is returned for authenticated users and invalid credentials
+	StatusOK               = 0
+	StatusUserDNError      = 1
+	StatusBindError        = 2
+	StatusGroupLookupError = 3
+	StatusNotGroupMember   = 4
+)
+
 type LdapAuthenticator struct {
 	Urls      []string
 	StartTLS  bool
 	TlsConfig *tls.Config
+	Timeout   time.Duration
 
 	UPNDomain string
 	UserDN    string
@@ -33,51 +48,124 @@ type LdapAuthenticator struct {
 	BindPassword   string
 	UserSearchBase string
 	UserFilter     string
+
+	GroupAttr       string
+	GroupSearchBase string
+	GroupFilter     string
+	NestedGroups    bool
+	RequiredGroups  []string
+
+	pool *connPool
+}
+
+func (pa *LdapAuthenticator) Authenticate(username, password string) (bool, int32, error) {
+	resp, err := pa.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: username, Password: password})
+	return resp.Authenticated, resp.Status, err
 }
 
-func (pa LdapAuthenticator) Authenticate(username, password string) (bool, int32, error) {
-	// logrus.Printf("Authenticate request for %s:%s,expected %s:%s ", username, password, pa.username, pa.password)
-	l, err := pa.DialLDAP()
+// Implements apis.PasswordAuthenticatorV2
+func (pa *LdapAuthenticator) AuthenticatePrincipal(_ context.Context, request apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	l, pooled, err := pa.getConn()
 	if err != nil {
-		logrus.Errorf("user %s ldap dial error %v", username, err)
-		return false, 1, nil
+		logrus.Errorf("user %s ldap dial error %v", request.Username, err)
+		return apis.AuthenticateResponse{Status: StatusUserDNError}, nil
 	}
-	if l == nil {
-		logrus.Errorf("ldap connection is nil")
-		return false, 1, nil
+	resp, err := pa.authenticate(l, request.Username, request.Password)
+	broken := isBrokenConn(l, err)
+	pa.putConn(l, err)
+	if pooled && broken {
+		// the pooled connection could be closed by the server, retry with a new connection
+		l, err = pa.DialLDAP()
+		if err != nil {
+			logrus.Errorf("user %s ldap dial error %v", request.Username, err)
+			return apis.AuthenticateResponse{Status: StatusUserDNError}, nil
+		}
+		resp, err = pa.authenticate(l, request.Username, request.Password)
+		pa.putConn(l, err)
+	}
+	return resp, nil
+}
+
+func (pa *LdapAuthenticator) getConn() (*ldap.Conn, bool, error) {
+	if pa.pool == nil {
+		conn, err := pa.DialLDAP()
+		return conn, false, err
 	}
-	defer l.Close()
+	return pa.pool.get()
+}
 
+func (pa *LdapAuthenticator) putConn(conn *ldap.Conn, err error) {
+	if pa.pool == nil {
+		conn.Close()
+		return
+	}
+	pa.pool.put(conn, err)
+}
+
+// authenticate binds as the user and checks the group membership. The returned error is set on LDAP errors to discard broken connections.
+func (pa *LdapAuthenticator) authenticate(l *ldap.Conn, username, password string) (apis.AuthenticateResponse, error) {
 	bindDN, err := pa.getUserBindDN(l, username)
 	if err != nil {
 		logrus.Errorf("user %s ldap get user bindDN error %v", username, err)
-		return false, 1, nil
+		return apis.AuthenticateResponse{Status: StatusUserDNError}, err
 	}
 	err = l.Bind(bindDN, password)
 	if err != nil {
 		if ldapErr, ok := err.(*ldap.Error); ok && ldapErr.ResultCode == ldap.LDAPResultInvalidCredentials {
 			logrus.Errorf("user %s credentials are invalid", username)
-			return false, 0, nil
+			return apis.AuthenticateResponse{Status: StatusOK}, nil
 		}
 		logrus.Errorf("user %s ldap bind error %v", username, err)
-		return false, 2, nil
+		return apis.AuthenticateResponse{Status: StatusBindError}, err
+	}
+	principal := apis.Principal{Name: username, Attributes: map[string]string{"dn": bindDN}}
+	if !pa.groupsEnabled() {
+		return apis.AuthenticateResponse{Authenticated: true, Status: StatusOK, Principal: principal}, nil
+	}
+	groupDNs, err := pa.getGroups(l, username, bindDN)
+	if err != nil {
+		logrus.Errorf("user %s ldap group lookup error %v", username, err)
+		return apis.AuthenticateResponse{Status: StatusGroupLookupError}, err
 	}
-	return true, 0, nil
+	if !pa.hasRequiredGroup(groupDNs) {
+		logrus.Errorf("user %s is not a member of the required groups %v", username, pa.RequiredGroups)
+		return apis.AuthenticateResponse{Status: StatusNotGroupMember}, nil
+	}
+	for _, groupDN := range groupDNs {
+		principal.Groups = append(principal.Groups, groupName(groupDN))
+	}
+	return apis.AuthenticateResponse{Authenticated: true, Status: StatusOK, Principal: principal}, nil
 }
 
-func (pa LdapAuthenticator) getUserBindDN(conn *ldap.Conn, username string) (string, error) {
+// getGroups looks up the groups as the service user when bind-dn is set, otherwise as the authenticated user
+func (pa *LdapAuthenticator) getGroups(conn *ldap.Conn, username, userDN string) ([]string, error) {
+	if pa.BindDN != "" {
+		if err := pa.serviceBind(conn); err != nil {
+			return nil, err
+		}
+	}
+	return pa.getUserGroups(conn, username, userDN)
+}
+
+// serviceBind binds as the service user. Without bind-dn, the pooled connection is reset to anonymous.
+func (pa *LdapAuthenticator) serviceBind(conn *ldap.Conn) error {
+	var err error
+	if pa.BindDN != "" && pa.BindPassword != "" {
+		err = conn.Bind(pa.BindDN, pa.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind(pa.BindDN)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "LDAP bind (service) failed")
+	}
+	return nil
+}
+
+func (pa *LdapAuthenticator) getUserBindDN(conn *ldap.Conn, username string) (string, error) {
 	bindDN := ""
 	if pa.SearchLDAP {
-		var err error
-		if pa.BindDN != "" {
-			if pa.BindPassword != "" {
-				err = conn.Bind(pa.BindDN, pa.BindPassword)
-			} else {
-				err = conn.UnauthenticatedBind(pa.BindDN)
-			}
-			if err != nil {
-				return "", errors.Wrapf(err, "LDAP bind (service) failed")
-			}
+		if err := pa.serviceBind(conn); err != nil {
+			return "", err
 		}
 		filter := strings.ReplaceAll(pa.UserFilter, UsernamePlaceholder, username)
 		searchRequest := ldap.NewSearchRequest(
@@ -145,7 +233,7 @@ func escapeLDAPValue(input string) string {
 	}
 	return input
 }
-func (pa LdapAuthenticator) DialLDAP() (*ldap.Conn, error) {
+func (pa *LdapAuthenticator) DialLDAP() (*ldap.Conn, error) {
 	var retErr *multierror.Error
 	var conn *ldap.Conn
 	for _, uut := range pa.Urls {
@@ -184,9 +272,16 @@ func (pa LdapAuthenticator) DialLDAP() (*ldap.Conn, error) {
 			continue
 		}
 		if err == nil {
+			if pa.Timeout > 0 {
+				conn.SetTimeout(pa.Timeout)
+			}
 			retErr = nil
 			break
 		}
+		if conn != nil {
+			conn.Close()
+			conn = nil
+		}
 		retErr = multierror.Append(retErr, fmt.Errorf("error connecting to host %q: %s", uut, err.Error()))
 	}
 	return conn, retErr.ErrorOrNil()
@@ -206,6 +301,16 @@ type pluginMeta struct {
 	bindPassword   string
 	userSearchBase string
 	userFilter     string
+
+	groupAttr       string
+	groupSearchBase string
+	groupFilter     string
+	nestedGroups    bool
+	requiredGroup   util.ArrayFlags
+
+	timeout                 time.Duration
+	poolSize                int
+	poolHealthCheckInterval time.Duration
 }
 
 func (f *pluginMeta) flagSet() *flag.FlagSet {
@@ -225,6 +330,16 @@ func (f *pluginMeta) flagSet() *flag.FlagSet {
 	fs.StringVar(&f.userSearchBase, "user-search-base", "", "The search base as the starting point for the user search e.g. ou=people,dc=example,dc=org")
 	fs.StringVar(&f.userFilter, "user-filter", "", fmt.Sprintf("The user search filter. It must contain '%s' placeholder for the username e.g. (&(objectClass=person)(uid=%s)(memberOf=cn=kafka-users,ou=realm-roles,dc=example,dc=org))", UsernamePlaceholder, UsernamePlaceholder))
 
+	fs.StringVar(&f.groupAttr, "group-attr", "", "The user entry attribute with the DNs of the user groups e.g. memberOf")
+	fs.StringVar(&f.groupSearchBase, "group-search-base", "", "The search base as the starting point for the group search e.g. ou=groups,dc=example,dc=org")
+	fs.StringVar(&f.groupFilter, "group-filter", "", fmt.Sprintf("The group search filter. The '%s' placeholder is replaced by the member DN and '%s' by the username e.g. (&(objectClass=groupOfUniqueNames)(uniqueMember=%s))", UserDNPlaceholder, UsernamePlaceholder, UserDNPlaceholder))
+	fs.BoolVar(&f.nestedGroups, "nested-groups", false, "Resolve the groups of the groups")
+	fs.Var(&f.requiredGroup, "required-group", "Name or DN of the group the user must be a member of. The user must be a member of one of the groups when set multiple times")
+
+	fs.DurationVar(&f.timeout, "timeout", 10*time.Second, "Timeout of the LDAP requests")
+	fs.IntVar(&f.poolSize, "pool-size", 4, "Maximum number of idle LDAP connections kept for reuse. 0 disables the pooling")
+	fs.DurationVar(&f.poolHealthCheckInterval, "pool-health-check-interval", 30*time.Second, "Health check interval of the idle LDAP connections. 0 disables the health check")
+
 	return fs
 }
 
@@ -287,28 +402,55 @@ func main() {
 		os.Exit(1)
 	}
 
+	if pluginMeta.groupAttr != "" && pluginMeta.groupFilter != "" {
+		logrus.Errorf("parameters group-attr and group-filter are mutually exclusive")
+		os.Exit(1)
+	}
+	if pluginMeta.groupFilter != "" && pluginMeta.groupSearchBase == "" {
+		logrus.Errorf("parameter group-search-base is required")
+		os.Exit(1)
+	}
+	if len(pluginMeta.requiredGroup) != 0 && pluginMeta.groupAttr == "" && pluginMeta.groupFilter == "" {
+		logrus.Errorf("parameter group-attr or group-filter is required")
+		os.Exit(1)
+	}
+
 	tlsConfig, err := getTlsConfig(pluginMeta.caCertFile, pluginMeta.insecureSkipVerify)
 	if err != nil {
 		logrus.Errorf("error %v getting TLS config", err)
 		os.Exit(1)
 	}
 
+	ldapAuthenticator := &LdapAuthenticator{
+		Urls:            urls,
+		TlsConfig:       tlsConfig,
+		StartTLS:        pluginMeta.startTLS,
+		Timeout:         pluginMeta.timeout,
+		UPNDomain:       pluginMeta.upnDomain,
+		UserDN:          pluginMeta.userDN,
+		UserAttr:        pluginMeta.userAttr,
+		SearchLDAP:      pluginMeta.searchLDAP || pluginMeta.bindDN != "",
+		BindDN:          pluginMeta.bindDN,
+		BindPassword:    pluginMeta.bindPassword,
+		UserSearchBase:  pluginMeta.userSearchBase,
+		UserFilter:      pluginMeta.userFilter,
+		GroupAttr:       pluginMeta.groupAttr,
+		GroupSearchBase: pluginMeta.groupSearchBase,
+		GroupFilter:     pluginMeta.groupFilter,
+		NestedGroups:    pluginMeta.nestedGroups,
+		RequiredGroups:  pluginMeta.requiredGroup,
+	}
+	if pluginMeta.poolSize > 0 {
+		ldapAuthenticator.pool = newConnPool(ldapAuthenticator.DialLDAP, pluginMeta.poolSize, pluginMeta.poolHealthCheckInterval)
+		defer ldapAuthenticator.pool.close()
+	}
+
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: shared.Handshake,
-		Plugins: map[string]plugin.Plugin{
-			"passwordAuthenticator": &shared.PasswordAuthenticatorPlugin{Impl: &LdapAuthenticator{
-				Urls:           urls,
-				TlsConfig:      tlsConfig,
-				StartTLS:       pluginMeta.startTLS,
-				UPNDomain:      pluginMeta.upnDomain,
-				UserDN:         pluginMeta.userDN,
-				UserAttr:       pluginMeta.userAttr,
-				SearchLDAP:     pluginMeta.searchLDAP || pluginMeta.bindDN != "",
-				BindDN:         pluginMeta.bindDN,
-				BindPassword:   pluginMeta.bindPassword,
-				UserSearchBase: pluginMeta.userSearchBase,
-				UserFilter:     pluginMeta.userFilter,
-			}},
+		// version 2 returns the groups of the user
+		VersionedPlugins: map[int]plugin.PluginSet{
+			1: {"passwordAuthenticator": &shared.PasswordAuthenticatorPlugin{Impl: ldapAuthenticator}},
+			2: {"passwordAuthenticator": &shared.PasswordAuthenticatorV2Plugin{Impl: ldapAuthenticator}},
 		},
 		// A non-nil value here enables gRPC serving for this plugin...
 		GRPCServer: plugin.DefaultGRPCServer,
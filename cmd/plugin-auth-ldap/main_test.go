@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDirectory(t *testing.T) *testLDAPServer {
+	return newTestLDAPServer(t,
+		testLDAPEntry{dn: "dc=example,dc=org", attributes: map[string][]string{"objectClass": {"domain"}}},
+		testLDAPEntry{dn: "cn=admin,dc=example,dc=org", attributes: map[string][]string{"objectClass": {"person"}, "userPassword": {"admin"}}},
+		testLDAPEntry{dn: "ou=people,dc=example,dc=org", attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		testLDAPEntry{dn: "uid=jbrown,ou=people,dc=example,dc=org", attributes: map[string][]string{
+			"objectClass":  {"person"},
+			"uid":          {"jbrown"},
+			"userPassword": {"password1"},
+			"memberOf":     {"cn=kafka-users,ou=groups,dc=example,dc=org"},
+		}},
+		testLDAPEntry{dn: "uid=bwilson,ou=people,dc=example,dc=org", attributes: map[string][]string{
+			"objectClass":  {"person"},
+			"uid":          {"bwilson"},
+			"userPassword": {"password2"},
+			"memberOf":     {"cn=ldap-users,ou=groups,dc=example,dc=org"},
+		}},
+		testLDAPEntry{dn: "ou=groups,dc=example,dc=org", attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		testLDAPEntry{dn: "cn=kafka-users,ou=groups,dc=example,dc=org", attributes: map[string][]string{
+			"objectClass":  {"groupOfUniqueNames"},
+			"uniqueMember": {"uid=jbrown,ou=people,dc=example,dc=org"},
+			"memberOf":     {"cn=all-users,ou=groups,dc=example,dc=org"},
+		}},
+		testLDAPEntry{dn: "cn=ldap-users,ou=groups,dc=example,dc=org", attributes: map[string][]string{
+			"objectClass":  {"groupOfUniqueNames"},
+			"uniqueMember": {"uid=bwilson,ou=people,dc=example,dc=org"},
+		}},
+		testLDAPEntry{dn: "cn=all-users,ou=groups,dc=example,dc=org", attributes: map[string][]string{
+			"objectClass":  {"groupOfUniqueNames"},
+			"uniqueMember": {"cn=kafka-users,ou=groups,dc=example,dc=org"},
+		}},
+	)
+}
+
+func TestAuthenticateUserDN(t *testing.T) {
+	server := newTestDirectory(t)
+	authenticator := &LdapAuthenticator{Urls: []string{server.url()}, UserDN: "ou=people,dc=example,dc=org", UserAttr: "uid", Timeout: 5 * time.Second}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		expected apis.AuthenticateResponse
+	}{
+		{
+			name:     "authenticated",
+			username: "jbrown",
+			password: "password1",
+			expected: apis.AuthenticateResponse{Authenticated: true, Status: StatusOK, Principal: apis.Principal{Name: "jbrown", Attributes: map[string]string{"dn": "uid=jbrown,ou=people,dc=example,dc=org"}}},
+		},
+		{
+			name:     "wrong password",
+			username: "jbrown",
+			password: "password2",
+			expected: apis.AuthenticateResponse{Authenticated: false, Status: StatusOK},
+		},
+		{
+			name:     "unknown user",
+			username: "unknown",
+			password: "password1",
+			expected: apis.AuthenticateResponse{Authenticated: false, Status: StatusOK},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			resp, err := authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: tc.username, Password: tc.password})
+			a.Nil(err)
+			a.Equal(tc.expected, resp)
+		})
+	}
+}
+
+func TestAuthenticateGroups(t *testing.T) {
+	server := newTestDirectory(t)
+
+	groupSearch := func(pa *LdapAuthenticator) {
+		pa.GroupSearchBase = "ou=groups,dc=example,dc=org"
+		pa.GroupFilter = "(&(objectClass=groupOfUniqueNames)(uniqueMember=%d))"
+	}
+	tests := []struct {
+		name          string
+		configure     func(pa *LdapAuthenticator)
+		username      string
+		password      string
+		authenticated bool
+		status        int32
+		groups        []string
+	}{
+		{
+			name:          "memberOf attribute",
+			configure:     func(pa *LdapAuthenticator) { pa.GroupAttr = "memberOf" },
+			username:      "jbrown",
+			password:      "password1",
+			authenticated: true,
+			groups:        []string{"kafka-users"},
+		},
+		{
+			name:          "nested memberOf attribute",
+			configure:     func(pa *LdapAuthenticator) { pa.GroupAttr = "memberOf"; pa.NestedGroups = true },
+			username:      "jbrown",
+			password:      "password1",
+			authenticated: true,
+			groups:        []string{"kafka-users", "all-users"},
+		},
+		{
+			name:          "group search",
+			configure:     groupSearch,
+			username:      "jbrown",
+			password:      "password1",
+			authenticated: true,
+			groups:        []string{"kafka-users"},
+		},
+		{
+			name:          "nested group search",
+			configure:     func(pa *LdapAuthenticator) { groupSearch(pa); pa.NestedGroups = true },
+			username:      "jbrown",
+			password:      "password1",
+			authenticated: true,
+			groups:        []string{"kafka-users", "all-users"},
+		},
+		{
+			name: "required group name",
+			configure: func(pa *LdapAuthenticator) {
+				groupSearch(pa)
+				pa.RequiredGroups = []string{"admins", "kafka-users"}
+			},
+			username:      "jbrown",
+			password:      "password1",
+			authenticated: true,
+			groups:        []string{"kafka-users"},
+		},
+		{
+			name: "required nested group DN",
+			configure: func(pa *LdapAuthenticator) {
+				groupSearch(pa)
+				pa.NestedGroups = true
+				pa.RequiredGroups = []string{"cn=all-users,ou=groups,dc=example,dc=org"}
+			},
+			username:      "jbrown",
+			password:      "password1",
+			authenticated: true,
+			groups:        []string{"kafka-users", "all-users"},
+		},
+		{
+			name: "not a member of required group",
+			configure: func(pa *LdapAuthenticator) {
+				pa.GroupAttr = "memberOf"
+				pa.RequiredGroups = []string{"kafka-users"}
+			},
+			username: "bwilson",
+			password: "password2",
+			status:   StatusNotGroupMember,
+		},
+		{
+			name:      "wrong password",
+			configure: groupSearch,
+			username:  "jbrown",
+			password:  "password2",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			authenticator := &LdapAuthenticator{Urls: []string{server.url()}, UserDN: "ou=people,dc=example,dc=org", UserAttr: "uid", Timeout: 5 * time.Second}
+			tc.configure(authenticator)
+
+			resp, err := authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: tc.username, Password: tc.password})
+			a.Nil(err)
+			a.Equal(tc.authenticated, resp.Authenticated)
+			a.Equal(tc.status, resp.Status)
+			a.Equal(tc.groups, resp.Principal.Groups)
+		})
+	}
+}
+
+func TestAuthenticateUserSearch(t *testing.T) {
+	a := assert.New(t)
+
+	server := newTestDirectory(t)
+	authenticator := &LdapAuthenticator{
+		Urls:            []string{server.url()},
+		Timeout:         5 * time.Second,
+		SearchLDAP:      true,
+		BindDN:          "cn=admin,dc=example,dc=org",
+		BindPassword:    "admin",
+		UserSearchBase:  "ou=people,dc=example,dc=org",
+		UserFilter:      "(&(objectClass=person)(uid=%u))",
+		GroupSearchBase: "ou=groups,dc=example,dc=org",
+		GroupFilter:     "(uniqueMember=%d)",
+		RequiredGroups:  []string{"ldap-users"},
+	}
+	ok, status, err := authenticator.Authenticate("bwilson", "password2")
+	a.Nil(err)
+	a.True(ok)
+	a.Equal(int32(StatusOK), status)
+
+	ok, status, err = authenticator.Authenticate("jbrown", "password1")
+	a.Nil(err)
+	a.False(ok)
+	a.Equal(int32(StatusNotGroupMember), status)
+
+	ok, status, err = authenticator.Authenticate("unknown", "password1")
+	a.Nil(err)
+	a.False(ok)
+	a.Equal(int32(StatusUserDNError), status)
+
+	authenticator.BindPassword = "wrong"
+	ok, status, err = authenticator.Authenticate("bwilson", "password2")
+	a.Nil(err)
+	a.False(ok)
+	a.Equal(int32(StatusUserDNError), status)
+}
+
+func TestAuthenticateConnectionPool(t *testing.T) {
+	a := assert.New(t)
+
+	server := newTestDirectory(t)
+	authenticator := &LdapAuthenticator{
+		Urls:         []string{"ldap://127.0.0.1:1", server.url()},
+		Timeout:      5 * time.Second,
+		SearchLDAP:   true,
+		UserDN:       "ou=people,dc=example,dc=org",
+		UserAttr:     "uid",
+		GroupAttr:    "memberOf",
+		NestedGroups: true,
+	}
+	authenticator.UserSearchBase = "ou=people,dc=example,dc=org"
+	authenticator.UserFilter = "(uid=%u)"
+	authenticator.pool = newConnPool(authenticator.DialLDAP, 2, 0)
+	defer authenticator.pool.close()
+
+	// connections are reused, the pooled connection bound as the previous user is reset to anonymous for the user search
+	for _, credentials := range [][2]string{{"jbrown", "password1"}, {"bwilson", "password2"}, {"jbrown", "wrong"}, {"jbrown", "password1"}} {
+		ok, _, err := authenticator.Authenticate(credentials[0], credentials[1])
+		a.Nil(err)
+		a.Equal(credentials[1] != "wrong", ok)
+	}
+	a.Equal(1, server.getConnections())
+	a.Len(authenticator.pool.idle, 1)
+
+	// connections closed by the server are dropped by the health check
+	server.closeConnections()
+	authenticator.pool.healthCheck()
+	a.Len(authenticator.pool.idle, 0)
+
+	ok, _, err := authenticator.Authenticate("jbrown", "password1")
+	a.Nil(err)
+	a.True(ok)
+	a.Equal(2, server.getConnections())
+
+	// broken pooled connections are replaced
+	server.closeConnections()
+	ok, _, err = authenticator.Authenticate("jbrown", "password1")
+	a.Nil(err)
+	a.True(ok)
+	a.Equal(3, server.getConnections())
+}
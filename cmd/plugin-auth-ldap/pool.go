@@ -0,0 +1,112 @@
+package main
+
+import (
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// connPool keeps idle LDAP connections for reuse. Connections are dialed by the DialLDAP function which tries the configured urls in order.
+// Idle connections are health-checked periodically and dropped when closed by the server or on network errors.
+type connPool struct {
+	dial                func() (*ldap.Conn, error)
+	idle                chan *ldap.Conn
+	healthCheckInterval time.Duration
+	done                chan struct{}
+}
+
+func newConnPool(dial func() (*ldap.Conn, error), size int, healthCheckInterval time.Duration) *connPool {
+	pool := &connPool{
+		dial:                dial,
+		idle:                make(chan *ldap.Conn, size),
+		healthCheckInterval: healthCheckInterval,
+		done:                make(chan struct{}),
+	}
+	if size > 0 && healthCheckInterval > 0 {
+		go pool.healthCheckLoop()
+	}
+	return pool
+}
+
+// get returns an idle connection or dials a new one. The returned flag reports whether the connection was pooled.
+func (p *connPool) get() (*ldap.Conn, bool, error) {
+	for {
+		select {
+		case conn := <-p.idle:
+			if conn.IsClosing() {
+				conn.Close()
+				continue
+			}
+			return conn, true, nil
+		default:
+			conn, err := p.dial()
+			return conn, false, err
+		}
+	}
+}
+
+// put returns the connection to the pool, connections with network errors are closed
+func (p *connPool) put(conn *ldap.Conn, err error) {
+	if conn == nil {
+		return
+	}
+	if isBrokenConn(conn, err) || conn.IsClosing() {
+		conn.Close()
+		return
+	}
+	select {
+	case p.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (p *connPool) close() {
+	close(p.done)
+	for {
+		select {
+		case conn := <-p.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (p *connPool) healthCheckLoop() {
+	ticker := time.NewTicker(p.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.healthCheck()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// healthCheck reads the root DSE with each idle connection
+func (p *connPool) healthCheck() {
+	for n := len(p.idle); n > 0; n-- {
+		select {
+		case conn := <-p.idle:
+			_, err := conn.Search(ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false, "(objectClass=*)", []string{"1.1"}, nil))
+			if err != nil {
+				logrus.Warnf("ldap connection health check failed: %v", err)
+				conn.Close()
+				continue
+			}
+			p.put(conn, nil)
+		default:
+			return
+		}
+	}
+}
+
+// isBrokenConn reports whether the request failed because of the connection, the connection is closing after read errors
+func isBrokenConn(conn *ldap.Conn, err error) bool {
+	return err != nil && (conn.IsClosing() || ldap.IsErrorWithCode(errors.Cause(err), ldap.ErrorNetwork))
+}
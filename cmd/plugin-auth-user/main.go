@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
 	"github.com/grepplabs/kafka-proxy/plugin/local-auth/shared"
 	"github.com/hashicorp/go-plugin"
 	"github.com/sirupsen/logrus"
@@ -21,6 +23,15 @@ func (pa PasswordAuthenticator) Authenticate(username, password string) (bool, i
 	return username == pa.Username && password == pa.Password, 0, nil
 }
 
+// Implements apis.PasswordAuthenticatorV2
+func (pa PasswordAuthenticator) AuthenticatePrincipal(_ context.Context, request apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	ok, status, err := pa.Authenticate(request.Username, request.Password)
+	if !ok || err != nil {
+		return apis.AuthenticateResponse{Authenticated: false, Status: status}, err
+	}
+	return apis.AuthenticateResponse{Authenticated: true, Status: status, Principal: apis.Principal{Name: request.Username}}, nil
+}
+
 func (f *PasswordAuthenticator) flagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet("auth plugin settings", flag.ContinueOnError)
 	fs.StringVar(&f.Username, "username", "", "Expected SASL username")
@@ -47,8 +58,10 @@ func main() {
 
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: shared.Handshake,
-		Plugins: map[string]plugin.Plugin{
-			"passwordAuthenticator": &shared.PasswordAuthenticatorPlugin{Impl: passwordAuthenticator},
+		// version 2 returns the principal of the user
+		VersionedPlugins: map[int]plugin.PluginSet{
+			1: {"passwordAuthenticator": &shared.PasswordAuthenticatorPlugin{Impl: passwordAuthenticator}},
+			2: {"passwordAuthenticator": &shared.PasswordAuthenticatorV2Plugin{Impl: passwordAuthenticator}},
 		},
 		// A non-nil value here enables gRPC serving for this plugin...
 		GRPCServer: plugin.DefaultGRPCServer,
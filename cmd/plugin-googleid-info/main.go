@@ -17,8 +17,10 @@ func main() {
 
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: shared.Handshake,
-		Plugins: map[string]plugin.Plugin{
-			"tokenProvider": &shared.TokenInfoPlugin{Impl: tokenInfo},
+		// version 2 returns the email claim as the principal
+		VersionedPlugins: map[int]plugin.PluginSet{
+			1: {"tokenProvider": &shared.TokenInfoPlugin{Impl: tokenInfo}},
+			2: {"tokenProvider": &shared.TokenInfoV2Plugin{Impl: tokenInfo}},
 		},
 		// A non-nil value here enables gRPC serving for this plugin...
 		GRPCServer: plugin.DefaultGRPCServer,
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+
+	scramfile "github.com/grepplabs/kafka-proxy/pkg/libs/scram-file"
+	"github.com/grepplabs/kafka-proxy/plugin/scram-credentials/shared"
+	"github.com/hashicorp/go-plugin"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	credentialStore, err := new(scramfile.Factory).New(os.Args[1:])
+	if err != nil {
+		logrus.Errorf("error creating SCRAM credential store: %v", err)
+		os.Exit(1)
+	}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: shared.Handshake,
+		Plugins: map[string]plugin.Plugin{
+			"scramCredentialStore": &shared.ScramCredentialStorePlugin{Impl: credentialStore},
+		},
+		// A non-nil value here enables gRPC serving for this plugin...
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
@@ -90,7 +90,7 @@ func (v UnsecuredJWTVerifier) VerifyToken(ctx context.Context, request apis.Veri
 	if unix > latest {
 		return getVerifyResponseResponse(StatusTokenExpired)
 	}
-	return getVerifyResponseResponse(StatusOK)
+	return apis.VerifyResponse{Success: true, Status: StatusOK, Principal: apis.Principal{Name: claimSet.Sub}}, nil
 }
 
 type Header struct {
@@ -151,8 +151,10 @@ func main() {
 
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: shared.Handshake,
-		Plugins: map[string]plugin.Plugin{
-			"unsecuredJWTInfo": &shared.TokenInfoPlugin{Impl: unsecuredJWTVerifier},
+		// version 2 returns the subject claim as the principal
+		VersionedPlugins: map[int]plugin.PluginSet{
+			1: {"unsecuredJWTInfo": &shared.TokenInfoPlugin{Impl: unsecuredJWTVerifier}},
+			2: {"unsecuredJWTInfo": &shared.TokenInfoV2Plugin{Impl: unsecuredJWTVerifier}},
 		},
 		// A non-nil value here enables gRPC serving for this plugin...
 		GRPCServer: plugin.DefaultGRPCServer,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
 	"github.com/grepplabs/kafka-proxy/plugin/token-provider/shared"
 	"github.com/hashicorp/go-plugin"
 	"github.com/sirupsen/logrus"
@@ -18,7 +19,9 @@ const (
 )
 
 type UnsecuredJWTProvider struct {
-	claimSub string
+	claimSub       string
+	authzid        string
+	saslExtensions map[string]string
 }
 
 func (v UnsecuredJWTProvider) GetToken(ctx context.Context, request apis.TokenRequest) (apis.TokenResponse, error) {
@@ -27,7 +30,10 @@ func (v UnsecuredJWTProvider) GetToken(ctx context.Context, request apis.TokenRe
 		return getGetTokenResponse(StatusEncodeError, "")
 	}
 
-	return getGetTokenResponse(StatusOK, token)
+	response, err := getGetTokenResponse(StatusOK, token)
+	response.AuthzID = v.authzid
+	response.Extensions = v.saslExtensions
+	return response, err
 }
 
 func getGetTokenResponse(status int, token string) (apis.TokenResponse, error) {
@@ -49,12 +55,16 @@ func (v UnsecuredJWTProvider) encodeToken() (string, error) {
 }
 
 type pluginMeta struct {
-	claimSub string
+	claimSub       string
+	authzid        string
+	saslExtensions util.ArrayFlags
 }
 
 func (f *pluginMeta) flagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet("unsecured-jwt-info info settings", flag.ContinueOnError)
 	fs.StringVar(&f.claimSub, "claim-sub", "", "subject claim")
+	fs.StringVar(&f.authzid, "authzid", "", "authorization identity sent with the token")
+	fs.Var(&f.saslExtensions, "sasl-extension", "SASL extension sent with the token (key=value)")
 	return fs
 }
 
@@ -67,9 +77,16 @@ func main() {
 		logrus.Errorf("parameter claim-sub is required")
 		os.Exit(1)
 	}
+	saslExtensions, err := pluginMeta.saslExtensions.AsKeyValueMap()
+	if err != nil {
+		logrus.Errorf("parameter sasl-extension: %v", err)
+		os.Exit(1)
+	}
 
 	unsecuredJWTProvider := &UnsecuredJWTProvider{
-		claimSub: pluginMeta.claimSub,
+		claimSub:       pluginMeta.claimSub,
+		authzid:        pluginMeta.authzid,
+		saslExtensions: saslExtensions,
 	}
 
 	plugin.Serve(&plugin.ServeConfig{
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AuthChainCommand is the built-in command of the PLAIN and OAUTHBEARER authentication trying the stages given by the auth parameters in order
+	AuthChainCommand = "chain"
+
+	AuthChainOnRejectNext   = "next"
+	AuthChainOnRejectReject = "reject"
+	AuthChainOnErrorNext    = "next"
+	AuthChainOnErrorFail    = "fail"
+)
+
+// AuthChainConfig is an ordered list of authenticators. Each stage accepts, rejects definitively or passes to the next stage
+type AuthChainConfig struct {
+	Stages []AuthChainStage
+}
+
+// AuthChainStage is a built-in or plugin authenticator of the chain
+type AuthChainStage struct {
+	Name       string
+	Command    string
+	Parameters []string
+	LogLevel   string
+	// OnReject is next to pass rejected credentials to the next stage or reject to reject them definitively
+	OnReject string
+	// NextStatuses are statuses of rejected credentials passed to the next stage when OnReject is reject e.g. unknown user
+	NextStatuses []int32
+	// OnError is next to pass to the next stage when the authenticator fails or fail to fail the authentication
+	OnError string
+}
+
+// ParseAuthChain parses the stages of the chain from the auth parameters:
+//
+//	--stage=name=command           stage authenticator, built-in name or path to the plugin binary, stages are tried in the given order
+//	--stage-param=name=param       authenticator parameter of the stage
+//	--stage-on-reject=name=next    pass rejected credentials to the next stage (next|reject, default reject)
+//	--stage-next-status=name=1     pass credentials rejected with the status to the next stage
+//	--stage-on-error=name=next     pass to the next stage when the authenticator fails (next|fail, default fail)
+//	--stage-log-level=name=level   log level of the stage plugin
+func ParseAuthChain(parameters []string, logLevel string) (AuthChainConfig, error) {
+	var stages, params, onReject, nextStatuses, onError, logLevels util.ArrayFlags
+	fs := flag.NewFlagSet(AuthChainCommand, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&stages, "stage", "Stage authenticator (name=command)")
+	fs.Var(&params, "stage-param", "Authenticator parameter of the stage (name=param)")
+	fs.Var(&onReject, "stage-on-reject", "Action on rejected credentials next or reject (name=action)")
+	fs.Var(&nextStatuses, "stage-next-status", "Status of rejected credentials passed to the next stage (name=status)")
+	fs.Var(&onError, "stage-on-error", "Action on authenticator failure next or fail (name=action)")
+	fs.Var(&logLevels, "stage-log-level", "Log level of the stage plugin (name=level)")
+	if err := fs.Parse(parameters); err != nil {
+		return AuthChainConfig{}, errors.Wrap(err, "invalid chain parameters")
+	}
+	if fs.NArg() != 0 {
+		return AuthChainConfig{}, errors.Errorf("unexpected chain parameters %v", fs.Args())
+	}
+
+	chain := AuthChainConfig{}
+	index := make(map[string]int)
+	for _, v := range stages {
+		name, command, ok := strings.Cut(v, "=")
+		if !ok || name == "" || command == "" {
+			return AuthChainConfig{}, errors.Errorf("chain stage must be in form 'name=command', got '%s'", v)
+		}
+		if _, ok := index[name]; ok {
+			return AuthChainConfig{}, errors.Errorf("chain stage '%s' configured twice", name)
+		}
+		if command == AuthChainCommand {
+			return AuthChainConfig{}, errors.Errorf("chain stage '%s' must not be a chain", name)
+		}
+		index[name] = len(chain.Stages)
+		chain.Stages = append(chain.Stages, AuthChainStage{Name: name, Command: command, Parameters: []string{}, LogLevel: logLevel,
+			OnReject: AuthChainOnRejectReject, OnError: AuthChainOnErrorFail})
+	}
+	if len(chain.Stages) == 0 {
+		return AuthChainConfig{}, errors.New("chain requires at least one stage")
+	}
+	stageOf := func(flagName string, v string) (*AuthChainStage, string, error) {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return nil, "", errors.Errorf("%s must be in form 'name=value', got '%s'", flagName, v)
+		}
+		i, ok := index[name]
+		if !ok {
+			return nil, "", errors.Errorf("%s '%s' is given for unknown stage", flagName, v)
+		}
+		return &chain.Stages[i], value, nil
+	}
+	for _, v := range params {
+		stage, value, err := stageOf("stage-param", v)
+		if err != nil {
+			return AuthChainConfig{}, err
+		}
+		stage.Parameters = append(stage.Parameters, value)
+	}
+	for _, v := range onReject {
+		stage, value, err := stageOf("stage-on-reject", v)
+		if err != nil {
+			return AuthChainConfig{}, err
+		}
+		if value != AuthChainOnRejectNext && value != AuthChainOnRejectReject {
+			return AuthChainConfig{}, errors.Errorf("stage-on-reject of stage '%s' must be next or reject, got '%s'", stage.Name, value)
+		}
+		stage.OnReject = value
+	}
+	for _, v := range nextStatuses {
+		stage, value, err := stageOf("stage-next-status", v)
+		if err != nil {
+			return AuthChainConfig{}, err
+		}
+		status, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return AuthChainConfig{}, errors.Errorf("stage-next-status of stage '%s' must be a number, got '%s'", stage.Name, value)
+		}
+		stage.NextStatuses = append(stage.NextStatuses, int32(status))
+	}
+	for _, v := range onError {
+		stage, value, err := stageOf("stage-on-error", v)
+		if err != nil {
+			return AuthChainConfig{}, err
+		}
+		if value != AuthChainOnErrorNext && value != AuthChainOnErrorFail {
+			return AuthChainConfig{}, errors.Errorf("stage-on-error of stage '%s' must be next or fail, got '%s'", stage.Name, value)
+		}
+		stage.OnError = value
+	}
+	for _, v := range logLevels {
+		stage, value, err := stageOf("stage-log-level", v)
+		if err != nil {
+			return AuthChainConfig{}, err
+		}
+		stage.LogLevel = value
+	}
+	return chain, nil
+}
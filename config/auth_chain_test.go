@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAuthChain(t *testing.T) {
+	a := assert.New(t)
+	chain, err := ParseAuthChain([]string{
+		"--stage=file=user-file",
+		"--stage=ldap=/opt/kafka-proxy/bin/auth-ldap",
+		"--stage-param=file=--file=/etc/kafka-proxy/users.yaml",
+		"--stage-param=ldap=--url=ldaps://ldap.example.com:636",
+		"--stage-param=ldap=--user-dn=cn=%u,ou=people,dc=example,dc=com",
+		"--stage-next-status=file=1",
+		"--stage-on-error=file=next",
+		"--stage-log-level=ldap=info",
+	}, "trace")
+	a.Nil(err)
+	a.Equal([]AuthChainStage{
+		{Name: "file", Command: "user-file", Parameters: []string{"--file=/etc/kafka-proxy/users.yaml"}, LogLevel: "trace",
+			OnReject: "reject", NextStatuses: []int32{1}, OnError: "next"},
+		{Name: "ldap", Command: "/opt/kafka-proxy/bin/auth-ldap", Parameters: []string{"--url=ldaps://ldap.example.com:636", "--user-dn=cn=%u,ou=people,dc=example,dc=com"}, LogLevel: "info",
+			OnReject: "reject", OnError: "fail"},
+	}, chain.Stages)
+}
+
+func TestParseAuthChainErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters []string
+		err        string
+	}{
+		{name: "no stages", parameters: []string{}, err: "chain requires at least one stage"},
+		{name: "invalid stage", parameters: []string{"--stage=file"}, err: "chain stage must be in form 'name=command', got 'file'"},
+		{name: "duplicate stage", parameters: []string{"--stage=file=user-file", "--stage=file=http-auth"}, err: "chain stage 'file' configured twice"},
+		{name: "nested chain", parameters: []string{"--stage=inner=chain"}, err: "chain stage 'inner' must not be a chain"},
+		{name: "unknown stage param", parameters: []string{"--stage=file=user-file", "--stage-param=ldap=--url=x"}, err: "stage-param 'ldap=--url=x' is given for unknown stage"},
+		{name: "invalid on-reject", parameters: []string{"--stage=file=user-file", "--stage-on-reject=file=skip"}, err: "stage-on-reject of stage 'file' must be next or reject, got 'skip'"},
+		{name: "invalid on-error", parameters: []string{"--stage=file=user-file", "--stage-on-error=file=skip"}, err: "stage-on-error of stage 'file' must be next or fail, got 'skip'"},
+		{name: "invalid status", parameters: []string{"--stage=file=user-file", "--stage-next-status=file=unknown"}, err: "stage-next-status of stage 'file' must be a number, got 'unknown'"},
+		{name: "unknown flag", parameters: []string{"--file=users.yaml"}, err: "invalid chain parameters: flag provided but not defined: -file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAuthChain(tt.parameters, "info")
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
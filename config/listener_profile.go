@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultListenerProfileName is the name of the profile of listeners not assigned to any listener profile
+const DefaultListenerProfileName = ""
+
+// ListenerProfile is a named security configuration of listeners. Listeners of bootstrap and external server mappings
+// which are not assigned to any profile use the default profile configured with proxy-listener-* and auth-local-* flags.
+// Dynamic listeners belong to the profile of the listener which advertised them.
+type ListenerProfile struct {
+	Name string `yaml:"name"`
+	// listener addresses of bootstrap and external server mappings (host:port)
+	Listeners                 []string          `yaml:"listeners"`
+	DynamicAdvertisedListener string            `yaml:"dynamic-advertised-listener"`
+	DynamicSequentialMinPort  uint16            `yaml:"dynamic-sequential-min-port"`
+	DynamicSequentialMaxPorts uint16            `yaml:"dynamic-sequential-max-ports"`
+	TLS                       ListenerTLSConfig `yaml:"tls"`
+	LocalAuth                 LocalAuthConfig   `yaml:"local-auth"`
+	ForbiddenApiKeys          []int             `yaml:"forbidden-api-keys"`
+}
+
+type listenerProfiles struct {
+	Profiles []ListenerProfile `yaml:"profiles"`
+}
+
+// InitListenerProfiles reads listener profiles from the YAML file
+func (c *Config) InitListenerProfiles() error {
+	if c.Proxy.ListenerProfilesFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(c.Proxy.ListenerProfilesFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read listener profiles file '%s'", c.Proxy.ListenerProfilesFile)
+	}
+	var file listenerProfiles
+	if err = yaml.UnmarshalStrict(content, &file); err != nil {
+		return errors.Wrapf(err, "failed to parse listener profiles file '%s'", c.Proxy.ListenerProfilesFile)
+	}
+	for i := range file.Profiles {
+		profile := &file.Profiles[i]
+		for j, listener := range profile.Listeners {
+			host, port, err := util.SplitHostPort(listener)
+			if err != nil {
+				return errors.Wrapf(err, "invalid listener '%s' of listener profile '%s'", listener, profile.Name)
+			}
+			profile.Listeners[j] = net.JoinHostPort(host, fmt.Sprint(port))
+		}
+		if profile.TLS.DetectTimeout == 0 {
+			profile.TLS.DetectTimeout = 10 * time.Second
+		}
+		if profile.LocalAuth.Mechanism == "" {
+			profile.LocalAuth.Mechanism = "PLAIN"
+		}
+		if profile.LocalAuth.LogLevel == "" {
+			profile.LocalAuth.LogLevel = "trace"
+		}
+		for j := range profile.LocalAuth.Mechanisms {
+			if profile.LocalAuth.Mechanisms[j].LogLevel == "" {
+				profile.LocalAuth.Mechanisms[j].LogLevel = profile.LocalAuth.LogLevel
+			}
+		}
+		if profile.LocalAuth.Timeout == 0 {
+			profile.LocalAuth.Timeout = 10 * time.Second
+		}
+	}
+	c.Proxy.ListenerProfiles = file.Profiles
+	return nil
+}
+
+// GetDefaultListenerProfile returns the profile of listeners not assigned to any listener profile
+func (c *Config) GetDefaultListenerProfile() ListenerProfile {
+	return ListenerProfile{
+		Name:                      DefaultListenerProfileName,
+		DynamicAdvertisedListener: c.Proxy.DynamicAdvertisedListener,
+		DynamicSequentialMinPort:  c.Proxy.DynamicSequentialMinPort,
+		DynamicSequentialMaxPorts: c.Proxy.DynamicSequentialMaxPorts,
+		TLS:                       c.Proxy.TLS,
+		LocalAuth:                 c.Auth.Local,
+		ForbiddenApiKeys:          c.Kafka.ForbiddenApiKeys,
+	}
+}
+
+// GetListenerProfileName returns the name of the profile the listener is assigned to
+func (c *Config) GetListenerProfileName(listenerAddress string) string {
+	for _, profile := range c.Proxy.ListenerProfiles {
+		for _, listener := range profile.Listeners {
+			if listener == listenerAddress {
+				return profile.Name
+			}
+		}
+	}
+	return DefaultListenerProfileName
+}
+
+func (c *Config) validateListenerProfiles() error {
+	serverListeners := make(map[string]struct{})
+	for _, v := range c.Proxy.BootstrapServers {
+		serverListeners[v.ListenerAddress] = struct{}{}
+	}
+	for _, v := range c.Proxy.ExternalServers {
+		serverListeners[v.ListenerAddress] = struct{}{}
+	}
+	names := make(map[string]struct{})
+	assigned := make(map[string]string)
+	for i := range c.Proxy.ListenerProfiles {
+		profile := &c.Proxy.ListenerProfiles[i]
+		if profile.Name == DefaultListenerProfileName {
+			return errors.New("Listener profile name must not be empty")
+		}
+		if _, ok := names[profile.Name]; ok {
+			return errors.Errorf("Listener profile '%s' configured twice", profile.Name)
+		}
+		names[profile.Name] = struct{}{}
+		if len(profile.Listeners) == 0 {
+			return errors.Errorf("Listeners of listener profile '%s' must not be empty", profile.Name)
+		}
+		for _, listener := range profile.Listeners {
+			if _, ok := serverListeners[listener]; !ok {
+				return errors.Errorf("Listener '%s' of listener profile '%s' is not a listener of bootstrap or external server mapping", listener, profile.Name)
+			}
+			if other, ok := assigned[listener]; ok {
+				return errors.Errorf("Listener '%s' is assigned to listener profiles '%s' and '%s'", listener, other, profile.Name)
+			}
+			assigned[listener] = profile.Name
+		}
+		if err := profile.validate(); err != nil {
+			return errors.Wrapf(err, "invalid listener profile '%s'", profile.Name)
+		}
+		if c.Proxy.DisableDynamicListeners {
+			continue
+		}
+		if profile.DynamicSequentialMinPort == 0 && c.Proxy.DeterministicListeners {
+			return errors.Errorf("DynamicSequentialMinPort of listener profile '%s' must be set when Proxy.DeterministicListeners is enabled", profile.Name)
+		}
+		if profile.DynamicSequentialMaxPorts == 0 && profile.DynamicSequentialMinPort > 0 {
+			profile.DynamicSequentialMaxPorts = uint16(65536 - uint32(profile.DynamicSequentialMinPort))
+		}
+	}
+	return nil
+}
+
+func (p *ListenerProfile) validate() error {
+	if p.TLS.Enable && (p.TLS.ListenerKeyFile == "" || p.TLS.ListenerCertFile == "") {
+		return errors.New("ListenerKeyFile and ListenerCertFile are required when TLS is enabled")
+	}
+	if err := p.TLS.validateModes(); err != nil {
+		return err
+	}
+	if p.LocalAuth.Enable {
+		if p.LocalAuth.Command == "" && len(p.LocalAuth.Mechanisms) == 0 {
+			return errors.New("Command is required when LocalAuth.Enable is enabled")
+		}
+		if !isLocalAuthMechanism(p.LocalAuth.Mechanism) {
+			return errors.New("Mechanism PLAIN, OAUTHBEARER, SCRAM-SHA-256, SCRAM-SHA-512 or GSSAPI is required when LocalAuth.Enable is enabled")
+		}
+		if err := p.LocalAuth.validateMechanisms(); err != nil {
+			return err
+		}
+		if p.LocalAuth.Timeout <= 0 {
+			return errors.New("LocalAuth.Timeout must be greater than 0")
+		}
+		if p.LocalAuth.MaxSessionLifetime < 0 {
+			return errors.New("LocalAuth.MaxSessionLifetime must not be negative")
+		}
+	}
+	return nil
+}
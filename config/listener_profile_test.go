@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testListenerProfiles = `
+profiles:
+  - name: external
+    listeners:
+      - 0.0.0.0:32401
+    dynamic-advertised-listener: kafka-{{.brokerId}}.example.com
+    dynamic-sequential-min-port: 33000
+    dynamic-sequential-max-ports: 100
+    tls:
+      enable: true
+      cert-file: server.crt
+      key-file: server.pem
+      client-cert:
+        required-subjects:
+          - s:/CN=[client]
+    local-auth:
+      enable: true
+      command: /opt/kafka-proxy/bin/auth-user
+      parameters:
+        - --username=my-test-user
+      timeout: 5s
+      mechanisms:
+        - mechanism: OAUTHBEARER
+          command: google-id-info
+          parameters:
+            - --audience=kafka
+    forbidden-api-keys: [20, 37]
+`
+
+func newListenerProfilesTestConfig(t *testing.T, profiles string) *Config {
+	file, err := os.CreateTemp("", "listener-profiles-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Remove(file.Name()) })
+	if _, err = file.WriteString(profiles); err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfig()
+	c.Proxy.ListenerProfilesFile = file.Name()
+	if err = c.InitBootstrapServers([]string{"192.168.99.100:32400,0.0.0.0:32400", "192.168.99.100:32400,0.0.0.0:32401"}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestInitListenerProfiles(t *testing.T) {
+	a := assert.New(t)
+
+	c := newListenerProfilesTestConfig(t, testListenerProfiles)
+	a.Nil(c.InitListenerProfiles())
+	a.Nil(c.Validate())
+
+	a.Len(c.Proxy.ListenerProfiles, 1)
+	profile := c.Proxy.ListenerProfiles[0]
+	a.Equal("external", profile.Name)
+	a.Equal([]string{"0.0.0.0:32401"}, profile.Listeners)
+	a.Equal("kafka-{{.brokerId}}.example.com", profile.DynamicAdvertisedListener)
+	a.True(profile.TLS.Enable)
+	a.Equal(ListenerTLSModeTLS, profile.TLS.GetListenerMode("0.0.0.0:32401"))
+	a.Equal([]string{"s:/CN=[client]"}, profile.TLS.ClientCert.Subjects)
+	a.True(profile.LocalAuth.Enable)
+	a.Equal("PLAIN", profile.LocalAuth.Mechanism)
+	a.Equal(5*time.Second, profile.LocalAuth.Timeout)
+	a.Equal([]string{"--username=my-test-user"}, profile.LocalAuth.Parameters)
+	a.Equal([]LocalAuthMechanism{
+		{Mechanism: "PLAIN", Command: "/opt/kafka-proxy/bin/auth-user", Parameters: []string{"--username=my-test-user"}, LogLevel: "trace"},
+		{Mechanism: "OAUTHBEARER", Command: "google-id-info", Parameters: []string{"--audience=kafka"}, LogLevel: "trace"},
+	}, profile.LocalAuth.GetMechanisms())
+	a.Equal([]int{20, 37}, profile.ForbiddenApiKeys)
+
+	a.Equal("external", c.GetListenerProfileName("0.0.0.0:32401"))
+	a.Equal(DefaultListenerProfileName, c.GetListenerProfileName("0.0.0.0:32400"))
+}
+
+func TestInitListenerProfilesUnknownField(t *testing.T) {
+	c := newListenerProfilesTestConfig(t, "profiles:\n  - name: external\n    unknown: true\n")
+	assert.NotNil(t, c.InitListenerProfiles())
+}
+
+func TestValidateListenerProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles string
+		errorMsg string
+	}{
+		{
+			name:     "unknown listener",
+			profiles: "profiles:\n  - name: external\n    listeners: [0.0.0.0:32402]\n",
+			errorMsg: "Listener '0.0.0.0:32402' of listener profile 'external' is not a listener of bootstrap or external server mapping",
+		},
+		{
+			name:     "listener in two profiles",
+			profiles: "profiles:\n  - name: p1\n    listeners: [0.0.0.0:32401]\n  - name: p2\n    listeners: [0.0.0.0:32401]\n",
+			errorMsg: "Listener '0.0.0.0:32401' is assigned to listener profiles 'p1' and 'p2'",
+		},
+		{
+			name:     "duplicated name",
+			profiles: "profiles:\n  - name: p1\n    listeners: [0.0.0.0:32401]\n  - name: p1\n    listeners: [0.0.0.0:32400]\n",
+			errorMsg: "Listener profile 'p1' configured twice",
+		},
+		{
+			name:     "missing TLS files",
+			profiles: "profiles:\n  - name: p1\n    listeners: [0.0.0.0:32401]\n    tls:\n      enable: true\n",
+			errorMsg: "invalid listener profile 'p1': ListenerKeyFile and ListenerCertFile are required when TLS is enabled",
+		},
+		{
+			name:     "missing local auth command",
+			profiles: "profiles:\n  - name: p1\n    listeners: [0.0.0.0:32401]\n    local-auth:\n      enable: true\n",
+			errorMsg: "invalid listener profile 'p1': Command is required when LocalAuth.Enable is enabled",
+		},
+		{
+			name:     "unsupported local auth mechanism",
+			profiles: "profiles:\n  - name: p1\n    listeners: [0.0.0.0:32401]\n    local-auth:\n      enable: true\n      command: scram-file\n      mechanism: SCRAM-SHA-1\n",
+			errorMsg: "invalid listener profile 'p1': Mechanism PLAIN, OAUTHBEARER, SCRAM-SHA-256, SCRAM-SHA-512 or GSSAPI is required when LocalAuth.Enable is enabled",
+		},
+		{
+			name:     "local auth mechanism without command",
+			profiles: "profiles:\n  - name: p1\n    listeners: [0.0.0.0:32401]\n    local-auth:\n      enable: true\n      mechanisms:\n        - mechanism: OAUTHBEARER\n",
+			errorMsg: "invalid listener profile 'p1': Command is required for local auth mechanism 'OAUTHBEARER'",
+		},
+		{
+			name:     "overlapping dynamic ports",
+			profiles: "profiles:\n  - name: p1\n    listeners: [0.0.0.0:32401]\n    dynamic-sequential-min-port: 30050\n",
+			errorMsg: "Dynamic sequential ports of default listeners and listener profile 'p1' overlap",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newListenerProfilesTestConfig(t, tt.profiles)
+			c.Proxy.DynamicSequentialMinPort = 30000
+			c.Proxy.DynamicSequentialMaxPorts = 100
+			assert.Nil(t, c.InitListenerProfiles())
+			err := c.Validate()
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.errorMsg, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// UpstreamCluster is a named Kafka cluster proxied in addition to the cluster configured with command line flags.
+// Kafka client, listener and authentication settings are shared with the command line cluster, whereas
+// server mappings, broker TLS, SASL, forward proxy and dynamic listeners are configured per cluster.
+type UpstreamCluster struct {
+	Name                      string          `yaml:"name"`
+	BootstrapServerMapping    []string        `yaml:"bootstrap-server-mapping"`
+	ExternalServerMapping     []string        `yaml:"external-server-mapping"`
+	DialAddressMapping        []string        `yaml:"dial-address-mapping"`
+	DynamicAdvertisedListener string          `yaml:"dynamic-advertised-listener"`
+	DynamicSequentialMinPort  uint16          `yaml:"dynamic-sequential-min-port"`
+	DynamicSequentialMaxPorts uint16          `yaml:"dynamic-sequential-max-ports"`
+	TLS                       KafkaTLSConfig  `yaml:"tls"`
+	SASL                      KafkaSASLConfig `yaml:"sasl"`
+	ForwardProxy              string          `yaml:"forward-proxy"`
+}
+
+type upstreamClusters struct {
+	Clusters []yaml.MapSlice `yaml:"clusters"`
+}
+
+type upstreamClusterDefaults struct {
+	TLS  KafkaTLSConfig
+	SASL KafkaSASLConfig
+}
+
+// InitUpstreamClusterDefaults keeps the current broker TLS and SASL settings as defaults of upstream clusters.
+// It must be called after the command line flags are registered and before they are parsed.
+// Credentials and key material are not kept, every upstream cluster must set its own.
+func (c *Config) InitUpstreamClusterDefaults() {
+	defaults := &upstreamClusterDefaults{TLS: c.Kafka.TLS, SASL: c.Kafka.SASL}
+	defaults.TLS.ClientCertFile = ""
+	defaults.TLS.ClientKeyFile = ""
+	defaults.TLS.ClientKeyPassword = ""
+	defaults.TLS.ClientCertIssuer.CAKeyFile = ""
+	defaults.SASL.Username = ""
+	defaults.SASL.Password = ""
+	defaults.SASL.JaasConfigFile = ""
+	defaults.SASL.GSSAPI.Password = ""
+	defaults.SASL.GSSAPI.KeyTabPath = ""
+	defaults.SASL.TokenRelay.Exchange.ClientSecret = ""
+	defaults.SASL.TokenRelay.Exchange.ClientSecretFile = ""
+	c.upstreamClusterDefaults = defaults
+}
+
+// InitUpstreamClusters reads upstream clusters from the YAML file. It must be called after the command line cluster is initialized.
+func (c *Config) InitUpstreamClusters() error {
+	if c.UpstreamClustersFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(c.UpstreamClustersFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read upstream clusters file '%s'", c.UpstreamClustersFile)
+	}
+	var file upstreamClusters
+	if err = yaml.UnmarshalStrict(content, &file); err != nil {
+		return errors.Wrapf(err, "failed to parse upstream clusters file '%s'", c.UpstreamClustersFile)
+	}
+	clusters := make([]*Config, 0, len(file.Clusters))
+	for i, item := range file.Clusters {
+		cluster, err := c.parseUpstreamCluster(item)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse upstream cluster %d in file '%s'", i, c.UpstreamClustersFile)
+		}
+		clusterConfig, err := c.newUpstreamClusterConfig(cluster)
+		if err != nil {
+			return errors.Wrapf(err, "invalid upstream cluster '%s'", cluster.Name)
+		}
+		clusters = append(clusters, clusterConfig)
+	}
+	c.UpstreamClusters = clusters
+	return nil
+}
+
+// parseUpstreamCluster decodes the cluster over the broker TLS and SASL flag defaults, so settings missing in the file keep their default values
+func (c *Config) parseUpstreamCluster(item yaml.MapSlice) (UpstreamCluster, error) {
+	var cluster UpstreamCluster
+	if c.upstreamClusterDefaults != nil {
+		cluster.TLS = c.upstreamClusterDefaults.TLS
+		cluster.SASL = c.upstreamClusterDefaults.SASL
+		// maps are decoded in place and must not be shared between clusters
+		cluster.SASL.GSSAPI.SPNHostsMapping = nil
+	}
+	content, err := yaml.Marshal(item)
+	if err != nil {
+		return cluster, err
+	}
+	err = yaml.UnmarshalStrict(content, &cluster)
+	return cluster, err
+}
+
+func (c *Config) newUpstreamClusterConfig(cluster UpstreamCluster) (*Config, error) {
+	clusterConfig := *c
+	clusterConfig.Cluster = cluster.Name
+	clusterConfig.UpstreamClustersFile = ""
+	clusterConfig.UpstreamClusters = nil
+	// listener profiles are assigned to listeners of the command line cluster
+	clusterConfig.Proxy.ListenerProfilesFile = ""
+	clusterConfig.Proxy.ListenerProfiles = nil
+	clusterConfig.Proxy.DynamicAdvertisedListener = cluster.DynamicAdvertisedListener
+	clusterConfig.Proxy.DynamicSequentialMinPort = cluster.DynamicSequentialMinPort
+	clusterConfig.Proxy.DynamicSequentialMaxPorts = cluster.DynamicSequentialMaxPorts
+
+	clusterConfig.Kafka.TLS = cluster.TLS
+	clusterConfig.Kafka.SASL = cluster.SASL
+	clusterConfig.ForwardProxy.Url = cluster.ForwardProxy
+	clusterConfig.ForwardProxy.Scheme = ""
+	clusterConfig.ForwardProxy.Address = ""
+	clusterConfig.ForwardProxy.Username = ""
+	clusterConfig.ForwardProxy.Password = ""
+
+	if err := clusterConfig.InitBootstrapServers(cluster.BootstrapServerMapping); err != nil {
+		return nil, err
+	}
+	if err := clusterConfig.InitExternalServers(cluster.ExternalServerMapping); err != nil {
+		return nil, err
+	}
+	if err := clusterConfig.InitDialAddressMappings(cluster.DialAddressMapping); err != nil {
+		return nil, err
+	}
+	if err := clusterConfig.InitSASLCredentials(); err != nil {
+		return nil, err
+	}
+	return &clusterConfig, nil
+}
+
+func (c *Config) validateUpstreamClusters() error {
+	listeners := make(map[string]string)
+	for _, v := range c.Proxy.BootstrapServers {
+		listeners[v.ListenerAddress] = ""
+	}
+	names := make(map[string]struct{})
+	for _, cluster := range c.UpstreamClusters {
+		if cluster.Cluster == "" {
+			return errors.New("Upstream cluster name must not be empty")
+		}
+		if _, ok := names[cluster.Cluster]; ok {
+			return errors.Errorf("Upstream cluster '%s' configured twice", cluster.Cluster)
+		}
+		names[cluster.Cluster] = struct{}{}
+		if err := cluster.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid upstream cluster '%s'", cluster.Cluster)
+		}
+		for _, v := range cluster.Proxy.BootstrapServers {
+			if other, ok := listeners[v.ListenerAddress]; ok && other != cluster.Cluster {
+				return errors.Errorf("Listener '%s' of upstream cluster '%s' is already used by %s", v.ListenerAddress, cluster.Cluster, clusterDisplayName(other))
+			}
+			listeners[v.ListenerAddress] = cluster.Cluster
+		}
+	}
+	return nil
+}
+
+type dynamicPortRange struct {
+	owner    string
+	min, max uint32
+}
+
+// validateDynamicSequentialPorts checks that dynamic listeners of listener profiles and upstream clusters do not share ports
+func (c *Config) validateDynamicSequentialPorts() error {
+	if c.Proxy.DisableDynamicListeners {
+		return nil
+	}
+	ranges := make([]dynamicPortRange, 0)
+	addRange := func(owner string, minPort uint16, maxPorts uint16) {
+		if minPort == 0 {
+			return
+		}
+		ranges = append(ranges, dynamicPortRange{owner: owner, min: uint32(minPort), max: uint32(minPort) + uint32(maxPorts)})
+	}
+	addRange("default listeners", c.Proxy.DynamicSequentialMinPort, c.Proxy.DynamicSequentialMaxPorts)
+	for _, profile := range c.Proxy.ListenerProfiles {
+		addRange(fmt.Sprintf("listener profile '%s'", profile.Name), profile.DynamicSequentialMinPort, profile.DynamicSequentialMaxPorts)
+	}
+	for _, cluster := range c.UpstreamClusters {
+		addRange(clusterDisplayName(cluster.Cluster), cluster.Proxy.DynamicSequentialMinPort, cluster.Proxy.DynamicSequentialMaxPorts)
+	}
+	for i, r1 := range ranges {
+		if r1.max > 65536 {
+			return errors.Errorf("Dynamic sequential ports of %s exceed port 65535", r1.owner)
+		}
+		for _, r2 := range ranges[i+1:] {
+			if r1.min < r2.max && r2.min < r1.max {
+				return errors.Errorf("Dynamic sequential ports of %s and %s overlap", r1.owner, r2.owner)
+			}
+		}
+	}
+	return nil
+}
+
+func clusterDisplayName(cluster string) string {
+	if cluster == "" {
+		return "the default cluster"
+	}
+	return fmt.Sprintf("upstream cluster '%s'", cluster)
+}
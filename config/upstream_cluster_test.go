@@ -0,0 +1,224 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+const testUpstreamClusters = `
+clusters:
+  - name: cluster-b
+    bootstrap-server-mapping:
+      - kafka-b-0:9092,0.0.0.0:32500
+    dial-address-mapping:
+      - kafka-b-0:9092,10.0.0.1:9092
+    dynamic-sequential-min-port: 33000
+    dynamic-sequential-max-ports: 100
+    tls:
+      enable: true
+      ca-chain-cert-file: ca.crt
+    sasl:
+      enable: true
+      method: SCRAM-SHA-512
+      username: alice
+      password: secret
+    forward-proxy: socks5://localhost:1080
+`
+
+func newUpstreamClustersTestConfig(t *testing.T, clusters string) *Config {
+	file, err := os.CreateTemp("", "upstream-clusters-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Remove(file.Name()) })
+	if _, err = file.WriteString(clusters); err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfig()
+	c.Kafka.SASL.Method = "PLAIN"
+	c.Kafka.SASL.GSSAPI.ServiceName = "kafka"
+	c.InitUpstreamClusterDefaults()
+	c.UpstreamClustersFile = file.Name()
+	c.Proxy.DynamicSequentialMinPort = 30000
+	c.Proxy.DynamicSequentialMaxPorts = 100
+	c.Kafka.SASL.Enable = true
+	c.Kafka.SASL.Method = "PLAIN"
+	c.Kafka.SASL.Username = "bob"
+	c.Kafka.SASL.Password = "password"
+	c.ForwardProxy.Url = "http://localhost:3128"
+	if err = c.InitBootstrapServers([]string{"kafka-a-0:9092,0.0.0.0:32400"}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestInitUpstreamClusters(t *testing.T) {
+	a := assert.New(t)
+
+	c := newUpstreamClustersTestConfig(t, testUpstreamClusters)
+	a.Nil(c.InitUpstreamClusters())
+	a.Nil(c.Validate())
+
+	a.Len(c.UpstreamClusters, 1)
+	cluster := c.UpstreamClusters[0]
+	a.Equal("cluster-b", cluster.Cluster)
+	a.Equal([]ListenerConfig{{BrokerAddress: "kafka-b-0:9092", ListenerAddress: "0.0.0.0:32500", AdvertisedAddress: "0.0.0.0:32500"}}, cluster.Proxy.BootstrapServers)
+	a.Equal([]DialAddressMapping{{SourceAddress: "kafka-b-0:9092", DestinationAddress: "10.0.0.1:9092"}}, cluster.Proxy.DialAddressMappings)
+	a.Equal(uint16(33000), cluster.Proxy.DynamicSequentialMinPort)
+	a.True(cluster.Kafka.TLS.Enable)
+	a.Equal("ca.crt", cluster.Kafka.TLS.CAChainCertFile)
+	a.Equal("SCRAM-SHA-512", cluster.Kafka.SASL.Method)
+	a.Equal("alice", cluster.Kafka.SASL.Username)
+	a.Equal("socks5", cluster.ForwardProxy.Scheme)
+	a.Equal("localhost:1080", cluster.ForwardProxy.Address)
+	a.Equal(c.Kafka.ClientID, cluster.Kafka.ClientID)
+	a.Equal("kafka", cluster.Kafka.SASL.GSSAPI.ServiceName)
+
+	// command line cluster is not changed
+	a.Equal("", c.Cluster)
+	a.Equal("bob", c.Kafka.SASL.Username)
+	a.Equal("http", c.ForwardProxy.Scheme)
+	a.Equal([]ListenerConfig{{BrokerAddress: "kafka-a-0:9092", ListenerAddress: "0.0.0.0:32400", AdvertisedAddress: "0.0.0.0:32400"}}, c.Proxy.BootstrapServers)
+}
+
+func TestInitUpstreamClustersDefaults(t *testing.T) {
+	a := assert.New(t)
+
+	c := newUpstreamClustersTestConfig(t, "clusters:\n  - name: cluster-b\n    bootstrap-server-mapping: [\"kafka-b-0:9092,0.0.0.0:32500\"]\n    sasl:\n      gssapi:\n        realm: EXAMPLE.COM\n")
+	a.Nil(c.InitUpstreamClusters())
+
+	cluster := c.UpstreamClusters[0]
+	a.False(cluster.Kafka.SASL.Enable)
+	a.Equal("PLAIN", cluster.Kafka.SASL.Method)
+	a.Equal("", cluster.Kafka.SASL.Username)
+	a.Equal("kafka", cluster.Kafka.SASL.GSSAPI.ServiceName)
+	a.Equal("EXAMPLE.COM", cluster.Kafka.SASL.GSSAPI.Realm)
+}
+
+func TestInitUpstreamClusterDefaultsWithoutSecrets(t *testing.T) {
+	a := assert.New(t)
+
+	c := NewConfig()
+	c.Kafka.TLS.ClientCertFile = "client.crt"
+	c.Kafka.TLS.ClientKeyFile = "client.key"
+	c.Kafka.TLS.ClientKeyPassword = "key-secret"
+	c.Kafka.TLS.ClientCertIssuer.CAKeyFile = "ca.key"
+	c.Kafka.SASL.Username = "bob"
+	c.Kafka.SASL.Password = "password"
+	c.Kafka.SASL.JaasConfigFile = "jaas.conf"
+	c.Kafka.SASL.GSSAPI.Password = "krb-secret"
+	c.Kafka.SASL.GSSAPI.KeyTabPath = "krb5.keytab"
+	c.Kafka.SASL.TokenRelay.Exchange.ClientSecret = "client-secret"
+	c.Kafka.SASL.TokenRelay.Exchange.ClientSecretFile = "client-secret-file"
+	c.InitUpstreamClusterDefaults()
+
+	cluster, err := c.parseUpstreamCluster(yaml.MapSlice{{Key: "name", Value: "cluster-b"}})
+	a.Nil(err)
+	a.Equal(KafkaTLSConfig{}, cluster.TLS)
+	a.Equal("", cluster.SASL.Username)
+	a.Equal("", cluster.SASL.Password)
+	a.Equal("", cluster.SASL.JaasConfigFile)
+	a.Equal("", cluster.SASL.GSSAPI.Password)
+	a.Equal("", cluster.SASL.GSSAPI.KeyTabPath)
+	a.Equal("", cluster.SASL.TokenRelay.Exchange.ClientSecret)
+	a.Equal("", cluster.SASL.TokenRelay.Exchange.ClientSecretFile)
+
+	// the command line cluster keeps its credentials
+	a.Equal("password", c.Kafka.SASL.Password)
+}
+
+func TestParseUpstreamClusterWithSASLPlugin(t *testing.T) {
+	a := assert.New(t)
+
+	const clusters = `
+clusters:
+  - name: cluster-b
+    bootstrap-server-mapping:
+      - kafka-b-0:9092,0.0.0.0:32500
+    sasl:
+      enable: true
+      plugin:
+        enable: true
+        command: build/oidc-provider
+        mechanism: OAUTHBEARER
+        parameters:
+          - --token-url=https://idp.example.com/token
+        log-level: debug
+        timeout: 5s
+`
+	c := newUpstreamClustersTestConfig(t, clusters)
+	a.Nil(c.InitUpstreamClusters())
+	a.Nil(c.Validate())
+
+	plugin := c.UpstreamClusters[0].Kafka.SASL.Plugin
+	a.True(plugin.Enable)
+	a.Equal("build/oidc-provider", plugin.Command)
+	a.Equal("OAUTHBEARER", plugin.Mechanism)
+	a.Equal([]string{"--token-url=https://idp.example.com/token"}, plugin.Parameters)
+	a.Equal("debug", plugin.LogLevel)
+	a.Equal(5*time.Second, plugin.Timeout)
+
+	// plugin settings are validated per cluster
+	c = newUpstreamClustersTestConfig(t, strings.Replace(clusters, "mechanism: OAUTHBEARER", "mechanism: PLAIN", 1))
+	a.Nil(c.InitUpstreamClusters())
+	a.EqualError(c.Validate(), "invalid upstream cluster 'cluster-b': Mechanism OAUTHBEARER is required when Kafka.SASL.Plugin.Enable is enabled")
+}
+
+func TestInitUpstreamClustersUnknownField(t *testing.T) {
+	c := newUpstreamClustersTestConfig(t, "clusters:\n  - name: cluster-b\n    sasl:\n      plugin: true\n")
+	assert.NotNil(t, c.InitUpstreamClusters())
+}
+
+func TestValidateUpstreamClusters(t *testing.T) {
+	tests := []struct {
+		name     string
+		clusters string
+		errorMsg string
+	}{
+		{
+			name:     "empty name",
+			clusters: "clusters:\n  - bootstrap-server-mapping: [\"kafka-b-0:9092,0.0.0.0:32500\"]\n",
+			errorMsg: "Upstream cluster name must not be empty",
+		},
+		{
+			name:     "duplicated name",
+			clusters: "clusters:\n  - name: b\n    bootstrap-server-mapping: [\"kafka-b-0:9092,0.0.0.0:32500\"]\n  - name: b\n    bootstrap-server-mapping: [\"kafka-c-0:9092,0.0.0.0:32600\"]\n",
+			errorMsg: "Upstream cluster 'b' configured twice",
+		},
+		{
+			name:     "missing bootstrap servers",
+			clusters: "clusters:\n  - name: b\n",
+			errorMsg: "invalid upstream cluster 'b': list of bootstrap-server-mapping must not be empty",
+		},
+		{
+			name:     "missing SASL credentials",
+			clusters: "clusters:\n  - name: b\n    bootstrap-server-mapping: [\"kafka-b-0:9092,0.0.0.0:32500\"]\n    sasl:\n      enable: true\n",
+			errorMsg: "invalid upstream cluster 'b': SASL.Username and SASL.Password are required when SASL is enabled and plugin is not used",
+		},
+		{
+			name:     "listener of other cluster",
+			clusters: "clusters:\n  - name: b\n    bootstrap-server-mapping: [\"kafka-b-0:9092,0.0.0.0:32400\"]\n",
+			errorMsg: "Listener '0.0.0.0:32400' of upstream cluster 'b' is already used by the default cluster",
+		},
+		{
+			name:     "overlapping dynamic ports",
+			clusters: "clusters:\n  - name: b\n    bootstrap-server-mapping: [\"kafka-b-0:9092,0.0.0.0:32500\"]\n    dynamic-sequential-min-port: 30050\n",
+			errorMsg: "Dynamic sequential ports of default listeners and upstream cluster 'b' overlap",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newUpstreamClustersTestConfig(t, tt.clusters)
+			assert.Nil(t, c.InitUpstreamClusters())
+			err := c.Validate()
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.errorMsg, err.Error())
+			}
+		})
+	}
+}
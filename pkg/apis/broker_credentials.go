@@ -0,0 +1,26 @@
+package apis
+
+import "context"
+
+// BrokerCredentialsRequest describes the locally authenticated client the broker credentials are requested for
+type BrokerCredentialsRequest struct {
+	// Principal authenticated by the local SASL authentication
+	Principal Principal
+	// Connection is the client connection the principal was authenticated on
+	Connection ConnectionInfo
+}
+
+// BrokerCredentials are the SASL/PLAIN credentials the proxy uses to authenticate to the brokers on behalf of the client
+type BrokerCredentials struct {
+	Username string
+	Password string
+}
+
+type BrokerCredentialsProvider interface {
+	// GetBrokerCredentials returns the broker credentials of the principal. Found is false when the principal is not mapped
+	GetBrokerCredentials(ctx context.Context, request BrokerCredentialsRequest) (credentials BrokerCredentials, found bool, err error)
+}
+
+type BrokerCredentialsProviderFactory interface {
+	New(params []string) (BrokerCredentialsProvider, error)
+}
@@ -0,0 +1,29 @@
+package apis
+
+// ConnectionInfo describes the client connection the credentials were sent on
+type ConnectionInfo struct {
+	// ClientAddress is the remote address of the client, the source address of the PROXY protocol header when enabled
+	ClientAddress string
+	// ListenerAddress is the address of the proxy listener which accepted the connection
+	ListenerAddress string
+	// ListenerProfile is the name of the listener profile which accepted the connection
+	ListenerProfile string
+	// TLSClientSubject is the subject DN of the verified client certificate, empty without client certificate
+	TLSClientSubject string
+	// TLSClientPrincipal is the identity of the verified client certificate matched by the client certificate rules of the listener:
+	// the SPIFFE ID, the matched URI, DNS, email or IP SAN, otherwise the subject DN. Empty without client certificate
+	TLSClientPrincipal string
+	// TLSServerName is the server name indication requested by the client, empty for plaintext connections.
+	// Without TLS at the proxy it is the authority sent by the load balancer in the PROXY protocol v2 header
+	TLSServerName string
+	// ProxySSL is true if the client connected with TLS to the load balancer, sent in the PROXY protocol v2 SSL TLV
+	ProxySSL bool
+	// ProxySSLClientCert is true if the client presented a certificate to the load balancer
+	ProxySSLClientCert bool
+	// ProxySSLClientCertVerified is true if the load balancer verified the client certificate successfully
+	ProxySSLClientCertVerified bool
+	// ProxySSLVersion is the TLS version of the client connection to the load balancer e.g. TLSv1.3
+	ProxySSLVersion string
+	// ProxySSLClientCN is the common name of the client certificate presented to the load balancer
+	ProxySSLClientCN string
+}
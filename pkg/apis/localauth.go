@@ -1,5 +1,9 @@
 package apis
 
+import (
+	"context"
+)
+
 type PasswordAuthenticator interface {
 	Authenticate(username, password string) (bool, int32, error)
 }
@@ -7,3 +11,23 @@ type PasswordAuthenticator interface {
 type PasswordAuthenticatorFactory interface {
 	New(params []string) (PasswordAuthenticator, error)
 }
+
+type AuthenticateRequest struct {
+	Username string
+	Password string
+	// Connection of the client
+	Connection ConnectionInfo
+}
+
+type AuthenticateResponse struct {
+	Authenticated bool
+	Status        int32
+	// Principal of the authenticated user, the user name is used when the principal name is empty
+	Principal Principal
+}
+
+// PasswordAuthenticatorV2 is the version 2 of the PasswordAuthenticator which returns the principal of the authenticated user
+type PasswordAuthenticatorV2 interface {
+	// AuthenticatePrincipal authenticates the user. The returned error is only used by the underlying rpc protocol
+	AuthenticatePrincipal(ctx context.Context, request AuthenticateRequest) (AuthenticateResponse, error)
+}
@@ -0,0 +1,11 @@
+package apis
+
+// Principal is the identity of the authenticated client returned by the auth plugins
+type Principal struct {
+	// Name of the principal e.g. user name or subject of the token
+	Name string
+	// Groups of the principal e.g. LDAP groups or groups claim of the token
+	Groups []string
+	// Attributes are additional claims of the principal
+	Attributes map[string]string
+}
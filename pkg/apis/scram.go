@@ -0,0 +1,18 @@
+package apis
+
+// ScramCredentials are the salted SCRAM credentials of a user (RFC 5802)
+type ScramCredentials struct {
+	Salt       []byte
+	Iterations int
+	StoredKey  []byte
+	ServerKey  []byte
+}
+
+type ScramCredentialStore interface {
+	// GetCredentials returns credentials of the user for the mechanism SCRAM-SHA-256 or SCRAM-SHA-512. Found is false when the user is unknown
+	GetCredentials(mechanism, username string) (credentials ScramCredentials, found bool, err error)
+}
+
+type ScramCredentialStoreFactory interface {
+	New(params []string) (ScramCredentialStore, error)
+}
@@ -11,6 +11,10 @@ type TokenResponse struct {
 	Success bool
 	Status  int32
 	Token   string
+	// AuthzID is the authorization identity sent in the OAUTHBEARER client initial response, empty when the token identity is used
+	AuthzID string
+	// Extensions are the SASL extensions (KIP-342) sent in the OAUTHBEARER client initial response
+	Extensions map[string]string
 }
 
 type TokenProvider interface {
@@ -25,11 +29,19 @@ type TokenProviderFactory interface {
 type VerifyRequest struct {
 	Token  string
 	Params []string
+	// Connection of the client, empty when the token is not verified for a client connection e.g. gateway auth
+	Connection ConnectionInfo
+	// AuthzID is the authorization identity of the OAUTHBEARER client initial response
+	AuthzID string
+	// Extensions are the SASL extensions of the OAUTHBEARER client initial response
+	Extensions map[string]string
 }
 
 type VerifyResponse struct {
 	Success bool
 	Status  int32
+	// Principal of the token owner, returned only by plugins of the protocol version 2 and built-in token infos
+	Principal Principal
 }
 
 type TokenInfo interface {
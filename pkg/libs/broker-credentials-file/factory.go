@@ -0,0 +1,41 @@
+package brokercredentialsfile
+
+import (
+	"flag"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/registry"
+)
+
+func init() {
+	registry.NewComponentInterface(new(apis.BrokerCredentialsProviderFactory))
+	registry.Register(new(Factory), "broker-credentials-file")
+}
+
+type pluginMeta struct {
+	file  string
+	watch bool
+}
+
+func (f *pluginMeta) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("broker-credentials-file settings", flag.ContinueOnError)
+	fs.StringVar(&f.file, "file", "", "Path to the YAML file mapping principals and groups to broker credentials")
+	fs.BoolVar(&f.watch, "watch", true, "Reload the broker credentials file on change")
+	return fs
+}
+
+type Factory struct {
+}
+
+// New implements apis.BrokerCredentialsProviderFactory
+func (t *Factory) New(params []string) (apis.BrokerCredentialsProvider, error) {
+	pluginMeta := &pluginMeta{}
+	fs := pluginMeta.flagSet()
+	if err := fs.Parse(params); err != nil {
+		return nil, err
+	}
+	return NewBrokerCredentialsProvider(BrokerCredentialsProviderOptions{
+		File:  pluginMeta.file,
+		Watch: pluginMeta.watch,
+	})
+}
@@ -0,0 +1,140 @@
+package brokercredentialsfile
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+type BrokerCredentialsProviderOptions struct {
+	File  string
+	Watch bool
+}
+
+// BrokerCredentialsProvider maps principals to broker credentials from a YAML file
+//
+//	principals:
+//	  - name: alice
+//	    username: team-a
+//	    password: secret-a
+//	groups:
+//	  - name: team-b
+//	    username: team-b
+//	    password: secret-b
+//
+// The credentials of the principal name take precedence over the credentials of the first mapped group of the principal.
+type BrokerCredentialsProvider struct {
+	mu       sync.RWMutex
+	mappings mappings
+}
+
+type mappings struct {
+	principals map[string]apis.BrokerCredentials
+	groups     map[string]apis.BrokerCredentials
+}
+
+type yamlMapping struct {
+	Name     string `yaml:"name"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type yamlBrokerCredentialsFile struct {
+	Principals []yamlMapping `yaml:"principals"`
+	Groups     []yamlMapping `yaml:"groups"`
+}
+
+func NewBrokerCredentialsProvider(options BrokerCredentialsProviderOptions) (*BrokerCredentialsProvider, error) {
+	if options.File == "" {
+		return nil, errors.New("parameter file is required")
+	}
+	mappings, err := readBrokerCredentialsFile(options.File)
+	if err != nil {
+		return nil, err
+	}
+	provider := &BrokerCredentialsProvider{mappings: mappings}
+	if options.Watch {
+		action := func() {
+			logrus.Infof("reloading broker credentials file %s", options.File)
+
+			mappings, err := readBrokerCredentialsFile(options.File)
+			if err != nil {
+				logrus.Errorf("error while reloading broker credentials file: %s", err)
+				return
+			}
+			provider.mu.Lock()
+			provider.mappings = mappings
+			provider.mu.Unlock()
+		}
+		if err = util.WatchForUpdates(options.File, make(chan bool, 1), action); err != nil {
+			return nil, errors.Wrap(err, "cannot watch broker credentials file")
+		}
+	}
+	return provider, nil
+}
+
+// GetBrokerCredentials implements apis.BrokerCredentialsProvider
+func (p *BrokerCredentialsProvider) GetBrokerCredentials(_ context.Context, request apis.BrokerCredentialsRequest) (apis.BrokerCredentials, bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if credentials, ok := p.mappings.principals[request.Principal.Name]; ok {
+		return credentials, true, nil
+	}
+	for _, group := range request.Principal.Groups {
+		if credentials, ok := p.mappings.groups[group]; ok {
+			return credentials, true, nil
+		}
+	}
+	return apis.BrokerCredentials{}, false, nil
+}
+
+func readBrokerCredentialsFile(filename string) (mappings, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return mappings{}, errors.Wrapf(err, "failed to read broker credentials file '%s'", filename)
+	}
+	result, err := parseBrokerCredentials(content)
+	if err != nil {
+		return mappings{}, errors.Wrapf(err, "failed to parse broker credentials file '%s'", filename)
+	}
+	return result, nil
+}
+
+func parseBrokerCredentials(content []byte) (mappings, error) {
+	var file yamlBrokerCredentialsFile
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return mappings{}, err
+	}
+	principals, err := credentialsByName("principal", file.Principals)
+	if err != nil {
+		return mappings{}, err
+	}
+	groups, err := credentialsByName("group", file.Groups)
+	if err != nil {
+		return mappings{}, err
+	}
+	return mappings{principals: principals, groups: groups}, nil
+}
+
+func credentialsByName(kind string, items []yamlMapping) (map[string]apis.BrokerCredentials, error) {
+	result := make(map[string]apis.BrokerCredentials, len(items))
+	for i, item := range items {
+		if item.Name == "" {
+			return nil, errors.Errorf("%s %d: name is required", kind, i+1)
+		}
+		if item.Username == "" || item.Password == "" {
+			return nil, errors.Errorf("%s '%s': username and password are required", kind, item.Name)
+		}
+		if _, ok := result[item.Name]; ok {
+			return nil, errors.Errorf("%s '%s' configured twice", kind, item.Name)
+		}
+		result[item.Name] = apis.BrokerCredentials{Username: item.Username, Password: item.Password}
+	}
+	return result, nil
+}
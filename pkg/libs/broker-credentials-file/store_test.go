@@ -0,0 +1,91 @@
+package brokercredentialsfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/stretchr/testify/assert"
+)
+
+const testBrokerCredentials = `
+principals:
+  - name: alice
+    username: team-a
+    password: secret-a
+groups:
+  - name: team-b
+    username: team-b
+    password: secret-b
+  - name: team-c
+    username: team-c
+    password: secret-c
+`
+
+func TestGetBrokerCredentials(t *testing.T) {
+	a := assert.New(t)
+	filename := filepath.Join(t.TempDir(), "broker-credentials.yaml")
+	a.Nil(os.WriteFile(filename, []byte(testBrokerCredentials), 0600))
+
+	provider, err := NewBrokerCredentialsProvider(BrokerCredentialsProviderOptions{File: filename})
+	a.Nil(err)
+
+	tests := []struct {
+		principal   apis.Principal
+		found       bool
+		credentials apis.BrokerCredentials
+	}{
+		{principal: apis.Principal{Name: "alice", Groups: []string{"team-b"}}, found: true, credentials: apis.BrokerCredentials{Username: "team-a", Password: "secret-a"}},
+		{principal: apis.Principal{Name: "bob", Groups: []string{"other", "team-c", "team-b"}}, found: true, credentials: apis.BrokerCredentials{Username: "team-c", Password: "secret-c"}},
+		{principal: apis.Principal{Name: "carol", Groups: []string{"other"}}, found: false},
+		{principal: apis.Principal{}, found: false},
+	}
+	for _, tt := range tests {
+		credentials, found, err := provider.GetBrokerCredentials(context.Background(), apis.BrokerCredentialsRequest{Principal: tt.principal})
+		a.Nil(err)
+		a.Equal(tt.found, found, tt.principal.Name)
+		a.Equal(tt.credentials, credentials, tt.principal.Name)
+	}
+}
+
+func TestParseBrokerCredentialsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		errorMsg string
+	}{
+		{
+			name:     "missing name",
+			content:  "principals:\n  - username: u\n    password: p\n",
+			errorMsg: "principal 1: name is required",
+		},
+		{
+			name:     "missing password",
+			content:  "groups:\n  - name: team-a\n    username: u\n",
+			errorMsg: "group 'team-a': username and password are required",
+		},
+		{
+			name:     "duplicate",
+			content:  "principals:\n  - name: alice\n    username: u\n    password: p\n  - name: alice\n    username: u\n    password: p\n",
+			errorMsg: "principal 'alice' configured twice",
+		},
+		{
+			name:     "unknown field",
+			content:  "users:\n  - name: alice\n",
+			errorMsg: "yaml: unmarshal errors:\n  line 1: field users not found in type brokercredentialsfile.yamlBrokerCredentialsFile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseBrokerCredentials([]byte(tt.content))
+			assert.EqualError(t, err, tt.errorMsg)
+		})
+	}
+}
+
+func TestNewBrokerCredentialsProviderRequiresFile(t *testing.T) {
+	_, err := NewBrokerCredentialsProvider(BrokerCredentialsProviderOptions{})
+	assert.EqualError(t, err, "parameter file is required")
+}
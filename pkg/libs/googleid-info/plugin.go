@@ -190,7 +190,8 @@ func (p *TokenInfo) VerifyToken(parent context.Context, request apis.VerifyReque
 	if err != nil {
 		return getVerifyResponseResponse(StatusWrongSignature)
 	}
-	return apis.VerifyResponse{Success: true}, nil
+	principal := apis.Principal{Name: token.ClaimSet.Email, Attributes: map[string]string{"sub": token.ClaimSet.Sub, "iss": token.ClaimSet.Iss}}
+	return apis.VerifyResponse{Success: true, Principal: principal}, nil
 }
 
 func (p *TokenInfo) checkEmail(email string) bool {
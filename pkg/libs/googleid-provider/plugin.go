@@ -29,6 +29,9 @@ type TokenProvider struct {
 	timeout       time.Duration
 	idTokenSource idTokenSource
 
+	authzid        string
+	saslExtensions map[string]string
+
 	idToken *googleid.Token
 	l       sync.RWMutex
 }
@@ -40,6 +43,10 @@ type TokenProviderOptions struct {
 	CredentialsWatch bool
 	CredentialsFile  string
 	TargetAudience   string
+
+	// AuthzID and SaslExtensions are sent with the token in the OAUTHBEARER client initial response
+	AuthzID        string
+	SaslExtensions map[string]string
 }
 
 func NewTokenProvider(options TokenProviderOptions) (*TokenProvider, error) {
@@ -77,7 +84,7 @@ func NewTokenProvider(options TokenProviderOptions) (*TokenProvider, error) {
 			}
 		}
 	}
-	tokenProvider := &TokenProvider{timeout: time.Duration(options.Timeout) * time.Second, idTokenSource: idTokenSource}
+	tokenProvider := &TokenProvider{timeout: time.Duration(options.Timeout) * time.Second, idTokenSource: idTokenSource, authzid: options.AuthzID, saslExtensions: options.SaslExtensions}
 	op := func() error {
 		return initToken(tokenProvider)
 	}
@@ -154,7 +161,7 @@ func (p *TokenProvider) GetToken(parent context.Context, _ apis.TokenRequest) (a
 
 	currentToken := p.getCurrentToken()
 	if currentToken != "" {
-		return getTokenResponse(currentToken, StatusOK)
+		return p.getTokenResponse(currentToken, StatusOK)
 	}
 
 	ctx, cancel := context.WithTimeout(parent, p.timeout)
@@ -163,23 +170,26 @@ func (p *TokenProvider) GetToken(parent context.Context, _ apis.TokenRequest) (a
 	token, err := p.idTokenSource.GetIDToken(ctx)
 	if err != nil {
 		logrus.Error(err)
-		return getTokenResponse("", StatusGetTokenFailed)
+		return p.getTokenResponse("", StatusGetTokenFailed)
 	}
 
 	idToken, err := googleid.ParseJWT(token)
 	if err != nil {
 		logrus.Error(err)
-		return getTokenResponse("", StatusParseTokenFailed)
+		return p.getTokenResponse("", StatusParseTokenFailed)
 	}
 	p.setCurrentToken(idToken)
 	logrus.Infof("New token expiry %d (%v)", idToken.ClaimSet.Exp, time.Unix(idToken.ClaimSet.Exp, 0))
 
-	return getTokenResponse(token, StatusOK)
+	return p.getTokenResponse(token, StatusOK)
 }
 
-func getTokenResponse(token string, status int) (apis.TokenResponse, error) {
+func (p *TokenProvider) getTokenResponse(token string, status int) (apis.TokenResponse, error) {
 	success := status == StatusOK
-	return apis.TokenResponse{Success: success, Status: int32(status), Token: token}, nil
+	if !success {
+		return apis.TokenResponse{Success: success, Status: int32(status), Token: token}, nil
+	}
+	return apis.TokenResponse{Success: success, Status: int32(status), Token: token, AuthzID: p.authzid, Extensions: p.saslExtensions}, nil
 }
 
 type idTokenSource interface {
@@ -0,0 +1,69 @@
+package httpauth
+
+import (
+	"flag"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/registry"
+)
+
+func init() {
+	registry.NewComponentInterface(new(apis.PasswordAuthenticatorFactory))
+	registry.Register(new(Factory), "http-auth")
+}
+
+func (f *pluginMeta) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("http auth settings", flag.ContinueOnError)
+	return fs
+}
+
+type pluginMeta struct {
+	url             string
+	timeout         time.Duration
+	retries         int
+	retryBackoff    time.Duration
+	caCertFile      string
+	clientCertFile  string
+	clientKeyFile   string
+	bearerToken     string
+	bearerTokenFile string
+}
+
+// Factory type
+type Factory struct {
+}
+
+// New implements apis.PasswordAuthenticatorFactory
+func (t *Factory) New(params []string) (apis.PasswordAuthenticator, error) {
+	pluginMeta := &pluginMeta{}
+	fs := pluginMeta.flagSet()
+	fs.StringVar(&pluginMeta.url, "url", "", "URL of the HTTP authentication endpoint")
+	fs.DurationVar(&pluginMeta.timeout, "timeout", 5*time.Second, "Request timeout")
+	fs.IntVar(&pluginMeta.retries, "retries", 2, "Number of retries of failed requests")
+	fs.DurationVar(&pluginMeta.retryBackoff, "retry-backoff", 200*time.Millisecond, "Wait time between the retries")
+	fs.StringVar(&pluginMeta.caCertFile, "ca-cert-file", "", "PEM encoded CA certificates used to verify the endpoint certificate")
+	fs.StringVar(&pluginMeta.clientCertFile, "client-cert-file", "", "PEM encoded client certificate for mTLS")
+	fs.StringVar(&pluginMeta.clientKeyFile, "client-key-file", "", "PEM encoded client key for mTLS")
+	fs.StringVar(&pluginMeta.bearerToken, "bearer-token", "", "Bearer token sent to the endpoint")
+	fs.StringVar(&pluginMeta.bearerTokenFile, "bearer-token-file", "", "Location of the file with the bearer token, the file is read on every request")
+
+	err := fs.Parse(params)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := PasswordAuthenticatorOptions{
+		URL:             pluginMeta.url,
+		Timeout:         pluginMeta.timeout,
+		Retries:         pluginMeta.retries,
+		RetryBackoff:    pluginMeta.retryBackoff,
+		CACertFile:      pluginMeta.caCertFile,
+		ClientCertFile:  pluginMeta.clientCertFile,
+		ClientKeyFile:   pluginMeta.clientKeyFile,
+		BearerToken:     pluginMeta.bearerToken,
+		BearerTokenFile: pluginMeta.bearerTokenFile,
+	}
+
+	return NewPasswordAuthenticator(opts)
+}
@@ -0,0 +1,261 @@
+package httpauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusOK       = 0
+	StatusRejected = 1
+)
+
+type PasswordAuthenticatorOptions struct {
+	URL     string
+	Timeout time.Duration
+	// Retries is the number of retries of failed requests, rejections are not retried
+	Retries      int
+	RetryBackoff time.Duration
+	// CACertFile is used to verify the endpoint certificate, system roots are used when empty
+	CACertFile     string
+	ClientCertFile string
+	ClientKeyFile  string
+	// BearerToken or the content of BearerTokenFile is sent in the Authorization header
+	BearerToken     string
+	BearerTokenFile string
+}
+
+// PasswordAuthenticator authenticates users by the HTTP endpoint
+type PasswordAuthenticator struct {
+	url             string
+	httpClient      *http.Client
+	retries         int
+	retryBackoff    time.Duration
+	bearerToken     string
+	bearerTokenFile string
+}
+
+type authenticateRequest struct {
+	Username   string            `json:"username"`
+	Password   string            `json:"password"`
+	Connection connectionRequest `json:"connection"`
+}
+
+type connectionRequest struct {
+	ClientAddress              string `json:"client_address,omitempty"`
+	ListenerAddress            string `json:"listener_address,omitempty"`
+	ListenerProfile            string `json:"listener_profile,omitempty"`
+	TLSClientSubject           string `json:"tls_client_subject,omitempty"`
+	TLSServerName              string `json:"tls_server_name,omitempty"`
+	TLSClientPrincipal         string `json:"tls_client_principal,omitempty"`
+	ProxySSL                   bool   `json:"proxy_ssl,omitempty"`
+	ProxySSLClientCert         bool   `json:"proxy_ssl_client_cert,omitempty"`
+	ProxySSLClientCertVerified bool   `json:"proxy_ssl_client_cert_verified,omitempty"`
+	ProxySSLVersion            string `json:"proxy_ssl_version,omitempty"`
+	ProxySSLClientCN           string `json:"proxy_ssl_client_cn,omitempty"`
+}
+
+// authenticateResponse is the optional JSON body of the endpoint response
+type authenticateResponse struct {
+	Authenticated *bool              `json:"authenticated"`
+	Status        *int32             `json:"status"`
+	Principal     *principalResponse `json:"principal"`
+}
+
+type principalResponse struct {
+	Name       string            `json:"name"`
+	Groups     []string          `json:"groups"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+func NewPasswordAuthenticator(options PasswordAuthenticatorOptions) (*PasswordAuthenticator, error) {
+	if options.URL == "" {
+		return nil, errors.New("parameter url is required")
+	}
+	if _, err := url.ParseRequestURI(options.URL); err != nil {
+		return nil, errors.Wrap(err, "invalid url")
+	}
+	if options.BearerToken != "" && options.BearerTokenFile != "" {
+		return nil, errors.New("parameters bearer-token and bearer-token-file are mutually exclusive")
+	}
+	if (options.ClientCertFile == "") != (options.ClientKeyFile == "") {
+		return nil, errors.New("parameters client-cert-file and client-key-file must be set together")
+	}
+	tlsConfig, err := newTLSConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	logrus.Infof("HTTP authentication url: %s", options.URL)
+	return &PasswordAuthenticator{
+		url:             options.URL,
+		httpClient:      &http.Client{Timeout: options.Timeout, Transport: transport},
+		retries:         options.Retries,
+		retryBackoff:    options.RetryBackoff,
+		bearerToken:     options.BearerToken,
+		bearerTokenFile: options.BearerTokenFile,
+	}, nil
+}
+
+func newTLSConfig(options PasswordAuthenticatorOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if options.CACertFile != "" {
+		caCert, err := os.ReadFile(options.CACertFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading of CA cert file failed")
+		}
+		rootCAs := x509.NewCertPool()
+		if ok := rootCAs.AppendCertsFromPEM(caCert); !ok {
+			return nil, fmt.Errorf("no certificates found in CA cert file %s", options.CACertFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if options.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(options.ClientCertFile, options.ClientKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading of client certificate failed")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Authenticate implements apis.PasswordAuthenticator
+func (p *PasswordAuthenticator) Authenticate(username, password string) (bool, int32, error) {
+	resp, err := p.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: username, Password: password})
+	return resp.Authenticated, resp.Status, err
+}
+
+// AuthenticatePrincipal implements apis.PasswordAuthenticatorV2
+func (p *PasswordAuthenticator) AuthenticatePrincipal(ctx context.Context, request apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	body, err := json.Marshal(authenticateRequest{
+		Username: request.Username,
+		Password: request.Password,
+		Connection: connectionRequest{
+			ClientAddress:              request.Connection.ClientAddress,
+			ListenerAddress:            request.Connection.ListenerAddress,
+			ListenerProfile:            request.Connection.ListenerProfile,
+			TLSClientSubject:           request.Connection.TLSClientSubject,
+			TLSServerName:              request.Connection.TLSServerName,
+			TLSClientPrincipal:         request.Connection.TLSClientPrincipal,
+			ProxySSL:                   request.Connection.ProxySSL,
+			ProxySSLClientCert:         request.Connection.ProxySSLClientCert,
+			ProxySSLClientCertVerified: request.Connection.ProxySSLClientCertVerified,
+			ProxySSLVersion:            request.Connection.ProxySSLVersion,
+			ProxySSLClientCN:           request.Connection.ProxySSLClientCN,
+		},
+	})
+	if err != nil {
+		return apis.AuthenticateResponse{}, err
+	}
+	var resp apis.AuthenticateResponse
+	op := func() error {
+		resp, err = p.post(ctx, body)
+		return err
+	}
+	err = backoff.Retry(op, backoff.WithContext(p.newBackOff(), ctx))
+	if err != nil {
+		return apis.AuthenticateResponse{}, errors.Wrap(err, "http authentication failed")
+	}
+	return resp, nil
+}
+
+func (p *PasswordAuthenticator) newBackOff() backoff.BackOff {
+	// WithMaxTries retries forever when max is 0
+	if p.retries <= 0 {
+		return &backoff.StopBackOff{}
+	}
+	return backoff.WithMaxTries(backoff.NewConstantBackOff(p.retryBackoff), uint64(p.retries))
+}
+
+// post sends the authentication request, errors of the server responses and the transport are retried
+func (p *PasswordAuthenticator) post(ctx context.Context, body []byte) (apis.AuthenticateResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return apis.AuthenticateResponse{}, backoff.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	bearerToken, err := p.getBearerToken()
+	if err != nil {
+		return apis.AuthenticateResponse{}, backoff.Permanent(err)
+	}
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+	httpResp, err := p.httpClient.Do(req)
+	if err != nil {
+		return apis.AuthenticateResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return apis.AuthenticateResponse{}, err
+	}
+	switch {
+	case httpResp.StatusCode >= 200 && httpResp.StatusCode < 300:
+		return newAuthenticateResponse(respBody, true, StatusOK)
+	case httpResp.StatusCode == http.StatusUnauthorized || httpResp.StatusCode == http.StatusForbidden:
+		return newAuthenticateResponse(respBody, false, StatusRejected)
+	case httpResp.StatusCode == http.StatusTooManyRequests || httpResp.StatusCode >= 500:
+		return apis.AuthenticateResponse{}, fmt.Errorf("authentication endpoint returned status %d", httpResp.StatusCode)
+	default:
+		return apis.AuthenticateResponse{}, backoff.Permanent(fmt.Errorf("authentication endpoint returned status %d", httpResp.StatusCode))
+	}
+}
+
+// newAuthenticateResponse maps the optional JSON body, the status code defines the result when the body does not
+func newAuthenticateResponse(body []byte, authenticated bool, status int32) (apis.AuthenticateResponse, error) {
+	resp := apis.AuthenticateResponse{Authenticated: authenticated, Status: status}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return resp, nil
+	}
+	var decoded authenticateResponse
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return apis.AuthenticateResponse{}, backoff.Permanent(errors.Wrap(err, "decoding of authentication response failed"))
+	}
+	// the body cannot authenticate a rejected request
+	if decoded.Authenticated != nil && authenticated {
+		resp.Authenticated = *decoded.Authenticated
+		if !resp.Authenticated {
+			resp.Status = StatusRejected
+		}
+	}
+	if decoded.Status != nil {
+		resp.Status = *decoded.Status
+	}
+	if decoded.Principal != nil && resp.Authenticated {
+		resp.Principal = apis.Principal{Name: decoded.Principal.Name, Groups: decoded.Principal.Groups, Attributes: decoded.Principal.Attributes}
+	}
+	return resp, nil
+}
+
+func (p *PasswordAuthenticator) getBearerToken() (string, error) {
+	if p.bearerTokenFile == "" {
+		return p.bearerToken, nil
+	}
+	// the file is read on every request to pick up rotated tokens
+	data, err := os.ReadFile(p.bearerTokenFile)
+	if err != nil {
+		return "", errors.Wrap(err, "reading of bearer token file failed")
+	}
+	return strings.TrimSpace(string(data)), nil
+}
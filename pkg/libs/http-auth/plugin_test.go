@@ -0,0 +1,323 @@
+package httpauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAuthServer records the authentication requests and answers with the configured handler
+type testAuthServer struct {
+	server   *httptest.Server
+	handler  func(w http.ResponseWriter, req authenticateRequest)
+	requests []authenticateRequest
+	headers  []http.Header
+	l        sync.Mutex
+}
+
+func newTestAuthServer(handler func(w http.ResponseWriter, req authenticateRequest)) *testAuthServer {
+	s := &testAuthServer{handler: handler}
+	s.server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *testAuthServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req authenticateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.l.Lock()
+	s.requests = append(s.requests, req)
+	s.headers = append(s.headers, r.Header.Clone())
+	s.l.Unlock()
+	s.handler(w, req)
+}
+
+func (s *testAuthServer) getRequests() []authenticateRequest {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return append([]authenticateRequest(nil), s.requests...)
+}
+
+func credentialsHandler(w http.ResponseWriter, req authenticateRequest) {
+	switch req.Username {
+	case "alice":
+		if req.Password != "alice-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"principal":{"name":"User:alice","groups":["admins"],"attributes":{"tenant":"a"}}}`))
+	case "bob":
+		w.WriteHeader(http.StatusNoContent)
+	case "carol":
+		_, _ = w.Write([]byte(`{"authenticated":false,"status":42}`))
+	case "dave":
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"status":43}`))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestAuthenticatePrincipal(t *testing.T) {
+	server := newTestAuthServer(credentialsHandler)
+	server.server.Start()
+	defer server.server.Close()
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		expected apis.AuthenticateResponse
+		err      bool
+	}{
+		{
+			name:     "authenticated with principal",
+			username: "alice",
+			password: "alice-secret",
+			expected: apis.AuthenticateResponse{Authenticated: true, Status: StatusOK, Principal: apis.Principal{Name: "User:alice", Groups: []string{"admins"}, Attributes: map[string]string{"tenant": "a"}}},
+		},
+		{
+			name:     "authenticated without body",
+			username: "bob",
+			expected: apis.AuthenticateResponse{Authenticated: true, Status: StatusOK},
+		},
+		{
+			name:     "unauthorized status code",
+			username: "alice",
+			password: "wrong",
+			expected: apis.AuthenticateResponse{Authenticated: false, Status: StatusRejected},
+		},
+		{
+			name:     "rejected by body",
+			username: "carol",
+			expected: apis.AuthenticateResponse{Authenticated: false, Status: 42},
+		},
+		{
+			name:     "forbidden with status",
+			username: "dave",
+			expected: apis.AuthenticateResponse{Authenticated: false, Status: 43},
+		},
+		{
+			name:     "unexpected status code",
+			username: "eve",
+			err:      true,
+		},
+	}
+	authenticator, err := NewPasswordAuthenticator(PasswordAuthenticatorOptions{URL: server.server.URL, Timeout: 5 * time.Second, BearerToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection := apis.ConnectionInfo{ClientAddress: "10.0.0.1:5000", ListenerAddress: "0.0.0.0:32400", ListenerProfile: "internal"}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			resp, err := authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: tc.username, Password: tc.password, Connection: connection})
+			if tc.err {
+				a.NotNil(err)
+				return
+			}
+			a.Nil(err)
+			a.Equal(tc.expected, resp)
+		})
+	}
+	a := assert.New(t)
+	requests := server.getRequests()
+	a.Equal(authenticateRequest{Username: "alice", Password: "alice-secret", Connection: connectionRequest{ClientAddress: "10.0.0.1:5000", ListenerAddress: "0.0.0.0:32400", ListenerProfile: "internal"}}, requests[0])
+	a.Equal("Bearer token", server.headers[0].Get("Authorization"))
+
+	// apis.PasswordAuthenticator
+	ok, status, err := authenticator.Authenticate("alice", "alice-secret")
+	a.Nil(err)
+	a.True(ok)
+	a.Equal(int32(StatusOK), status)
+}
+
+func TestAuthenticateRetries(t *testing.T) {
+	a := assert.New(t)
+
+	failures := 2
+	server := newTestAuthServer(func(w http.ResponseWriter, req authenticateRequest) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server.server.Start()
+	defer server.server.Close()
+
+	authenticator, err := NewPasswordAuthenticator(PasswordAuthenticatorOptions{URL: server.server.URL, Timeout: 5 * time.Second, Retries: 2, RetryBackoff: time.Millisecond})
+	a.Nil(err)
+	resp, err := authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: "alice"})
+	a.Nil(err)
+	a.True(resp.Authenticated)
+	a.Len(server.getRequests(), 3)
+
+	// retries exhausted
+	failures = 3
+	_, err = authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: "alice"})
+	a.NotNil(err)
+	a.Len(server.getRequests(), 6)
+
+	// rejections are not retried
+	server.handler = func(w http.ResponseWriter, req authenticateRequest) { w.WriteHeader(http.StatusUnauthorized) }
+	resp, err = authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: "alice"})
+	a.Nil(err)
+	a.False(resp.Authenticated)
+	a.Len(server.getRequests(), 7)
+}
+
+func TestAuthenticateTimeout(t *testing.T) {
+	a := assert.New(t)
+
+	done := make(chan struct{})
+	server := newTestAuthServer(func(w http.ResponseWriter, req authenticateRequest) {
+		<-done
+	})
+	server.server.Start()
+	defer server.server.Close()
+	defer close(done)
+
+	authenticator, err := NewPasswordAuthenticator(PasswordAuthenticatorOptions{URL: server.server.URL, Timeout: 50 * time.Millisecond})
+	a.Nil(err)
+	_, err = authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: "alice"})
+	a.NotNil(err)
+}
+
+func TestAuthenticateMutualTLS(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	ca, caKey := newTestCertificate(t, "test-ca", nil, nil)
+	serverCert, serverKey := newTestCertificate(t, "localhost", ca, caKey)
+	clientCert, clientKey := newTestCertificate(t, "kafka-proxy", ca, caKey)
+	caCertFile := writeTestPEM(t, dir, "ca.pem", "CERTIFICATE", ca.Raw)
+	clientCertFile := writeTestPEM(t, dir, "client.pem", "CERTIFICATE", clientCert.Raw)
+	clientKeyFile := writeTestPEM(t, dir, "client-key.pem", "EC PRIVATE KEY", marshalTestKey(t, clientKey))
+	tokenFile := filepath.Join(dir, "token")
+	a.Nil(os.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+	server := newTestAuthServer(func(w http.ResponseWriter, req authenticateRequest) { w.WriteHeader(http.StatusOK) })
+	server.server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.server.StartTLS()
+	defer server.server.Close()
+
+	authenticator, err := NewPasswordAuthenticator(PasswordAuthenticatorOptions{URL: server.server.URL, Timeout: 5 * time.Second, CACertFile: caCertFile, ClientCertFile: clientCertFile, ClientKeyFile: clientKeyFile, BearerTokenFile: tokenFile})
+	a.Nil(err)
+	resp, err := authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: "alice"})
+	a.Nil(err)
+	a.True(resp.Authenticated)
+	a.Equal("Bearer file-token", server.headers[0].Get("Authorization"))
+
+	// no client certificate
+	authenticator, err = NewPasswordAuthenticator(PasswordAuthenticatorOptions{URL: server.server.URL, Timeout: 5 * time.Second, CACertFile: caCertFile})
+	a.Nil(err)
+	_, err = authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: "alice"})
+	a.NotNil(err)
+}
+
+func TestNewPasswordAuthenticatorErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  PasswordAuthenticatorOptions
+		errorMsg string
+	}{
+		{
+			name:     "no url",
+			options:  PasswordAuthenticatorOptions{},
+			errorMsg: "parameter url is required",
+		},
+		{
+			name:     "several bearer tokens",
+			options:  PasswordAuthenticatorOptions{URL: "http://localhost/auth", BearerToken: "token", BearerTokenFile: "token.txt"},
+			errorMsg: "parameters bearer-token and bearer-token-file are mutually exclusive",
+		},
+		{
+			name:     "client cert without key",
+			options:  PasswordAuthenticatorOptions{URL: "http://localhost/auth", ClientCertFile: "client.pem"},
+			errorMsg: "parameters client-cert-file and client-key-file must be set together",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewPasswordAuthenticator(tc.options)
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tc.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func newTestCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func marshalTestKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func writeTestPEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
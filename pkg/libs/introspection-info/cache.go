@@ -0,0 +1,70 @@
+package introspectioninfo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+)
+
+type cacheEntry struct {
+	resp    apis.VerifyResponse
+	expires time.Time
+}
+
+// responseCache caches the verify responses until they expire
+type responseCache struct {
+	entries    map[string]cacheEntry
+	maxEntries int
+	l          sync.Mutex
+}
+
+func newResponseCache(maxEntries int) *responseCache {
+	return &responseCache{entries: make(map[string]cacheEntry), maxEntries: maxEntries}
+}
+
+func (c *responseCache) get(key string) (apis.VerifyResponse, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return apis.VerifyResponse{}, false
+	}
+	if !nowFn().Before(entry.expires) {
+		delete(c.entries, key)
+		return apis.VerifyResponse{}, false
+	}
+	return entry.resp, true
+}
+
+func (c *responseCache) set(key string, resp apis.VerifyResponse, ttl time.Duration) {
+	if ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	now := nowFn()
+	if len(c.entries) >= c.maxEntries {
+		c.purgeExpired(now)
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+	c.entries[key] = cacheEntry{resp: resp, expires: now.Add(ttl)}
+}
+
+func (c *responseCache) purgeExpired(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *responseCache) len() int {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return len(c.entries)
+}
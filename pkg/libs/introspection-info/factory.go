@@ -0,0 +1,88 @@
+package introspectioninfo
+
+import (
+	"flag"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
+	"github.com/grepplabs/kafka-proxy/pkg/registry"
+)
+
+func init() {
+	registry.NewComponentInterface(new(apis.TokenInfoFactory))
+	registry.Register(new(Factory), "introspection-info")
+}
+
+func (f *pluginMeta) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("introspection info settings", flag.ContinueOnError)
+	return fs
+}
+
+type pluginMeta struct {
+	timeout          int
+	introspectionURL string
+	clientID         string
+	clientSecret     string
+	clientSecretFile string
+	authMethod       string
+	tokenTypeHint    string
+	issuer           string
+	audience         util.ArrayFlags
+	requiredScope    util.ArrayFlags
+	principalClaim   string
+	groupsClaim      string
+	cacheTTL         time.Duration
+	negativeCacheTTL time.Duration
+	cacheMaxEntries  int
+}
+
+// Factory type
+type Factory struct {
+}
+
+// New implements apis.TokenInfoFactory
+func (t *Factory) New(params []string) (apis.TokenInfo, error) {
+	pluginMeta := &pluginMeta{}
+	fs := pluginMeta.flagSet()
+	fs.IntVar(&pluginMeta.timeout, "timeout", 10, "Request timeout in seconds")
+	fs.StringVar(&pluginMeta.introspectionURL, "introspection-url", "", "URL of the OAuth2 token introspection endpoint")
+	fs.StringVar(&pluginMeta.clientID, "client-id", "", "Client ID used to authenticate at the introspection endpoint")
+	fs.StringVar(&pluginMeta.clientSecret, "client-secret", "", "Client secret used to authenticate at the introspection endpoint")
+	fs.StringVar(&pluginMeta.clientSecretFile, "client-secret-file", "", "Location of the file with the client secret")
+	fs.StringVar(&pluginMeta.authMethod, "auth-method", AuthMethodClientSecretBasic, "Client authentication method, client_secret_basic or client_secret_post")
+	fs.StringVar(&pluginMeta.tokenTypeHint, "token-type-hint", "access_token", "Token type hint sent to the introspection endpoint")
+	fs.StringVar(&pluginMeta.issuer, "issuer", "", "Expected issuer of the token")
+	fs.Var(&pluginMeta.audience, "audience", "The audience of a token")
+	fs.Var(&pluginMeta.requiredScope, "required-scope", "Scope the token must contain")
+	fs.StringVar(&pluginMeta.principalClaim, "principal-claim", "sub", "Introspection response member of the principal name")
+	fs.StringVar(&pluginMeta.groupsClaim, "groups-claim", "groups", "Introspection response member of the principal groups")
+	fs.DurationVar(&pluginMeta.cacheTTL, "cache-ttl", 5*time.Minute, "Maximum time an active token is cached, bounded by the token exp. 0 disables the cache")
+	fs.DurationVar(&pluginMeta.negativeCacheTTL, "negative-cache-ttl", 30*time.Second, "Time an inactive or rejected token is cached. 0 disables the cache")
+	fs.IntVar(&pluginMeta.cacheMaxEntries, "cache-max-entries", 10000, "Maximum number of cached introspection results")
+
+	err := fs.Parse(params)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := TokenInfoOptions{
+		Timeout:          time.Duration(pluginMeta.timeout) * time.Second,
+		IntrospectionURL: pluginMeta.introspectionURL,
+		ClientID:         pluginMeta.clientID,
+		ClientSecret:     pluginMeta.clientSecret,
+		ClientSecretFile: pluginMeta.clientSecretFile,
+		AuthMethod:       pluginMeta.authMethod,
+		TokenTypeHint:    pluginMeta.tokenTypeHint,
+		Issuer:           pluginMeta.issuer,
+		Audience:         pluginMeta.audience,
+		RequiredScopes:   pluginMeta.requiredScope,
+		PrincipalClaim:   pluginMeta.principalClaim,
+		GroupsClaim:      pluginMeta.groupsClaim,
+		CacheTTL:         pluginMeta.cacheTTL,
+		NegativeCacheTTL: pluginMeta.negativeCacheTTL,
+		CacheMaxEntries:  pluginMeta.cacheMaxEntries,
+	}
+
+	return NewTokenInfo(opts)
+}
@@ -0,0 +1,306 @@
+package introspectioninfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusOK                  = 0
+	StatusEmptyToken          = 1
+	StatusIntrospectionFailed = 2
+	StatusTokenInactive       = 3
+	StatusWrongIssuer         = 4
+	StatusWrongAudience       = 5
+	StatusTokenExpired        = 6
+	StatusMissingScope        = 7
+)
+
+const (
+	AuthMethodClientSecretBasic = "client_secret_basic"
+	AuthMethodClientSecretPost  = "client_secret_post"
+)
+
+var (
+	nowFn = time.Now
+)
+
+type TokenInfoOptions struct {
+	Timeout time.Duration
+	// IntrospectionURL is the RFC 7662 token introspection endpoint
+	IntrospectionURL string
+	ClientID         string
+	ClientSecret     string
+	ClientSecretFile string
+	// AuthMethod is client_secret_basic or client_secret_post
+	AuthMethod    string
+	TokenTypeHint string
+	Issuer        string
+	Audience      []string
+	// RequiredScopes must be contained in the space-delimited scope of the introspection response
+	RequiredScopes []string
+	PrincipalClaim string
+	GroupsClaim    string
+	// CacheTTL is the maximum time an active token is cached, the token exp bounds the TTL
+	CacheTTL time.Duration
+	// NegativeCacheTTL is the time an inactive or rejected token is cached
+	NegativeCacheTTL time.Duration
+	CacheMaxEntries  int
+}
+
+type TokenInfo struct {
+	httpClient       *http.Client
+	introspectionURL string
+	clientID         string
+	clientSecret     string
+	authMethod       string
+	tokenTypeHint    string
+	issuer           string
+	audience         map[string]struct{}
+	requiredScopes   []string
+	principalClaim   string
+	groupsClaim      string
+	cacheTTL         time.Duration
+	negativeCacheTTL time.Duration
+
+	cache *responseCache
+}
+
+func NewTokenInfo(options TokenInfoOptions) (*TokenInfo, error) {
+	if options.IntrospectionURL == "" {
+		return nil, errors.New("parameter introspection-url is required")
+	}
+	if _, err := url.ParseRequestURI(options.IntrospectionURL); err != nil {
+		return nil, errors.Wrap(err, "invalid introspection-url")
+	}
+	if options.ClientSecret != "" && options.ClientSecretFile != "" {
+		return nil, errors.New("parameters client-secret and client-secret-file are mutually exclusive")
+	}
+	authMethod := options.AuthMethod
+	if authMethod == "" {
+		authMethod = AuthMethodClientSecretBasic
+	}
+	if authMethod != AuthMethodClientSecretBasic && authMethod != AuthMethodClientSecretPost {
+		return nil, fmt.Errorf("unsupported auth method %s", authMethod)
+	}
+	clientSecret := options.ClientSecret
+	if options.ClientSecretFile != "" {
+		data, err := os.ReadFile(options.ClientSecretFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading of client secret file failed")
+		}
+		clientSecret = strings.TrimSpace(string(data))
+	}
+	tokenInfo := &TokenInfo{
+		httpClient:       &http.Client{Timeout: options.Timeout},
+		introspectionURL: options.IntrospectionURL,
+		clientID:         options.ClientID,
+		clientSecret:     clientSecret,
+		authMethod:       authMethod,
+		tokenTypeHint:    options.TokenTypeHint,
+		issuer:           options.Issuer,
+		audience:         make(map[string]struct{}),
+		requiredScopes:   options.RequiredScopes,
+		principalClaim:   options.PrincipalClaim,
+		groupsClaim:      options.GroupsClaim,
+		cacheTTL:         options.CacheTTL,
+		negativeCacheTTL: options.NegativeCacheTTL,
+		cache:            newResponseCache(options.CacheMaxEntries),
+	}
+	for _, elem := range options.Audience {
+		tokenInfo.audience[elem] = struct{}{}
+	}
+	logrus.Infof("Token introspection url: %s, audience: %v, required scopes: %v", options.IntrospectionURL, options.Audience, options.RequiredScopes)
+	return tokenInfo, nil
+}
+
+// VerifyToken implements apis.TokenInfo
+func (p *TokenInfo) VerifyToken(ctx context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
+	if request.Token == "" {
+		return getVerifyResponseResponse(StatusEmptyToken)
+	}
+	key := cacheKey(request.Token)
+	if resp, ok := p.cache.get(key); ok {
+		return resp, nil
+	}
+	introspection, err := p.introspect(ctx, request.Token)
+	if err != nil {
+		// failures of the introspection endpoint are not cached
+		logrus.Errorf("token introspection failed: %v", err)
+		return getVerifyResponseResponse(StatusIntrospectionFailed)
+	}
+	resp, _ := p.verifyIntrospection(introspection)
+	p.cache.set(key, resp, p.responseTTL(resp, introspection))
+	return resp, nil
+}
+
+// responseTTL bounds the cache time of active tokens by the token expiration
+func (p *TokenInfo) responseTTL(resp apis.VerifyResponse, introspection introspectionResponse) time.Duration {
+	if !resp.Success {
+		return p.negativeCacheTTL
+	}
+	ttl := p.cacheTTL
+	if exp, ok := introspection.numericClaim("exp"); ok {
+		if untilExp := time.Unix(exp, 0).Sub(nowFn()); untilExp < ttl {
+			ttl = untilExp
+		}
+	}
+	return ttl
+}
+
+func (p *TokenInfo) verifyIntrospection(introspection introspectionResponse) (apis.VerifyResponse, error) {
+	if active, _ := introspection["active"].(bool); !active {
+		return getVerifyResponseResponse(StatusTokenInactive)
+	}
+	if p.issuer != "" && introspection.stringClaim("iss") != p.issuer {
+		return getVerifyResponseResponse(StatusWrongIssuer)
+	}
+	if len(p.audience) != 0 && !p.checkAudience(introspection.stringsClaim("aud")) {
+		return getVerifyResponseResponse(StatusWrongAudience)
+	}
+	if exp, ok := introspection.numericClaim("exp"); ok && nowFn().Unix() > exp {
+		return getVerifyResponseResponse(StatusTokenExpired)
+	}
+	if !p.checkRequiredScopes(strings.Fields(introspection.stringClaim("scope"))) {
+		return getVerifyResponseResponse(StatusMissingScope)
+	}
+	principal := apis.Principal{
+		Name:       introspection.stringClaim(p.principalClaim),
+		Groups:     introspection.stringsClaim(p.groupsClaim),
+		Attributes: make(map[string]string),
+	}
+	for _, name := range []string{"iss", "client_id"} {
+		if value := introspection.stringClaim(name); value != "" {
+			principal.Attributes[name] = value
+		}
+	}
+	return apis.VerifyResponse{Success: true, Status: StatusOK, Principal: principal}, nil
+}
+
+func (p *TokenInfo) checkAudience(audience []string) bool {
+	for _, aud := range audience {
+		if _, ok := p.audience[aud]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *TokenInfo) checkRequiredScopes(scopes []string) bool {
+	granted := make(map[string]struct{})
+	for _, scope := range scopes {
+		granted[scope] = struct{}{}
+	}
+	for _, scope := range p.requiredScopes {
+		if _, ok := granted[scope]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// introspect posts the token to the introspection endpoint authenticated with the client credentials
+func (p *TokenInfo) introspect(ctx context.Context, token string) (introspectionResponse, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	if p.tokenTypeHint != "" {
+		form.Set("token_type_hint", p.tokenTypeHint)
+	}
+	if p.authMethod == AuthMethodClientSecretPost {
+		form.Set("client_id", p.clientID)
+		form.Set("client_secret", p.clientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.introspectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.authMethod == AuthMethodClientSecretBasic && p.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned status %d", resp.StatusCode)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	introspection := make(introspectionResponse)
+	if err = decoder.Decode(&introspection); err != nil {
+		return nil, errors.Wrap(err, "decoding of introspection response failed")
+	}
+	return introspection, nil
+}
+
+// introspectionResponse is the RFC 7662 introspection response
+type introspectionResponse map[string]interface{}
+
+func (r introspectionResponse) stringClaim(name string) string {
+	value, _ := r[name].(string)
+	return value
+}
+
+func (r introspectionResponse) numericClaim(name string) (int64, bool) {
+	value, ok := r[name].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	if i, err := value.Int64(); err == nil {
+		return i, true
+	}
+	f, err := value.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+func (r introspectionResponse) stringsClaim(name string) []string {
+	switch value := r[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, elem := range value {
+			if s, ok := elem.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// cacheKey does not keep the raw token in memory
+func cacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return string(sum[:])
+}
+
+func getVerifyResponseResponse(status int) (apis.VerifyResponse, error) {
+	success := status == StatusOK
+	return apis.VerifyResponse{Success: success, Status: int32(status)}, nil
+}
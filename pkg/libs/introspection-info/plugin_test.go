@@ -0,0 +1,247 @@
+package introspectioninfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/stretchr/testify/assert"
+)
+
+// testIntrospectionServer answers introspection requests with the responses of the known tokens
+type testIntrospectionServer struct {
+	server    *httptest.Server
+	responses map[string]map[string]interface{}
+	requests  int
+	l         sync.Mutex
+}
+
+func newTestIntrospectionServer(t *testing.T, clientID, clientSecret string, responses map[string]map[string]interface{}) *testIntrospectionServer {
+	s := &testIntrospectionServer{responses: responses}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.l.Lock()
+		s.requests++
+		s.l.Unlock()
+
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		id, secret, ok := r.BasicAuth()
+		if !ok {
+			id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if r.Method != http.MethodPost || id != clientID || secret != clientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		resp, ok := s.responses[r.PostForm.Get("token")]
+		if !ok {
+			resp = map[string]interface{}{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	return s
+}
+
+func (s *testIntrospectionServer) getRequests() int {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.requests
+}
+
+func newTestTokenInfo(t *testing.T, options TokenInfoOptions) *TokenInfo {
+	if options.Timeout == 0 {
+		options.Timeout = 5 * time.Second
+	}
+	if options.PrincipalClaim == "" {
+		options.PrincipalClaim = "sub"
+	}
+	if options.GroupsClaim == "" {
+		options.GroupsClaim = "groups"
+	}
+	if options.CacheMaxEntries == 0 {
+		options.CacheMaxEntries = 100
+	}
+	tokenInfo, err := NewTokenInfo(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenInfo
+}
+
+func TestVerifyToken(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	responses := map[string]map[string]interface{}{
+		"valid":          {"active": true, "iss": "https://idp.example.com", "aud": []string{"kafka", "other"}, "scope": "kafka.read kafka.write", "sub": "alice", "client_id": "app", "groups": []string{"admins"}, "exp": exp},
+		"inactive":       {"active": false},
+		"wrong-issuer":   {"active": true, "iss": "https://other.example.com", "aud": "kafka", "scope": "kafka.write", "exp": exp},
+		"wrong-audience": {"active": true, "iss": "https://idp.example.com", "aud": "other", "scope": "kafka.write", "exp": exp},
+		"expired":        {"active": true, "iss": "https://idp.example.com", "aud": "kafka", "scope": "kafka.write", "exp": time.Now().Add(-time.Minute).Unix()},
+		"missing-scope":  {"active": true, "iss": "https://idp.example.com", "aud": "kafka", "scope": "kafka.read", "exp": exp},
+	}
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "valid", token: "valid", status: StatusOK},
+		{name: "empty token", token: "", status: StatusEmptyToken},
+		{name: "inactive", token: "inactive", status: StatusTokenInactive},
+		{name: "unknown token", token: "unknown", status: StatusTokenInactive},
+		{name: "wrong issuer", token: "wrong-issuer", status: StatusWrongIssuer},
+		{name: "wrong audience", token: "wrong-audience", status: StatusWrongAudience},
+		{name: "expired", token: "expired", status: StatusTokenExpired},
+		{name: "missing scope", token: "missing-scope", status: StatusMissingScope},
+	}
+	for _, authMethod := range []string{AuthMethodClientSecretBasic, AuthMethodClientSecretPost} {
+		t.Run(authMethod, func(t *testing.T) {
+			server := newTestIntrospectionServer(t, "proxy", "secret", responses)
+			defer server.server.Close()
+
+			tokenInfo := newTestTokenInfo(t, TokenInfoOptions{
+				IntrospectionURL: server.server.URL,
+				ClientID:         "proxy",
+				ClientSecret:     "secret",
+				AuthMethod:       authMethod,
+				Issuer:           "https://idp.example.com",
+				Audience:         []string{"kafka"},
+				RequiredScopes:   []string{"kafka.write"},
+			})
+			for _, tc := range tests {
+				t.Run(tc.name, func(t *testing.T) {
+					a := assert.New(t)
+					resp, err := tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: tc.token})
+					a.Nil(err)
+					a.Equal(int32(tc.status), resp.Status)
+					a.Equal(tc.status == StatusOK, resp.Success)
+					if tc.status == StatusOK {
+						a.Equal(apis.Principal{Name: "alice", Groups: []string{"admins"}, Attributes: map[string]string{"iss": "https://idp.example.com", "client_id": "app"}}, resp.Principal)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestVerifyTokenIntrospectionFailed(t *testing.T) {
+	a := assert.New(t)
+
+	server := newTestIntrospectionServer(t, "proxy", "secret", map[string]map[string]interface{}{"valid": {"active": true}})
+	defer server.server.Close()
+
+	tokenInfo := newTestTokenInfo(t, TokenInfoOptions{IntrospectionURL: server.server.URL, ClientID: "proxy", ClientSecret: "wrong", CacheTTL: time.Minute, NegativeCacheTTL: time.Minute})
+	for i := 0; i < 2; i++ {
+		resp, err := tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: "valid"})
+		a.Nil(err)
+		a.Equal(int32(StatusIntrospectionFailed), resp.Status)
+		a.False(resp.Success)
+	}
+	// failures of the endpoint are not cached
+	a.Equal(2, server.getRequests())
+	a.Equal(0, tokenInfo.cache.len())
+}
+
+func TestVerifyTokenCache(t *testing.T) {
+	defer func() { nowFn = time.Now }()
+	now := time.Now()
+	nowFn = func() time.Time { return now }
+
+	server := newTestIntrospectionServer(t, "proxy", "secret", map[string]map[string]interface{}{
+		"long-lived":  {"active": true, "sub": "alice", "exp": now.Add(time.Hour).Unix()},
+		"short-lived": {"active": true, "sub": "bob", "exp": now.Add(10 * time.Second).Unix()},
+	})
+	defer server.server.Close()
+
+	tokenInfo := newTestTokenInfo(t, TokenInfoOptions{IntrospectionURL: server.server.URL, ClientID: "proxy", ClientSecret: "secret", CacheTTL: time.Minute, NegativeCacheTTL: 5 * time.Second})
+	verify := func(token string) int32 {
+		resp, err := tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: token})
+		assert.Nil(t, err)
+		return resp.Status
+	}
+	a := assert.New(t)
+
+	a.Equal(int32(StatusOK), verify("long-lived"))
+	a.Equal(int32(StatusOK), verify("short-lived"))
+	a.Equal(int32(StatusTokenInactive), verify("revoked"))
+	a.Equal(3, server.getRequests())
+
+	// positive and negative results are served from the cache
+	a.Equal(int32(StatusOK), verify("long-lived"))
+	a.Equal(int32(StatusOK), verify("short-lived"))
+	a.Equal(int32(StatusTokenInactive), verify("revoked"))
+	a.Equal(3, server.getRequests())
+
+	// negative cache TTL elapsed
+	now = now.Add(6 * time.Second)
+	a.Equal(int32(StatusTokenInactive), verify("revoked"))
+	a.Equal(4, server.getRequests())
+
+	// TTL of the short-lived token is bounded by its exp
+	now = now.Add(5 * time.Second)
+	a.Equal(int32(StatusOK), verify("long-lived"))
+	a.Equal(int32(StatusTokenExpired), verify("short-lived"))
+	a.Equal(5, server.getRequests())
+
+	// cache TTL elapsed
+	now = now.Add(time.Minute)
+	a.Equal(int32(StatusOK), verify("long-lived"))
+	a.Equal(6, server.getRequests())
+}
+
+func TestResponseCacheMaxEntries(t *testing.T) {
+	a := assert.New(t)
+
+	cache := newResponseCache(2)
+	cache.set("a", apis.VerifyResponse{Success: true}, time.Minute)
+	cache.set("b", apis.VerifyResponse{Success: true}, time.Millisecond)
+	cache.set("c", apis.VerifyResponse{Success: true}, time.Minute)
+	a.Equal(2, cache.len())
+	_, ok := cache.get("c")
+	a.False(ok)
+
+	time.Sleep(5 * time.Millisecond)
+	cache.set("c", apis.VerifyResponse{Success: true}, time.Minute)
+	_, ok = cache.get("c")
+	a.True(ok)
+	_, ok = cache.get("b")
+	a.False(ok)
+}
+
+func TestNewTokenInfoErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  TokenInfoOptions
+		errorMsg string
+	}{
+		{
+			name:     "no introspection url",
+			options:  TokenInfoOptions{},
+			errorMsg: "parameter introspection-url is required",
+		},
+		{
+			name:     "several client secrets",
+			options:  TokenInfoOptions{IntrospectionURL: "http://localhost/introspect", ClientSecret: "secret", ClientSecretFile: "secret.txt"},
+			errorMsg: "parameters client-secret and client-secret-file are mutually exclusive",
+		},
+		{
+			name:     "unsupported auth method",
+			options:  TokenInfoOptions{IntrospectionURL: "http://localhost/introspect", AuthMethod: "private_key_jwt"},
+			errorMsg: "unsupported auth method private_key_jwt",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTokenInfo(tc.options)
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tc.errorMsg, err.Error())
+			}
+		})
+	}
+}
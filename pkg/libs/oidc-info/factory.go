@@ -0,0 +1,85 @@
+package oidcinfo
+
+import (
+	"flag"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
+	"github.com/grepplabs/kafka-proxy/pkg/registry"
+)
+
+func init() {
+	registry.NewComponentInterface(new(apis.TokenInfoFactory))
+	registry.Register(new(Factory), "oidc-info")
+}
+
+func (f *pluginMeta) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("oidc info settings", flag.ContinueOnError)
+	return fs
+}
+
+type pluginMeta struct {
+	timeout                int
+	issuerURL              string
+	jwksURL                string
+	jwksFile               string
+	jwksRefreshInterval    int
+	jwksMinRefreshInterval int
+	audience               util.ArrayFlags
+	algorithm              util.ArrayFlags
+	clockSkew              time.Duration
+	requiredClaim          util.ArrayFlags
+	requiredScope          util.ArrayFlags
+	scopeClaim             string
+	principalClaim         string
+	groupsClaim            string
+}
+
+// Factory type
+type Factory struct {
+}
+
+// New implements apis.TokenInfoFactory
+func (t *Factory) New(params []string) (apis.TokenInfo, error) {
+	pluginMeta := &pluginMeta{}
+	fs := pluginMeta.flagSet()
+	fs.IntVar(&pluginMeta.timeout, "timeout", 10, "Request timeout in seconds")
+	fs.StringVar(&pluginMeta.issuerURL, "issuer-url", "", "Expected issuer of the token. The JWKS url is discovered from the OpenID configuration of the issuer, when jwks-url and jwks-file are not set")
+	fs.StringVar(&pluginMeta.jwksURL, "jwks-url", "", "URL of the JWKS")
+	fs.StringVar(&pluginMeta.jwksFile, "jwks-file", "", "Location of the local JWKS file")
+	fs.IntVar(&pluginMeta.jwksRefreshInterval, "jwks-refresh-interval", 60*60, "JWKS refresh interval in seconds")
+	fs.IntVar(&pluginMeta.jwksMinRefreshInterval, "jwks-min-refresh-interval", 60, "Minimum interval in seconds between JWKS refreshes triggered by unknown key ids")
+	fs.Var(&pluginMeta.audience, "audience", "The audience of a token")
+	fs.Var(&pluginMeta.algorithm, "algorithm", "Allowed signature algorithm, RS256, RS384, RS512, PS256, PS384, PS512, ES256, ES384, ES512 and EdDSA are allowed when not set")
+	fs.DurationVar(&pluginMeta.clockSkew, "clock-skew", time.Minute, "Allowed clock skew for the exp, nbf and iat claims")
+	fs.Var(&pluginMeta.requiredClaim, "required-claim", "Claim name or name=value pair the token must contain")
+	fs.Var(&pluginMeta.requiredScope, "required-scope", "Scope the token must contain")
+	fs.StringVar(&pluginMeta.scopeClaim, "scope-claim", "scope", "Claim of the space-delimited or array scopes")
+	fs.StringVar(&pluginMeta.principalClaim, "principal-claim", "sub", "Claim of the principal name")
+	fs.StringVar(&pluginMeta.groupsClaim, "groups-claim", "groups", "Claim of the principal groups")
+
+	err := fs.Parse(params)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := TokenInfoOptions{
+		Timeout:                time.Duration(pluginMeta.timeout) * time.Second,
+		IssuerURL:              pluginMeta.issuerURL,
+		JWKSURL:                pluginMeta.jwksURL,
+		JWKSFile:               pluginMeta.jwksFile,
+		JWKSRefreshInterval:    time.Duration(pluginMeta.jwksRefreshInterval) * time.Second,
+		JWKSMinRefreshInterval: time.Duration(pluginMeta.jwksMinRefreshInterval) * time.Second,
+		Audience:               pluginMeta.audience,
+		Algorithms:             pluginMeta.algorithm,
+		ClockSkew:              pluginMeta.clockSkew,
+		RequiredClaims:         pluginMeta.requiredClaim,
+		RequiredScopes:         pluginMeta.requiredScope,
+		ScopeClaim:             pluginMeta.scopeClaim,
+		PrincipalClaim:         pluginMeta.principalClaim,
+		GroupsClaim:            pluginMeta.groupsClaim,
+	}
+
+	return NewTokenInfo(opts)
+}
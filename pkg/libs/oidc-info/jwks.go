@@ -0,0 +1,207 @@
+package oidcinfo
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// https://tools.ietf.org/html/rfc7517#section-5
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// https://tools.ietf.org/html/rfc7517#section-4
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey is a verification key of the key set
+type publicKey struct {
+	// alg restricts the algorithm of the key, empty when the key set does not specify it
+	alg string
+	key crypto.PublicKey
+}
+
+type openIDConfiguration struct {
+	Issuer  string `json:"issuer"`
+	JwksURI string `json:"jwks_uri"`
+}
+
+// parsePublicKeys returns the signature keys of the key set by key id, keys of unsupported types are skipped
+func parsePublicKeys(data []byte) (map[string]publicKey, error) {
+	var keySet jsonWebKeySet
+	if err := json.Unmarshal(data, &keySet); err != nil {
+		return nil, errors.Wrap(err, "cannot parse JWKS")
+	}
+	publicKeys := make(map[string]publicKey)
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse key %s", jwk.Kid)
+		}
+		if key == nil {
+			continue
+		}
+		publicKeys[jwk.Kid] = publicKey{alg: jwk.Alg, key: key}
+	}
+	if len(publicKeys) == 0 {
+		return nil, errors.New("JWKS does not contain signature keys")
+	}
+	return publicKeys, nil
+}
+
+func (jwk *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %s", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// readJWKSFile reads the key set from the local file
+func readJWKSFile(filename string) (map[string]publicKey, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parsePublicKeys(data)
+}
+
+// fetchJWKS fetches the key set from the JWKS url
+func fetchJWKS(ctx context.Context, client *http.Client, jwksURL string) (map[string]publicKey, error) {
+	data, err := httpGet(ctx, client, jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	return parsePublicKeys(data)
+}
+
+// discoverJWKSURL returns the jwks_uri of the issuer OpenID configuration
+func discoverJWKSURL(ctx context.Context, client *http.Client, issuerURL string) (string, error) {
+	data, err := httpGet(ctx, client, wellKnownOpenIDConfiguration(issuerURL))
+	if err != nil {
+		return "", err
+	}
+	var configuration openIDConfiguration
+	if err = json.Unmarshal(data, &configuration); err != nil {
+		return "", errors.Wrap(err, "cannot parse OpenID configuration")
+	}
+	if configuration.Issuer != issuerURL {
+		return "", fmt.Errorf("OpenID configuration issuer %s does not match %s", configuration.Issuer, issuerURL)
+	}
+	if configuration.JwksURI == "" {
+		return "", errors.New("OpenID configuration does not contain jwks_uri")
+	}
+	return configuration.JwksURI, nil
+}
+
+func wellKnownOpenIDConfiguration(issuerURL string) string {
+	return strings.TrimRight(issuerURL, "/") + "/.well-known/openid-configuration"
+}
+
+func httpGet(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if c := resp.StatusCode; c < 200 || c > 299 {
+		return nil, fmt.Errorf("cannot fetch %s: %v", url, resp.Status)
+	}
+	return body, nil
+}
@@ -0,0 +1,207 @@
+package oidcinfo
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// supportedAlgorithms are the JWS algorithms accepted when the algorithms are not configured
+var supportedAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// jwt is the parsed token, the signature is verified separately
+type jwt struct {
+	header       jwtHeader
+	claims       map[string]interface{}
+	signingInput []byte
+	signature    []byte
+}
+
+func parseJWT(token string) (*jwt, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token must have 3 parts")
+	}
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode header")
+	}
+	var header jwtHeader
+	if err = json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, errors.Wrap(err, "cannot parse header")
+	}
+	claimsBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode claims")
+	}
+	claims := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(claimsBytes))
+	decoder.UseNumber()
+	if err = decoder.Decode(&claims); err != nil {
+		return nil, errors.Wrap(err, "cannot parse claims")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode signature")
+	}
+	return &jwt{
+		header:       header,
+		claims:       claims,
+		signingInput: []byte(parts[0] + "." + parts[1]),
+		signature:    signature,
+	}, nil
+}
+
+// verifySignature verifies the signature with the key of the algorithm of the token header
+func (t *jwt) verifySignature(key crypto.PublicKey) error {
+	hash, err := algorithmHash(t.header.Algorithm)
+	if err != nil {
+		return err
+	}
+	var digest []byte
+	if hash != 0 {
+		h := hash.New()
+		h.Write(t.signingInput)
+		digest = h.Sum(nil)
+	}
+	switch t.header.Algorithm[:2] {
+	case "RS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RSA key is required")
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, hash, digest, t.signature)
+	case "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RSA key is required")
+		}
+		return rsa.VerifyPSS(rsaKey, hash, digest, t.signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("EC key is required")
+		}
+		keySize := (ecKey.Curve.Params().BitSize + 7) / 8
+		if ecKey.Curve.Params().BitSize != ecdsaCurveBits[t.header.Algorithm] {
+			return fmt.Errorf("EC curve %s does not match algorithm %s", ecKey.Curve.Params().Name, t.header.Algorithm)
+		}
+		if len(t.signature) != 2*keySize {
+			return errors.New("invalid ECDSA signature size")
+		}
+		r := new(big.Int).SetBytes(t.signature[:keySize])
+		s := new(big.Int).SetBytes(t.signature[keySize:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("ECDSA verification failed")
+		}
+		return nil
+	default:
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errors.New("Ed25519 key is required")
+		}
+		if !ed25519.Verify(edKey, t.signingInput, t.signature) {
+			return errors.New("EdDSA verification failed")
+		}
+		return nil
+	}
+}
+
+var ecdsaCurveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+// algorithmHash returns the hash of the algorithm, 0 for EdDSA which signs the message
+func algorithmHash(algorithm string) (crypto.Hash, error) {
+	switch algorithm {
+	case "RS256", "PS256", "ES256":
+		return crypto.SHA256, nil
+	case "RS384", "PS384", "ES384":
+		return crypto.SHA384, nil
+	case "RS512", "PS512", "ES512":
+		return crypto.SHA512, nil
+	case "EdDSA":
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+}
+
+func (t *jwt) stringClaim(name string) string {
+	s, _ := t.claims[name].(string)
+	return s
+}
+
+// numericClaim returns the NumericDate claim in seconds, false when the claim is missing
+func (t *jwt) numericClaim(name string) (int64, bool) {
+	n, ok := t.claims[name].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// stringsClaim returns the claim which is a string or an array of strings e.g. aud
+func (t *jwt) stringsClaim(name string) []string {
+	switch v := t.claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, elem := range v {
+			if s, ok := elem.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// claimValues returns the string representation of the scalar claim or the elements of the array claim
+func (t *jwt) claimValues(name string) []string {
+	values, ok := t.claims[name].([]interface{})
+	if !ok {
+		values = []interface{}{t.claims[name]}
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			result = append(result, v)
+		case json.Number:
+			result = append(result, v.String())
+		case bool:
+			result = append(result, strconv.FormatBool(v))
+		}
+	}
+	return result
+}
+
+// scopes returns the scopes of the space-delimited scope claim or the array scp claim
+func (t *jwt) scopes(name string) []string {
+	if s, ok := t.claims[name].(string); ok {
+		return strings.Fields(s)
+	}
+	return t.stringsClaim(name)
+}
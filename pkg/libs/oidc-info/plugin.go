@@ -0,0 +1,321 @@
+package oidcinfo
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusOK                      = 0
+	StatusEmptyToken              = 1
+	StatusParseJWTFailed          = 2
+	StatusWrongAlgorithm          = 3
+	StatusPublicKeyNotFound       = 4
+	StatusWrongSignature          = 5
+	StatusWrongIssuer             = 6
+	StatusWrongAudience           = 7
+	StatusNoExpirationTimeInToken = 8
+	StatusTokenExpired            = 9
+	StatusTokenTooEarly           = 10
+	StatusMissingClaim            = 11
+	StatusMissingScope            = 12
+)
+
+var (
+	nowFn = time.Now
+)
+
+type TokenInfoOptions struct {
+	Timeout time.Duration
+	// IssuerURL is the expected iss claim, the JWKS url is discovered from the OpenID configuration when JWKSURL and JWKSFile are not set
+	IssuerURL string
+	JWKSURL   string
+	JWKSFile  string
+	// JWKSRefreshInterval is the interval of the periodic key set refresh
+	JWKSRefreshInterval time.Duration
+	// JWKSMinRefreshInterval limits the refreshes triggered by tokens signed with unknown keys
+	JWKSMinRefreshInterval time.Duration
+	Audience               []string
+	Algorithms             []string
+	ClockSkew              time.Duration
+	// RequiredClaims are claim names or name=value pairs the token must contain
+	RequiredClaims []string
+	RequiredScopes []string
+	ScopeClaim     string
+	PrincipalClaim string
+	GroupsClaim    string
+}
+
+type TokenInfo struct {
+	timeout                time.Duration
+	httpClient             *http.Client
+	issuerURL              string
+	jwksURL                string
+	jwksFile               string
+	jwksMinRefreshInterval time.Duration
+	audience               map[string]struct{}
+	algorithms             map[string]struct{}
+	clockSkew              time.Duration
+	requiredClaims         map[string]string
+	requiredScopes         []string
+	scopeClaim             string
+	principalClaim         string
+	groupsClaim            string
+
+	publicKeys  map[string]publicKey
+	lastRefresh time.Time
+	l           sync.RWMutex
+	// refreshLock serializes the key set refreshes
+	refreshLock sync.Mutex
+}
+
+func NewTokenInfo(options TokenInfoOptions) (*TokenInfo, error) {
+	if options.JWKSURL == "" && options.JWKSFile == "" && options.IssuerURL == "" {
+		return nil, errors.New("parameter issuer-url, jwks-url or jwks-file is required")
+	}
+	if options.JWKSURL != "" && options.JWKSFile != "" {
+		return nil, errors.New("parameters jwks-url and jwks-file are mutually exclusive")
+	}
+	algorithms := options.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = supportedAlgorithms
+	}
+	tokenInfo := &TokenInfo{
+		timeout:                options.Timeout,
+		httpClient:             &http.Client{Timeout: options.Timeout},
+		issuerURL:              options.IssuerURL,
+		jwksURL:                options.JWKSURL,
+		jwksFile:               options.JWKSFile,
+		jwksMinRefreshInterval: options.JWKSMinRefreshInterval,
+		audience:               make(map[string]struct{}),
+		algorithms:             make(map[string]struct{}),
+		clockSkew:              options.ClockSkew,
+		requiredClaims:         make(map[string]string),
+		requiredScopes:         options.RequiredScopes,
+		scopeClaim:             options.ScopeClaim,
+		principalClaim:         options.PrincipalClaim,
+		groupsClaim:            options.GroupsClaim,
+	}
+	for _, algorithm := range algorithms {
+		if _, err := algorithmHash(algorithm); err != nil {
+			return nil, err
+		}
+		tokenInfo.algorithms[algorithm] = struct{}{}
+	}
+	for _, elem := range options.Audience {
+		tokenInfo.audience[elem] = struct{}{}
+	}
+	for _, claim := range options.RequiredClaims {
+		name, value, _ := strings.Cut(claim, "=")
+		tokenInfo.requiredClaims[name] = value
+	}
+	logrus.Infof("OIDC issuer: %s, audience: %v, algorithms: %v", options.IssuerURL, options.Audience, algorithms)
+
+	op := func() error {
+		return tokenInfo.refreshKeys()
+	}
+	err := backoff.Retry(op, backoff.WithMaxTries(backoff.NewConstantBackOff(1*time.Second), 3))
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting of JWKS failed")
+	}
+	if options.JWKSRefreshInterval > 0 {
+		keysRefresher := newKeysRefresher(tokenInfo, make(chan struct{}, 1), options.JWKSRefreshInterval)
+		go keysRefresher.refreshLoop()
+	}
+	return tokenInfo, nil
+}
+
+func (p *TokenInfo) getPublicKey(kid string) (publicKey, bool) {
+	p.l.RLock()
+	defer p.l.RUnlock()
+
+	key, ok := p.publicKeys[kid]
+	return key, ok
+}
+
+func (p *TokenInfo) getPublicKeyIDs() []string {
+	p.l.RLock()
+	defer p.l.RUnlock()
+	kids := make([]string, 0)
+	for kid := range p.publicKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	return kids
+}
+
+func (p *TokenInfo) setPublicKeys(publicKeys map[string]publicKey) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.publicKeys = publicKeys
+	p.lastRefresh = nowFn()
+}
+
+func (p *TokenInfo) refreshKeys() error {
+	p.refreshLock.Lock()
+	defer p.refreshLock.Unlock()
+
+	if p.jwksFile != "" {
+		publicKeys, err := readJWKSFile(p.jwksFile)
+		if err != nil {
+			return err
+		}
+		p.setPublicKeys(publicKeys)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	if p.jwksURL == "" {
+		jwksURL, err := discoverJWKSURL(ctx, p.httpClient, p.issuerURL)
+		if err != nil {
+			return err
+		}
+		p.jwksURL = jwksURL
+	}
+	publicKeys, err := fetchJWKS(ctx, p.httpClient, p.jwksURL)
+	if err != nil {
+		return err
+	}
+	p.setPublicKeys(publicKeys)
+	return nil
+}
+
+// refreshKeysForKeyID refreshes the key set when the token is signed with an unknown key e.g. after key rotation
+func (p *TokenInfo) refreshKeysForKeyID(kid string) (publicKey, bool) {
+	p.l.RLock()
+	lastRefresh := p.lastRefresh
+	p.l.RUnlock()
+	if nowFn().Sub(lastRefresh) < p.jwksMinRefreshInterval {
+		return publicKey{}, false
+	}
+	if err := p.refreshKeys(); err != nil {
+		logrus.Errorf("JWKS refresh for key %s failed: %v", kid, err)
+		return publicKey{}, false
+	}
+	logrus.Infof("Refreshed JWKS Key IDs: %v", p.getPublicKeyIDs())
+	return p.getPublicKey(kid)
+}
+
+// VerifyToken implements apis.TokenInfo
+func (p *TokenInfo) VerifyToken(ctx context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
+	if request.Token == "" {
+		return getVerifyResponseResponse(StatusEmptyToken)
+	}
+	token, err := parseJWT(request.Token)
+	if err != nil {
+		return getVerifyResponseResponse(StatusParseJWTFailed)
+	}
+	if _, ok := p.algorithms[token.header.Algorithm]; !ok {
+		return getVerifyResponseResponse(StatusWrongAlgorithm)
+	}
+	key, ok := p.getPublicKey(token.header.KeyID)
+	if !ok {
+		if key, ok = p.refreshKeysForKeyID(token.header.KeyID); !ok {
+			return getVerifyResponseResponse(StatusPublicKeyNotFound)
+		}
+	}
+	if key.alg != "" && key.alg != token.header.Algorithm {
+		return getVerifyResponseResponse(StatusWrongAlgorithm)
+	}
+	if err = token.verifySignature(key.key); err != nil {
+		return getVerifyResponseResponse(StatusWrongSignature)
+	}
+
+	if p.issuerURL != "" && token.stringClaim("iss") != p.issuerURL {
+		return getVerifyResponseResponse(StatusWrongIssuer)
+	}
+	if len(p.audience) != 0 && !p.checkAudience(token.stringsClaim("aud")) {
+		return getVerifyResponseResponse(StatusWrongAudience)
+	}
+
+	exp, ok := token.numericClaim("exp")
+	if !ok {
+		return getVerifyResponseResponse(StatusNoExpirationTimeInToken)
+	}
+	unix := nowFn().Unix()
+	if unix > exp+int64(p.clockSkew.Seconds()) {
+		return getVerifyResponseResponse(StatusTokenExpired)
+	}
+	if nbf, ok := token.numericClaim("nbf"); ok && unix < nbf-int64(p.clockSkew.Seconds()) {
+		return getVerifyResponseResponse(StatusTokenTooEarly)
+	}
+	if iat, ok := token.numericClaim("iat"); ok && unix < iat-int64(p.clockSkew.Seconds()) {
+		return getVerifyResponseResponse(StatusTokenTooEarly)
+	}
+
+	if !p.checkRequiredClaims(token) {
+		return getVerifyResponseResponse(StatusMissingClaim)
+	}
+	if !p.checkRequiredScopes(token) {
+		return getVerifyResponseResponse(StatusMissingScope)
+	}
+	principal := apis.Principal{
+		Name:       token.stringClaim(p.principalClaim),
+		Groups:     token.stringsClaim(p.groupsClaim),
+		Attributes: map[string]string{"iss": token.stringClaim("iss")},
+	}
+	return apis.VerifyResponse{Success: true, Status: StatusOK, Principal: principal}, nil
+}
+
+func (p *TokenInfo) checkAudience(audience []string) bool {
+	for _, aud := range audience {
+		if _, ok := p.audience[aud]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *TokenInfo) checkRequiredClaims(token *jwt) bool {
+	for name, value := range p.requiredClaims {
+		if _, ok := token.claims[name]; !ok {
+			return false
+		}
+		if value != "" && !contains(token.claimValues(name), value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *TokenInfo) checkRequiredScopes(token *jwt) bool {
+	if len(p.requiredScopes) == 0 {
+		return true
+	}
+	scopes := make(map[string]struct{})
+	for _, scope := range token.scopes(p.scopeClaim) {
+		scopes[scope] = struct{}{}
+	}
+	for _, scope := range p.requiredScopes {
+		if _, ok := scopes[scope]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func getVerifyResponseResponse(status int) (apis.VerifyResponse, error) {
+	success := status == StatusOK
+	return apis.VerifyResponse{Success: success, Status: int32(status)}, nil
+}
@@ -0,0 +1,441 @@
+package oidcinfo
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/stretchr/testify/assert"
+)
+
+// testSigningKey signs tokens with the private key of the JWKS key
+type testSigningKey struct {
+	kid        string
+	algorithm  string
+	privateKey crypto.Signer
+}
+
+func newTestSigningKey(t *testing.T, kid, algorithm string) *testSigningKey {
+	var privateKey crypto.Signer
+	var err error
+	switch algorithm {
+	case "RS256", "PS256":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "EdDSA":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("unsupported test algorithm %s", algorithm)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testSigningKey{kid: kid, algorithm: algorithm, privateKey: privateKey}
+}
+
+func (k *testSigningKey) jwk() map[string]string {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch publicKey := k.privateKey.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": k.kid, "use": "sig", "n": encode(publicKey.N.Bytes()), "e": encode(big.NewInt(int64(publicKey.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		return map[string]string{"kty": "EC", "kid": k.kid, "crv": publicKey.Curve.Params().Name, "x": encode(publicKey.X.FillBytes(make([]byte, size))), "y": encode(publicKey.Y.FillBytes(make([]byte, size)))}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": k.kid, "crv": "Ed25519", "x": encode(publicKey)}
+	default:
+		return nil
+	}
+}
+
+func (k *testSigningKey) sign(t *testing.T, claims map[string]interface{}) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": k.algorithm, "kid": k.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := encode(header) + "." + encode(payload)
+
+	var signature []byte
+	var err error
+	switch privateKey := k.privateKey.(type) {
+	case *rsa.PrivateKey:
+		digest := crypto.SHA256.New()
+		digest.Write([]byte(signingInput))
+		if k.algorithm == "PS256" {
+			signature, err = rsa.SignPSS(rand.Reader, privateKey, crypto.SHA256, digest.Sum(nil), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest.Sum(nil))
+		}
+	case *ecdsa.PrivateKey:
+		hash := crypto.SHA256
+		if k.algorithm == "ES384" {
+			hash = crypto.SHA384
+		}
+		digest := hash.New()
+		digest.Write([]byte(signingInput))
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, privateKey, digest.Sum(nil))
+		size := (privateKey.Curve.Params().BitSize + 7) / 8
+		signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(privateKey, []byte(signingInput))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + encode(signature)
+}
+
+// testIssuer serves the OpenID configuration and the JWKS of the signing keys
+type testIssuer struct {
+	server       *httptest.Server
+	keys         []*testSigningKey
+	jwksRequests int
+	l            sync.Mutex
+}
+
+func newTestIssuer(keys ...*testSigningKey) *testIssuer {
+	issuer := &testIssuer{keys: keys}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.server.URL, "jwks_uri": issuer.server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.l.Lock()
+		defer issuer.l.Unlock()
+		issuer.jwksRequests++
+		_, _ = w.Write(issuer.jwks())
+	})
+	issuer.server = httptest.NewServer(mux)
+	return issuer
+}
+
+func (i *testIssuer) jwks() []byte {
+	keys := make([]map[string]string, 0, len(i.keys))
+	for _, key := range i.keys {
+		keys = append(keys, key.jwk())
+	}
+	b, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	return b
+}
+
+func (i *testIssuer) setKeys(keys ...*testSigningKey) {
+	i.l.Lock()
+	defer i.l.Unlock()
+	i.keys = keys
+}
+
+func (i *testIssuer) getJWKSRequests() int {
+	i.l.Lock()
+	defer i.l.Unlock()
+	return i.jwksRequests
+}
+
+func newTestTokenInfo(t *testing.T, options TokenInfoOptions) *TokenInfo {
+	if options.Timeout == 0 {
+		options.Timeout = 5 * time.Second
+	}
+	if options.PrincipalClaim == "" {
+		options.PrincipalClaim = "sub"
+	}
+	if options.GroupsClaim == "" {
+		options.GroupsClaim = "groups"
+	}
+	if options.ScopeClaim == "" {
+		options.ScopeClaim = "scope"
+	}
+	tokenInfo, err := NewTokenInfo(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenInfo
+}
+
+func TestVerifyTokenAlgorithms(t *testing.T) {
+	for _, algorithm := range []string{"RS256", "PS256", "ES256", "ES384", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			a := assert.New(t)
+
+			key := newTestSigningKey(t, "key-1", algorithm)
+			issuer := newTestIssuer(key)
+			defer issuer.server.Close()
+
+			tokenInfo := newTestTokenInfo(t, TokenInfoOptions{IssuerURL: issuer.server.URL, Audience: []string{"kafka"}})
+			token := key.sign(t, map[string]interface{}{
+				"iss":    issuer.server.URL,
+				"aud":    []string{"kafka", "other"},
+				"sub":    "alice",
+				"groups": []string{"admins", "readers"},
+				"exp":    time.Now().Add(time.Hour).Unix(),
+			})
+			resp, err := tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: token})
+			a.Nil(err)
+			a.Equal(int32(StatusOK), resp.Status)
+			a.True(resp.Success)
+			a.Equal(apis.Principal{Name: "alice", Groups: []string{"admins", "readers"}, Attributes: map[string]string{"iss": issuer.server.URL}}, resp.Principal)
+		})
+	}
+}
+
+func TestVerifyTokenRejected(t *testing.T) {
+	key := newTestSigningKey(t, "key-1", "RS256")
+	otherKey := newTestSigningKey(t, "key-1", "RS256")
+	issuer := newTestIssuer(key)
+	defer issuer.server.Close()
+
+	now := time.Now()
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   issuer.server.URL,
+			"aud":   "kafka",
+			"sub":   "alice",
+			"scope": "kafka.read kafka.write",
+			"tier":  "gold",
+			"exp":   now.Add(time.Hour).Unix(),
+		}
+	}
+	tests := []struct {
+		name   string
+		token  func() string
+		status int
+	}{
+		{
+			name:   "valid",
+			token:  func() string { return key.sign(t, validClaims()) },
+			status: StatusOK,
+		},
+		{
+			name:   "empty token",
+			token:  func() string { return "" },
+			status: StatusEmptyToken,
+		},
+		{
+			name:   "malformed token",
+			token:  func() string { return "not-a-jwt" },
+			status: StatusParseJWTFailed,
+		},
+		{
+			name: "unsigned token",
+			token: func() string {
+				encode := base64.RawURLEncoding.EncodeToString
+				payload, _ := json.Marshal(validClaims())
+				return encode([]byte(`{"alg":"none","kid":"key-1"}`)) + "." + encode(payload) + "."
+			},
+			status: StatusWrongAlgorithm,
+		},
+		{
+			name:   "wrong signature",
+			token:  func() string { return otherKey.sign(t, validClaims()) },
+			status: StatusWrongSignature,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://other.example.com"
+				return key.sign(t, claims)
+			},
+			status: StatusWrongIssuer,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = "other"
+				return key.sign(t, claims)
+			},
+			status: StatusWrongAudience,
+		},
+		{
+			name: "no expiration time",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "exp")
+				return key.sign(t, claims)
+			},
+			status: StatusNoExpirationTimeInToken,
+		},
+		{
+			name: "expired within clock skew",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = now.Add(-30 * time.Second).Unix()
+				return key.sign(t, claims)
+			},
+			status: StatusOK,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = now.Add(-2 * time.Minute).Unix()
+				return key.sign(t, claims)
+			},
+			status: StatusTokenExpired,
+		},
+		{
+			name: "not before",
+			token: func() string {
+				claims := validClaims()
+				claims["nbf"] = now.Add(2 * time.Minute).Unix()
+				return key.sign(t, claims)
+			},
+			status: StatusTokenTooEarly,
+		},
+		{
+			name: "missing claim",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "tier")
+				return key.sign(t, claims)
+			},
+			status: StatusMissingClaim,
+		},
+		{
+			name: "wrong claim value",
+			token: func() string {
+				claims := validClaims()
+				claims["tier"] = "silver"
+				return key.sign(t, claims)
+			},
+			status: StatusMissingClaim,
+		},
+		{
+			name: "missing scope",
+			token: func() string {
+				claims := validClaims()
+				claims["scope"] = "kafka.read"
+				return key.sign(t, claims)
+			},
+			status: StatusMissingScope,
+		},
+	}
+
+	tokenInfo := newTestTokenInfo(t, TokenInfoOptions{
+		IssuerURL:      issuer.server.URL,
+		Audience:       []string{"kafka"},
+		Algorithms:     []string{"RS256"},
+		ClockSkew:      time.Minute,
+		RequiredClaims: []string{"sub", "tier=gold"},
+		RequiredScopes: []string{"kafka.write"},
+	})
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			resp, err := tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: tc.token()})
+			a.Nil(err)
+			a.Equal(int32(tc.status), resp.Status)
+			a.Equal(tc.status == StatusOK, resp.Success)
+		})
+	}
+}
+
+func TestVerifyTokenKeyRotation(t *testing.T) {
+	a := assert.New(t)
+
+	oldKey := newTestSigningKey(t, "key-1", "RS256")
+	newKey := newTestSigningKey(t, "key-2", "ES256")
+	issuer := newTestIssuer(oldKey)
+	defer issuer.server.Close()
+
+	tokenInfo := newTestTokenInfo(t, TokenInfoOptions{IssuerURL: issuer.server.URL, JWKSMinRefreshInterval: time.Hour})
+	a.Equal([]string{"key-1"}, tokenInfo.getPublicKeyIDs())
+	a.Equal(1, issuer.getJWKSRequests())
+
+	// refresh of the key set is rate limited
+	issuer.setKeys(oldKey, newKey)
+	token := newKey.sign(t, map[string]interface{}{"iss": issuer.server.URL, "sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+	resp, err := tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: token})
+	a.Nil(err)
+	a.Equal(int32(StatusPublicKeyNotFound), resp.Status)
+	a.Equal(1, issuer.getJWKSRequests())
+
+	// unknown key id triggers the refresh
+	tokenInfo.jwksMinRefreshInterval = 0
+	resp, err = tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: token})
+	a.Nil(err)
+	a.Equal(int32(StatusOK), resp.Status)
+	a.Equal(2, issuer.getJWKSRequests())
+	a.Equal([]string{"key-1", "key-2"}, tokenInfo.getPublicKeyIDs())
+}
+
+func TestVerifyTokenJWKSFile(t *testing.T) {
+	a := assert.New(t)
+
+	key := newTestSigningKey(t, "key-1", "EdDSA")
+	issuer := &testIssuer{keys: []*testSigningKey{key}}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	a.Nil(os.WriteFile(jwksFile, issuer.jwks(), 0600))
+
+	tokenInfo := newTestTokenInfo(t, TokenInfoOptions{JWKSFile: jwksFile, PrincipalClaim: "email"})
+	token := key.sign(t, map[string]interface{}{"email": "alice@example.com", "exp": time.Now().Add(time.Hour).Unix()})
+	resp, err := tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: token})
+	a.Nil(err)
+	a.Equal(int32(StatusOK), resp.Status)
+	a.Equal("alice@example.com", resp.Principal.Name)
+}
+
+func TestNewTokenInfoErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  TokenInfoOptions
+		errorMsg string
+	}{
+		{
+			name:     "no key source",
+			options:  TokenInfoOptions{},
+			errorMsg: "parameter issuer-url, jwks-url or jwks-file is required",
+		},
+		{
+			name:     "several key sources",
+			options:  TokenInfoOptions{JWKSURL: "http://localhost/jwks", JWKSFile: "jwks.json"},
+			errorMsg: "parameters jwks-url and jwks-file are mutually exclusive",
+		},
+		{
+			name:     "unsupported algorithm",
+			options:  TokenInfoOptions{JWKSFile: "jwks.json", Algorithms: []string{"HS256"}},
+			errorMsg: "unsupported algorithm HS256",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTokenInfo(tc.options)
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tc.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestFactory(t *testing.T) {
+	a := assert.New(t)
+
+	key := newTestSigningKey(t, "key-1", "RS256")
+	issuer := newTestIssuer(key)
+	defer issuer.server.Close()
+
+	tokenInfo, err := new(Factory).New([]string{"--issuer-url", issuer.server.URL, "--audience", "kafka", "--required-scope", "kafka", "--scope-claim", "scp"})
+	a.Nil(err)
+	token := key.sign(t, map[string]interface{}{"iss": issuer.server.URL, "aud": "kafka", "sub": "alice", "scp": []string{"kafka"}, "exp": time.Now().Add(time.Hour).Unix()})
+	resp, err := tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: token})
+	a.Nil(err)
+	a.Equal(int32(StatusOK), resp.Status, fmt.Sprintf("status %d", resp.Status))
+}
@@ -0,0 +1,59 @@
+package oidcinfo
+
+import (
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/sirupsen/logrus"
+)
+
+type keysRefresher struct {
+	tokenInfo   *TokenInfo
+	stopChannel chan struct{}
+	interval    time.Duration
+}
+
+func newKeysRefresher(tokenInfo *TokenInfo, stopChannel chan struct{}, interval time.Duration) *keysRefresher {
+	return &keysRefresher{
+		tokenInfo:   tokenInfo,
+		stopChannel: stopChannel,
+		interval:    interval,
+	}
+}
+
+func (p *keysRefresher) refreshLoop() {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			err, ok := r.(error)
+			if ok {
+				logrus.Errorf("JWKS refresh loop error %v", err)
+			}
+		}
+	}()
+	logrus.Infof("Refreshing JWKS every: %v", p.interval)
+	syncTicker := time.NewTicker(p.interval)
+	for {
+		select {
+		case <-syncTicker.C:
+			p.refreshTick()
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+func (p *keysRefresher) refreshTick() {
+	op := func() error {
+		return p.tokenInfo.refreshKeys()
+	}
+	backOff := backoff.NewExponentialBackOff()
+	backOff.MaxElapsedTime = 30 * time.Minute
+	backOff.MaxInterval = 2 * time.Minute
+	err := backoff.Retry(op, backOff)
+	if err != nil {
+		logrus.Errorf("JWKS refresh failed : %v", err)
+		return
+	}
+	logrus.Infof("Refreshed JWKS Key IDs: %v", p.tokenInfo.getPublicKeyIDs())
+}
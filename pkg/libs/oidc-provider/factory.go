@@ -4,7 +4,9 @@ import (
 	"flag"
 
 	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
 	"github.com/grepplabs/kafka-proxy/pkg/registry"
+	"github.com/pkg/errors"
 )
 
 func init() {
@@ -23,6 +25,9 @@ type pluginMeta struct {
 	credentialsWatch bool
 	credentialsFile  string
 	targetAudience   string
+
+	authzid        string
+	saslExtensions util.ArrayFlags
 }
 
 // Factory type
@@ -37,16 +42,24 @@ func (t *Factory) New(params []string) (apis.TokenProvider, error) {
 	fs.StringVar(&pluginMeta.credentialsFile, "credentials-file", "", "Location of the JSON file with the application credentials")
 	fs.BoolVar(&pluginMeta.credentialsWatch, "credentials-watch", true, "Watch credential for reload")
 	fs.StringVar(&pluginMeta.targetAudience, "target-audience", "", "URI of audience claim")
+	fs.StringVar(&pluginMeta.authzid, "authzid", "", "Authorization identity sent with the token")
+	fs.Var(&pluginMeta.saslExtensions, "sasl-extension", "SASL extension sent with the token (key=value)")
 
 	err := fs.Parse(params)
 	if err != nil {
 		return nil, err
 	}
+	saslExtensions, err := pluginMeta.saslExtensions.AsKeyValueMap()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid parameter sasl-extension")
+	}
 	options := TokenProviderOptions{
 		Timeout:          pluginMeta.timeout,
 		CredentialsWatch: pluginMeta.credentialsWatch,
 		CredentialsFile:  pluginMeta.credentialsFile,
 		TargetAudience:   pluginMeta.targetAudience,
+		AuthzID:          pluginMeta.authzid,
+		SaslExtensions:   saslExtensions,
 	}
 
 	return NewTokenProvider(options)
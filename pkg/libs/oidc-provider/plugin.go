@@ -32,6 +32,9 @@ type TokenProvider struct {
 	timeout       time.Duration
 	idTokenSource idTokenSource
 
+	authzid        string
+	saslExtensions map[string]string
+
 	idToken *oidc.Token
 	l       sync.RWMutex
 }
@@ -43,6 +46,10 @@ type TokenProviderOptions struct {
 	CredentialsWatch bool
 	CredentialsFile  string
 	TargetAudience   string
+
+	// AuthzID and SaslExtensions are sent with the token in the OAUTHBEARER client initial response
+	AuthzID        string
+	SaslExtensions map[string]string
 }
 
 type GrantType struct {
@@ -81,8 +88,10 @@ func NewTokenProvider(options TokenProviderOptions) (*TokenProvider, error) {
 	}
 
 	tokenProvider := &TokenProvider{
-		timeout:       time.Duration(options.Timeout) * time.Second,
-		idTokenSource: idTokenSource}
+		timeout:        time.Duration(options.Timeout) * time.Second,
+		idTokenSource:  idTokenSource,
+		authzid:        options.AuthzID,
+		saslExtensions: options.SaslExtensions}
 
 	op := func() error {
 		return initToken(tokenProvider)
@@ -171,7 +180,7 @@ func (p *TokenProvider) GetToken(parent context.Context, _ apis.TokenRequest) (a
 	currentToken := p.getCurrentToken()
 
 	if currentToken != "" {
-		return getTokenResponse(currentToken, StatusOK)
+		return p.getTokenResponse(currentToken, StatusOK)
 	}
 
 	ctx, cancel := context.WithTimeout(parent, p.timeout)
@@ -181,26 +190,29 @@ func (p *TokenProvider) GetToken(parent context.Context, _ apis.TokenRequest) (a
 
 	if err != nil {
 		logrus.Errorf("GetIDToken failed %v", err)
-		return getTokenResponse("", StatusGetTokenFailed)
+		return p.getTokenResponse("", StatusGetTokenFailed)
 	}
 
 	idToken, err := oidc.ParseJWT(token)
 
 	if err != nil {
 		logrus.Error(err)
-		return getTokenResponse("", StatusParseTokenFailed)
+		return p.getTokenResponse("", StatusParseTokenFailed)
 	}
 
 	p.setCurrentToken(idToken)
 
 	logrus.Infof("New token expiry %d (%v)", idToken.ClaimSet.Exp, time.Unix(idToken.ClaimSet.Exp, 0))
 
-	return getTokenResponse(token, StatusOK)
+	return p.getTokenResponse(token, StatusOK)
 }
 
-func getTokenResponse(token string, status int) (apis.TokenResponse, error) {
+func (p *TokenProvider) getTokenResponse(token string, status int) (apis.TokenResponse, error) {
 	success := status == StatusOK
-	return apis.TokenResponse{Success: success, Status: int32(status), Token: token}, nil
+	if !success {
+		return apis.TokenResponse{Success: success, Status: int32(status), Token: token}, nil
+	}
+	return apis.TokenResponse{Success: success, Status: int32(status), Token: token, AuthzID: p.authzid, Extensions: p.saslExtensions}, nil
 }
 
 func getTokenSource(credentialsFilePath string, targetAud string) (idTokenSource, error) {
@@ -0,0 +1,41 @@
+package scramfile
+
+import (
+	"flag"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/registry"
+)
+
+func init() {
+	registry.NewComponentInterface(new(apis.ScramCredentialStoreFactory))
+	registry.Register(new(Factory), "scram-file")
+}
+
+type pluginMeta struct {
+	file  string
+	watch bool
+}
+
+func (f *pluginMeta) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("scram-file settings", flag.ContinueOnError)
+	fs.StringVar(&f.file, "file", "", "Path to the SCRAM credentials file")
+	fs.BoolVar(&f.watch, "watch", false, "Reload the SCRAM credentials file on change")
+	return fs
+}
+
+type Factory struct {
+}
+
+// New implements apis.ScramCredentialStoreFactory
+func (t *Factory) New(params []string) (apis.ScramCredentialStore, error) {
+	pluginMeta := &pluginMeta{}
+	fs := pluginMeta.flagSet()
+	if err := fs.Parse(params); err != nil {
+		return nil, err
+	}
+	return NewCredentialStore(CredentialStoreOptions{
+		File:  pluginMeta.file,
+		Watch: pluginMeta.watch,
+	})
+}
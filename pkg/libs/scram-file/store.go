@@ -0,0 +1,211 @@
+package scramfile
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xdg-go/scram"
+)
+
+const (
+	MechanismSHA256 = "SCRAM-SHA-256"
+	MechanismSHA512 = "SCRAM-SHA-512"
+
+	DefaultIterations = 4096
+	minIterations     = 4096
+	saltSize          = 32
+)
+
+var hashGenerators = map[string]scram.HashGeneratorFcn{
+	MechanismSHA256: func() hash.Hash { return sha256.New() },
+	MechanismSHA512: func() hash.Hash { return sha512.New() },
+}
+
+type CredentialStoreOptions struct {
+	File  string
+	Watch bool
+}
+
+// CredentialStore is a SCRAM credential store backed by a file. Each line of the file contains
+// the username and the credentials of one mechanism in the format used by kafka-configs:
+//
+//	alice SCRAM-SHA-256=salt=<base64>,stored_key=<base64>,server_key=<base64>,iterations=4096
+//
+// Empty lines and lines starting with # are ignored.
+type CredentialStore struct {
+	mu          sync.RWMutex
+	credentials map[string]apis.ScramCredentials
+}
+
+func NewCredentialStore(options CredentialStoreOptions) (*CredentialStore, error) {
+	if options.File == "" {
+		return nil, errors.New("parameter file is required")
+	}
+	credentials, err := readCredentialsFile(options.File)
+	if err != nil {
+		return nil, err
+	}
+	store := &CredentialStore{credentials: credentials}
+	if options.Watch {
+		action := func() {
+			logrus.Infof("reloading SCRAM credentials file %s", options.File)
+
+			credentials, err := readCredentialsFile(options.File)
+			if err != nil {
+				logrus.Errorf("error while reloading SCRAM credentials file: %s", err)
+				return
+			}
+			store.mu.Lock()
+			store.credentials = credentials
+			store.mu.Unlock()
+		}
+		if err = util.WatchForUpdates(options.File, make(chan bool, 1), action); err != nil {
+			return nil, errors.Wrap(err, "cannot watch SCRAM credentials file")
+		}
+	}
+	return store, nil
+}
+
+// GetCredentials implements apis.ScramCredentialStore
+func (s *CredentialStore) GetCredentials(mechanism, username string) (apis.ScramCredentials, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	credentials, ok := s.credentials[credentialsKey(mechanism, username)]
+	return credentials, ok, nil
+}
+
+func credentialsKey(mechanism, username string) string {
+	return mechanism + " " + username
+}
+
+func readCredentialsFile(filename string) (map[string]apis.ScramCredentials, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read SCRAM credentials file '%s'", filename)
+	}
+	credentials, err := parseCredentials(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse SCRAM credentials file '%s'", filename)
+	}
+	return credentials, nil
+}
+
+func parseCredentials(content []byte) (map[string]apis.ScramCredentials, error) {
+	result := make(map[string]apis.ScramCredentials)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		username, mechanism, credentials, err := ParseCredentialsLine(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNumber)
+		}
+		key := credentialsKey(mechanism, username)
+		if _, ok := result[key]; ok {
+			return nil, errors.Errorf("line %d: %s credentials of user '%s' configured twice", lineNumber, mechanism, username)
+		}
+		result[key] = credentials
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ParseCredentialsLine parses the line of the SCRAM credentials file
+func ParseCredentialsLine(line string) (username string, mechanism string, credentials apis.ScramCredentials, err error) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return "", "", credentials, errors.New("expected username and credentials separated by whitespace")
+	}
+	username = fields[0]
+	mechanism, value, ok := strings.Cut(fields[1], "=")
+	if !ok {
+		return "", "", credentials, errors.New("expected credentials in format <mechanism>=salt=...,stored_key=...,server_key=...,iterations=...")
+	}
+	if _, ok = hashGenerators[mechanism]; !ok {
+		return "", "", credentials, errors.Errorf("unsupported mechanism '%s'", mechanism)
+	}
+	for _, attr := range strings.Split(value, ",") {
+		name, attrValue, ok := strings.Cut(attr, "=")
+		if !ok {
+			return "", "", credentials, errors.Errorf("invalid attribute '%s'", attr)
+		}
+		switch name {
+		case "salt":
+			credentials.Salt, err = base64.StdEncoding.DecodeString(attrValue)
+		case "stored_key":
+			credentials.StoredKey, err = base64.StdEncoding.DecodeString(attrValue)
+		case "server_key":
+			credentials.ServerKey, err = base64.StdEncoding.DecodeString(attrValue)
+		case "iterations":
+			credentials.Iterations, err = strconv.Atoi(attrValue)
+		default:
+			err = errors.Errorf("unknown attribute '%s'", name)
+		}
+		if err != nil {
+			return "", "", credentials, errors.Wrapf(err, "invalid attribute '%s'", name)
+		}
+	}
+	if len(credentials.Salt) == 0 || len(credentials.StoredKey) == 0 || len(credentials.ServerKey) == 0 {
+		return "", "", credentials, errors.New("salt, stored_key and server_key are required")
+	}
+	if credentials.Iterations < minIterations {
+		return "", "", credentials, errors.Errorf("iterations must be at least %d", minIterations)
+	}
+	return username, mechanism, credentials, nil
+}
+
+// NewCredentials derives SCRAM credentials of the password with a random salt
+func NewCredentials(mechanism string, password string, iterations int) (apis.ScramCredentials, error) {
+	hashGenerator, ok := hashGenerators[mechanism]
+	if !ok {
+		return apis.ScramCredentials{}, errors.Errorf("unsupported mechanism '%s'", mechanism)
+	}
+	if iterations < minIterations {
+		return apis.ScramCredentials{}, errors.Errorf("iterations must be at least %d", minIterations)
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return apis.ScramCredentials{}, err
+	}
+	// username is not a part of the stored credentials
+	client, err := hashGenerator.NewClient("", password, "")
+	if err != nil {
+		return apis.ScramCredentials{}, err
+	}
+	stored := client.GetStoredCredentials(scram.KeyFactors{Salt: string(salt), Iters: iterations})
+	return apis.ScramCredentials{
+		Salt:       salt,
+		Iterations: iterations,
+		StoredKey:  stored.StoredKey,
+		ServerKey:  stored.ServerKey,
+	}, nil
+}
+
+// FormatCredentialsLine formats the line of the SCRAM credentials file
+func FormatCredentialsLine(username string, mechanism string, credentials apis.ScramCredentials) string {
+	return fmt.Sprintf("%s %s=salt=%s,stored_key=%s,server_key=%s,iterations=%d", username, mechanism,
+		base64.StdEncoding.EncodeToString(credentials.Salt),
+		base64.StdEncoding.EncodeToString(credentials.StoredKey),
+		base64.StdEncoding.EncodeToString(credentials.ServerKey),
+		credentials.Iterations)
+}
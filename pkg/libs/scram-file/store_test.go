@@ -0,0 +1,115 @@
+package scramfile
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatAndParseCredentialsLine(t *testing.T) {
+	a := assert.New(t)
+
+	credentials, err := NewCredentials(MechanismSHA256, "secret", DefaultIterations)
+	a.Nil(err)
+	a.Len(credentials.Salt, saltSize)
+	a.Len(credentials.StoredKey, 32)
+	a.Len(credentials.ServerKey, 32)
+
+	username, mechanism, parsed, err := ParseCredentialsLine(FormatCredentialsLine("alice", MechanismSHA256, credentials))
+	a.Nil(err)
+	a.Equal("alice", username)
+	a.Equal(MechanismSHA256, mechanism)
+	a.Equal(credentials, parsed)
+
+	_, err = NewCredentials("SCRAM-SHA-1", "secret", DefaultIterations)
+	a.NotNil(err)
+	_, err = NewCredentials(MechanismSHA512, "secret", 1)
+	a.NotNil(err)
+}
+
+func TestParseCredentialsLineErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		errorMsg string
+	}{
+		{
+			name:     "missing credentials",
+			line:     "alice",
+			errorMsg: "expected username and credentials separated by whitespace",
+		},
+		{
+			name:     "unsupported mechanism",
+			line:     "alice SCRAM-SHA-1=salt=c2FsdA==,stored_key=a2V5,server_key=a2V5,iterations=4096",
+			errorMsg: "unsupported mechanism 'SCRAM-SHA-1'",
+		},
+		{
+			name:     "invalid base64",
+			line:     "alice SCRAM-SHA-256=salt=!,stored_key=a2V5,server_key=a2V5,iterations=4096",
+			errorMsg: "invalid attribute 'salt': illegal base64 data at input byte 0",
+		},
+		{
+			name:     "unknown attribute",
+			line:     "alice SCRAM-SHA-256=salt=c2FsdA==,stored_key=a2V5,server_key=a2V5,iterations=4096,nonce=abc",
+			errorMsg: "invalid attribute 'nonce': unknown attribute 'nonce'",
+		},
+		{
+			name:     "missing server key",
+			line:     "alice SCRAM-SHA-256=salt=c2FsdA==,stored_key=a2V5,iterations=4096",
+			errorMsg: "salt, stored_key and server_key are required",
+		},
+		{
+			name:     "too few iterations",
+			line:     "alice SCRAM-SHA-256=salt=c2FsdA==,stored_key=a2V5,server_key=a2V5,iterations=1",
+			errorMsg: "iterations must be at least 4096",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := ParseCredentialsLine(tt.line)
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestCredentialStore(t *testing.T) {
+	a := assert.New(t)
+
+	file, err := os.CreateTemp("", "scram-credentials-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	content := "# users\n\n" +
+		"alice SCRAM-SHA-256=salt=c2FsdA==,stored_key=c3RvcmVk,server_key=c2VydmVy,iterations=4096\n" +
+		"alice SCRAM-SHA-512=salt=c2FsdA==,stored_key=c3RvcmVk,server_key=c2VydmVy,iterations=8192\n"
+	_, err = file.WriteString(content)
+	a.Nil(err)
+
+	store, err := new(Factory).New([]string{"--file", file.Name()})
+	a.Nil(err)
+
+	credentials, ok, err := store.GetCredentials(MechanismSHA512, "alice")
+	a.Nil(err)
+	a.True(ok)
+	a.Equal(8192, credentials.Iterations)
+	a.Equal([]byte("salt"), credentials.Salt)
+	a.Equal([]byte("stored"), credentials.StoredKey)
+	a.Equal([]byte("server"), credentials.ServerKey)
+
+	_, ok, err = store.GetCredentials(MechanismSHA256, "bob")
+	a.Nil(err)
+	a.False(ok)
+
+	_, err = new(Factory).New([]string{})
+	a.NotNil(err)
+
+	_, err = parseCredentials([]byte(content + "alice SCRAM-SHA-256=salt=c2FsdA==,stored_key=c3RvcmVk,server_key=c2VydmVy,iterations=4096\n"))
+	if a.NotNil(err) {
+		a.Equal("line 5: SCRAM-SHA-256 credentials of user 'alice' configured twice", err.Error())
+	}
+}
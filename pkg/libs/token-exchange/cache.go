@@ -0,0 +1,62 @@
+package tokenexchange
+
+import (
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	token   string
+	expires time.Time
+}
+
+// tokenCache caches the exchanged tokens until they expire
+type tokenCache struct {
+	entries    map[string]cacheEntry
+	maxEntries int
+	l          sync.Mutex
+}
+
+func newTokenCache(maxEntries int) *tokenCache {
+	return &tokenCache{entries: make(map[string]cacheEntry), maxEntries: maxEntries}
+}
+
+func (c *tokenCache) get(key string) (string, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !nowFn().Before(entry.expires) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.token, true
+}
+
+func (c *tokenCache) set(key string, token string, ttl time.Duration) {
+	if ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	now := nowFn()
+	if len(c.entries) >= c.maxEntries {
+		c.purgeExpired(now)
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+	c.entries[key] = cacheEntry{token: token, expires: now.Add(ttl)}
+}
+
+func (c *tokenCache) purgeExpired(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
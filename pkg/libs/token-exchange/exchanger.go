@@ -0,0 +1,190 @@
+package tokenexchange
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+var (
+	nowFn = time.Now
+)
+
+type TokenExchangerOptions struct {
+	Timeout time.Duration
+	// TokenURL is the RFC 8693 token endpoint
+	TokenURL         string
+	ClientID         string
+	ClientSecret     string
+	ClientSecretFile string
+	Audience         []string
+	Resource         []string
+	Scopes           []string
+	SubjectTokenType string
+	// RequestedTokenType is optional, the token endpoint decides on the type of the issued token when it is empty
+	RequestedTokenType string
+	// CacheMaxEntries is the maximum number of cached exchanged tokens, 0 disables the cache
+	CacheMaxEntries int
+}
+
+// TokenExchanger exchanges the tokens of the clients for tokens accepted by the brokers (RFC 8693)
+type TokenExchanger struct {
+	httpClient         *http.Client
+	tokenURL           string
+	clientID           string
+	clientSecret       string
+	audience           []string
+	resource           []string
+	scopes             []string
+	subjectTokenType   string
+	requestedTokenType string
+
+	cache *tokenCache
+}
+
+func NewTokenExchanger(options TokenExchangerOptions) (*TokenExchanger, error) {
+	if options.TokenURL == "" {
+		return nil, errors.New("token exchange url is required")
+	}
+	if _, err := url.ParseRequestURI(options.TokenURL); err != nil {
+		return nil, errors.Wrap(err, "invalid token exchange url")
+	}
+	if options.ClientSecret != "" && options.ClientSecretFile != "" {
+		return nil, errors.New("client secret and client secret file are mutually exclusive")
+	}
+	clientSecret := options.ClientSecret
+	if options.ClientSecretFile != "" {
+		data, err := os.ReadFile(options.ClientSecretFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading of client secret file failed")
+		}
+		clientSecret = strings.TrimSpace(string(data))
+	}
+	subjectTokenType := options.SubjectTokenType
+	if subjectTokenType == "" {
+		subjectTokenType = TokenTypeAccessToken
+	}
+	logrus.Infof("Token exchange url: %s, audience: %v, resource: %v, scopes: %v", options.TokenURL, options.Audience, options.Resource, options.Scopes)
+	return &TokenExchanger{
+		httpClient:         &http.Client{Timeout: options.Timeout},
+		tokenURL:           options.TokenURL,
+		clientID:           options.ClientID,
+		clientSecret:       clientSecret,
+		audience:           options.Audience,
+		resource:           options.Resource,
+		scopes:             options.Scopes,
+		subjectTokenType:   subjectTokenType,
+		requestedTokenType: options.RequestedTokenType,
+		cache:              newTokenCache(options.CacheMaxEntries),
+	}, nil
+}
+
+type tokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// ExchangeToken returns the token issued for the subject token, exchanged tokens are cached until they expire
+func (e *TokenExchanger) ExchangeToken(ctx context.Context, subjectToken string) (string, error) {
+	if subjectToken == "" {
+		return "", errors.New("subject token is empty")
+	}
+	key := cacheKey(subjectToken)
+	if token, ok := e.cache.get(key); ok {
+		return token, nil
+	}
+	resp, err := e.exchange(ctx, subjectToken)
+	if err != nil {
+		return "", err
+	}
+	if resp.ExpiresIn > 0 {
+		// the exchanged token must not expire while it is sent to the brokers
+		e.cache.set(key, resp.AccessToken, time.Duration(resp.ExpiresIn)*time.Second*9/10)
+	}
+	return resp.AccessToken, nil
+}
+
+// exchange posts the subject token to the token endpoint authenticated with the client credentials
+func (e *TokenExchanger) exchange(ctx context.Context, subjectToken string) (tokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", GrantTypeTokenExchange)
+	form.Set("subject_token", subjectToken)
+	form.Set("subject_token_type", e.subjectTokenType)
+	if e.requestedTokenType != "" {
+		form.Set("requested_token_type", e.requestedTokenType)
+	}
+	for _, audience := range e.audience {
+		form.Add("audience", audience)
+	}
+	for _, resource := range e.resource {
+		form.Add("resource", resource)
+	}
+	if len(e.scopes) != 0 {
+		form.Set("scope", strings.Join(e.scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if e.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(e.clientID), url.QueryEscape(e.clientSecret))
+	}
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+			if errResp.ErrorDescription != "" {
+				return tokenResponse{}, fmt.Errorf("token exchange failed with status %d: %s: %s", resp.StatusCode, errResp.Error, errResp.ErrorDescription)
+			}
+			return tokenResponse{}, fmt.Errorf("token exchange failed with status %d: %s", resp.StatusCode, errResp.Error)
+		}
+		return tokenResponse{}, fmt.Errorf("token exchange failed with status %d", resp.StatusCode)
+	}
+	var tokenResp tokenResponse
+	if err = json.Unmarshal(body, &tokenResp); err != nil {
+		return tokenResponse{}, errors.Wrap(err, "invalid token exchange response")
+	}
+	if tokenResp.AccessToken == "" {
+		return tokenResponse{}, errors.New("token exchange response without access_token")
+	}
+	return tokenResp, nil
+}
+
+func cacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return string(sum[:])
+}
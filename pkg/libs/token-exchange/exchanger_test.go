@@ -0,0 +1,128 @@
+package tokenexchange
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTokenServer issues exchanged tokens for the known subject tokens
+type testTokenServer struct {
+	server   *httptest.Server
+	tokens   map[string]string
+	forms    []url.Values
+	requests int
+	l        sync.Mutex
+}
+
+func newTestTokenServer(clientID, clientSecret string, tokens map[string]string) *testTokenServer {
+	s := &testTokenServer{tokens: tokens}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.l.Lock()
+		s.requests++
+		s.forms = append(s.forms, r.PostForm)
+		s.l.Unlock()
+
+		id, secret, ok := r.BasicAuth()
+		if r.Method != http.MethodPost || !ok || id != clientID || secret != clientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(errorResponse{Error: "invalid_client"})
+			return
+		}
+		token, ok := s.tokens[r.PostForm.Get("subject_token")]
+		if !ok || r.PostForm.Get("grant_type") != GrantTypeTokenExchange {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(errorResponse{Error: "invalid_grant", ErrorDescription: "subject token is not valid"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(tokenResponse{AccessToken: token, IssuedTokenType: TokenTypeAccessToken, TokenType: "Bearer", ExpiresIn: 300})
+	}))
+	return s
+}
+
+func (s *testTokenServer) getRequests() int {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.requests
+}
+
+func TestExchangeToken(t *testing.T) {
+	a := assert.New(t)
+
+	server := newTestTokenServer("kafka-proxy", "secret", map[string]string{"client-token": "broker-token"})
+	defer server.server.Close()
+
+	exchanger, err := NewTokenExchanger(TokenExchangerOptions{
+		Timeout:            time.Second,
+		TokenURL:           server.server.URL,
+		ClientID:           "kafka-proxy",
+		ClientSecret:       "secret",
+		Audience:           []string{"kafka"},
+		Resource:           []string{"https://kafka.example.com"},
+		Scopes:             []string{"produce", "consume"},
+		RequestedTokenType: TokenTypeJWT,
+		CacheMaxEntries:    10,
+	})
+	a.Nil(err)
+
+	token, err := exchanger.ExchangeToken(context.Background(), "client-token")
+	a.Nil(err)
+	a.Equal("broker-token", token)
+
+	if a.Len(server.forms, 1) {
+		form := server.forms[0]
+		a.Equal(TokenTypeAccessToken, form.Get("subject_token_type"))
+		a.Equal(TokenTypeJWT, form.Get("requested_token_type"))
+		a.Equal([]string{"kafka"}, form["audience"])
+		a.Equal([]string{"https://kafka.example.com"}, form["resource"])
+		a.Equal("produce consume", form.Get("scope"))
+	}
+
+	// exchanged token is cached
+	token, err = exchanger.ExchangeToken(context.Background(), "client-token")
+	a.Nil(err)
+	a.Equal("broker-token", token)
+	a.Equal(1, server.getRequests())
+
+	// cached token expires before the exchanged token
+	nowFn = func() time.Time { return time.Now().Add(280 * time.Second) }
+	defer func() { nowFn = time.Now }()
+	_, err = exchanger.ExchangeToken(context.Background(), "client-token")
+	a.Nil(err)
+	a.Equal(2, server.getRequests())
+
+	_, err = exchanger.ExchangeToken(context.Background(), "unknown-token")
+	a.EqualError(err, "token exchange failed with status 400: invalid_grant: subject token is not valid")
+}
+
+func TestExchangeTokenErrors(t *testing.T) {
+	a := assert.New(t)
+
+	server := newTestTokenServer("kafka-proxy", "secret", map[string]string{"client-token": "broker-token"})
+	defer server.server.Close()
+
+	exchanger, err := NewTokenExchanger(TokenExchangerOptions{Timeout: time.Second, TokenURL: server.server.URL, ClientID: "kafka-proxy", ClientSecret: "wrong"})
+	a.Nil(err)
+	_, err = exchanger.ExchangeToken(context.Background(), "client-token")
+	a.EqualError(err, "token exchange failed with status 401: invalid_client")
+
+	_, err = exchanger.ExchangeToken(context.Background(), "")
+	a.EqualError(err, "subject token is empty")
+
+	_, err = NewTokenExchanger(TokenExchangerOptions{})
+	a.EqualError(err, "token exchange url is required")
+	_, err = NewTokenExchanger(TokenExchangerOptions{TokenURL: server.server.URL, ClientSecret: "a", ClientSecretFile: "b"})
+	a.EqualError(err, "client secret and client secret file are mutually exclusive")
+}
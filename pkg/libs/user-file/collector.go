@@ -0,0 +1,21 @@
+package userfile
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	userFileUsers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "proxy_user_file_users",
+			Help: "Number of users loaded from the user file.",
+		}, []string{"file"})
+	userFileReloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "proxy_user_file_reloads_total",
+			Help: "Number of user file reloads.",
+		}, []string{"file", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(userFileUsers)
+	prometheus.MustRegister(userFileReloadsTotal)
+}
@@ -0,0 +1,44 @@
+package userfile
+
+import (
+	"flag"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/registry"
+)
+
+func init() {
+	registry.NewComponentInterface(new(apis.PasswordAuthenticatorFactory))
+	registry.Register(new(Factory), "user-file")
+}
+
+type pluginMeta struct {
+	file   string
+	format string
+	watch  bool
+}
+
+func (f *pluginMeta) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("user-file settings", flag.ContinueOnError)
+	fs.StringVar(&f.file, "file", "", "Path to the htpasswd or YAML user file")
+	fs.StringVar(&f.format, "format", FormatAuto, "Format of the user file: htpasswd, yaml or auto (yaml for .yaml and .yml files)")
+	fs.BoolVar(&f.watch, "watch", true, "Reload the user file on change")
+	return fs
+}
+
+type Factory struct {
+}
+
+// New implements apis.PasswordAuthenticatorFactory
+func (t *Factory) New(params []string) (apis.PasswordAuthenticator, error) {
+	pluginMeta := &pluginMeta{}
+	fs := pluginMeta.flagSet()
+	if err := fs.Parse(params); err != nil {
+		return nil, err
+	}
+	return NewPasswordAuthenticator(PasswordAuthenticatorOptions{
+		File:   pluginMeta.file,
+		Format: pluginMeta.format,
+		Watch:  pluginMeta.watch,
+	})
+}
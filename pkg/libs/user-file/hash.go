@@ -0,0 +1,266 @@
+package userfile
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordHash verifies passwords against the stored hash
+type passwordHash interface {
+	verify(password string) bool
+}
+
+// parsePasswordHash parses bcrypt ($2a$, $2b$, $2y$), Argon2 PHC ($argon2id$, $argon2i$) and SHA-crypt ($5$, $6$) hashes
+func parsePasswordHash(value string) (passwordHash, error) {
+	switch {
+	case strings.HasPrefix(value, "$2a$"), strings.HasPrefix(value, "$2b$"), strings.HasPrefix(value, "$2y$"):
+		if _, err := bcrypt.Cost([]byte(value)); err != nil {
+			return nil, errors.Wrap(err, "invalid bcrypt hash")
+		}
+		return bcryptHash(value), nil
+	case strings.HasPrefix(value, "$argon2id$"), strings.HasPrefix(value, "$argon2i$"):
+		return parseArgon2Hash(value)
+	case strings.HasPrefix(value, "$5$"), strings.HasPrefix(value, "$6$"):
+		return parseSHACryptHash(value)
+	default:
+		return nil, errors.New("unsupported password hash, bcrypt, argon2 or SHA-crypt hash expected")
+	}
+}
+
+type bcryptHash string
+
+func (h bcryptHash) verify(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(h), []byte(password)) == nil
+}
+
+type argon2Hash struct {
+	variant string
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2Hash parses the PHC string format $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+func parseArgon2Hash(value string) (*argon2Hash, error) {
+	parts := strings.Split(value, "$")
+	if len(parts) != 6 {
+		return nil, errors.New("invalid argon2 hash: expected $<variant>$v=<version>$m=<memory>,t=<time>,p=<threads>$<salt>$<key>")
+	}
+	h := &argon2Hash{variant: parts[1]}
+	if parts[2] != "v="+strconv.Itoa(argon2.Version) {
+		return nil, errors.Errorf("invalid argon2 hash: unsupported version '%s'", parts[2])
+	}
+	for _, param := range strings.Split(parts[3], ",") {
+		name, paramValue, _ := strings.Cut(param, "=")
+		n, err := strconv.ParseUint(paramValue, 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid argon2 hash parameter '%s'", param)
+		}
+		switch name {
+		case "m":
+			h.memory = uint32(n)
+		case "t":
+			h.time = uint32(n)
+		case "p":
+			if n > 255 {
+				return nil, errors.Errorf("invalid argon2 hash parameter '%s'", param)
+			}
+			h.threads = uint8(n)
+		default:
+			return nil, errors.Errorf("invalid argon2 hash parameter '%s'", param)
+		}
+	}
+	if h.memory == 0 || h.time == 0 || h.threads == 0 {
+		return nil, errors.New("invalid argon2 hash: parameters m, t and p are required")
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errors.Wrap(err, "invalid argon2 salt")
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, errors.Wrap(err, "invalid argon2 key")
+	}
+	if len(h.key) == 0 {
+		return nil, errors.New("invalid argon2 hash: empty key")
+	}
+	return h, nil
+}
+
+func (h *argon2Hash) verify(password string) bool {
+	var key []byte
+	if h.variant == "argon2id" {
+		key = argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	} else {
+		key = argon2.Key([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	}
+	return subtle.ConstantTimeCompare(key, h.key) == 1
+}
+
+const (
+	shaCryptDefaultRounds = 5000
+	shaCryptMinRounds     = 1000
+	shaCryptMaxRounds     = 999999999
+	shaCryptMaxSaltLength = 16
+	shaCryptAlphabet      = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// byte order of the SHA-crypt digest encoding, each group of three bytes is encoded into four characters
+var (
+	sha256CryptOrder = [][3]int{{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14}, {15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29}}
+	sha512CryptOrder = [][3]int{{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+		{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41}}
+)
+
+type shaCryptHash struct {
+	value          string
+	id             string
+	rounds         int
+	explicitRounds bool
+	salt           string
+}
+
+// parseSHACryptHash parses $5$[rounds=<rounds>$]<salt>$<hash> and $6$[rounds=<rounds>$]<salt>$<hash>
+func parseSHACryptHash(value string) (*shaCryptHash, error) {
+	parts := strings.Split(value[1:], "$")
+	h := &shaCryptHash{value: value, id: parts[0], rounds: shaCryptDefaultRounds}
+	parts = parts[1:]
+	if len(parts) == 3 && strings.HasPrefix(parts[0], "rounds=") {
+		rounds, err := strconv.Atoi(strings.TrimPrefix(parts[0], "rounds="))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid SHA-crypt rounds")
+		}
+		if rounds < shaCryptMinRounds || rounds > shaCryptMaxRounds {
+			return nil, errors.Errorf("invalid SHA-crypt rounds %d", rounds)
+		}
+		h.rounds = rounds
+		h.explicitRounds = true
+		parts = parts[1:]
+	}
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, errors.New("invalid SHA-crypt hash: expected $<id>$[rounds=<rounds>$]<salt>$<hash>")
+	}
+	if len(parts[0]) > shaCryptMaxSaltLength {
+		return nil, errors.Errorf("invalid SHA-crypt hash: salt longer than %d characters", shaCryptMaxSaltLength)
+	}
+	h.salt = parts[0]
+	return h, nil
+}
+
+func (h *shaCryptHash) verify(password string) bool {
+	computed := shaCrypt(h.id, password, h.salt, h.rounds, h.explicitRounds)
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(h.value)) == 1
+}
+
+// shaCrypt implements the SHA-crypt algorithm of https://www.akkadia.org/drepper/SHA-crypt.txt
+func shaCrypt(id, password, salt string, rounds int, explicitRounds bool) string {
+	newHash := sha256.New
+	order := sha256CryptOrder
+	if id == "6" {
+		newHash = sha512.New
+		order = sha512CryptOrder
+	}
+	p, s := []byte(password), []byte(salt)
+
+	b := newHash()
+	b.Write(p)
+	b.Write(s)
+	b.Write(p)
+	digestB := b.Sum(nil)
+
+	a := newHash()
+	a.Write(p)
+	a.Write(s)
+	writeRepeated(a, digestB, len(p))
+	for n := len(p); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			a.Write(digestB)
+		} else {
+			a.Write(p)
+		}
+	}
+	digestA := a.Sum(nil)
+
+	dp := newHash()
+	for i := 0; i < len(p); i++ {
+		dp.Write(p)
+	}
+	pBytes := repeated(dp.Sum(nil), len(p))
+
+	ds := newHash()
+	for i := 0; i < 16+int(digestA[0]); i++ {
+		ds.Write(s)
+	}
+	sBytes := repeated(ds.Sum(nil), len(s))
+
+	digestC := digestA
+	for i := 0; i < rounds; i++ {
+		c := newHash()
+		if i&1 != 0 {
+			c.Write(pBytes)
+		} else {
+			c.Write(digestC)
+		}
+		if i%3 != 0 {
+			c.Write(sBytes)
+		}
+		if i%7 != 0 {
+			c.Write(pBytes)
+		}
+		if i&1 != 0 {
+			c.Write(digestC)
+		} else {
+			c.Write(pBytes)
+		}
+		digestC = c.Sum(nil)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("$" + id + "$")
+	if explicitRounds {
+		sb.WriteString("rounds=" + strconv.Itoa(rounds) + "$")
+	}
+	sb.WriteString(salt + "$")
+	for _, group := range order {
+		encode24Bit(&sb, digestC[group[0]], digestC[group[1]], digestC[group[2]], 4)
+	}
+	if id == "6" {
+		encode24Bit(&sb, 0, 0, digestC[63], 2)
+	} else {
+		encode24Bit(&sb, 0, digestC[31], digestC[30], 3)
+	}
+	return sb.String()
+}
+
+func writeRepeated(h hash.Hash, digest []byte, length int) {
+	for ; length > len(digest); length -= len(digest) {
+		h.Write(digest)
+	}
+	h.Write(digest[:length])
+}
+
+func repeated(digest []byte, length int) []byte {
+	result := make([]byte, 0, length)
+	for ; length > len(digest); length -= len(digest) {
+		result = append(result, digest...)
+	}
+	return append(result, digest[:length]...)
+}
+
+func encode24Bit(sb *strings.Builder, b2, b1, b0 byte, n int) {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	for i := 0; i < n; i++ {
+		sb.WriteByte(shaCryptAlphabet[w&0x3f])
+		w >>= 6
+	}
+}
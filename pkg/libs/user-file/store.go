@@ -0,0 +1,207 @@
+package userfile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	StatusOK            = 0
+	StatusUnknownUser   = 1
+	StatusWrongPassword = 2
+)
+
+const (
+	FormatAuto     = "auto"
+	FormatHtpasswd = "htpasswd"
+	FormatYAML     = "yaml"
+)
+
+type PasswordAuthenticatorOptions struct {
+	File string
+	// Format is htpasswd, yaml or auto which selects yaml for the .yaml and .yml extensions
+	Format string
+	Watch  bool
+}
+
+type user struct {
+	hash   passwordHash
+	groups []string
+}
+
+// PasswordAuthenticator authenticates users from a file in the htpasswd format
+//
+//	alice:$2y$10$...
+//
+// or in the YAML format, which additionally defines the groups of the principal
+//
+//	users:
+//	  - name: alice
+//	    password: $argon2id$v=19$m=65536,t=3,p=4$...
+//	    groups: [admins]
+//
+// Passwords are bcrypt, argon2 or SHA-crypt hashes.
+type PasswordAuthenticator struct {
+	mu    sync.RWMutex
+	users map[string]user
+}
+
+type yamlUserFile struct {
+	Users []struct {
+		Name     string   `yaml:"name"`
+		Password string   `yaml:"password"`
+		Groups   []string `yaml:"groups"`
+	} `yaml:"users"`
+}
+
+func NewPasswordAuthenticator(options PasswordAuthenticatorOptions) (*PasswordAuthenticator, error) {
+	if options.File == "" {
+		return nil, errors.New("parameter file is required")
+	}
+	format, err := fileFormat(options.File, options.Format)
+	if err != nil {
+		return nil, err
+	}
+	users, err := readUserFile(options.File, format)
+	if err != nil {
+		return nil, err
+	}
+	userFileUsers.WithLabelValues(options.File).Set(float64(len(users)))
+	authenticator := &PasswordAuthenticator{users: users}
+	if options.Watch {
+		action := func() {
+			logrus.Infof("reloading user file %s", options.File)
+
+			users, err := readUserFile(options.File, format)
+			if err != nil {
+				logrus.Errorf("error while reloading user file: %s", err)
+				userFileReloadsTotal.WithLabelValues(options.File, "error").Inc()
+				return
+			}
+			authenticator.mu.Lock()
+			authenticator.users = users
+			authenticator.mu.Unlock()
+			userFileReloadsTotal.WithLabelValues(options.File, "success").Inc()
+			userFileUsers.WithLabelValues(options.File).Set(float64(len(users)))
+		}
+		if err = util.WatchForUpdates(options.File, make(chan bool, 1), action); err != nil {
+			return nil, errors.Wrap(err, "cannot watch user file")
+		}
+	}
+	return authenticator, nil
+}
+
+// Authenticate implements apis.PasswordAuthenticator
+func (p *PasswordAuthenticator) Authenticate(username, password string) (bool, int32, error) {
+	resp, err := p.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: username, Password: password})
+	return resp.Authenticated, resp.Status, err
+}
+
+// AuthenticatePrincipal implements apis.PasswordAuthenticatorV2
+func (p *PasswordAuthenticator) AuthenticatePrincipal(_ context.Context, request apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	p.mu.RLock()
+	u, ok := p.users[request.Username]
+	p.mu.RUnlock()
+	if !ok {
+		return apis.AuthenticateResponse{Authenticated: false, Status: StatusUnknownUser}, nil
+	}
+	if !u.hash.verify(request.Password) {
+		return apis.AuthenticateResponse{Authenticated: false, Status: StatusWrongPassword}, nil
+	}
+	return apis.AuthenticateResponse{Authenticated: true, Status: StatusOK, Principal: apis.Principal{Name: request.Username, Groups: u.groups}}, nil
+}
+
+func fileFormat(filename string, format string) (string, error) {
+	switch format {
+	case FormatHtpasswd, FormatYAML:
+		return format, nil
+	case "", FormatAuto:
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".yaml", ".yml":
+			return FormatYAML, nil
+		default:
+			return FormatHtpasswd, nil
+		}
+	default:
+		return "", errors.Errorf("unsupported user file format '%s'", format)
+	}
+}
+
+func readUserFile(filename string, format string) (map[string]user, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read user file '%s'", filename)
+	}
+	var users map[string]user
+	if format == FormatYAML {
+		users, err = parseYAMLUsers(content)
+	} else {
+		users, err = parseHtpasswdUsers(content)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse user file '%s'", filename)
+	}
+	return users, nil
+}
+
+func parseHtpasswdUsers(content []byte) (map[string]user, error) {
+	result := make(map[string]user)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		username, value, ok := strings.Cut(line, ":")
+		if !ok || username == "" {
+			return nil, errors.Errorf("line %d: expected username:hash", lineNumber)
+		}
+		if _, ok = result[username]; ok {
+			return nil, errors.Errorf("line %d: user '%s' configured twice", lineNumber, username)
+		}
+		hash, err := parsePasswordHash(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNumber)
+		}
+		result[username] = user{hash: hash}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseYAMLUsers(content []byte) (map[string]user, error) {
+	var userFile yamlUserFile
+	if err := yaml.UnmarshalStrict(content, &userFile); err != nil {
+		return nil, err
+	}
+	result := make(map[string]user)
+	for i, u := range userFile.Users {
+		if u.Name == "" {
+			return nil, errors.Errorf("user %d: name is required", i+1)
+		}
+		if _, ok := result[u.Name]; ok {
+			return nil, errors.Errorf("user '%s' configured twice", u.Name)
+		}
+		hash, err := parsePasswordHash(u.Password)
+		if err != nil {
+			return nil, errors.Wrapf(err, "user '%s'", u.Name)
+		}
+		result[u.Name] = user{hash: hash, groups: u.Groups}
+	}
+	return result, nil
+}
@@ -0,0 +1,202 @@
+package userfile
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newBcryptHash(t *testing.T, password string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(hash)
+}
+
+func newArgon2Hash(t *testing.T, password string) string {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		t.Fatal(err)
+	}
+	key := argon2.IDKey([]byte(password), salt, 1, 1024, 1, 32)
+	return fmt.Sprintf("$argon2id$v=%d$m=1024,t=1,p=1$%s$%s", argon2.Version, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestPasswordHashes(t *testing.T) {
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+	}{
+		{name: "bcrypt", hash: newBcryptHash(t, "Hello world!"), password: "Hello world!"},
+		{name: "argon2id", hash: newArgon2Hash(t, "Hello world!"), password: "Hello world!"},
+		{name: "argon2i", hash: "$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA", password: "password"},
+		// test vectors of https://www.akkadia.org/drepper/SHA-crypt.txt
+		{name: "sha256-crypt", hash: "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5", password: "Hello world!"},
+		{name: "sha256-crypt rounds", hash: "$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA", password: "Hello world!"},
+		{name: "sha512-crypt", hash: "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", password: "Hello world!"},
+		{name: "sha512-crypt rounds", hash: "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.", password: "Hello world!"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			hash, err := parsePasswordHash(tc.hash)
+			a.Nil(err)
+			a.True(hash.verify(tc.password))
+			a.False(hash.verify(tc.password + "x"))
+			a.False(hash.verify(""))
+		})
+	}
+}
+
+func TestParsePasswordHashErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		hash     string
+		errorMsg string
+	}{
+		{name: "plain text", hash: "secret", errorMsg: "unsupported password hash, bcrypt, argon2 or SHA-crypt hash expected"},
+		{name: "apr1", hash: "$apr1$salt$hash", errorMsg: "unsupported password hash, bcrypt, argon2 or SHA-crypt hash expected"},
+		{name: "bcrypt", hash: "$2y$10$short", errorMsg: "invalid bcrypt hash: crypto/bcrypt: hashedSecret too short to be a bcrypted password"},
+		{name: "argon2 version", hash: "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5", errorMsg: "invalid argon2 hash: unsupported version 'v=16'"},
+		{name: "argon2 parameters", hash: "$argon2id$v=19$m=1024,t=1$c2FsdA$a2V5", errorMsg: "invalid argon2 hash: parameters m, t and p are required"},
+		{name: "sha-crypt rounds", hash: "$6$rounds=10$salt$hash", errorMsg: "invalid SHA-crypt rounds 10"},
+		{name: "sha-crypt format", hash: "$5$salt", errorMsg: "invalid SHA-crypt hash: expected $<id>$[rounds=<rounds>$]<salt>$<hash>"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parsePasswordHash(tc.hash)
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tc.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestPasswordAuthenticator(t *testing.T) {
+	dir := t.TempDir()
+	htpasswdFile := filepath.Join(dir, "users.htpasswd")
+	yamlFile := filepath.Join(dir, "users.yaml")
+	htpasswd := fmt.Sprintf("# kafka users\nalice:%s\n\nbob:%s\n", newBcryptHash(t, "alice-secret"), newArgon2Hash(t, "bob-secret"))
+	users := fmt.Sprintf("users:\n  - name: alice\n    password: '%s'\n    groups: [admins]\n  - name: bob\n    password: '%s'\n", newBcryptHash(t, "alice-secret"), newArgon2Hash(t, "bob-secret"))
+	assert.Nil(t, os.WriteFile(htpasswdFile, []byte(htpasswd), 0600))
+	assert.Nil(t, os.WriteFile(yamlFile, []byte(users), 0600))
+
+	for _, tc := range []struct {
+		file        string
+		aliceGroups []string
+	}{
+		{file: htpasswdFile},
+		{file: yamlFile, aliceGroups: []string{"admins"}},
+	} {
+		t.Run(filepath.Base(tc.file), func(t *testing.T) {
+			a := assert.New(t)
+			authenticator, err := NewPasswordAuthenticator(PasswordAuthenticatorOptions{File: tc.file})
+			a.Nil(err)
+			a.Equal(float64(2), metricValue(t, userFileUsers.WithLabelValues(tc.file)))
+
+			resp, err := authenticator.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: "alice", Password: "alice-secret"})
+			a.Nil(err)
+			a.Equal(apis.AuthenticateResponse{Authenticated: true, Status: StatusOK, Principal: apis.Principal{Name: "alice", Groups: tc.aliceGroups}}, resp)
+
+			ok, status, err := authenticator.Authenticate("bob", "bob-secret")
+			a.Nil(err)
+			a.True(ok)
+			a.Equal(int32(StatusOK), status)
+
+			ok, status, err = authenticator.Authenticate("bob", "alice-secret")
+			a.Nil(err)
+			a.False(ok)
+			a.Equal(int32(StatusWrongPassword), status)
+
+			ok, status, err = authenticator.Authenticate("carol", "carol-secret")
+			a.Nil(err)
+			a.False(ok)
+			a.Equal(int32(StatusUnknownUser), status)
+		})
+	}
+}
+
+func TestPasswordAuthenticatorFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		errorMsg string
+	}{
+		{name: "missing separator", filename: "users", content: "alice\n", errorMsg: "line 1: expected username:hash"},
+		{name: "duplicate user", filename: "users", content: "alice:$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5\nalice:$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5\n", errorMsg: "line 2: user 'alice' configured twice"},
+		{name: "plain text password", filename: "users", content: "alice:secret\n", errorMsg: "line 1: unsupported password hash, bcrypt, argon2 or SHA-crypt hash expected"},
+		{name: "yaml unknown field", filename: "users.yml", content: "users:\n  - name: alice\n    pass: secret\n", errorMsg: "yaml: unmarshal errors:\n  line 3: field pass not found in type struct { Name string \"yaml:\\\"name\\\"\"; Password string \"yaml:\\\"password\\\"\"; Groups []string \"yaml:\\\"groups\\\"\" }"},
+		{name: "yaml missing name", filename: "users.yml", content: "users:\n  - password: secret\n", errorMsg: "user 1: name is required"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tc.filename)
+			assert.Nil(t, os.WriteFile(filename, []byte(tc.content), 0600))
+			_, err := NewPasswordAuthenticator(PasswordAuthenticatorOptions{File: filename})
+			if assert.NotNil(t, err) {
+				assert.Equal(t, fmt.Sprintf("failed to parse user file '%s': %s", filename, tc.errorMsg), err.Error())
+			}
+		})
+	}
+	_, err := NewPasswordAuthenticator(PasswordAuthenticatorOptions{File: "users", Format: "json"})
+	assert.EqualError(t, err, "unsupported user file format 'json'")
+}
+
+func TestPasswordAuthenticatorReload(t *testing.T) {
+	a := assert.New(t)
+
+	filename := filepath.Join(t.TempDir(), "users.htpasswd")
+	a.Nil(os.WriteFile(filename, []byte("alice:"+newBcryptHash(t, "old-secret")+"\n"), 0600))
+
+	authenticator, err := NewPasswordAuthenticator(PasswordAuthenticatorOptions{File: filename, Watch: true})
+	a.Nil(err)
+	ok, _, _ := authenticator.Authenticate("alice", "old-secret")
+	a.True(ok)
+
+	// rotated password
+	a.Nil(os.WriteFile(filename, []byte("alice:"+newBcryptHash(t, "new-secret")+"\nbob:"+newBcryptHash(t, "bob-secret")+"\n"), 0600))
+	a.Eventually(func() bool {
+		ok, _, _ := authenticator.Authenticate("alice", "new-secret")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	ok, _, _ = authenticator.Authenticate("alice", "old-secret")
+	a.False(ok)
+	a.Eventually(func() bool {
+		return metricValue(t, userFileUsers.WithLabelValues(filename)) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// invalid file keeps the loaded users
+	a.Nil(os.WriteFile(filename, []byte("alice:new-secret\n"), 0600))
+	a.Eventually(func() bool {
+		return metricValue(t, userFileReloadsTotal.WithLabelValues(filename, "error")) >= 1
+	}, 5*time.Second, 10*time.Millisecond)
+	ok, _, _ = authenticator.Authenticate("alice", "new-secret")
+	a.True(ok)
+}
+
+func metricValue(t *testing.T, metric prometheus.Metric) float64 {
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.GetGauge() != nil {
+		return m.GetGauge().GetValue()
+	}
+	return m.GetCounter().GetValue()
+}
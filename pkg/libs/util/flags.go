@@ -1,6 +1,9 @@
 package util
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type ArrayFlags []string
 
@@ -20,3 +23,16 @@ func (i *ArrayFlags) AsMap() map[string]struct{} {
 	}
 	return result
 }
+
+// AsKeyValueMap parses the key=value elements
+func (i *ArrayFlags) AsKeyValueMap() (map[string]string, error) {
+	result := make(map[string]string)
+	for _, elem := range *i {
+		kv := strings.SplitN(elem, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("key=value expected, got '%s'", elem)
+		}
+		result[kv[0]] = kv[1]
+	}
+	return result, nil
+}
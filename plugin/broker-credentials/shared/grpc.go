@@ -0,0 +1,82 @@
+package shared
+
+import (
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/plugin/broker-credentials/proto"
+	"github.com/hashicorp/go-plugin"
+	"golang.org/x/net/context"
+)
+
+// GRPCClient is an implementation of BrokerCredentialsProvider that talks over gRPC.
+type GRPCClient struct {
+	broker *plugin.GRPCBroker
+	client proto.BrokerCredentialsProviderClient
+}
+
+func (m *GRPCClient) GetBrokerCredentials(ctx context.Context, request apis.BrokerCredentialsRequest) (apis.BrokerCredentials, bool, error) {
+	resp, err := m.client.GetBrokerCredentials(ctx, &proto.BrokerCredentialsRequest{
+		Principal: &proto.Principal{
+			Name:       request.Principal.Name,
+			Groups:     request.Principal.Groups,
+			Attributes: request.Principal.Attributes,
+		},
+		Connection: &proto.ClientConnection{
+			ClientAddress:              request.Connection.ClientAddress,
+			ListenerAddress:            request.Connection.ListenerAddress,
+			ListenerProfile:            request.Connection.ListenerProfile,
+			TlsClientSubject:           request.Connection.TLSClientSubject,
+			TlsServerName:              request.Connection.TLSServerName,
+			TlsClientPrincipal:         request.Connection.TLSClientPrincipal,
+			ProxySsl:                   request.Connection.ProxySSL,
+			ProxySslClientCert:         request.Connection.ProxySSLClientCert,
+			ProxySslClientCertVerified: request.Connection.ProxySSLClientCertVerified,
+			ProxySslVersion:            request.Connection.ProxySSLVersion,
+			ProxySslClientCn:           request.Connection.ProxySSLClientCN,
+		},
+	})
+	if err != nil {
+		return apis.BrokerCredentials{}, false, err
+	}
+	return apis.BrokerCredentials{
+		Username: resp.Username,
+		Password: resp.Password,
+	}, resp.Found, nil
+}
+
+// Here is the gRPC server that GRPCClient talks to.
+type GRPCServer struct {
+	broker *plugin.GRPCBroker
+	Impl   apis.BrokerCredentialsProvider
+	proto.UnimplementedBrokerCredentialsProviderServer
+}
+
+func (m *GRPCServer) GetBrokerCredentials(
+	ctx context.Context,
+	req *proto.BrokerCredentialsRequest) (*proto.BrokerCredentialsResponse, error) {
+	request := apis.BrokerCredentialsRequest{
+		Principal: apis.Principal{
+			Name:       req.GetPrincipal().GetName(),
+			Groups:     req.GetPrincipal().GetGroups(),
+			Attributes: req.GetPrincipal().GetAttributes(),
+		},
+		Connection: apis.ConnectionInfo{
+			ClientAddress:              req.GetConnection().GetClientAddress(),
+			ListenerAddress:            req.GetConnection().GetListenerAddress(),
+			ListenerProfile:            req.GetConnection().GetListenerProfile(),
+			TLSClientSubject:           req.GetConnection().GetTlsClientSubject(),
+			TLSServerName:              req.GetConnection().GetTlsServerName(),
+			TLSClientPrincipal:         req.GetConnection().GetTlsClientPrincipal(),
+			ProxySSL:                   req.GetConnection().GetProxySsl(),
+			ProxySSLClientCert:         req.GetConnection().GetProxySslClientCert(),
+			ProxySSLClientCertVerified: req.GetConnection().GetProxySslClientCertVerified(),
+			ProxySSLVersion:            req.GetConnection().GetProxySslVersion(),
+			ProxySSLClientCN:           req.GetConnection().GetProxySslClientCn(),
+		},
+	}
+	credentials, found, err := m.Impl.GetBrokerCredentials(ctx, request)
+	return &proto.BrokerCredentialsResponse{
+		Found:    found,
+		Username: credentials.Username,
+		Password: credentials.Password,
+	}, err
+}
@@ -0,0 +1,50 @@
+// Package shared contains shared data between the host and plugins.
+package shared
+
+import (
+	"net/rpc"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/plugin/broker-credentials/proto"
+	"github.com/hashicorp/go-plugin"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// Handshake is a common handshake that is shared by plugin and host.
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "BROKER_CREDENTIALS_PLUGIN",
+	MagicCookieValue: "hello",
+}
+
+var PluginMap = map[string]plugin.Plugin{
+	"brokerCredentialsProvider": &BrokerCredentialsProviderPlugin{},
+}
+
+type BrokerCredentialsProviderPlugin struct {
+	Impl apis.BrokerCredentialsProvider
+}
+
+func (p *BrokerCredentialsProviderPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterBrokerCredentialsProviderServer(s, &GRPCServer{
+		Impl:   p.Impl,
+		broker: broker,
+	})
+	return nil
+}
+
+func (p *BrokerCredentialsProviderPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &GRPCClient{
+		client: proto.NewBrokerCredentialsProviderClient(c),
+		broker: broker,
+	}, nil
+}
+
+func (p *BrokerCredentialsProviderPlugin) Server(*plugin.MuxBroker) (interface{}, error) {
+	return &RPCServer{Impl: p.Impl}, nil
+}
+
+func (*BrokerCredentialsProviderPlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &RPCClient{client: c}, nil
+}
@@ -0,0 +1,50 @@
+package shared
+
+import (
+	"context"
+	"encoding/gob"
+	"net/rpc"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+)
+
+func init() {
+	// request structs are passed as interface values of the net/rpc arguments
+	gob.Register(apis.Principal{})
+	gob.Register(apis.ConnectionInfo{})
+}
+
+type RPCClient struct{ client *rpc.Client }
+
+func (m *RPCClient) GetBrokerCredentials(_ context.Context, request apis.BrokerCredentialsRequest) (apis.BrokerCredentials, bool, error) {
+	var resp map[string]interface{}
+	err := m.client.Call("Plugin.GetBrokerCredentials", map[string]interface{}{
+		"principal":  request.Principal,
+		"connection": request.Connection,
+	}, &resp)
+	if err != nil {
+		return apis.BrokerCredentials{}, false, err
+	}
+	return apis.BrokerCredentials{
+		Username: resp["username"].(string),
+		Password: resp["password"].(string),
+	}, resp["found"].(bool), nil
+}
+
+type RPCServer struct {
+	Impl apis.BrokerCredentialsProvider
+}
+
+func (m *RPCServer) GetBrokerCredentials(args map[string]interface{}, resp *map[string]interface{}) error {
+	request := apis.BrokerCredentialsRequest{
+		Principal:  args["principal"].(apis.Principal),
+		Connection: args["connection"].(apis.ConnectionInfo),
+	}
+	credentials, found, err := m.Impl.GetBrokerCredentials(context.Background(), request)
+	*resp = map[string]interface{}{
+		"found":    found,
+		"username": credentials.Username,
+		"password": credentials.Password,
+	}
+	return err
+}
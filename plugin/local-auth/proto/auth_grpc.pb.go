@@ -103,3 +103,90 @@ var PasswordAuthenticator_ServiceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "auth.proto",
 }
+
+// PasswordAuthenticatorV2Client is the client API for PasswordAuthenticatorV2 service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PasswordAuthenticatorV2Client interface {
+	Authenticate(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*AuthenticateResponseV2, error)
+}
+
+type passwordAuthenticatorV2Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPasswordAuthenticatorV2Client(cc grpc.ClientConnInterface) PasswordAuthenticatorV2Client {
+	return &passwordAuthenticatorV2Client{cc}
+}
+
+func (c *passwordAuthenticatorV2Client) Authenticate(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*AuthenticateResponseV2, error) {
+	out := new(AuthenticateResponseV2)
+	err := c.cc.Invoke(ctx, "/proto.PasswordAuthenticatorV2/Authenticate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PasswordAuthenticatorV2Server is the server API for PasswordAuthenticatorV2 service.
+// All implementations must embed UnimplementedPasswordAuthenticatorV2Server
+// for forward compatibility
+type PasswordAuthenticatorV2Server interface {
+	Authenticate(context.Context, *CredentialsRequest) (*AuthenticateResponseV2, error)
+	mustEmbedUnimplementedPasswordAuthenticatorV2Server()
+}
+
+// UnimplementedPasswordAuthenticatorV2Server must be embedded to have forward compatible implementations.
+type UnimplementedPasswordAuthenticatorV2Server struct {
+}
+
+func (UnimplementedPasswordAuthenticatorV2Server) Authenticate(context.Context, *CredentialsRequest) (*AuthenticateResponseV2, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Authenticate not implemented")
+}
+func (UnimplementedPasswordAuthenticatorV2Server) mustEmbedUnimplementedPasswordAuthenticatorV2Server() {
+}
+
+// UnsafePasswordAuthenticatorV2Server may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PasswordAuthenticatorV2Server will
+// result in compilation errors.
+type UnsafePasswordAuthenticatorV2Server interface {
+	mustEmbedUnimplementedPasswordAuthenticatorV2Server()
+}
+
+func RegisterPasswordAuthenticatorV2Server(s grpc.ServiceRegistrar, srv PasswordAuthenticatorV2Server) {
+	s.RegisterService(&PasswordAuthenticatorV2_ServiceDesc, srv)
+}
+
+func _PasswordAuthenticatorV2_Authenticate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CredentialsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PasswordAuthenticatorV2Server).Authenticate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.PasswordAuthenticatorV2/Authenticate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PasswordAuthenticatorV2Server).Authenticate(ctx, req.(*CredentialsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PasswordAuthenticatorV2_ServiceDesc is the grpc.ServiceDesc for PasswordAuthenticatorV2 service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PasswordAuthenticatorV2_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.PasswordAuthenticatorV2",
+	HandlerType: (*PasswordAuthenticatorV2Server)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Authenticate",
+			Handler:    _PasswordAuthenticatorV2_Authenticate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "auth.proto",
+}
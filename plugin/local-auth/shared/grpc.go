@@ -37,3 +37,79 @@ func (m *GRPCServer) Authenticate(
 	a, s, err := m.Impl.Authenticate(req.Username, req.Password)
 	return &proto.AuthenticateResponse{Authenticated: a, Status: s}, err
 }
+
+// GRPCClientV2 is an implementation of PasswordAuthenticatorV2 and PasswordAuthenticator that talks over gRPC.
+type GRPCClientV2 struct {
+	broker *plugin.GRPCBroker
+	client proto.PasswordAuthenticatorV2Client
+}
+
+func (m *GRPCClientV2) AuthenticatePrincipal(ctx context.Context, request apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	resp, err := m.client.Authenticate(ctx, &proto.CredentialsRequest{
+		Username: request.Username,
+		Password: request.Password,
+		Connection: &proto.UserConnection{
+			ClientAddress:              request.Connection.ClientAddress,
+			ListenerAddress:            request.Connection.ListenerAddress,
+			ListenerProfile:            request.Connection.ListenerProfile,
+			TlsClientSubject:           request.Connection.TLSClientSubject,
+			TlsServerName:              request.Connection.TLSServerName,
+			TlsClientPrincipal:         request.Connection.TLSClientPrincipal,
+			ProxySsl:                   request.Connection.ProxySSL,
+			ProxySslClientCert:         request.Connection.ProxySSLClientCert,
+			ProxySslClientCertVerified: request.Connection.ProxySSLClientCertVerified,
+			ProxySslVersion:            request.Connection.ProxySSLVersion,
+			ProxySslClientCn:           request.Connection.ProxySSLClientCN,
+		},
+	})
+	if err != nil {
+		return apis.AuthenticateResponse{}, err
+	}
+	return apis.AuthenticateResponse{Authenticated: resp.Authenticated, Status: resp.Status, Principal: toPrincipal(resp.Principal)}, nil
+}
+
+func (m *GRPCClientV2) Authenticate(username, password string) (bool, int32, error) {
+	resp, err := m.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: username, Password: password})
+	return resp.Authenticated, resp.Status, err
+}
+
+// Here is the gRPC server that GRPCClientV2 talks to.
+type GRPCServerV2 struct {
+	broker *plugin.GRPCBroker
+	Impl   apis.PasswordAuthenticatorV2
+	proto.UnimplementedPasswordAuthenticatorV2Server
+}
+
+func (m *GRPCServerV2) Authenticate(
+	ctx context.Context,
+	req *proto.CredentialsRequest) (*proto.AuthenticateResponseV2, error) {
+	request := apis.AuthenticateRequest{Username: req.Username, Password: req.Password}
+	if req.Connection != nil {
+		request.Connection = apis.ConnectionInfo{
+			ClientAddress:              req.Connection.ClientAddress,
+			ListenerAddress:            req.Connection.ListenerAddress,
+			ListenerProfile:            req.Connection.ListenerProfile,
+			TLSClientSubject:           req.Connection.TlsClientSubject,
+			TLSServerName:              req.Connection.TlsServerName,
+			TLSClientPrincipal:         req.Connection.TlsClientPrincipal,
+			ProxySSL:                   req.Connection.ProxySsl,
+			ProxySSLClientCert:         req.Connection.ProxySslClientCert,
+			ProxySSLClientCertVerified: req.Connection.ProxySslClientCertVerified,
+			ProxySSLVersion:            req.Connection.ProxySslVersion,
+			ProxySSLClientCN:           req.Connection.ProxySslClientCn,
+		}
+	}
+	resp, err := m.Impl.AuthenticatePrincipal(ctx, request)
+	return &proto.AuthenticateResponseV2{Authenticated: resp.Authenticated, Status: resp.Status, Principal: fromPrincipal(resp.Principal)}, err
+}
+
+func toPrincipal(p *proto.UserPrincipal) apis.Principal {
+	if p == nil {
+		return apis.Principal{}
+	}
+	return apis.Principal{Name: p.Name, Groups: p.Groups, Attributes: p.Attributes}
+}
+
+func fromPrincipal(p apis.Principal) *proto.UserPrincipal {
+	return &proto.UserPrincipal{Name: p.Name, Groups: p.Groups, Attributes: p.Attributes}
+}
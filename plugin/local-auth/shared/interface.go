@@ -48,3 +48,30 @@ func (p *PasswordAuthenticatorPlugin) Server(*plugin.MuxBroker) (interface{}, er
 func (*PasswordAuthenticatorPlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
 	return &RPCClient{client: c}, nil
 }
+
+// VersionedPlugins are the plugins of the protocol versions supported by the host. Plugins of the version 2 return the principal of the user.
+var VersionedPlugins = map[int]plugin.PluginSet{
+	1: PluginMap,
+	2: {"passwordAuthenticator": &PasswordAuthenticatorV2Plugin{}},
+}
+
+// PasswordAuthenticatorV2Plugin is the protocol version 2 plugin, it is served only over gRPC
+type PasswordAuthenticatorV2Plugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	Impl apis.PasswordAuthenticatorV2
+}
+
+func (p *PasswordAuthenticatorV2Plugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterPasswordAuthenticatorV2Server(s, &GRPCServerV2{
+		Impl:   p.Impl,
+		broker: broker,
+	})
+	return nil
+}
+
+func (p *PasswordAuthenticatorV2Plugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &GRPCClientV2{
+		client: proto.NewPasswordAuthenticatorV2Client(c),
+		broker: broker,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package shared
+
+import (
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/plugin/scram-credentials/proto"
+	"github.com/hashicorp/go-plugin"
+	"golang.org/x/net/context"
+)
+
+// GRPCClient is an implementation of ScramCredentialStore that talks over gRPC.
+type GRPCClient struct {
+	broker *plugin.GRPCBroker
+	client proto.ScramCredentialStoreClient
+}
+
+func (m *GRPCClient) GetCredentials(mechanism, username string) (apis.ScramCredentials, bool, error) {
+	resp, err := m.client.GetCredentials(context.Background(), &proto.ScramCredentialsRequest{
+		Mechanism: mechanism,
+		Username:  username,
+	})
+	if err != nil {
+		return apis.ScramCredentials{}, false, err
+	}
+	return apis.ScramCredentials{
+		Salt:       resp.Salt,
+		Iterations: int(resp.Iterations),
+		StoredKey:  resp.StoredKey,
+		ServerKey:  resp.ServerKey,
+	}, resp.Found, nil
+}
+
+// Here is the gRPC server that GRPCClient talks to.
+type GRPCServer struct {
+	broker *plugin.GRPCBroker
+	Impl   apis.ScramCredentialStore
+	proto.UnimplementedScramCredentialStoreServer
+}
+
+func (m *GRPCServer) GetCredentials(
+	ctx context.Context,
+	req *proto.ScramCredentialsRequest) (*proto.ScramCredentialsResponse, error) {
+	credentials, found, err := m.Impl.GetCredentials(req.Mechanism, req.Username)
+	return &proto.ScramCredentialsResponse{
+		Found:      found,
+		Salt:       credentials.Salt,
+		Iterations: int32(credentials.Iterations),
+		StoredKey:  credentials.StoredKey,
+		ServerKey:  credentials.ServerKey,
+	}, err
+}
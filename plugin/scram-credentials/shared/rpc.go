@@ -0,0 +1,42 @@
+package shared
+
+import (
+	"net/rpc"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+)
+
+type RPCClient struct{ client *rpc.Client }
+
+func (m *RPCClient) GetCredentials(mechanism, username string) (apis.ScramCredentials, bool, error) {
+	var resp map[string]interface{}
+	err := m.client.Call("Plugin.GetCredentials", map[string]interface{}{
+		"mechanism": mechanism,
+		"username":  username,
+	}, &resp)
+	if err != nil {
+		return apis.ScramCredentials{}, false, err
+	}
+	return apis.ScramCredentials{
+		Salt:       resp["salt"].([]byte),
+		Iterations: resp["iterations"].(int),
+		StoredKey:  resp["storedKey"].([]byte),
+		ServerKey:  resp["serverKey"].([]byte),
+	}, resp["found"].(bool), nil
+}
+
+type RPCServer struct {
+	Impl apis.ScramCredentialStore
+}
+
+func (m *RPCServer) GetCredentials(args map[string]interface{}, resp *map[string]interface{}) error {
+	credentials, found, err := m.Impl.GetCredentials(args["mechanism"].(string), args["username"].(string))
+	*resp = map[string]interface{}{
+		"found":      found,
+		"salt":       credentials.Salt,
+		"iterations": credentials.Iterations,
+		"storedKey":  credentials.StoredKey,
+		"serverKey":  credentials.ServerKey,
+	}
+	return err
+}
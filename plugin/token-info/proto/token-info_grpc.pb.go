@@ -103,3 +103,89 @@ var TokenInfo_ServiceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "token-info.proto",
 }
+
+// TokenInfoV2Client is the client API for TokenInfoV2 service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TokenInfoV2Client interface {
+	VerifyToken(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponseV2, error)
+}
+
+type tokenInfoV2Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenInfoV2Client(cc grpc.ClientConnInterface) TokenInfoV2Client {
+	return &tokenInfoV2Client{cc}
+}
+
+func (c *tokenInfoV2Client) VerifyToken(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponseV2, error) {
+	out := new(VerifyResponseV2)
+	err := c.cc.Invoke(ctx, "/proto.TokenInfoV2/VerifyToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenInfoV2Server is the server API for TokenInfoV2 service.
+// All implementations must embed UnimplementedTokenInfoV2Server
+// for forward compatibility
+type TokenInfoV2Server interface {
+	VerifyToken(context.Context, *VerifyRequest) (*VerifyResponseV2, error)
+	mustEmbedUnimplementedTokenInfoV2Server()
+}
+
+// UnimplementedTokenInfoV2Server must be embedded to have forward compatible implementations.
+type UnimplementedTokenInfoV2Server struct {
+}
+
+func (UnimplementedTokenInfoV2Server) VerifyToken(context.Context, *VerifyRequest) (*VerifyResponseV2, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyToken not implemented")
+}
+func (UnimplementedTokenInfoV2Server) mustEmbedUnimplementedTokenInfoV2Server() {}
+
+// UnsafeTokenInfoV2Server may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenInfoV2Server will
+// result in compilation errors.
+type UnsafeTokenInfoV2Server interface {
+	mustEmbedUnimplementedTokenInfoV2Server()
+}
+
+func RegisterTokenInfoV2Server(s grpc.ServiceRegistrar, srv TokenInfoV2Server) {
+	s.RegisterService(&TokenInfoV2_ServiceDesc, srv)
+}
+
+func _TokenInfoV2_VerifyToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenInfoV2Server).VerifyToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.TokenInfoV2/VerifyToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenInfoV2Server).VerifyToken(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TokenInfoV2_ServiceDesc is the grpc.ServiceDesc for TokenInfoV2 service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TokenInfoV2_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.TokenInfoV2",
+	HandlerType: (*TokenInfoV2Server)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "VerifyToken",
+			Handler:    _TokenInfoV2_VerifyToken_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "token-info.proto",
+}
@@ -14,7 +14,7 @@ type GRPCClient struct {
 }
 
 func (m *GRPCClient) VerifyToken(ctx context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
-	resp, err := m.client.VerifyToken(ctx, &proto.VerifyRequest{Token: request.Token, Params: request.Params})
+	resp, err := m.client.VerifyToken(ctx, toProtoVerifyRequest(request))
 	return apis.VerifyResponse{Success: resp.Success, Status: resp.Status}, err
 }
 
@@ -28,6 +28,88 @@ type GRPCServer struct {
 func (m *GRPCServer) VerifyToken(
 	ctx context.Context,
 	req *proto.VerifyRequest) (*proto.VerifyResponse, error) {
-	resp, err := m.Impl.VerifyToken(ctx, apis.VerifyRequest{Token: req.Token, Params: req.Params})
+	resp, err := m.Impl.VerifyToken(ctx, fromProtoVerifyRequest(req))
 	return &proto.VerifyResponse{Success: resp.Success, Status: resp.Status}, err
 }
+
+// GRPCClientV2 is an implementation of TokenInfo returning the principal that talks over gRPC.
+type GRPCClientV2 struct {
+	broker *plugin.GRPCBroker
+	client proto.TokenInfoV2Client
+}
+
+func (m *GRPCClientV2) VerifyToken(ctx context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
+	resp, err := m.client.VerifyToken(ctx, toProtoVerifyRequest(request))
+	if err != nil {
+		return apis.VerifyResponse{}, err
+	}
+	return apis.VerifyResponse{Success: resp.Success, Status: resp.Status, Principal: toPrincipal(resp.Principal)}, nil
+}
+
+// Here is the gRPC server that GRPCClientV2 talks to.
+type GRPCServerV2 struct {
+	broker *plugin.GRPCBroker
+	Impl   apis.TokenInfo
+	proto.UnimplementedTokenInfoV2Server
+}
+
+func (m *GRPCServerV2) VerifyToken(
+	ctx context.Context,
+	req *proto.VerifyRequest) (*proto.VerifyResponseV2, error) {
+	resp, err := m.Impl.VerifyToken(ctx, fromProtoVerifyRequest(req))
+	return &proto.VerifyResponseV2{Success: resp.Success, Status: resp.Status, Principal: fromPrincipal(resp.Principal)}, err
+}
+
+func toPrincipal(p *proto.TokenPrincipal) apis.Principal {
+	if p == nil {
+		return apis.Principal{}
+	}
+	return apis.Principal{Name: p.Name, Groups: p.Groups, Attributes: p.Attributes}
+}
+
+func fromPrincipal(p apis.Principal) *proto.TokenPrincipal {
+	return &proto.TokenPrincipal{Name: p.Name, Groups: p.Groups, Attributes: p.Attributes}
+}
+
+func toProtoVerifyRequest(request apis.VerifyRequest) *proto.VerifyRequest {
+	return &proto.VerifyRequest{
+		Token:  request.Token,
+		Params: request.Params,
+		Connection: &proto.TokenConnection{
+			ClientAddress:              request.Connection.ClientAddress,
+			ListenerAddress:            request.Connection.ListenerAddress,
+			ListenerProfile:            request.Connection.ListenerProfile,
+			TlsClientSubject:           request.Connection.TLSClientSubject,
+			TlsServerName:              request.Connection.TLSServerName,
+			TlsClientPrincipal:         request.Connection.TLSClientPrincipal,
+			ProxySsl:                   request.Connection.ProxySSL,
+			ProxySslClientCert:         request.Connection.ProxySSLClientCert,
+			ProxySslClientCertVerified: request.Connection.ProxySSLClientCertVerified,
+			ProxySslVersion:            request.Connection.ProxySSLVersion,
+			ProxySslClientCn:           request.Connection.ProxySSLClientCN,
+		},
+		Authzid:    request.AuthzID,
+		Extensions: request.Extensions,
+	}
+}
+
+func fromProtoVerifyRequest(req *proto.VerifyRequest) apis.VerifyRequest {
+	request := apis.VerifyRequest{Token: req.Token, Params: req.Params, AuthzID: req.Authzid, Extensions: req.Extensions}
+	// connection is not sent by older hosts
+	if req.Connection != nil {
+		request.Connection = apis.ConnectionInfo{
+			ClientAddress:              req.Connection.ClientAddress,
+			ListenerAddress:            req.Connection.ListenerAddress,
+			ListenerProfile:            req.Connection.ListenerProfile,
+			TLSClientSubject:           req.Connection.TlsClientSubject,
+			TLSServerName:              req.Connection.TlsServerName,
+			TLSClientPrincipal:         req.Connection.TlsClientPrincipal,
+			ProxySSL:                   req.Connection.ProxySsl,
+			ProxySSLClientCert:         req.Connection.ProxySslClientCert,
+			ProxySSLClientCertVerified: req.Connection.ProxySslClientCertVerified,
+			ProxySSLVersion:            req.Connection.ProxySslVersion,
+			ProxySSLClientCN:           req.Connection.ProxySslClientCn,
+		}
+	}
+	return request
+}
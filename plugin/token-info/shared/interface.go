@@ -48,3 +48,30 @@ func (p *TokenInfoPlugin) Server(*plugin.MuxBroker) (interface{}, error) {
 func (*TokenInfoPlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
 	return &RPCClient{client: c}, nil
 }
+
+// VersionedPlugins are the plugins of the protocol versions supported by the host. Plugins of the version 2 return the principal of the token owner.
+var VersionedPlugins = map[int]plugin.PluginSet{
+	1: PluginMap,
+	2: {"tokenInfo": &TokenInfoV2Plugin{}},
+}
+
+// TokenInfoV2Plugin is the protocol version 2 plugin, it is served only over gRPC
+type TokenInfoV2Plugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	Impl apis.TokenInfo
+}
+
+func (p *TokenInfoV2Plugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterTokenInfoV2Server(s, &GRPCServerV2{
+		Impl:   p.Impl,
+		broker: broker,
+	})
+	return nil
+}
+
+func (p *TokenInfoV2Plugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &GRPCClientV2{
+		client: proto.NewTokenInfoV2Client(c),
+		broker: broker,
+	}, nil
+}
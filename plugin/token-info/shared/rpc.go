@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/grepplabs/kafka-proxy/pkg/apis"
 	"net/rpc"
+	"sort"
+	"strings"
 )
 
 type RPCClient struct{ client *rpc.Client }
@@ -11,8 +13,22 @@ type RPCClient struct{ client *rpc.Client }
 func (m *RPCClient) VerifyToken(ctx context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
 	var resp map[string]interface{}
 	err := m.client.Call("Plugin.VerifyToken", map[string]interface{}{
-		"token":  request.Token,
-		"params": request.Params,
+		"token":                          request.Token,
+		"params":                         request.Params,
+		"client_address":                 request.Connection.ClientAddress,
+		"listener_address":               request.Connection.ListenerAddress,
+		"listener_profile":               request.Connection.ListenerProfile,
+		"tls_client_subject":             request.Connection.TLSClientSubject,
+		"tls_server_name":                request.Connection.TLSServerName,
+		"tls_client_principal":           request.Connection.TLSClientPrincipal,
+		"proxy_ssl":                      request.Connection.ProxySSL,
+		"proxy_ssl_client_cert":          request.Connection.ProxySSLClientCert,
+		"proxy_ssl_client_cert_verified": request.Connection.ProxySSLClientCertVerified,
+		"proxy_ssl_version":              request.Connection.ProxySSLVersion,
+		"proxy_ssl_client_cn":            request.Connection.ProxySSLClientCN,
+		"authzid":                        request.AuthzID,
+		// extensions are sent as key=value pairs, plugins built before the extensions support cannot decode a map
+		"extensions": encodeExtensions(request.Extensions),
 	}, &resp)
 	return apis.VerifyResponse{Success: resp["success"].(bool), Status: resp["status"].(int32)}, err
 }
@@ -23,10 +39,49 @@ type RPCServer struct {
 
 func (m *RPCServer) VerifyToken(args map[string]interface{}, resp *map[string]interface{}) error {
 
-	r, err := m.Impl.VerifyToken(context.Background(), apis.VerifyRequest{Token: args["token"].(string), Params: args["params"].([]string)})
+	request := apis.VerifyRequest{Token: args["token"].(string), Params: args["params"].([]string)}
+	// values are not sent by older hosts
+	request.Connection.ClientAddress, _ = args["client_address"].(string)
+	request.Connection.ListenerAddress, _ = args["listener_address"].(string)
+	request.Connection.ListenerProfile, _ = args["listener_profile"].(string)
+	request.Connection.TLSClientSubject, _ = args["tls_client_subject"].(string)
+	request.Connection.TLSServerName, _ = args["tls_server_name"].(string)
+	request.Connection.TLSClientPrincipal, _ = args["tls_client_principal"].(string)
+	request.Connection.ProxySSL, _ = args["proxy_ssl"].(bool)
+	request.Connection.ProxySSLClientCert, _ = args["proxy_ssl_client_cert"].(bool)
+	request.Connection.ProxySSLClientCertVerified, _ = args["proxy_ssl_client_cert_verified"].(bool)
+	request.Connection.ProxySSLVersion, _ = args["proxy_ssl_version"].(string)
+	request.Connection.ProxySSLClientCN, _ = args["proxy_ssl_client_cn"].(string)
+	request.AuthzID, _ = args["authzid"].(string)
+	if extensions, ok := args["extensions"].([]string); ok {
+		request.Extensions = decodeExtensions(extensions)
+	}
+	r, err := m.Impl.VerifyToken(context.Background(), request)
 	*resp = map[string]interface{}{
 		"success": r.Success,
 		"status":  r.Status,
 	}
 	return err
 }
+
+func encodeExtensions(extensions map[string]string) []string {
+	result := make([]string, 0, len(extensions))
+	for key, value := range extensions {
+		result = append(result, key+"="+value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func decodeExtensions(extensions []string) map[string]string {
+	if len(extensions) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(extensions))
+	for _, extension := range extensions {
+		if key, value, ok := strings.Cut(extension, "="); ok {
+			result[key] = value
+		}
+	}
+	return result
+}
@@ -15,7 +15,10 @@ type GRPCClient struct {
 
 func (m *GRPCClient) GetToken(ctx context.Context, request apis.TokenRequest) (apis.TokenResponse, error) {
 	resp, err := m.client.GetToken(ctx, &proto.TokenRequest{Params: request.Params})
-	return apis.TokenResponse{Success: resp.Success, Status: resp.Status, Token: resp.Token}, err
+	if err != nil {
+		return apis.TokenResponse{}, err
+	}
+	return apis.TokenResponse{Success: resp.Success, Status: resp.Status, Token: resp.Token, AuthzID: resp.Authzid, Extensions: resp.Extensions}, nil
 }
 
 // Here is the gRPC server that GRPCClient talks to.
@@ -29,5 +32,5 @@ func (m *GRPCServer) GetToken(
 	ctx context.Context,
 	req *proto.TokenRequest) (*proto.TokenResponse, error) {
 	resp, err := m.Impl.GetToken(ctx, apis.TokenRequest{Params: req.Params})
-	return &proto.TokenResponse{Success: resp.Success, Status: resp.Status, Token: resp.Token}, err
+	return &proto.TokenResponse{Success: resp.Success, Status: resp.Status, Token: resp.Token, Authzid: resp.AuthzID, Extensions: resp.Extensions}, err
 }
@@ -2,10 +2,16 @@ package shared
 
 import (
 	"context"
+	"encoding/gob"
 	"github.com/grepplabs/kafka-proxy/pkg/apis"
 	"net/rpc"
 )
 
+func init() {
+	// extensions are sent as interface value
+	gob.Register(map[string]string{})
+}
+
 type RPCClient struct{ client *rpc.Client }
 
 func (m *RPCClient) GetToken(request apis.TokenRequest) (apis.TokenResponse, error) {
@@ -13,7 +19,17 @@ func (m *RPCClient) GetToken(request apis.TokenRequest) (apis.TokenResponse, err
 	err := m.client.Call("Plugin.GetToken", map[string]interface{}{
 		"params": request.Params,
 	}, &resp)
-	return apis.TokenResponse{Success: resp["success"].(bool), Status: resp["status"].(int32), Token: resp["token"].(string)}, err
+	if err != nil {
+		return apis.TokenResponse{}, err
+	}
+	response := apis.TokenResponse{Success: resp["success"].(bool), Status: resp["status"].(int32), Token: resp["token"].(string)}
+	if authzid, ok := resp["authzid"].(string); ok {
+		response.AuthzID = authzid
+	}
+	if extensions, ok := resp["extensions"].(map[string]string); ok {
+		response.Extensions = extensions
+	}
+	return response, nil
 }
 
 type RPCServer struct {
@@ -27,6 +43,10 @@ func (m *RPCServer) GetToken(args map[string]interface{}, resp *map[string]inter
 		"success": r.Success,
 		"status":  r.Status,
 		"token":   r.Token,
+		"authzid": r.AuthzID,
+	}
+	if len(r.Extensions) != 0 {
+		(*resp)["extensions"] = r.Extensions
 	}
 	return err
 }
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"net"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type advertisedListenerRule struct {
+	listenerAddress    string
+	localNet           *net.IPNet
+	clientNet          *net.IPNet
+	advertisedListener string
+}
+
+// advertisedListenerRules select advertised broker addresses by the client connection (split-horizon)
+type advertisedListenerRules []advertisedListenerRule
+
+func newAdvertisedListenerRules(rules []config.AdvertisedListenerRule) (advertisedListenerRules, error) {
+	result := make(advertisedListenerRules, 0, len(rules))
+	for _, rule := range rules {
+		r := advertisedListenerRule{
+			listenerAddress:    rule.ListenerAddress,
+			advertisedListener: rule.AdvertisedListener,
+		}
+		var err error
+		if rule.LocalCIDR != "" {
+			if _, r.localNet, err = net.ParseCIDR(rule.LocalCIDR); err != nil {
+				return nil, errors.Wrapf(err, "invalid local CIDR of advertised listener rule '%s'", rule.AdvertisedListener)
+			}
+		}
+		if rule.ClientCIDR != "" {
+			if _, r.clientNet, err = net.ParseCIDR(rule.ClientCIDR); err != nil {
+				return nil, errors.Wrapf(err, "invalid client CIDR of advertised listener rule '%s'", rule.AdvertisedListener)
+			}
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func (r *advertisedListenerRule) matches(conn Conn) bool {
+	if r.listenerAddress != "" && r.listenerAddress != conn.ListenerAddress {
+		return false
+	}
+	if r.localNet != nil && !containsAddr(r.localNet, conn.LocalConnection.LocalAddr()) {
+		return false
+	}
+	if r.clientNet != nil && !containsAddr(r.clientNet, conn.LocalConnection.RemoteAddr()) {
+		return false
+	}
+	return true
+}
+
+func containsAddr(network *net.IPNet, addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	return ok && network.Contains(tcpAddr.IP)
+}
+
+// netAddressMapping returns the net address mapping of the client connection. Listeners are resolved by the
+// netAddressMappingFunc, the advertised address is replaced by the first rule matching the connection.
+func (rules advertisedListenerRules) netAddressMapping(netAddressMappingFunc config.NetAddressMappingFunc, conn Conn) config.NetAddressMappingFunc {
+	if netAddressMappingFunc == nil {
+		return nil
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.matches(conn) {
+			continue
+		}
+		logrus.Debugf("Advertised listener %s selected for connection from %v on %s", rule.advertisedListener, conn.LocalConnection.RemoteAddr(), conn.ListenerAddress)
+		return func(brokerHost string, brokerPort int32, brokerId int32) (string, int32, error) {
+			_, listenerPort, err := netAddressMappingFunc(brokerHost, brokerPort, brokerId)
+			if err != nil {
+				return "", 0, err
+			}
+			advertisedHost, advertisedPort, err := getAdvertisedAddress(rule.advertisedListener, brokerId, int(listenerPort))
+			if err != nil {
+				return "", 0, err
+			}
+			return advertisedHost, int32(advertisedPort), nil
+		}
+	}
+	return netAddressMappingFunc
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type addrConn struct {
+	net.Conn
+	localAddr, remoteAddr net.Addr
+}
+
+func (c *addrConn) LocalAddr() net.Addr  { return c.localAddr }
+func (c *addrConn) RemoteAddr() net.Addr { return c.remoteAddr }
+
+func newAddrConn(listenerAddress string, local string, remote string) Conn {
+	return Conn{
+		ListenerAddress: listenerAddress,
+		LocalConnection: &addrConn{
+			localAddr:  &net.TCPAddr{IP: net.ParseIP(local), Port: 32401},
+			remoteAddr: &net.TCPAddr{IP: net.ParseIP(remote), Port: 56324},
+		},
+	}
+}
+
+func TestAdvertisedListenerRules(t *testing.T) {
+	a := assert.New(t)
+
+	rules, err := newAdvertisedListenerRules([]config.AdvertisedListenerRule{
+		{ClientCIDR: "10.8.0.0/16", AdvertisedListener: "kafka-{{.brokerId}}.vpn.example.com"},
+		{ListenerAddress: "0.0.0.0:32401", LocalCIDR: "172.16.0.0/12", AdvertisedListener: "kafka.peering.example.com:9093"},
+	})
+	a.Nil(err)
+
+	mappingCalls := 0
+	mapping := func(brokerHost string, brokerPort int32, brokerId int32) (string, int32, error) {
+		mappingCalls++
+		return "0.0.0.0", 30000 + brokerId, nil
+	}
+	tests := []struct {
+		name         string
+		conn         Conn
+		expectedHost string
+		expectedPort int32
+	}{
+		{
+			name:         "client network",
+			conn:         newAddrConn("0.0.0.0:32400", "192.168.0.1", "10.8.1.2"),
+			expectedHost: "kafka-2.vpn.example.com",
+			expectedPort: 30002,
+		},
+		{
+			name:         "listener and local network",
+			conn:         newAddrConn("0.0.0.0:32401", "172.16.0.1", "192.168.1.2"),
+			expectedHost: "kafka.peering.example.com",
+			expectedPort: 9093,
+		},
+		{
+			name:         "listener without local network",
+			conn:         newAddrConn("0.0.0.0:32401", "192.168.0.1", "192.168.1.2"),
+			expectedHost: "0.0.0.0",
+			expectedPort: 30002,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, err := rules.netAddressMapping(mapping, tt.conn)("kafka-2", 9092, 2)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedHost, host)
+			assert.Equal(t, tt.expectedPort, port)
+		})
+	}
+	// listeners are always resolved by the listener mapping
+	a.Equal(len(tests), mappingCalls)
+}
@@ -0,0 +1,234 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+)
+
+var authCacheNow = time.Now
+
+// authCache is a LRU cache of authentication results. Keys are HMACs of the credentials with a random per process key,
+// so plaintext passwords and tokens are never stored. Concurrent authentications with the same credentials share one delegate call.
+type authCache struct {
+	name        string
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+	hashKey     []byte
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	calls   map[string]*authCacheCall
+}
+
+type authCacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+type authCacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// authCacheResult is the result of the delegate call. Success selects the TTL, a non zero expiry limits it.
+type authCacheResult struct {
+	value   interface{}
+	success bool
+	expiry  time.Time
+}
+
+func newAuthCache(name string, cacheConfig config.AuthCacheConfig) *authCache {
+	hashKey := make([]byte, sha256.Size)
+	if _, err := rand.Read(hashKey); err != nil {
+		panic(err)
+	}
+	return &authCache{
+		name:        name,
+		ttl:         cacheConfig.TTL,
+		negativeTTL: cacheConfig.NegativeTTL,
+		maxEntries:  cacheConfig.MaxEntries,
+		hashKey:     hashKey,
+		lru:         list.New(),
+		entries:     make(map[string]*list.Element),
+		calls:       make(map[string]*authCacheCall),
+	}
+}
+
+// key returns the HMAC-SHA256 of the key parts
+func (c *authCache) key(parts ...string) string {
+	mac := hmac.New(sha256.New, c.hashKey)
+	for _, part := range parts {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return string(mac.Sum(nil))
+}
+
+// get returns the cached value of the key or the result of fn. Errors of fn are not cached.
+func (c *authCache) get(key string, fn func() (authCacheResult, error)) (interface{}, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*authCacheEntry)
+		if authCacheNow().Before(entry.expires) {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			proxyAuthCacheRequestsTotal.WithLabelValues(c.name, "hit").Inc()
+			return entry.value, nil
+		}
+		c.removeElement(elem)
+	}
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		proxyAuthCacheRequestsTotal.WithLabelValues(c.name, "hit").Inc()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &authCacheCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+	proxyAuthCacheRequestsTotal.WithLabelValues(c.name, "miss").Inc()
+
+	result, err := fn()
+	call.value, call.err = result.value, err
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	if err == nil {
+		c.add(key, result)
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return call.value, call.err
+}
+
+func (c *authCache) add(key string, result authCacheResult) {
+	now := authCacheNow()
+	ttl := c.negativeTTL
+	if result.success {
+		ttl = c.ttl
+		if !result.expiry.IsZero() && result.expiry.Sub(now) < ttl {
+			ttl = result.expiry.Sub(now)
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&authCacheEntry{key: key, value: result.value, expires: now.Add(ttl)})
+	for c.lru.Len() > c.maxEntries {
+		c.removeElement(c.lru.Back())
+		proxyAuthCacheEvictionsTotal.WithLabelValues(c.name).Inc()
+	}
+}
+
+func (c *authCache) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*authCacheEntry).key)
+}
+
+// len returns the number of cached results
+func (c *authCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// connectionKeyParts returns the connection fields passed to the plugins. The client port is omitted as it changes with every connection.
+func connectionKeyParts(connection apis.ConnectionInfo) []string {
+	clientHost := connection.ClientAddress
+	if host, _, err := net.SplitHostPort(connection.ClientAddress); err == nil {
+		clientHost = host
+	}
+	return []string{clientHost, connection.ListenerAddress, connection.ListenerProfile, connection.TLSClientSubject, connection.TLSServerName, connection.TLSClientPrincipal,
+		strconv.FormatBool(connection.ProxySSL), strconv.FormatBool(connection.ProxySSLClientCert), strconv.FormatBool(connection.ProxySSLClientCertVerified),
+		connection.ProxySSLVersion, connection.ProxySSLClientCN}
+}
+
+// CachingPasswordAuthenticator caches results of the delegate PasswordAuthenticator
+type CachingPasswordAuthenticator struct {
+	delegate apis.PasswordAuthenticator
+	cache    *authCache
+}
+
+// NewCachingPasswordAuthenticator returns a PasswordAuthenticator caching accepted credentials for the TTL and rejected credentials for the negative TTL
+func NewCachingPasswordAuthenticator(delegate apis.PasswordAuthenticator, name string, cacheConfig config.AuthCacheConfig) *CachingPasswordAuthenticator {
+	return &CachingPasswordAuthenticator{delegate: delegate, cache: newAuthCache(name, cacheConfig)}
+}
+
+// implements apis.PasswordAuthenticator
+func (a *CachingPasswordAuthenticator) Authenticate(username, password string) (bool, int32, error) {
+	resp, err := a.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: username, Password: password})
+	return resp.Authenticated, resp.Status, err
+}
+
+// implements apis.PasswordAuthenticatorV2
+func (a *CachingPasswordAuthenticator) AuthenticatePrincipal(ctx context.Context, req apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	delegateV2, isV2 := a.delegate.(apis.PasswordAuthenticatorV2)
+	parts := []string{req.Username, req.Password}
+	if isV2 {
+		// only V2 authenticators see the connection
+		parts = append(parts, connectionKeyParts(req.Connection)...)
+	}
+	value, err := a.cache.get(a.cache.key(parts...), func() (authCacheResult, error) {
+		var resp apis.AuthenticateResponse
+		var err error
+		if isV2 {
+			resp, err = delegateV2.AuthenticatePrincipal(ctx, req)
+		} else {
+			resp.Authenticated, resp.Status, err = a.delegate.Authenticate(req.Username, req.Password)
+		}
+		return authCacheResult{value: resp, success: resp.Authenticated}, err
+	})
+	if err != nil {
+		return apis.AuthenticateResponse{}, err
+	}
+	return value.(apis.AuthenticateResponse), nil
+}
+
+// CachingTokenInfo caches results of the delegate TokenInfo
+type CachingTokenInfo struct {
+	delegate apis.TokenInfo
+	cache    *authCache
+}
+
+// NewCachingTokenInfo returns a TokenInfo caching valid tokens for the TTL, limited by the token exp claim, and rejected tokens for the negative TTL
+func NewCachingTokenInfo(delegate apis.TokenInfo, name string, cacheConfig config.AuthCacheConfig) *CachingTokenInfo {
+	return &CachingTokenInfo{delegate: delegate, cache: newAuthCache(name, cacheConfig)}
+}
+
+// implements apis.TokenInfo
+func (t *CachingTokenInfo) VerifyToken(ctx context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
+	parts := []string{request.Token, request.AuthzID}
+	extensionKeys := make([]string, 0, len(request.Extensions))
+	for k := range request.Extensions {
+		extensionKeys = append(extensionKeys, k)
+	}
+	sort.Strings(extensionKeys)
+	for _, k := range extensionKeys {
+		parts = append(parts, k, request.Extensions[k])
+	}
+	parts = append(parts, connectionKeyParts(request.Connection)...)
+
+	value, err := t.cache.get(t.cache.key(parts...), func() (authCacheResult, error) {
+		resp, err := t.delegate.VerifyToken(ctx, request)
+		return authCacheResult{value: resp, success: resp.Success, expiry: jwtExpiry(request.Token)}, err
+	})
+	if err != nil {
+		return apis.VerifyResponse{}, err
+	}
+	return value.(apis.VerifyResponse), nil
+}
@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingPasswordAuthenticator struct {
+	fakePasswordAuthenticator
+	calls int32
+	err   error
+}
+
+func (pa *countingPasswordAuthenticator) Authenticate(username, password string) (bool, int32, error) {
+	atomic.AddInt32(&pa.calls, 1)
+	if pa.err != nil {
+		return false, 0, pa.err
+	}
+	return pa.fakePasswordAuthenticator.Authenticate(username, password)
+}
+
+type countingTokenInfo struct {
+	calls   int32
+	release chan struct{}
+}
+
+func (p *countingTokenInfo) VerifyToken(_ context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
+	atomic.AddInt32(&p.calls, 1)
+	if p.release != nil {
+		<-p.release
+	}
+	return apis.VerifyResponse{Success: request.Token != "bad", Principal: apis.Principal{Name: request.AuthzID}}, nil
+}
+
+func withAuthCacheNow(t *testing.T, now *time.Time) {
+	authCacheNow = func() time.Time { return *now }
+	t.Cleanup(func() { authCacheNow = time.Now })
+}
+
+func authCacheCounter(t *testing.T, cache, result string) float64 {
+	m := &dto.Metric{}
+	var err error
+	if result == "" {
+		err = proxyAuthCacheEvictionsTotal.WithLabelValues(cache).Write(m)
+	} else {
+		err = proxyAuthCacheRequestsTotal.WithLabelValues(cache, result).Write(m)
+	}
+	assert.Nil(t, err)
+	return m.GetCounter().GetValue()
+}
+
+func TestCachingPasswordAuthenticatorTTL(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	withAuthCacheNow(t, &now)
+
+	delegate := &countingPasswordAuthenticator{fakePasswordAuthenticator: fakePasswordAuthenticator{Username: "alice", Password: "secret"}}
+	authenticator := NewCachingPasswordAuthenticator(delegate, "test-ttl", config.AuthCacheConfig{TTL: time.Minute, NegativeTTL: 10 * time.Second, MaxEntries: 10})
+
+	for i := 0; i < 3; i++ {
+		ok, _, err := authenticator.Authenticate("alice", "secret")
+		a.Nil(err)
+		a.True(ok)
+		ok, _, err = authenticator.Authenticate("alice", "wrong")
+		a.Nil(err)
+		a.False(ok)
+	}
+	a.Equal(int32(2), delegate.calls)
+	a.Equal(float64(2), authCacheCounter(t, "test-ttl", "miss"))
+	a.Equal(float64(4), authCacheCounter(t, "test-ttl", "hit"))
+
+	// the negative entry expires first
+	now = now.Add(11 * time.Second)
+	_, _, _ = authenticator.Authenticate("alice", "secret")
+	_, _, _ = authenticator.Authenticate("alice", "wrong")
+	a.Equal(int32(3), delegate.calls)
+
+	now = now.Add(time.Minute)
+	_, _, _ = authenticator.Authenticate("alice", "secret")
+	a.Equal(int32(4), delegate.calls)
+}
+
+func TestCachingPasswordAuthenticatorErrorsNotCached(t *testing.T) {
+	a := assert.New(t)
+	delegate := &countingPasswordAuthenticator{err: errors.New("plugin unavailable")}
+	authenticator := NewCachingPasswordAuthenticator(delegate, "test-errors", config.AuthCacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, MaxEntries: 10})
+
+	for i := 0; i < 2; i++ {
+		_, _, err := authenticator.Authenticate("alice", "secret")
+		a.EqualError(err, "plugin unavailable")
+	}
+	a.Equal(int32(2), delegate.calls)
+	a.Equal(0, authenticator.cache.len())
+}
+
+func TestCachingPasswordAuthenticatorNegativeCacheDisabled(t *testing.T) {
+	a := assert.New(t)
+	delegate := &countingPasswordAuthenticator{fakePasswordAuthenticator: fakePasswordAuthenticator{Username: "alice", Password: "secret"}}
+	authenticator := NewCachingPasswordAuthenticator(delegate, "test-no-negative", config.AuthCacheConfig{TTL: time.Minute, MaxEntries: 10})
+
+	_, _, _ = authenticator.Authenticate("alice", "wrong")
+	_, _, _ = authenticator.Authenticate("alice", "wrong")
+	a.Equal(int32(2), delegate.calls)
+}
+
+func TestCachingPasswordAuthenticatorKeys(t *testing.T) {
+	a := assert.New(t)
+	authenticator := NewCachingPasswordAuthenticator(&countingPasswordAuthenticator{}, "test-keys", config.AuthCacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, MaxEntries: 10})
+
+	key := authenticator.cache.key("alice", "secret")
+	a.Len(key, 32)
+	a.NotContains(key, "secret")
+	a.Equal(key, authenticator.cache.key("alice", "secret"))
+	a.NotEqual(key, authenticator.cache.key("alice", "secret2"))
+	a.NotEqual(authenticator.cache.key("ab", "c"), authenticator.cache.key("a", "bc"))
+
+	other := NewCachingPasswordAuthenticator(&countingPasswordAuthenticator{}, "test-keys", config.AuthCacheConfig{TTL: time.Minute, MaxEntries: 10})
+	a.NotEqual(key, other.cache.key("alice", "secret"))
+}
+
+func TestCachingPasswordAuthenticatorV2Connection(t *testing.T) {
+	a := assert.New(t)
+	delegate := &countingPasswordAuthenticatorV2{principal: apis.Principal{Name: "alice", Groups: []string{"admins"}}}
+	authenticator := NewCachingPasswordAuthenticator(delegate, "test-v2", config.AuthCacheConfig{TTL: time.Minute, MaxEntries: 10})
+
+	request := apis.AuthenticateRequest{Username: "alice", Password: "secret", Connection: apis.ConnectionInfo{ClientAddress: "10.0.0.1:50000"}}
+	resp, err := authenticator.AuthenticatePrincipal(context.Background(), request)
+	a.Nil(err)
+	a.True(resp.Authenticated)
+	a.Equal(delegate.principal, resp.Principal)
+
+	// the client port is not part of the key
+	request.Connection.ClientAddress = "10.0.0.1:50001"
+	resp, err = authenticator.AuthenticatePrincipal(context.Background(), request)
+	a.Nil(err)
+	a.Equal(delegate.principal, resp.Principal)
+	a.Equal(int32(1), delegate.calls)
+
+	request.Connection.ClientAddress = "10.0.0.2:50000"
+	_, _ = authenticator.AuthenticatePrincipal(context.Background(), request)
+	a.Equal(int32(2), delegate.calls)
+}
+
+type countingPasswordAuthenticatorV2 struct {
+	calls     int32
+	principal apis.Principal
+}
+
+func (pa *countingPasswordAuthenticatorV2) Authenticate(string, string) (bool, int32, error) {
+	return false, 0, errors.New("unexpected call of Authenticate")
+}
+
+func (pa *countingPasswordAuthenticatorV2) AuthenticatePrincipal(_ context.Context, _ apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	atomic.AddInt32(&pa.calls, 1)
+	return apis.AuthenticateResponse{Authenticated: true, Principal: pa.principal}, nil
+}
+
+func TestCachingPasswordAuthenticatorMaxEntries(t *testing.T) {
+	a := assert.New(t)
+	delegate := &countingPasswordAuthenticator{}
+	authenticator := NewCachingPasswordAuthenticator(delegate, "test-max-entries", config.AuthCacheConfig{NegativeTTL: time.Minute, MaxEntries: 2})
+
+	_, _, _ = authenticator.Authenticate("u1", "p")
+	_, _, _ = authenticator.Authenticate("u2", "p")
+	_, _, _ = authenticator.Authenticate("u1", "p") // u1 is the most recently used
+	_, _, _ = authenticator.Authenticate("u3", "p") // evicts u2
+	a.Equal(2, authenticator.cache.len())
+	a.Equal(float64(1), authCacheCounter(t, "test-max-entries", ""))
+	a.Equal(int32(3), delegate.calls)
+
+	_, _, _ = authenticator.Authenticate("u1", "p")
+	a.Equal(int32(3), delegate.calls)
+	_, _, _ = authenticator.Authenticate("u2", "p")
+	a.Equal(int32(4), delegate.calls)
+}
+
+func TestCachingTokenInfo(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	withAuthCacheNow(t, &now)
+
+	delegate := &countingTokenInfo{}
+	tokenInfo := NewCachingTokenInfo(delegate, "test-token", config.AuthCacheConfig{TTL: time.Hour, MaxEntries: 10})
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"alice","exp":%d}`, now.Add(time.Minute).Unix())))
+	token := "e30." + payload + ".c2ln"
+	request := apis.VerifyRequest{Token: token, AuthzID: "alice", Extensions: map[string]string{"a": "1", "b": "2"}}
+	for i := 0; i < 2; i++ {
+		resp, err := tokenInfo.VerifyToken(context.Background(), request)
+		a.Nil(err)
+		a.True(resp.Success)
+		a.Equal("alice", resp.Principal.Name)
+	}
+	a.Equal(int32(1), delegate.calls)
+
+	// authzid and extensions are part of the key
+	_, _ = tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: token, AuthzID: "bob", Extensions: request.Extensions})
+	_, _ = tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: token, AuthzID: "alice", Extensions: map[string]string{"a": "1"}})
+	a.Equal(int32(3), delegate.calls)
+
+	// the token exp limits the TTL
+	now = now.Add(61 * time.Second)
+	_, _ = tokenInfo.VerifyToken(context.Background(), request)
+	a.Equal(int32(4), delegate.calls)
+}
+
+func TestCachingTokenInfoSharesConcurrentCalls(t *testing.T) {
+	a := assert.New(t)
+	delegate := &countingTokenInfo{release: make(chan struct{})}
+	tokenInfo := NewCachingTokenInfo(delegate, "test-concurrent", config.AuthCacheConfig{TTL: time.Minute, MaxEntries: 10})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := tokenInfo.VerifyToken(context.Background(), apis.VerifyRequest{Token: "opaque"})
+			a.Nil(err)
+			a.True(resp.Success)
+		}()
+	}
+	// wait until all callers are waiting for the first delegate call
+	for authCacheCounter(t, "test-concurrent", "hit")+authCacheCounter(t, "test-concurrent", "miss") < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	close(delegate.release)
+	wg.Wait()
+	a.Equal(int32(1), delegate.calls)
+}
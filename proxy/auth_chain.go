@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	authChainAccept = "accept"
+	authChainReject = "reject"
+	authChainNext   = "next"
+	authChainError  = "error"
+
+	// AuthChainStageAttribute is the principal attribute with the name of the chain stage which accepted the credentials
+	AuthChainStageAttribute = "auth-chain-stage"
+)
+
+// authChainDecision returns the decision of the stage on its authentication result
+func authChainDecision(stage config.AuthChainStage, accepted bool, status int32, err error) string {
+	if err != nil {
+		if stage.OnError == config.AuthChainOnErrorNext {
+			return authChainNext
+		}
+		return authChainError
+	}
+	if accepted {
+		return authChainAccept
+	}
+	if stage.OnReject == config.AuthChainOnRejectNext {
+		return authChainNext
+	}
+	for _, nextStatus := range stage.NextStatuses {
+		if status == nextStatus {
+			return authChainNext
+		}
+	}
+	return authChainReject
+}
+
+// withAuthChainStage returns a copy of the principal with the stage attribute
+func withAuthChainStage(principal apis.Principal, stage string) apis.Principal {
+	attributes := make(map[string]string, len(principal.Attributes)+1)
+	for k, v := range principal.Attributes {
+		attributes[k] = v
+	}
+	attributes[AuthChainStageAttribute] = stage
+	principal.Attributes = attributes
+	return principal
+}
+
+// PasswordAuthenticatorStage is a stage of the PasswordAuthenticatorChain
+type PasswordAuthenticatorStage struct {
+	Stage         config.AuthChainStage
+	Authenticator apis.PasswordAuthenticator
+}
+
+// PasswordAuthenticatorChain tries the stages in order until a stage accepts or rejects the credentials definitively
+type PasswordAuthenticatorChain struct {
+	name   string
+	stages []PasswordAuthenticatorStage
+}
+
+func NewPasswordAuthenticatorChain(name string, stages []PasswordAuthenticatorStage) *PasswordAuthenticatorChain {
+	return &PasswordAuthenticatorChain{name: name, stages: stages}
+}
+
+// implements apis.PasswordAuthenticator
+func (c *PasswordAuthenticatorChain) Authenticate(username, password string) (bool, int32, error) {
+	resp, err := c.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: username, Password: password})
+	return resp.Authenticated, resp.Status, err
+}
+
+// implements apis.PasswordAuthenticatorV2. When no stage decides, the result of the last stage is returned
+func (c *PasswordAuthenticatorChain) AuthenticatePrincipal(ctx context.Context, req apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	var resp apis.AuthenticateResponse
+	var err error
+	for _, stage := range c.stages {
+		if authenticatorV2, ok := stage.Authenticator.(apis.PasswordAuthenticatorV2); ok {
+			resp, err = authenticatorV2.AuthenticatePrincipal(ctx, req)
+		} else {
+			resp = apis.AuthenticateResponse{}
+			resp.Authenticated, resp.Status, err = stage.Authenticator.Authenticate(req.Username, req.Password)
+		}
+		decision := authChainDecision(stage.Stage, resp.Authenticated, resp.Status, err)
+		proxyAuthChainStageTotal.WithLabelValues(c.name, stage.Stage.Name, decision).Inc()
+		logrus.Debugf("Authentication chain '%s' stage '%s' decision for user %s: %s (status %d, error %v)", c.name, stage.Stage.Name, req.Username, decision, resp.Status, err)
+
+		switch decision {
+		case authChainAccept:
+			if resp.Principal.Name == "" {
+				resp.Principal.Name = req.Username
+			}
+			resp.Principal = withAuthChainStage(resp.Principal, stage.Stage.Name)
+			return resp, nil
+		case authChainReject:
+			return resp, nil
+		case authChainError:
+			return apis.AuthenticateResponse{}, err
+		}
+	}
+	if err != nil {
+		return apis.AuthenticateResponse{}, err
+	}
+	return resp, nil
+}
+
+// TokenInfoStage is a stage of the TokenInfoChain
+type TokenInfoStage struct {
+	Stage     config.AuthChainStage
+	TokenInfo apis.TokenInfo
+}
+
+// TokenInfoChain tries the stages in order until a stage accepts or rejects the token definitively
+type TokenInfoChain struct {
+	name   string
+	stages []TokenInfoStage
+}
+
+func NewTokenInfoChain(name string, stages []TokenInfoStage) *TokenInfoChain {
+	return &TokenInfoChain{name: name, stages: stages}
+}
+
+// implements apis.TokenInfo. When no stage decides, the result of the last stage is returned
+func (c *TokenInfoChain) VerifyToken(ctx context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
+	var resp apis.VerifyResponse
+	var err error
+	for _, stage := range c.stages {
+		resp, err = stage.TokenInfo.VerifyToken(ctx, request)
+		decision := authChainDecision(stage.Stage, resp.Success, resp.Status, err)
+		proxyAuthChainStageTotal.WithLabelValues(c.name, stage.Stage.Name, decision).Inc()
+		logrus.Debugf("Authentication chain '%s' stage '%s' decision for token: %s (status %d, error %v)", c.name, stage.Stage.Name, decision, resp.Status, err)
+
+		switch decision {
+		case authChainAccept:
+			if resp.Principal.Name == "" {
+				resp.Principal.Name = parseJWTClaims(request.Token).Sub
+			}
+			resp.Principal = withAuthChainStage(resp.Principal, stage.Stage.Name)
+			return resp, nil
+		case authChainReject:
+			return resp, nil
+		case authChainError:
+			return apis.VerifyResponse{}, err
+		}
+	}
+	if err != nil {
+		return apis.VerifyResponse{}, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// usersPasswordAuthenticator accepts known users with the right password, status 1 is unknown user and 2 wrong password
+type usersPasswordAuthenticator struct {
+	users map[string]string
+	err   error
+}
+
+func (pa usersPasswordAuthenticator) Authenticate(username, password string) (bool, int32, error) {
+	if pa.err != nil {
+		return false, 0, pa.err
+	}
+	expected, ok := pa.users[username]
+	if !ok {
+		return false, 1, nil
+	}
+	if expected != password {
+		return false, 2, nil
+	}
+	return true, 0, nil
+}
+
+func authChainStage(name string, modify func(stage *config.AuthChainStage)) config.AuthChainStage {
+	stage := config.AuthChainStage{Name: name, OnReject: config.AuthChainOnRejectReject, OnError: config.AuthChainOnErrorFail}
+	if modify != nil {
+		modify(&stage)
+	}
+	return stage
+}
+
+func TestPasswordAuthenticatorChain(t *testing.T) {
+	file := usersPasswordAuthenticator{users: map[string]string{"alice": "file-secret"}}
+	ldap := &fakePasswordAuthenticatorV2{fakePasswordAuthenticator: fakePasswordAuthenticator{Username: "bob", Password: "ldap-secret"},
+		principal: apis.Principal{Name: "bob", Groups: []string{"admins"}, Attributes: map[string]string{"dn": "uid=bob"}}}
+	unknownUserNext := func(stage *config.AuthChainStage) { stage.NextStatuses = []int32{1} }
+
+	tests := []struct {
+		name          string
+		stages        []PasswordAuthenticatorStage
+		username      string
+		password      string
+		authenticated bool
+		status        int32
+		principal     apis.Principal
+		err           string
+	}{
+		{
+			name:          "first stage accepts",
+			stages:        []PasswordAuthenticatorStage{{authChainStage("file", unknownUserNext), file}, {authChainStage("ldap", nil), ldap}},
+			username:      "alice",
+			password:      "file-secret",
+			authenticated: true,
+			principal:     apis.Principal{Name: "alice", Attributes: map[string]string{"auth-chain-stage": "file"}},
+		},
+		{
+			name:     "first stage rejects definitively",
+			stages:   []PasswordAuthenticatorStage{{authChainStage("file", unknownUserNext), file}, {authChainStage("ldap", nil), ldap}},
+			username: "alice",
+			password: "wrong",
+			status:   2,
+		},
+		{
+			name:          "unknown user passed to the next stage",
+			stages:        []PasswordAuthenticatorStage{{authChainStage("file", unknownUserNext), file}, {authChainStage("ldap", nil), ldap}},
+			username:      "bob",
+			password:      "ldap-secret",
+			authenticated: true,
+			principal:     apis.Principal{Name: "bob", Groups: []string{"admins"}, Attributes: map[string]string{"dn": "uid=bob", "auth-chain-stage": "ldap"}},
+		},
+		{
+			name:     "last stage rejects",
+			stages:   []PasswordAuthenticatorStage{{authChainStage("file", unknownUserNext), file}, {authChainStage("ldap", nil), ldap}},
+			username: "bob",
+			password: "wrong",
+		},
+		{
+			name:     "no stage decides",
+			stages:   []PasswordAuthenticatorStage{{authChainStage("file", unknownUserNext), file}},
+			username: "carol",
+			password: "secret",
+			status:   1,
+		},
+		{
+			name: "error fails the chain",
+			stages: []PasswordAuthenticatorStage{{authChainStage("broken", nil), usersPasswordAuthenticator{err: errors.New("unavailable")}},
+				{authChainStage("file", nil), file}},
+			username: "alice",
+			password: "file-secret",
+			err:      "unavailable",
+		},
+		{
+			name: "error passed to the next stage",
+			stages: []PasswordAuthenticatorStage{{authChainStage("broken", func(stage *config.AuthChainStage) { stage.OnError = config.AuthChainOnErrorNext }), usersPasswordAuthenticator{err: errors.New("unavailable")}},
+				{authChainStage("file", nil), file}},
+			username:      "alice",
+			password:      "file-secret",
+			authenticated: true,
+			principal:     apis.Principal{Name: "alice", Attributes: map[string]string{"auth-chain-stage": "file"}},
+		},
+		{
+			name: "error of the last stage",
+			stages: []PasswordAuthenticatorStage{{authChainStage("file", func(stage *config.AuthChainStage) { stage.OnReject = config.AuthChainOnRejectNext }), file},
+				{authChainStage("broken", func(stage *config.AuthChainStage) { stage.OnError = config.AuthChainOnErrorNext }), usersPasswordAuthenticator{err: errors.New("unavailable")}}},
+			username: "alice",
+			password: "wrong",
+			err:      "unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			chain := NewPasswordAuthenticatorChain("test", tt.stages)
+			resp, err := chain.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: tt.username, Password: tt.password})
+			if tt.err != "" {
+				a.EqualError(err, tt.err)
+				return
+			}
+			a.Nil(err)
+			a.Equal(tt.authenticated, resp.Authenticated)
+			a.Equal(tt.status, resp.Status)
+			if tt.authenticated {
+				a.Equal(tt.principal, resp.Principal)
+			}
+		})
+	}
+	// the principal of the stage is not modified
+	a := assert.New(t)
+	a.Equal(map[string]string{"dn": "uid=bob"}, ldap.principal.Attributes)
+
+	m := &dto.Metric{}
+	a.Nil(proxyAuthChainStageTotal.WithLabelValues("test", "file", "next").Write(m))
+	a.Equal(float64(4), m.GetCounter().GetValue())
+}
+
+type statusTokenInfo struct {
+	tokens map[string]int32
+}
+
+func (p statusTokenInfo) VerifyToken(_ context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
+	status, ok := p.tokens[request.Token]
+	if !ok {
+		return apis.VerifyResponse{Success: false, Status: 9}, nil
+	}
+	return apis.VerifyResponse{Success: status == 0, Status: status}, nil
+}
+
+func TestTokenInfoChain(t *testing.T) {
+	a := assert.New(t)
+	chain := NewTokenInfoChain("test", []TokenInfoStage{
+		{authChainStage("oidc", func(stage *config.AuthChainStage) { stage.NextStatuses = []int32{9} }), statusTokenInfo{tokens: map[string]int32{"e30.eyJzdWIiOiJhbGljZSJ9.c2ln": 0, "expired": 6}}},
+		{authChainStage("introspection", nil), statusTokenInfo{tokens: map[string]int32{"opaque": 0}}},
+	})
+
+	resp, err := chain.VerifyToken(context.Background(), apis.VerifyRequest{Token: "e30.eyJzdWIiOiJhbGljZSJ9.c2ln"})
+	a.Nil(err)
+	a.True(resp.Success)
+	a.Equal(apis.Principal{Name: "alice", Attributes: map[string]string{"auth-chain-stage": "oidc"}}, resp.Principal)
+
+	resp, err = chain.VerifyToken(context.Background(), apis.VerifyRequest{Token: "opaque"})
+	a.Nil(err)
+	a.True(resp.Success)
+	a.Equal("introspection", resp.Principal.Attributes["auth-chain-stage"])
+
+	resp, err = chain.VerifyToken(context.Background(), apis.VerifyRequest{Token: "expired"})
+	a.Nil(err)
+	a.False(resp.Success)
+	a.Equal(int32(6), resp.Status)
+}
@@ -0,0 +1,343 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	authLockoutUsername = "username"
+	authLockoutSource   = "source"
+)
+
+var authLockoutNow = time.Now
+
+type errLocalAuthLocked struct {
+	kind string
+	key  string
+}
+
+func (e errLocalAuthLocked) Error() string {
+	return fmt.Sprintf("%s %s is temporarily locked after too many failed authentications", e.kind, e.key)
+}
+
+// AuthLockout tracks failed local authentications per username and client IP. Failure responses are delayed exponentially
+// and usernames or client IPs exceeding the allowed failures are locked for a while.
+type AuthLockout struct {
+	maxFailures map[string]int
+	window      time.Duration
+	duration    time.Duration
+	delay       time.Duration
+	maxDelay    time.Duration
+	maxEntries  int
+	allowlist   []*net.IPNet
+
+	mu      sync.Mutex
+	entries map[string]map[string]*authFailures
+}
+
+type authFailures struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// AuthLockoutEntry is a tracked username or client IP
+type AuthLockoutEntry struct {
+	Kind        string     `json:"kind"`
+	Key         string     `json:"key"`
+	Failures    int        `json:"failures"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+func NewAuthLockout(lockoutConfig config.AuthLockoutConfig) (*AuthLockout, error) {
+	allowlist := make([]*net.IPNet, 0, len(lockoutConfig.Allowlist))
+	for _, cidr := range lockoutConfig.Allowlist {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid lockout allowlist entry '%s'", cidr)
+		}
+		allowlist = append(allowlist, ipNet)
+	}
+	return &AuthLockout{
+		maxFailures: map[string]int{authLockoutUsername: lockoutConfig.MaxUsernameFailures, authLockoutSource: lockoutConfig.MaxSourceFailures},
+		window:      lockoutConfig.Window,
+		duration:    lockoutConfig.Duration,
+		delay:       lockoutConfig.Delay,
+		maxDelay:    lockoutConfig.MaxDelay,
+		maxEntries:  lockoutConfig.MaxEntries,
+		allowlist:   allowlist,
+		entries:     map[string]map[string]*authFailures{authLockoutUsername: {}, authLockoutSource: {}},
+	}, nil
+}
+
+// sourceOf returns the client IP of the connection
+func sourceOf(connection apis.ConnectionInfo) string {
+	if host, _, err := net.SplitHostPort(connection.ClientAddress); err == nil {
+		return host
+	}
+	return connection.ClientAddress
+}
+
+func (l *AuthLockout) allowlisted(source string) bool {
+	ip := net.ParseIP(source)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range l.allowlist {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns errLocalAuthLocked when the client IP or the username is locked
+func (l *AuthLockout) check(source, username string) error {
+	if l.allowlisted(source) {
+		return nil
+	}
+	now := authLockoutNow()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, k := range []struct{ kind, key string }{{authLockoutSource, source}, {authLockoutUsername, username}} {
+		if entry, ok := l.entries[k.kind][k.key]; ok && now.Before(entry.lockedUntil) {
+			proxyLocalAuthLockedTotal.WithLabelValues(k.kind).Inc()
+			return errLocalAuthLocked{kind: k.kind, key: k.key}
+		}
+	}
+	return nil
+}
+
+// failure records the failed authentication and returns the delay of the failure response
+func (l *AuthLockout) failure(source, username string) time.Duration {
+	if l.allowlisted(source) {
+		return 0
+	}
+	now := authLockoutNow()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	failures := l.recordFailure(authLockoutSource, source, now)
+	if n := l.recordFailure(authLockoutUsername, username, now); n > failures {
+		failures = n
+	}
+	if failures == 0 || l.delay <= 0 {
+		return 0
+	}
+	delay := l.delay
+	for i := 1; i < failures && delay < l.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > l.maxDelay {
+		delay = l.maxDelay
+	}
+	return delay
+}
+
+// recordFailure returns the number of failures in the window, 0 if the key is not tracked
+func (l *AuthLockout) recordFailure(kind, key string, now time.Time) int {
+	if key == "" {
+		return 0
+	}
+	entries := l.entries[kind]
+	entry, ok := entries[key]
+	if !ok {
+		if len(entries) >= l.maxEntries {
+			l.purge(entries, now)
+		}
+		if len(entries) >= l.maxEntries {
+			return 0
+		}
+		entry = &authFailures{}
+		entries[key] = entry
+	}
+	if now.Sub(entry.lastFailure) > l.window {
+		entry.failures = 0
+	}
+	entry.failures++
+	entry.lastFailure = now
+	if maxFailures := l.maxFailures[kind]; maxFailures > 0 && entry.failures >= maxFailures && !now.Before(entry.lockedUntil) {
+		entry.lockedUntil = now.Add(l.duration)
+		proxyLocalAuthLockoutsTotal.WithLabelValues(kind).Inc()
+		logrus.Warnf("Local authentication of %s %s is locked until %s after %d failures", kind, key, entry.lockedUntil.Format(time.RFC3339), entry.failures)
+	}
+	return entry.failures
+}
+
+// purge removes entries which are neither locked nor failed within the window
+func (l *AuthLockout) purge(entries map[string]*authFailures, now time.Time) {
+	for key, entry := range entries {
+		if !now.Before(entry.lockedUntil) && now.Sub(entry.lastFailure) > l.window {
+			delete(entries, key)
+		}
+	}
+}
+
+// success forgets the failures of the username
+func (l *AuthLockout) success(username string) {
+	if username == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries[authLockoutUsername], username)
+}
+
+// Entries returns the tracked usernames and client IPs with failures in the window or an active lockout
+func (l *AuthLockout) Entries() []AuthLockoutEntry {
+	now := authLockoutNow()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := make([]AuthLockoutEntry, 0)
+	for kind, entries := range l.entries {
+		l.purge(entries, now)
+		for key, entry := range entries {
+			item := AuthLockoutEntry{Kind: kind, Key: key, Failures: entry.failures}
+			if now.Before(entry.lockedUntil) {
+				lockedUntil := entry.lockedUntil
+				item.LockedUntil = &lockedUntil
+			}
+			result = append(result, item)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// Clear removes the lockout and failures of the username or client IP, it returns false if the key is not tracked
+func (l *AuthLockout) Clear(kind, key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, ok := l.entries[kind]
+	if !ok {
+		return false
+	}
+	if _, ok = entries[key]; !ok {
+		return false
+	}
+	delete(entries, key)
+	logrus.Infof("Local authentication lockout of %s %s cleared", kind, key)
+	return true
+}
+
+// ServeHTTP lists the tracked entries on GET and clears the entry given by the username or source query parameter on DELETE
+func (l *AuthLockout) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(l.Entries())
+	case http.MethodDelete:
+		query := r.URL.Query()
+		var cleared bool
+		switch {
+		case query.Get(authLockoutUsername) != "":
+			cleared = l.Clear(authLockoutUsername, query.Get(authLockoutUsername))
+		case query.Get(authLockoutSource) != "":
+			cleared = l.Clear(authLockoutSource, query.Get(authLockoutSource))
+		default:
+			http.Error(w, "username or source query parameter is required", http.StatusBadRequest)
+			return
+		}
+		if !cleared {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// isLocalAuthRejected returns true if the client credentials were rejected, errors of the auth plugins are not failures of the client
+func isLocalAuthRejected(err error) bool {
+	switch errors.Cause(err).(type) {
+	case errLocalAuthFailed, errLocalTokenRejected:
+		return true
+	}
+	return false
+}
+
+// localSaslClaimedUsername is implemented by conversations of mechanisms sending the username before it is authenticated
+type localSaslClaimedUsername interface {
+	claimedUsername(saslAuthBytes []byte) string
+}
+
+// lockoutConversation rejects locked clients before the conversation step and records the failed steps
+type lockoutConversation struct {
+	conversation localSaslConversation
+	lockout      *AuthLockout
+	source       string
+	username     string
+}
+
+func newLockoutConversation(conversation localSaslConversation, lockout *AuthLockout, connection apis.ConnectionInfo) *lockoutConversation {
+	return &lockoutConversation{conversation: conversation, lockout: lockout, source: sourceOf(connection)}
+}
+
+func (c *lockoutConversation) step(saslAuthBytes []byte) (challenge []byte, done bool, err error) {
+	if claimed, ok := c.conversation.(localSaslClaimedUsername); ok {
+		if username := claimed.claimedUsername(saslAuthBytes); username != "" {
+			c.username = username
+		}
+	}
+	if err = c.lockout.check(c.source, c.username); err != nil {
+		return nil, true, err
+	}
+	challenge, done, err = c.conversation.step(saslAuthBytes)
+	if err != nil {
+		if isLocalAuthRejected(err) {
+			time.Sleep(c.lockout.failure(c.source, c.username))
+		}
+	} else if done {
+		c.lockout.success(c.username)
+	}
+	return challenge, done, err
+}
+
+// implements localSaslCredentialExpiry
+func (c *lockoutConversation) credentialExpiry() time.Time {
+	if credential, ok := c.conversation.(localSaslCredentialExpiry); ok {
+		return credential.credentialExpiry()
+	}
+	return time.Time{}
+}
+
+// implements localSaslAuthenticatedPrincipal
+func (c *lockoutConversation) authenticatedPrincipal() apis.Principal {
+	if authenticated, ok := c.conversation.(localSaslAuthenticatedPrincipal); ok {
+		return authenticated.authenticatedPrincipal()
+	}
+	return apis.Principal{}
+}
+
+// implements localSaslClientCredentials
+func (c *lockoutConversation) clientCredentials() (apis.BrokerCredentials, bool) {
+	if client, ok := c.conversation.(localSaslClientCredentials); ok {
+		return client.clientCredentials()
+	}
+	return apis.BrokerCredentials{}, false
+}
+
+// implements localSaslClientToken
+func (c *lockoutConversation) clientToken() (oauthBearerToken, bool) {
+	if client, ok := c.conversation.(localSaslClientToken); ok {
+		return client.clientToken()
+	}
+	return oauthBearerToken{}, false
+}
@@ -0,0 +1,231 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAuthLockout(t *testing.T, now *time.Time, modify func(c *config.AuthLockoutConfig)) *AuthLockout {
+	lockoutConfig := config.AuthLockoutConfig{
+		Enable:              true,
+		MaxUsernameFailures: 3,
+		MaxSourceFailures:   5,
+		Window:              time.Minute,
+		Duration:            10 * time.Minute,
+		Delay:               100 * time.Millisecond,
+		MaxDelay:            time.Second,
+		MaxEntries:          100,
+	}
+	if modify != nil {
+		modify(&lockoutConfig)
+	}
+	lockout, err := NewAuthLockout(lockoutConfig)
+	assert.Nil(t, err)
+	authLockoutNow = func() time.Time { return *now }
+	t.Cleanup(func() { authLockoutNow = time.Now })
+	return lockout
+}
+
+func TestAuthLockoutUsername(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	lockout := newTestAuthLockout(t, &now, nil)
+
+	a.Equal(100*time.Millisecond, lockout.failure("10.0.0.1", "alice"))
+	a.Equal(200*time.Millisecond, lockout.failure("10.0.0.2", "alice"))
+	a.Nil(lockout.check("10.0.0.3", "alice"))
+	a.Equal(400*time.Millisecond, lockout.failure("10.0.0.3", "alice"))
+
+	a.Equal(errLocalAuthLocked{kind: "username", key: "alice"}, lockout.check("10.0.0.4", "alice"))
+	a.Nil(lockout.check("10.0.0.4", "bob"))
+
+	now = now.Add(10 * time.Minute)
+	a.Nil(lockout.check("10.0.0.4", "alice"))
+}
+
+func TestAuthLockoutSource(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	lockout := newTestAuthLockout(t, &now, nil)
+
+	var delay time.Duration
+	for i := 0; i < 5; i++ {
+		delay = lockout.failure("10.0.0.1", "")
+	}
+	a.Equal(time.Second, delay)
+	a.Equal(errLocalAuthLocked{kind: "source", key: "10.0.0.1"}, lockout.check("10.0.0.1", "bob"))
+	a.Nil(lockout.check("10.0.0.2", "bob"))
+}
+
+func TestAuthLockoutWindow(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	lockout := newTestAuthLockout(t, &now, nil)
+
+	for i := 0; i < 5; i++ {
+		lockout.failure("10.0.0.1", "alice")
+		now = now.Add(2 * time.Minute)
+	}
+	a.Nil(lockout.check("10.0.0.1", "alice"))
+	a.Equal(100*time.Millisecond, lockout.failure("10.0.0.1", "alice"))
+}
+
+func TestAuthLockoutSuccessResetsUsername(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	lockout := newTestAuthLockout(t, &now, nil)
+
+	lockout.failure("10.0.0.1", "alice")
+	lockout.failure("10.0.0.1", "alice")
+	lockout.success("alice")
+	lockout.failure("10.0.0.1", "alice")
+	a.Nil(lockout.check("10.0.0.1", "alice"))
+
+	entries := lockout.Entries()
+	a.Len(entries, 2)
+	a.Equal(AuthLockoutEntry{Kind: "source", Key: "10.0.0.1", Failures: 3}, entries[0])
+	a.Equal(AuthLockoutEntry{Kind: "username", Key: "alice", Failures: 1}, entries[1])
+}
+
+func TestAuthLockoutAllowlist(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	lockout := newTestAuthLockout(t, &now, func(c *config.AuthLockoutConfig) {
+		c.Allowlist = []string{"10.1.0.0/16"}
+	})
+
+	for i := 0; i < 10; i++ {
+		a.Equal(time.Duration(0), lockout.failure("10.1.2.3", "alice"))
+	}
+	a.Nil(lockout.check("10.1.2.3", "alice"))
+	a.Nil(lockout.check("10.0.0.1", "alice"))
+	a.Empty(lockout.Entries())
+
+	_, err := NewAuthLockout(config.AuthLockoutConfig{Allowlist: []string{"10.1.0.0"}})
+	a.NotNil(err)
+}
+
+func TestAuthLockoutMaxEntries(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	lockout := newTestAuthLockout(t, &now, func(c *config.AuthLockoutConfig) {
+		c.MaxEntries = 2
+		c.MaxSourceFailures = 0
+	})
+
+	lockout.failure("10.0.0.1", "u1")
+	lockout.failure("10.0.0.1", "u2")
+	lockout.failure("10.0.0.1", "u3")
+	a.Len(lockout.entries["username"], 2)
+
+	// expired entries make room for new ones
+	now = now.Add(2 * time.Minute)
+	lockout.failure("10.0.0.1", "u3")
+	a.Len(lockout.entries["username"], 1)
+}
+
+func TestAuthLockoutHTTPHandler(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	lockout := newTestAuthLockout(t, &now, nil)
+	for i := 0; i < 3; i++ {
+		lockout.failure("10.0.0.1", "alice")
+	}
+
+	rec := httptest.NewRecorder()
+	lockout.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/lockouts", nil))
+	a.Equal(http.StatusOK, rec.Code)
+	var entries []AuthLockoutEntry
+	a.Nil(json.Unmarshal(rec.Body.Bytes(), &entries))
+	a.Len(entries, 2)
+	a.Equal("alice", entries[1].Key)
+	a.NotNil(entries[1].LockedUntil)
+	a.Nil(entries[0].LockedUntil)
+
+	rec = httptest.NewRecorder()
+	lockout.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/auth/lockouts?username=alice", nil))
+	a.Equal(http.StatusNoContent, rec.Code)
+	a.Nil(lockout.check("10.0.0.1", "alice"))
+
+	rec = httptest.NewRecorder()
+	lockout.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/auth/lockouts?username=alice", nil))
+	a.Equal(http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	lockout.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/auth/lockouts?source=10.0.0.1", nil))
+	a.Equal(http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	lockout.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/auth/lockouts", nil))
+	a.Equal(http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	lockout.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/lockouts", nil))
+	a.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestLockoutConversationPlain(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	lockout := newTestAuthLockout(t, &now, func(c *config.AuthLockoutConfig) {
+		c.Delay = 0
+	})
+	localSasl := NewLocalSasl(LocalSaslParams{lockout: lockout})
+	plain := NewLocalSaslPlain(fakePasswordAuthenticator{Username: "alice", Password: "secret"})
+	connection := apis.ConnectionInfo{ClientAddress: "10.0.0.1:5000"}
+
+	for i := 0; i < 3; i++ {
+		_, done, err := localSasl.newConversation(plain, connection).step([]byte("\x00alice\x00wrong"))
+		a.True(done)
+		a.Equal(errLocalAuthFailed{user: "alice"}, err)
+	}
+	// valid credentials of the locked username are rejected
+	conversation := localSasl.newConversation(plain, connection)
+	_, _, err := conversation.step([]byte("\x00alice\x00secret"))
+	a.Equal(errLocalAuthLocked{kind: "username", key: "alice"}, err)
+
+	lockout.Clear("username", "alice")
+	conversation = localSasl.newConversation(plain, connection)
+	_, _, err = conversation.step([]byte("\x00alice\x00secret"))
+	a.Nil(err)
+	a.Equal(apis.Principal{Name: "alice"}, conversation.(localSaslAuthenticatedPrincipal).authenticatedPrincipal())
+}
+
+func TestLockoutConversationPluginErrors(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	lockout := newTestAuthLockout(t, &now, nil)
+	localSasl := NewLocalSasl(LocalSaslParams{lockout: lockout})
+	plain := NewLocalSaslPlain(&countingPasswordAuthenticator{err: errors.New("plugin unavailable")})
+
+	for i := 0; i < 5; i++ {
+		_, _, err := localSasl.newConversation(plain, apis.ConnectionInfo{ClientAddress: "10.0.0.1:5000"}).step([]byte("\x00alice\x00secret"))
+		a.EqualError(err, "plugin unavailable")
+	}
+	a.Empty(lockout.Entries())
+}
+
+func TestLocalSaslScramClaimedUsername(t *testing.T) {
+	a := assert.New(t)
+	conversation := &localSaslScramConversation{}
+	a.Equal("user,name=1", conversation.claimedUsername([]byte("n,,n=user=2Cname=3D1,r=nonce")))
+	a.Equal("", conversation.claimedUsername([]byte("n,,r=nonce")))
+	conversation.started = true
+	a.Equal("", conversation.claimedUsername([]byte("n,,n=user,r=nonce")))
+}
+
+func TestIsLocalAuthRejected(t *testing.T) {
+	a := assert.New(t)
+	a.True(isLocalAuthRejected(errLocalAuthFailed{user: "alice"}))
+	a.True(isLocalAuthRejected(errLocalTokenRejected{status: 1}))
+	a.False(isLocalAuthRejected(errors.New("plugin unavailable")))
+	a.False(isLocalAuthRejected(errLocalAuthLocked{kind: "source", key: "10.0.0.1"}))
+}
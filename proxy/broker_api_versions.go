@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/proxy/protocol"
+)
+
+// brokerApiVersionsTTL is the time the api versions of a broker are answered locally before they are requested again, so broker upgrades are noticed
+const brokerApiVersionsTTL = 10 * time.Minute
+
+var brokerApiVersionsNow = time.Now
+
+// brokerApiVersionsFunc returns the api versions supported by the broker
+type brokerApiVersionsFunc func() ([]protocol.ApiVersion, error)
+
+type brokerApiVersionsEntry struct {
+	apiVersions []protocol.ApiVersion
+	expiry      time.Time
+}
+
+// brokerApiVersionsCache caches the api versions of the brokers, which are answered to the clients before their broker connection is opened
+type brokerApiVersionsCache struct {
+	mu      sync.Mutex
+	entries map[string]brokerApiVersionsEntry
+}
+
+func newBrokerApiVersionsCache() *brokerApiVersionsCache {
+	return &brokerApiVersionsCache{entries: make(map[string]brokerApiVersionsEntry)}
+}
+
+// apiVersions returns the cached api versions of the broker, fetch requests them when they are missing or expired
+func (c *brokerApiVersionsCache) apiVersions(brokerAddress string, fetch brokerApiVersionsFunc) ([]protocol.ApiVersion, error) {
+	now := brokerApiVersionsNow()
+
+	c.mu.Lock()
+	entry, ok := c.entries[brokerAddress]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiry) {
+		return entry.apiVersions, nil
+	}
+
+	apiVersions, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[brokerAddress] = brokerApiVersionsEntry{apiVersions: apiVersions, expiry: now.Add(brokerApiVersionsTTL)}
+	c.mu.Unlock()
+	return apiVersions, nil
+}
+
+// sendAndReceiveApiVersions requests the api versions supported by the broker, the request does not require authentication
+func sendAndReceiveApiVersions(conn DeadlineReaderWriter, clientID string, writeTimeout time.Duration, readTimeout time.Duration) ([]protocol.ApiVersion, error) {
+	req := &protocol.Request{
+		ClientID: clientID,
+		Body:     &protocol.ApiVersionsRequestV0{},
+	}
+	reqBuf, err := protocol.Encode(req)
+	if err != nil {
+		return nil, err
+	}
+	sizeBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(sizeBuf, uint32(len(reqBuf)))
+
+	if err = conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write(bytes.Join([][]byte{sizeBuf, reqBuf}, nil)); err != nil {
+		return nil, fmt.Errorf("failed to send api versions request: %w", err)
+	}
+	if err = conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+		return nil, err
+	}
+
+	//wait for the response
+	header := make([]byte, 8) // response header
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return nil, fmt.Errorf("failed to read api versions header: %w", err)
+	}
+	responseHeader := &protocol.ResponseHeader{}
+	if err = protocol.Decode(header, responseHeader); err != nil {
+		return nil, fmt.Errorf("failed to parse api versions header: %w", err)
+	}
+	if responseHeader.Length > protocol.MaxResponseSize {
+		return nil, protocol.PacketDecodingError{Info: fmt.Sprintf("api versions response of length %d too large", responseHeader.Length)}
+	}
+	payload := make([]byte, responseHeader.Length-4)
+	if _, err = io.ReadFull(conn, payload); err != nil {
+		return nil, fmt.Errorf("failed to read api versions payload: %w", err)
+	}
+	res := &protocol.ApiVersionsResponse{Version: 0}
+	if err = protocol.Decode(payload, res); err != nil {
+		return nil, fmt.Errorf("failed to parse api versions response: %w", err)
+	}
+	if !errors.Is(res.Err, protocol.ErrNoError) {
+		return nil, fmt.Errorf("api versions request failed: %w", res.Err)
+	}
+	return res.ApiKeys, nil
+}
+
+// receiveAndSendApiVersions answers the ApiVersions request of the client with the api versions of the broker.
+// ApiVersions is the only request the client sends before the local authentication, when its broker connection is not open yet.
+func (ctx *RequestsLoopContext) receiveAndSendApiVersions(src DeadlineReaderWriter, requestKeyVersion *protocol.RequestKeyVersion, apiVersions brokerApiVersionsFunc) error {
+	if requestKeyVersion.Length > protocol.MaxRequestSize {
+		return protocol.PacketDecodingError{Info: fmt.Sprintf("api versions message of length %d too large", requestKeyVersion.Length)}
+	}
+	if err := src.SetReadDeadline(time.Now().Add(ctx.timeout)); err != nil {
+		return err
+	}
+	// 4 bytes were read as keyVersionBuf (ApiKey, ApiVersion), the correlation id starts the rest of the request
+	request := make([]byte, int(requestKeyVersion.Length-4))
+	if _, err := io.ReadFull(src, request); err != nil {
+		return err
+	}
+	if len(request) < 4 {
+		return protocol.PacketDecodingError{Info: fmt.Sprintf("api versions message of length %d too small", requestKeyVersion.Length)}
+	}
+	correlationID := int32(binary.BigEndian.Uint32(request))
+
+	response := &protocol.ApiVersionsResponse{Version: requestKeyVersion.ApiVersion}
+	if requestKeyVersion.ApiVersion > protocol.ApiVersionsMaxVersion {
+		// the client retries with the highest supported version
+		response = &protocol.ApiVersionsResponse{Version: 0, Err: protocol.ErrUnsupportedVersion,
+			ApiKeys: []protocol.ApiVersion{{ApiKey: apiKeyApiApiVersions, MaxVersion: protocol.ApiVersionsMaxVersion}}}
+	} else {
+		brokerApiVersions, err := apiVersions()
+		if err != nil {
+			return fmt.Errorf("getting api versions of broker %s failed: %w", ctx.brokerAddress, err)
+		}
+		response.ApiKeys = make([]protocol.ApiVersion, 0, len(brokerApiVersions))
+		for _, apiVersion := range brokerApiVersions {
+			if apiVersion.ApiKey == apiKeyApiApiVersions && apiVersion.MaxVersion > protocol.ApiVersionsMaxVersion {
+				apiVersion.MaxVersion = protocol.ApiVersionsMaxVersion
+			}
+			response.ApiKeys = append(response.ApiKeys, apiVersion)
+		}
+	}
+	responseBuf, err := protocol.Encode(response)
+	if err != nil {
+		return err
+	}
+	// ApiVersions response always includes a v0 header
+	headerBuf, err := protocol.Encode(&protocol.ResponseHeader{Length: int32(len(responseBuf) + 4), CorrelationID: correlationID})
+	if err != nil {
+		return err
+	}
+
+	ctx.localWriteLock.Lock()
+	defer ctx.localWriteLock.Unlock()
+
+	if err = src.SetWriteDeadline(time.Now().Add(ctx.timeout)); err != nil {
+		return err
+	}
+	if _, err = src.Write(headerBuf); err != nil {
+		return err
+	}
+	if _, err = src.Write(responseBuf); err != nil {
+		return err
+	}
+	return src.SetDeadline(time.Time{})
+}
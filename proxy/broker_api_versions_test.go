@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/proxy/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrokerApiVersionsCache(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Now()
+	brokerApiVersionsNow = func() time.Time { return now }
+	defer func() { brokerApiVersionsNow = time.Now }()
+
+	fetched := 0
+	fetch := func() ([]protocol.ApiVersion, error) {
+		fetched++
+		return []protocol.ApiVersion{{ApiKey: apiKeyProduce, MaxVersion: int16(fetched)}}, nil
+	}
+	cache := newBrokerApiVersionsCache()
+
+	apiVersions, err := cache.apiVersions("broker:9092", fetch)
+	a.Nil(err)
+	a.Equal([]protocol.ApiVersion{{ApiKey: apiKeyProduce, MaxVersion: 1}}, apiVersions)
+
+	now = now.Add(brokerApiVersionsTTL - time.Second)
+	apiVersions, err = cache.apiVersions("broker:9092", fetch)
+	a.Nil(err)
+	a.Equal([]protocol.ApiVersion{{ApiKey: apiKeyProduce, MaxVersion: 1}}, apiVersions)
+	a.Equal(1, fetched)
+
+	// other brokers and expired entries are requested again
+	_, err = cache.apiVersions("broker:9093", fetch)
+	a.Nil(err)
+	now = now.Add(time.Second)
+	apiVersions, err = cache.apiVersions("broker:9092", fetch)
+	a.Nil(err)
+	a.Equal([]protocol.ApiVersion{{ApiKey: apiKeyProduce, MaxVersion: 3}}, apiVersions)
+
+	// errors are not cached
+	_, err = cache.apiVersions("broker:9094", func() ([]protocol.ApiVersion, error) { return nil, errors.New("connection refused") })
+	a.EqualError(err, "connection refused")
+	_, err = cache.apiVersions("broker:9094", fetch)
+	a.Nil(err)
+}
+
+func TestSendAndReceiveApiVersions(t *testing.T) {
+	a := assert.New(t)
+
+	proxyConn, brokerConn := net.Pipe()
+	defer proxyConn.Close()
+	defer brokerConn.Close()
+
+	broker := &testBroker{conn: brokerConn}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, correlationID, request := broker.readRequest(t)
+		if version := int16(binary.BigEndian.Uint16(request[2:4])); version != 0 {
+			t.Errorf("ApiVersions version 0 expected, got %d", version)
+		}
+		body, _ := protocol.Encode(&protocol.ApiVersionsResponse{Version: 0, ApiKeys: []protocol.ApiVersion{{ApiKey: apiKeyProduce, MinVersion: 3, MaxVersion: 9}}})
+		broker.writeResponse(t, correlationID, body)
+
+		_, correlationID, _ = broker.readRequest(t)
+		body, _ = protocol.Encode(&protocol.ApiVersionsResponse{Version: 0, Err: protocol.ErrUnsupportedVersion})
+		broker.writeResponse(t, correlationID, body)
+	}()
+
+	apiVersions, err := sendAndReceiveApiVersions(proxyConn, "kafka-proxy", 5*time.Second, 5*time.Second)
+	a.Nil(err)
+	a.Equal([]protocol.ApiVersion{{ApiKey: apiKeyProduce, MinVersion: 3, MaxVersion: 9}}, apiVersions)
+
+	_, err = sendAndReceiveApiVersions(proxyConn, "kafka-proxy", 5*time.Second, 5*time.Second)
+	a.NotNil(err)
+	wg.Wait()
+	a.Equal([]int16{apiKeyApiApiVersions, apiKeyApiApiVersions}, broker.apiKeys)
+}
+
+func TestReceiveAndSendApiVersionsUnsupportedVersion(t *testing.T) {
+	a := assert.New(t)
+
+	client, proxyLocal := net.Pipe()
+	defer client.Close()
+	defer proxyLocal.Close()
+
+	ctx := &RequestsLoopContext{timeout: 5 * time.Second, localWriteLock: &sync.Mutex{}}
+	go func() {
+		// ApiVersions v4 request of the client, the body is not read
+		request := make([]byte, 14)
+		binary.BigEndian.PutUint32(request[0:4], 10)
+		binary.BigEndian.PutUint16(request[4:6], uint16(apiKeyApiApiVersions))
+		binary.BigEndian.PutUint16(request[6:8], 4)
+		binary.BigEndian.PutUint32(request[8:12], 7)
+		binary.BigEndian.PutUint16(request[12:14], 0xffff)
+		_, _ = client.Write(request)
+	}()
+	keyVersionBuf := make([]byte, 8)
+	_, err := io.ReadFull(proxyLocal, keyVersionBuf)
+	a.Nil(err)
+	requestKeyVersion := &protocol.RequestKeyVersion{}
+	a.Nil(protocol.Decode(keyVersionBuf, requestKeyVersion))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ctx.receiveAndSendApiVersions(proxyLocal, requestKeyVersion, func() ([]protocol.ApiVersion, error) {
+			return nil, errors.New("broker api versions are not requested")
+		})
+	}()
+
+	header := make([]byte, 8)
+	_, err = io.ReadFull(client, header)
+	a.Nil(err)
+	a.Equal(int32(7), int32(binary.BigEndian.Uint32(header[4:8])))
+	payload := make([]byte, binary.BigEndian.Uint32(header[0:4])-4)
+	_, err = io.ReadFull(client, payload)
+	a.Nil(err)
+	a.Nil(<-done)
+
+	// the client retries with the announced version
+	response := &protocol.ApiVersionsResponse{Version: 0}
+	a.Nil(protocol.Decode(payload, response))
+	a.Equal(protocol.ErrUnsupportedVersion, response.Err)
+	a.Equal([]protocol.ApiVersion{{ApiKey: apiKeyApiApiVersions, MaxVersion: protocol.ApiVersionsMaxVersion}}, response.ApiKeys)
+}
@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// brokerClientCertDialFunc connects and authenticates to the broker presenting the client certificate issued for the DER encoded subject,
+// it returns the SASL session lifetime announced by the broker when the proxy re-authenticates
+type brokerClientCertDialFunc func(rawSubject []byte) (net.Conn, time.Duration, error)
+
+func newBrokerClientCertSession(conn net.Conn, brokerAddress string, dial brokerClientCertDialFunc) *BrokerClientCertSession {
+	return &BrokerClientCertSession{
+		conn:          newReconnectableConn(conn),
+		brokerAddress: brokerAddress,
+		dial:          dial,
+	}
+}
+
+// BrokerClientCertSession replaces the broker connection with the connection presenting the client certificate of the principal
+// after the first local authentication. The principal is not known when the client connects, requests sent before the local
+// authentication (ApiVersions) use the broker connection with the configured client certificate.
+type BrokerClientCertSession struct {
+	conn          *reconnectableConn
+	brokerAddress string
+	dial          brokerClientCertDialFunc
+
+	reconnected bool
+	// principal is the name of the principal the client certificate was issued for
+	principal string
+}
+
+// reconnect connects to the broker with the client certificate of the principal, local re-authentication must not change the principal
+func (s *BrokerClientCertSession) reconnect(ctx *RequestsLoopContext, session localSaslSession) error {
+	if s.reconnected {
+		if session.principal.Name != s.principal {
+			return fmt.Errorf("SASL re-authentication must not change the principal %s to %s", s.principal, session.principal.Name)
+		}
+		return nil
+	}
+	if session.principal.Name == "" {
+		return fmt.Errorf("client certificate for broker %s requires the principal, mechanism %s provided none", s.brokerAddress, session.mechanism)
+	}
+	// responses of the replaced connection would be lost
+	if len(ctx.openRequestsChannel) != 0 {
+		return fmt.Errorf("broker connection to %s cannot be replaced with %d requests in flight", s.brokerAddress, len(ctx.openRequestsChannel))
+	}
+	rawSubject, err := principalCertSubject(session.principal.Name)
+	if err != nil {
+		return err
+	}
+	conn, sessionLifetime, err := s.dial(rawSubject)
+	if err != nil {
+		return fmt.Errorf("connecting to broker %s with the client certificate of principal %s failed: %w", s.brokerAddress, session.principal.Name, err)
+	}
+	if err = s.conn.replace(conn); err != nil {
+		return err
+	}
+	// the re-authentication of the replaced connection is scheduled by the session lifetime of the new connection
+	if ctx.brokerSaslSession != nil {
+		ctx.brokerSaslSession.setSessionLifetime(sessionLifetime)
+	}
+	logrus.Debugf("Broker connection to %s replaced by the connection with the client certificate of principal %s", s.brokerAddress, session.principal.Name)
+	s.reconnected = true
+	s.principal = session.principal.Name
+	return nil
+}
+
+// reconnectableConn is the broker connection which can be replaced while the responses loop reads from it
+type reconnectableConn struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+	// opened is closed when the first connection is set or the connection is closed
+	opened chan struct{}
+	// deadlines are applied to the replacing connection
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func newReconnectableConn(conn net.Conn) *reconnectableConn {
+	c := newPendingConn()
+	c.conn = conn
+	close(c.opened)
+	return c
+}
+
+// newPendingConn returns the broker connection which is opened later by replace, reads wait until it is opened
+func newPendingConn() *reconnectableConn {
+	return &reconnectableConn{opened: make(chan struct{})}
+}
+
+func (c *reconnectableConn) current() net.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn
+}
+
+// replace closes the current connection, the pending read of the responses loop continues with the new connection
+func (c *reconnectableConn) replace(conn net.Conn) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		_ = conn.Close()
+		return errors.New("broker connection is closed")
+	}
+	if err := conn.SetReadDeadline(c.readDeadline); err != nil {
+		c.mu.Unlock()
+		_ = conn.Close()
+		return err
+	}
+	if err := conn.SetWriteDeadline(c.writeDeadline); err != nil {
+		c.mu.Unlock()
+		_ = conn.Close()
+		return err
+	}
+	replaced := c.conn
+	c.conn = conn
+	c.mu.Unlock()
+	if replaced == nil {
+		close(c.opened)
+		return nil
+	}
+	return replaced.Close()
+}
+
+func (c *reconnectableConn) Read(b []byte) (int, error) {
+	<-c.opened
+	for {
+		conn := c.current()
+		if conn == nil {
+			// closed before it was opened
+			return 0, net.ErrClosed
+		}
+		n, err := conn.Read(b)
+		if err != nil && n == 0 && c.current() != conn {
+			// read from the replaced connection
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *reconnectableConn) Write(b []byte) (int, error) {
+	conn := c.current()
+	if conn == nil {
+		return 0, errors.New("broker connection is not open")
+	}
+	return conn.Write(b)
+}
+
+func (c *reconnectableConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.conn == nil {
+		close(c.opened)
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *reconnectableConn) LocalAddr() net.Addr {
+	if conn := c.current(); conn != nil {
+		return conn.LocalAddr()
+	}
+	return nil
+}
+
+func (c *reconnectableConn) RemoteAddr() net.Addr {
+	if conn := c.current(); conn != nil {
+		return conn.RemoteAddr()
+	}
+	return nil
+}
+
+func (c *reconnectableConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	c.writeDeadline = t
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.SetDeadline(t)
+}
+
+func (c *reconnectableConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *reconnectableConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeDeadline = t
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.SetWriteDeadline(t)
+}
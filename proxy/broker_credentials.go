@@ -0,0 +1,183 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// BrokerCredentialsMapper maps the locally authenticated clients to their SASL/PLAIN credentials of the broker connections
+type BrokerCredentialsMapper struct {
+	source   string
+	provider apis.BrokerCredentialsProvider
+	timeout  time.Duration
+	// fallback are the credentials of unmapped clients, nil when the connections of unmapped clients are closed
+	fallback *apis.BrokerCredentials
+	cluster  string
+
+	clientID     string
+	writeTimeout time.Duration
+	readTimeout  time.Duration
+}
+
+func NewBrokerCredentialsMapper(c *config.Config, provider apis.BrokerCredentialsProvider) (*BrokerCredentialsMapper, error) {
+	brokerCredentials := c.Kafka.SASL.BrokerCredentials
+	if brokerCredentials.Source == config.BrokerCredentialsSourceProvider && provider == nil {
+		return nil, errors.New("Kafka.SASL.BrokerCredentials source provider is configured but provider is nil")
+	}
+	mapper := &BrokerCredentialsMapper{
+		source:       brokerCredentials.Source,
+		provider:     provider,
+		timeout:      brokerCredentials.Timeout,
+		cluster:      c.Cluster,
+		clientID:     c.Kafka.ClientID,
+		writeTimeout: c.Kafka.WriteTimeout,
+		readTimeout:  c.Kafka.ReadTimeout,
+	}
+	if brokerCredentials.Fallback {
+		mapper.fallback = &apis.BrokerCredentials{Username: c.Kafka.SASL.Username, Password: c.Kafka.SASL.Password}
+	}
+	return mapper, nil
+}
+
+// credentials returns the broker credentials of the locally authenticated session
+func (m *BrokerCredentialsMapper) credentials(session localSaslSession, connection apis.ConnectionInfo) (apis.BrokerCredentials, error) {
+	switch m.source {
+	case config.BrokerCredentialsSourceClient:
+		if session.clientCredentials != nil {
+			proxyBrokerCredentialsTotal.WithLabelValues(m.cluster, "client").Inc()
+			return *session.clientCredentials, nil
+		}
+	case config.BrokerCredentialsSourceProvider:
+		ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+		defer cancel()
+		credentials, found, err := m.provider.GetBrokerCredentials(ctx, apis.BrokerCredentialsRequest{Principal: session.principal, Connection: connection})
+		if err != nil {
+			proxyBrokerCredentialsTotal.WithLabelValues(m.cluster, "error").Inc()
+			return apis.BrokerCredentials{}, errors.Wrapf(err, "getting broker credentials of principal %s failed", session.principal.Name)
+		}
+		if found {
+			proxyBrokerCredentialsTotal.WithLabelValues(m.cluster, "provider").Inc()
+			return credentials, nil
+		}
+	}
+	if m.fallback != nil {
+		proxyBrokerCredentialsTotal.WithLabelValues(m.cluster, "fallback").Inc()
+		return *m.fallback, nil
+	}
+	proxyBrokerCredentialsTotal.WithLabelValues(m.cluster, "unmapped").Inc()
+	return apis.BrokerCredentials{}, errors.Errorf("no broker credentials for principal %s (%s)", session.principal.Name, session.mechanism)
+}
+
+// fallbackAuth returns the SASL/PLAIN auth of connections without local authentication, nil when they must be closed
+func (m *BrokerCredentialsMapper) fallbackAuth() SASLAuthByProxy {
+	if m.fallback == nil {
+		return nil
+	}
+	return m.newAuth(*m.fallback)
+}
+
+func (m *BrokerCredentialsMapper) newAuth(credentials apis.BrokerCredentials) *SASLPlainAuth {
+	return &SASLPlainAuth{
+		clientID:     m.clientID,
+		writeTimeout: m.writeTimeout,
+		readTimeout:  m.readTimeout,
+		username:     credentials.Username,
+		password:     credentials.Password,
+	}
+}
+
+// implements brokerClientAuth
+func (m *BrokerCredentialsMapper) authenticateClient(conn DeadlineReaderWriter, session localSaslSession, connection apis.ConnectionInfo) (string, error) {
+	credentials, err := m.credentials(session, connection)
+	if err != nil {
+		return "", err
+	}
+	if err = m.newAuth(credentials).sendAndReceiveSASLAuthenticate(conn); err != nil {
+		return "", err
+	}
+	return credentials.Username, nil
+}
+
+// implements brokerClientAuth, the credentials of the principal do not change on local re-authentication
+func (m *BrokerCredentialsMapper) reauthenticates() bool {
+	return false
+}
+
+// brokerClientAuth authenticates the broker connection on behalf of the locally authenticated client
+type brokerClientAuth interface {
+	// authenticateClient runs the SASL exchange with the broker and returns the identity used for the broker
+	authenticateClient(conn DeadlineReaderWriter, session localSaslSession, connection apis.ConnectionInfo) (string, error)
+	// reauthenticates reports whether the broker connection is re-authenticated on local re-authentication of the client
+	reauthenticates() bool
+}
+
+// brokerCredentialsDialFunc connects to the broker for the locally authenticated principal, the SASL authentication is done by the session
+type brokerCredentialsDialFunc func(principal string) (net.Conn, error)
+
+func newBrokerCredentialsSession(auth brokerClientAuth, brokerAddress string, dial brokerCredentialsDialFunc, apiVersions brokerApiVersionsFunc) *BrokerCredentialsSession {
+	return &BrokerCredentialsSession{
+		auth:          auth,
+		brokerAddress: brokerAddress,
+		conn:          newPendingConn(),
+		dial:          dial,
+		apiVersions:   apiVersions,
+		responses:     make(chan []byte, 1),
+	}
+}
+
+// BrokerCredentialsSession opens the broker connection after the local authentication of the client and authenticates it with the credentials
+// or the token of the client. Authentication is done by the requests loop, the SASL responses are passed by the responses loop.
+// ApiVersions requests sent before the local authentication are answered with the cached api versions of the broker.
+type BrokerCredentialsSession struct {
+	auth          brokerClientAuth
+	brokerAddress string
+	// conn is the broker connection, which is pending until the local authentication
+	conn        *reconnectableConn
+	dial        brokerCredentialsDialFunc
+	apiVersions brokerApiVersionsFunc
+	responses   chan []byte
+
+	authenticated bool
+	// principal is the name of the principal the broker connection is authenticated for
+	principal string
+}
+
+// authenticate opens and authenticates the broker connection after the first local authentication, local re-authentication must not change the principal
+func (s *BrokerCredentialsSession) authenticate(dst DeadlineWriter, ctx *RequestsLoopContext, session localSaslSession) error {
+	if s.authenticated {
+		if session.principal.Name != s.principal {
+			return fmt.Errorf("SASL re-authentication must not change the principal %s to %s", s.principal, session.principal.Name)
+		}
+		if !s.auth.reauthenticates() {
+			return nil
+		}
+	} else {
+		conn, err := s.dial(session.principal.Name)
+		if err != nil {
+			return fmt.Errorf("connecting to broker %s for principal %s failed: %w", s.brokerAddress, session.principal.Name, err)
+		}
+		if err = s.conn.replace(conn); err != nil {
+			return err
+		}
+	}
+	var connection apis.ConnectionInfo
+	if ctx.localConnectionInfoFunc != nil {
+		connection = ctx.localConnectionInfoFunc()
+	}
+	conn := &brokerSaslConn{dst: dst, ctx: ctx, responses: s.responses}
+	identity, err := s.auth.authenticateClient(conn, session, connection)
+	if err != nil {
+		return fmt.Errorf("SASL authentication to broker %s for principal %s failed: %w", s.brokerAddress, session.principal.Name, err)
+	}
+	logrus.Debugf("Broker connection to %s authenticated as %s for principal %s", s.brokerAddress, identity, session.principal.Name)
+	s.authenticated = true
+	s.principal = session.principal.Name
+	return nil
+}
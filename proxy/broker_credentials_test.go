@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/proxy/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBrokerCredentialsProvider struct {
+	credentials map[string]apis.BrokerCredentials
+	err         error
+	request     apis.BrokerCredentialsRequest
+}
+
+func (p *testBrokerCredentialsProvider) GetBrokerCredentials(_ context.Context, request apis.BrokerCredentialsRequest) (apis.BrokerCredentials, bool, error) {
+	p.request = request
+	credentials, ok := p.credentials[request.Principal.Name]
+	return credentials, ok, p.err
+}
+
+func newTestBrokerCredentialsMapper(t *testing.T, source string, fallback bool, provider apis.BrokerCredentialsProvider) *BrokerCredentialsMapper {
+	c := config.NewConfig()
+	c.Kafka.SASL.Username = "proxy"
+	c.Kafka.SASL.Password = "proxy-secret"
+	c.Kafka.SASL.BrokerCredentials = config.BrokerCredentialsConfig{Enable: true, Source: source, Timeout: time.Second, Fallback: fallback}
+	mapper, err := NewBrokerCredentialsMapper(c, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mapper
+}
+
+func TestBrokerCredentialsMapper(t *testing.T) {
+	provider := &testBrokerCredentialsProvider{credentials: map[string]apis.BrokerCredentials{"alice": {Username: "team-a", Password: "secret-a"}}}
+	clientCredentials := &apis.BrokerCredentials{Username: "bob", Password: "bob-secret"}
+
+	tests := []struct {
+		name        string
+		source      string
+		fallback    bool
+		session     localSaslSession
+		credentials apis.BrokerCredentials
+		errorMsg    string
+	}{
+		{
+			name:        "mapped principal",
+			source:      config.BrokerCredentialsSourceProvider,
+			session:     localSaslSession{mechanism: SASLOAuthBearer, principal: apis.Principal{Name: "alice"}},
+			credentials: apis.BrokerCredentials{Username: "team-a", Password: "secret-a"},
+		},
+		{
+			name:     "unmapped principal",
+			source:   config.BrokerCredentialsSourceProvider,
+			session:  localSaslSession{mechanism: SASLOAuthBearer, principal: apis.Principal{Name: "carol"}},
+			errorMsg: "no broker credentials for principal carol (OAUTHBEARER)",
+		},
+		{
+			name:        "unmapped principal with fallback",
+			source:      config.BrokerCredentialsSourceProvider,
+			fallback:    true,
+			session:     localSaslSession{mechanism: SASLOAuthBearer, principal: apis.Principal{Name: "carol"}},
+			credentials: apis.BrokerCredentials{Username: "proxy", Password: "proxy-secret"},
+		},
+		{
+			name:        "client credentials",
+			source:      config.BrokerCredentialsSourceClient,
+			session:     localSaslSession{mechanism: SASLPlain, principal: apis.Principal{Name: "bob"}, clientCredentials: clientCredentials},
+			credentials: *clientCredentials,
+		},
+		{
+			name:     "client without password",
+			source:   config.BrokerCredentialsSourceClient,
+			session:  localSaslSession{mechanism: SASLSCRAM256, principal: apis.Principal{Name: "bob"}},
+			errorMsg: "no broker credentials for principal bob (SCRAM-SHA-256)",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			mapper := newTestBrokerCredentialsMapper(t, tc.source, tc.fallback, provider)
+			credentials, err := mapper.credentials(tc.session, apis.ConnectionInfo{ClientAddress: "10.0.0.1:5000"})
+			if tc.errorMsg != "" {
+				a.EqualError(err, tc.errorMsg)
+				return
+			}
+			a.Nil(err)
+			a.Equal(tc.credentials, credentials)
+		})
+	}
+	a := assert.New(t)
+	a.Equal(apis.ConnectionInfo{ClientAddress: "10.0.0.1:5000"}, provider.request.Connection)
+
+	// provider errors are not replaced by the fallback credentials
+	mapper := newTestBrokerCredentialsMapper(t, config.BrokerCredentialsSourceProvider, true, &testBrokerCredentialsProvider{err: errors.New("vault unavailable")})
+	_, err := mapper.credentials(localSaslSession{principal: apis.Principal{Name: "alice"}}, apis.ConnectionInfo{})
+	a.EqualError(err, "getting broker credentials of principal alice failed: vault unavailable")
+
+	_, err = NewBrokerCredentialsMapper(&config.Config{Kafka: config.NewConfig().Kafka}, nil)
+	a.Nil(err)
+	c := config.NewConfig()
+	c.Kafka.SASL.BrokerCredentials.Source = config.BrokerCredentialsSourceProvider
+	_, err = NewBrokerCredentialsMapper(c, nil)
+	a.NotNil(err)
+}
+
+func TestBrokerCredentialsSessionAuthenticatesAfterLocalAuth(t *testing.T) {
+	a := assert.New(t)
+
+	client, proxyLocal := net.Pipe()
+	proxyRemote, brokerConn := net.Pipe()
+	defer client.Close()
+	defer brokerConn.Close()
+
+	mapper := newTestBrokerCredentialsMapper(t, config.BrokerCredentialsSourceClient, false, nil)
+	var dialedPrincipals []string
+	session := newBrokerCredentialsSession(mapper, "broker:9092", func(principal string) (net.Conn, error) {
+		dialedPrincipals = append(dialedPrincipals, principal)
+		return proxyRemote, nil
+	}, func() ([]protocol.ApiVersion, error) {
+		return []protocol.ApiVersion{{ApiKey: apiKeyProduce, MaxVersion: 9}, {ApiKey: apiKeyApiApiVersions, MaxVersion: 4}}, nil
+	})
+	localSasl := NewLocalSasl(LocalSaslParams{
+		enabled:             true,
+		timeout:             5 * time.Second,
+		localAuthenticators: map[string]LocalSaslAuth{SASLPlain: NewLocalSaslPlain(&fakePasswordAuthenticator{Username: "my-test-user", Password: "my-test-password"})},
+	})
+	processor := newProcessor(ProcessorConfig{LocalSasl: localSasl, AuthServer: &AuthServer{}, BrokerCredentialsSession: session}, "broker:9092")
+	go func() {
+		_, _ = processor.RequestsLoop(session.conn, proxyLocal)
+		_ = session.conn.Close()
+	}()
+	go func() {
+		_, _ = processor.ResponsesLoop(proxyLocal, session.conn)
+		_ = proxyLocal.Close()
+	}()
+
+	broker := &testBroker{conn: brokerConn}
+	brokerDone := make(chan struct{})
+	go func() {
+		defer close(brokerDone)
+		// ApiVersions is answered by the proxy, the broker connection is opened after the local authentication
+		apiKey, correlationID, _ := broker.readRequest(t)
+		if apiKey != apiKeySaslHandshake {
+			t.Errorf("SaslHandshake expected, got api key %d", apiKey)
+			return
+		}
+		body, _ := protocol.Encode(&protocol.SaslHandshakeResponseV0orV1{Err: protocol.ErrNoError, EnabledMechanisms: []string{SASLPlain}})
+		broker.writeResponse(t, correlationID, body)
+
+		apiKey, correlationID, request := broker.readRequest(t)
+		if apiKey != apiKeySaslAuthenticate {
+			t.Errorf("SaslAuthenticate expected, got api key %d", apiKey)
+			return
+		}
+		broker.saslMessages = append(broker.saslMessages, request)
+		body, _ = protocol.Encode(&protocol.SaslAuthenticateResponseV0{Err: protocol.ErrNoError, SaslAuthBytes: []byte{}})
+		broker.writeResponse(t, correlationID, body)
+
+		_, correlationID, _ = broker.readRequest(t)
+		broker.writeResponse(t, correlationID, []byte{0, 0, 0, 0, 0, 0})
+	}()
+
+	go func() {
+		writeTestApiVersionsRequest(t, client, 1)
+		if _, err := client.Write(newTestLocalSaslRequest(t, 1, SASLPlain)); err != nil {
+			t.Error(err)
+			return
+		}
+		writeTestApiVersionsRequest(t, client, 3)
+	}()
+
+	header := make([]byte, 8)
+	_, err := io.ReadFull(client, header)
+	a.Nil(err)
+	a.Equal(int32(1), int32(binary.BigEndian.Uint32(header[4:8])))
+	payload := make([]byte, binary.BigEndian.Uint32(header[0:4])-4)
+	_, err = io.ReadFull(client, payload)
+	a.Nil(err)
+	apiVersions := &protocol.ApiVersionsResponse{Version: 0}
+	a.Nil(protocol.Decode(payload, apiVersions))
+	// ApiVersions versions beyond the encodable ones are not announced
+	a.Equal([]protocol.ApiVersion{{ApiKey: apiKeyProduce, MaxVersion: 9}, {ApiKey: apiKeyApiApiVersions, MaxVersion: 3}}, apiVersions.ApiKeys)
+	a.Empty(dialedPrincipals)
+
+	// local SaslHandshake and SaslAuthenticate responses
+	readTestResponseCorrelationID(t, client)
+	readTestResponseCorrelationID(t, client)
+	// broker SASL responses are not passed to the client
+	a.Equal(int32(3), readTestResponseCorrelationID(t, client))
+	<-brokerDone
+
+	a.Equal([]int16{apiKeySaslHandshake, apiKeySaslAuthenticate, apiKeyApiApiVersions}, broker.apiKeys)
+	a.Equal([]string{"my-test-user"}, dialedPrincipals)
+	if a.Len(broker.saslMessages, 1) {
+		a.Contains(string(broker.saslMessages[0]), "\x00my-test-user\x00my-test-password")
+	}
+	a.True(session.authenticated)
+	a.Equal("my-test-user", session.principal)
+}
+
+func TestBrokerCredentialsSessionPrincipalChange(t *testing.T) {
+	a := assert.New(t)
+
+	session := newBrokerCredentialsSession(newTestBrokerCredentialsMapper(t, config.BrokerCredentialsSourceClient, false, nil), "broker:9092", nil, nil)
+	session.authenticated = true
+	session.principal = "alice"
+
+	// re-authentication of the same principal keeps the broker session
+	a.Nil(session.authenticate(nil, &RequestsLoopContext{}, localSaslSession{principal: apis.Principal{Name: "alice"}}))
+	a.EqualError(session.authenticate(nil, &RequestsLoopContext{}, localSaslSession{principal: apis.Principal{Name: "bob"}}),
+		"SASL re-authentication must not change the principal alice to bob")
+}
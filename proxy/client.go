@@ -9,6 +9,8 @@ import (
 
 	"github.com/grepplabs/kafka-proxy/config"
 	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/proxy/protocol"
+	"github.com/grepplabs/kafka-proxy/proxy/proxyprotocol"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -17,6 +19,12 @@ import (
 type Conn struct {
 	BrokerAddress   string
 	LocalConnection net.Conn
+	// address of the listener which accepted the connection
+	ListenerAddress string
+	// name of the listener profile which accepted the connection
+	ListenerProfile string
+	// PROXY protocol header sent by the load balancer, nil without the header
+	ProxyProtocolHeader *proxyprotocol.Header
 }
 
 // Client is a type to handle connecting to a Server. All fields are required
@@ -29,6 +37,8 @@ type Client struct {
 
 	// config of Proxy request-response processor (instance p)
 	processorConfig ProcessorConfig
+	// configs of Proxy request-response processor for connections accepted by listener profiles
+	profileProcessorConfigs map[string]ProcessorConfig
 
 	dialer         Dialer
 	tcpConnOptions TCPConnOptions
@@ -37,14 +47,25 @@ type Client struct {
 	stopOnce sync.Once
 
 	saslAuthByProxy SASLAuthByProxy
-	authClient      *AuthClient
+	// saslSessionAuthByProxy re-authenticates the broker connections, nil when re-authentication is disabled
+	saslSessionAuthByProxy SASLSessionAuthByProxy
+	authClient             *AuthClient
 
 	dialAddressMapping map[string]config.DialAddressMapping
 
-	kafkaClientCert *x509.Certificate
+	advertisedListenerRules advertisedListenerRules
+
+	// authLockout is shared by the local authentication of all listeners, nil when the brute-force protection is disabled
+	authLockout *AuthLockout
+	// brokerClientAuth authenticates the broker connections on behalf of the locally authenticated clients, nil when the proxy uses the same credentials for all clients
+	brokerClientAuth brokerClientAuth
+	// brokerApiVersions are answered to the clients whose broker connection is opened after their local authentication
+	brokerApiVersions *brokerApiVersionsCache
+	// clientCertIssuer issues the client certificates of the broker connections per client, nil when all connections use the configured client certificate
+	clientCertIssuer *clientCertIssuer
 }
 
-func NewClient(conns *ConnSet, c *config.Config, netAddressMappingFunc config.NetAddressMappingFunc, localPasswordAuthenticator apis.PasswordAuthenticator, localTokenAuthenticator apis.TokenInfo, saslTokenProvider apis.TokenProvider, gatewayTokenProvider apis.TokenProvider, gatewayTokenInfo apis.TokenInfo) (*Client, error) {
+func NewClient(conns *ConnSet, c *config.Config, netAddressMappingFunc config.NetAddressMappingFunc, localAuthenticators map[string]LocalSaslAuth, saslTokenProvider apis.TokenProvider, gatewayTokenProvider apis.TokenProvider, gatewayTokenInfo apis.TokenInfo, authLockout *AuthLockout, brokerCredentialsProvider apis.BrokerCredentialsProvider) (*Client, error) {
 	var (
 		kafkaClientCert *x509.Certificate
 		tlsConfigFunc   TLSConfigFunc
@@ -66,21 +87,28 @@ func NewClient(conns *ConnSet, c *config.Config, netAddressMappingFunc config.Ne
 	if err != nil {
 		return nil, err
 	}
+	var issuer *clientCertIssuer
+	if c.Kafka.TLS.Enable && c.Kafka.TLS.ClientCertIssuer.Enable {
+		if issuer, err = newClientCertIssuer(c); err != nil {
+			return nil, errors.Wrap(err, "client certificate issuer")
+		}
+	}
 	tcpConnOptions := TCPConnOptions{
 		KeepAlive:       c.Kafka.KeepAlive,
 		WriteBufferSize: c.Kafka.ConnectionWriteBufferSize,
 		ReadBufferSize:  c.Kafka.ConnectionReadBufferSize,
 	}
 
-	forbiddenApiKeys := make(map[int16]struct{})
-	if len(c.Kafka.ForbiddenApiKeys) != 0 {
-		logrus.Warnf("Kafka operations for Api Keys %v will be forbidden.", c.Kafka.ForbiddenApiKeys)
-		for _, apiKey := range c.Kafka.ForbiddenApiKeys {
-			forbiddenApiKeys[int16(apiKey)] = struct{}{}
-		}
-	}
-	if c.Auth.Local.Enable && (localPasswordAuthenticator == nil && localTokenAuthenticator == nil) {
-		return nil, errors.New("Auth.Local.Enable is enabled but passwordAuthenticator and localTokenAuthenticator are nil")
+	forbiddenApiKeys := getForbiddenApiKeys(c.Kafka.ForbiddenApiKeys)
+	localSasl := NewLocalSasl(LocalSaslParams{
+		enabled:             c.Auth.Local.Enable,
+		timeout:             c.Auth.Local.Timeout,
+		maxSessionLifetime:  c.Auth.Local.MaxSessionLifetime,
+		localAuthenticators: localAuthenticators,
+		lockout:             authLockout,
+	})
+	if c.Auth.Local.Enable && len(localSasl.localAuthenticators) == 0 {
+		return nil, errors.New("Auth.Local.Enable is enabled but local authenticators are not set")
 	}
 
 	if c.Auth.Gateway.Client.Enable && gatewayTokenProvider == nil {
@@ -139,14 +167,46 @@ func NewClient(conns *ConnSet, c *config.Config, netAddressMappingFunc config.Ne
 			return nil, errors.Errorf("SASL Mechanism not valid '%s'", c.Kafka.SASL.Method)
 		}
 	}
+	var clientAuth brokerClientAuth
+	if c.Kafka.SASL.Enable && c.Kafka.SASL.BrokerCredentials.Enable {
+		var brokerCredentialsMapper *BrokerCredentialsMapper
+		if brokerCredentialsMapper, err = NewBrokerCredentialsMapper(c, brokerCredentialsProvider); err != nil {
+			return nil, err
+		}
+		// connections without local authentication use the fallback credentials
+		saslAuthByProxy = brokerCredentialsMapper.fallbackAuth()
+		clientAuth = brokerCredentialsMapper
+	} else if c.Kafka.SASL.Enable && c.Kafka.SASL.TokenRelay.Enable {
+		if clientAuth, err = NewBrokerTokenRelay(c); err != nil {
+			return nil, err
+		}
+		// connections without local authentication have no token to relay
+		saslAuthByProxy = nil
+	}
+	var saslSessionAuthByProxy SASLSessionAuthByProxy
+	if c.Kafka.SASL.ReauthenticationEnable && !c.Kafka.SASL.TokenRelay.Enable {
+		var ok bool
+		if saslSessionAuthByProxy, ok = saslAuthByProxy.(SASLSessionAuthByProxy); !ok {
+			return nil, errors.New("SASL re-authentication is supported only for OAUTHBEARER plugin and AWS_MSK_IAM")
+		}
+	}
 
 	dialAddressMapping, err := getAddressToDialAddressMapping(c)
 	if err != nil {
 		return nil, err
 	}
+	advertisedListenerRules, err := newAdvertisedListenerRules(c.Proxy.AdvertisedListenerRules)
+	if err != nil {
+		return nil, err
+	}
+	clientCertIdentityFunc, err := tlsClientCertIdentityFunc(&c.Proxy.TLS)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Client{conns: conns, config: c, dialer: dialer, tcpConnOptions: tcpConnOptions, stopRun: make(chan struct{}, 1),
-		saslAuthByProxy: saslAuthByProxy,
+		saslAuthByProxy:        saslAuthByProxy,
+		saslSessionAuthByProxy: saslSessionAuthByProxy,
 		authClient: &AuthClient{
 			enabled:       c.Auth.Gateway.Client.Enable,
 			magic:         c.Auth.Gateway.Client.Magic,
@@ -161,12 +221,7 @@ func NewClient(conns *ConnSet, c *config.Config, netAddressMappingFunc config.Ne
 			ResponseBufferSize:    c.Proxy.ResponseBufferSize,
 			ReadTimeout:           c.Kafka.ReadTimeout,
 			WriteTimeout:          c.Kafka.WriteTimeout,
-			LocalSasl: NewLocalSasl(LocalSaslParams{
-				enabled:               c.Auth.Local.Enable,
-				timeout:               c.Auth.Local.Timeout,
-				passwordAuthenticator: localPasswordAuthenticator,
-				tokenAuthenticator:    localTokenAuthenticator,
-			}),
+			LocalSasl:             localSasl,
 			AuthServer: &AuthServer{
 				enabled:   c.Auth.Gateway.Server.Enable,
 				magic:     c.Auth.Gateway.Server.Magic,
@@ -174,14 +229,77 @@ func NewClient(conns *ConnSet, c *config.Config, netAddressMappingFunc config.Ne
 				timeout:   c.Auth.Gateway.Server.Timeout,
 				tokenInfo: gatewayTokenInfo,
 			},
-			ForbiddenApiKeys:      forbiddenApiKeys,
-			ProducerAcks0Disabled: c.Kafka.Producer.Acks0Disabled,
+			ForbiddenApiKeys:       forbiddenApiKeys,
+			ProducerAcks0Disabled:  c.Kafka.Producer.Acks0Disabled,
+			ClientCertIdentityFunc: clientCertIdentityFunc,
+			SameClientCert:         kafkaClientCert,
+			Cluster:                c.Cluster,
 		},
-		dialAddressMapping: dialAddressMapping,
-		kafkaClientCert:    kafkaClientCert,
+		profileProcessorConfigs: make(map[string]ProcessorConfig),
+		dialAddressMapping:      dialAddressMapping,
+		advertisedListenerRules: advertisedListenerRules,
+		authLockout:             authLockout,
+		brokerClientAuth:        clientAuth,
+		brokerApiVersions:       newBrokerApiVersionsCache(),
+		clientCertIssuer:        issuer,
 	}, nil
 }
 
+// AddListenerProfile configures processing of connections accepted by listeners of the listener profile.
+// It must be called before Run.
+func (c *Client) AddListenerProfile(profile config.ListenerProfile, netAddressMappingFunc config.NetAddressMappingFunc, localAuthenticators map[string]LocalSaslAuth) error {
+	if _, ok := c.profileProcessorConfigs[profile.Name]; ok || profile.Name == config.DefaultListenerProfileName {
+		return errors.Errorf("listener profile '%s' is already configured", profile.Name)
+	}
+	localSasl := NewLocalSasl(LocalSaslParams{
+		enabled:             profile.LocalAuth.Enable,
+		timeout:             profile.LocalAuth.Timeout,
+		maxSessionLifetime:  profile.LocalAuth.MaxSessionLifetime,
+		localAuthenticators: localAuthenticators,
+		lockout:             c.authLockout,
+	})
+	if profile.LocalAuth.Enable && len(localSasl.localAuthenticators) == 0 {
+		return errors.Errorf("LocalAuth.Enable is enabled for listener profile '%s' but local authenticators are not set", profile.Name)
+	}
+	processorConfig := c.processorConfig
+	processorConfig.NetAddressMappingFunc = netAddressMappingFunc
+	processorConfig.LocalSasl = localSasl
+	processorConfig.ForbiddenApiKeys = getForbiddenApiKeys(profile.ForbiddenApiKeys)
+	clientCertIdentityFunc, err := tlsClientCertIdentityFunc(&profile.TLS)
+	if err != nil {
+		return err
+	}
+	processorConfig.ClientCertIdentityFunc = clientCertIdentityFunc
+	if !profile.TLS.Enable {
+		// plaintext clients cannot present the client certificate
+		processorConfig.SameClientCert = nil
+	}
+	c.profileProcessorConfigs[profile.Name] = processorConfig
+	return nil
+}
+
+func (c *Client) getProcessorConfig(profile string) (ProcessorConfig, error) {
+	if profile == config.DefaultListenerProfileName {
+		return c.processorConfig, nil
+	}
+	processorConfig, ok := c.profileProcessorConfigs[profile]
+	if !ok {
+		return ProcessorConfig{}, errors.Errorf("listener profile '%s' is not configured", profile)
+	}
+	return processorConfig, nil
+}
+
+func getForbiddenApiKeys(apiKeys []int) map[int16]struct{} {
+	forbiddenApiKeys := make(map[int16]struct{})
+	if len(apiKeys) != 0 {
+		logrus.Warnf("Kafka operations for Api Keys %v will be forbidden.", apiKeys)
+		for _, apiKey := range apiKeys {
+			forbiddenApiKeys[int16(apiKey)] = struct{}{}
+		}
+	}
+	return forbiddenApiKeys
+}
+
 func getAddressToDialAddressMapping(cfg *config.Config) (map[string]config.DialAddressMapping, error) {
 	addressToDialAddressMapping := make(map[string]config.DialAddressMapping)
 
@@ -277,8 +395,17 @@ func (c *Client) Close() {
 
 func (c *Client) handleConn(conn Conn) {
 	localConn := conn.LocalConnection
-	if c.kafkaClientCert != nil {
-		err := handshakeAsTLSAndValidateClientCert(localConn, c.kafkaClientCert, c.config.Kafka.DialTimeout)
+	processorConfig, err := c.getProcessorConfig(conn.ListenerProfile)
+	if err != nil {
+		logrus.Info(err.Error())
+		_ = localConn.Close()
+		return
+	}
+	processorConfig.NetAddressMappingFunc = c.advertisedListenerRules.netAddressMapping(processorConfig.NetAddressMappingFunc, conn)
+	clientCertIdentityFunc := processorConfig.ClientCertIdentityFunc
+	processorConfig.LocalConnectionInfoFunc = func() apis.ConnectionInfo { return localConnectionInfo(conn, clientCertIdentityFunc) }
+	if processorConfig.SameClientCert != nil {
+		err := handshakeAsTLSAndValidateClientCert(localConn, processorConfig.SameClientCert, c.config.Kafka.DialTimeout)
 
 		if err != nil {
 			logrus.Info(err.Error())
@@ -287,7 +414,7 @@ func (c *Client) handleConn(conn Conn) {
 		}
 	}
 
-	proxyConnectionsTotal.WithLabelValues(conn.BrokerAddress).Inc()
+	proxyConnectionsTotal.WithLabelValues(conn.BrokerAddress, c.config.Cluster).Inc()
 
 	dialAddress := conn.BrokerAddress
 	if addressMapping, ok := c.dialAddressMapping[dialAddress]; ok {
@@ -295,62 +422,182 @@ func (c *Client) handleConn(conn Conn) {
 		logrus.Infof("Dial address changed from %s to %s", conn.BrokerAddress, dialAddress)
 	}
 
-	server, err := c.DialAndAuth(dialAddress)
-	if err != nil {
-		logrus.Infof("couldn't connect to %s(%s): %v", dialAddress, conn.BrokerAddress, err)
+	if c.brokerClientAuth != nil && !processorConfig.LocalSasl.enabled && c.saslAuthByProxy == nil {
+		logrus.Infof("couldn't connect to %s(%s): broker authentication on behalf of the client requires local authentication of the client", dialAddress, conn.BrokerAddress)
 		_ = conn.LocalConnection.Close()
 		return
 	}
-	if tcpConn, ok := server.(*net.TCPConn); ok {
-		if err := c.tcpConnOptions.setTCPConnOptions(tcpConn); err != nil {
-			logrus.Infof("WARNING: Error while setting TCP options for kafka connection %s on %v: %v", conn.BrokerAddress, server.LocalAddr(), err)
+	principalClientCert := c.clientCertIssuer != nil && c.config.Kafka.TLS.ClientCertIssuer.SubjectSource == config.ClientCertSubjectSourcePrincipal
+	if principalClientCert && !processorConfig.LocalSasl.enabled {
+		logrus.Infof("couldn't connect to %s(%s): client certificate of the principal requires local authentication of the client", dialAddress, conn.BrokerAddress)
+		_ = conn.LocalConnection.Close()
+		return
+	}
+
+	dialer := c.dialer
+	if c.clientCertIssuer != nil && c.config.Kafka.TLS.ClientCertIssuer.SubjectSource == config.ClientCertSubjectSourceTLS {
+		clientCert, err := handshakeAsTLSAndGetVerifiedClientCert(localConn, c.config.Kafka.DialTimeout)
+		if err == nil {
+			dialer, err = c.clientCertDialer(clientCert.RawSubject)
+		}
+		if err != nil {
+			logrus.Infof("couldn't connect to %s(%s): client certificate: %v", dialAddress, conn.BrokerAddress, err)
+			_ = conn.LocalConnection.Close()
+			return
 		}
 	}
+
+	var server net.Conn
+	var sessionLifetime time.Duration
+	if c.brokerClientAuth != nil && processorConfig.LocalSasl.enabled {
+		// the broker connection is opened and authenticated with the credentials of the client after its local authentication
+		brokerCredentialsSession := newBrokerCredentialsSession(c.brokerClientAuth, dialAddress, func(principal string) (net.Conn, error) {
+			brokerDialer := dialer
+			if principalClientCert {
+				var err error
+				if brokerDialer, err = c.principalClientCertDialer(principal); err != nil {
+					return nil, err
+				}
+			}
+			brokerConn, _, err := c.dialAndAuth(brokerDialer, dialAddress, true)
+			if err != nil {
+				return nil, err
+			}
+			c.setBrokerTCPConnOptions(brokerConn, conn.BrokerAddress)
+			return brokerConn, nil
+		}, func() ([]protocol.ApiVersion, error) {
+			return c.brokerApiVersions.apiVersions(dialAddress, func() ([]protocol.ApiVersion, error) {
+				return c.requestApiVersions(dialAddress)
+			})
+		})
+		processorConfig.BrokerCredentialsSession = brokerCredentialsSession
+		server = brokerCredentialsSession.conn
+	} else {
+		server, sessionLifetime, err = c.dialAndAuth(dialer, dialAddress, false)
+		if err != nil {
+			logrus.Infof("couldn't connect to %s(%s): %v", dialAddress, conn.BrokerAddress, err)
+			_ = conn.LocalConnection.Close()
+			return
+		}
+		if principalClientCert {
+			brokerClientCertSession := newBrokerClientCertSession(server, dialAddress, func(rawSubject []byte) (net.Conn, time.Duration, error) {
+				clientCertDialer, err := c.clientCertDialer(rawSubject)
+				if err != nil {
+					return nil, 0, err
+				}
+				brokerConn, sessionLifetime, err := c.dialAndAuth(clientCertDialer, dialAddress, false)
+				if err != nil {
+					return nil, 0, err
+				}
+				c.setBrokerTCPConnOptions(brokerConn, conn.BrokerAddress)
+				return brokerConn, sessionLifetime, nil
+			})
+			processorConfig.BrokerClientCertSession = brokerClientCertSession
+			server = brokerClientCertSession.conn
+		}
+		c.setBrokerTCPConnOptions(server, conn.BrokerAddress)
+	}
+	if c.saslSessionAuthByProxy != nil {
+		processorConfig.BrokerSaslSession = newBrokerSaslSession(c.saslSessionAuthByProxy, dialAddress, c.config.Cluster, sessionLifetime)
+	}
 	c.conns.Add(conn.BrokerAddress, conn.LocalConnection)
 	localDesc := "local connection on " + conn.LocalConnection.LocalAddr().String() + " from " + conn.LocalConnection.RemoteAddr().String() + " (" + conn.BrokerAddress + ")"
-	copyThenClose(c.processorConfig, server, conn.LocalConnection, conn.BrokerAddress, conn.BrokerAddress, localDesc)
+	copyThenClose(processorConfig, server, conn.LocalConnection, conn.BrokerAddress, conn.BrokerAddress, localDesc)
 	if err := c.conns.Remove(conn.BrokerAddress, conn.LocalConnection); err != nil {
 		logrus.Info(err)
 	}
 }
 
-func (c *Client) DialAndAuth(brokerAddress string) (net.Conn, error) {
-	conn, err := c.dialer.Dial("tcp", brokerAddress)
+// DialAndAuth connects and authenticates to the broker, it returns the SASL session lifetime announced by the broker when the proxy re-authenticates.
+// With deferSASL the SASL authentication is left to the requests loop, which authenticates with the client credentials after the local authentication
+func (c *Client) DialAndAuth(brokerAddress string, deferSASL bool) (net.Conn, time.Duration, error) {
+	return c.dialAndAuth(c.dialer, brokerAddress, deferSASL)
+}
+
+func (c *Client) dialAndAuth(dialer Dialer, brokerAddress string, deferSASL bool) (net.Conn, time.Duration, error) {
+	conn, err := dialer.Dial("tcp", brokerAddress)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if err := conn.SetDeadline(time.Time{}); err != nil {
 		_ = conn.Close()
+		return nil, 0, err
+	}
+	sessionLifetime, err := c.auth(conn, brokerAddress, deferSASL)
+	if err != nil {
+		return nil, 0, err
+	}
+	return conn, sessionLifetime, nil
+}
+
+// clientCertDialer returns the dialer presenting the client certificate issued for the DER encoded subject
+func (c *Client) clientCertDialer(rawSubject []byte) (Dialer, error) {
+	tlsDialer, ok := c.dialer.(tlsDialer)
+	if !ok {
+		return nil, errors.New("client certificate requires TLS connections to the brokers")
+	}
+	cert, err := c.clientCertIssuer.certificate(rawSubject)
+	if err != nil {
 		return nil, err
 	}
-	err = c.auth(conn, brokerAddress)
+	return tlsDialer.withClientCertificate(cert), nil
+}
+
+// principalClientCertDialer returns the dialer presenting the client certificate issued for the principal
+func (c *Client) principalClientCertDialer(principal string) (Dialer, error) {
+	if principal == "" {
+		return nil, errors.New("client certificate requires the principal, the local authentication provided none")
+	}
+	rawSubject, err := principalCertSubject(principal)
 	if err != nil {
 		return nil, err
 	}
-	return conn, nil
+	return c.clientCertDialer(rawSubject)
 }
 
-func (c *Client) auth(conn net.Conn, brokerAddress string) error {
+// requestApiVersions requests the api versions of the broker on a connection opened only for the request
+func (c *Client) requestApiVersions(brokerAddress string) ([]protocol.ApiVersion, error) {
+	conn, _, err := c.dialAndAuth(c.dialer, brokerAddress, true)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return sendAndReceiveApiVersions(conn, c.config.Kafka.ClientID, c.config.Kafka.WriteTimeout, c.config.Kafka.ReadTimeout)
+}
+
+func (c *Client) setBrokerTCPConnOptions(server net.Conn, brokerAddress string) {
+	if tcpConn, ok := server.(*net.TCPConn); ok {
+		if err := c.tcpConnOptions.setTCPConnOptions(tcpConn); err != nil {
+			logrus.Infof("WARNING: Error while setting TCP options for kafka connection %s on %v: %v", brokerAddress, server.LocalAddr(), err)
+		}
+	}
+}
+
+func (c *Client) auth(conn net.Conn, brokerAddress string, deferSASL bool) (sessionLifetime time.Duration, err error) {
 	if c.config.Auth.Gateway.Client.Enable {
 		if err := c.authClient.sendAndReceiveGatewayAuth(conn); err != nil {
 			_ = conn.Close()
-			return err
+			return 0, err
 		}
 		if err := conn.SetDeadline(time.Time{}); err != nil {
 			_ = conn.Close()
-			return err
+			return 0, err
 		}
 	}
-	if c.config.Kafka.SASL.Enable {
-		err := c.saslAuthByProxy.sendAndReceiveSASLAuth(conn, brokerAddress)
+	if c.config.Kafka.SASL.Enable && !deferSASL {
+		if c.saslSessionAuthByProxy != nil {
+			sessionLifetime, err = c.saslSessionAuthByProxy.sendAndReceiveSASLSessionAuth(conn, brokerAddress)
+		} else {
+			err = c.saslAuthByProxy.sendAndReceiveSASLAuth(conn, brokerAddress)
+		}
 		if err != nil {
 			_ = conn.Close()
-			return err
+			return 0, err
 		}
 		if err := conn.SetDeadline(time.Time{}); err != nil {
 			_ = conn.Close()
-			return err
+			return 0, err
 		}
 	}
-	return nil
+	return sessionLifetime, nil
 }
@@ -40,6 +40,21 @@ func (e InvalidPatternValueError) Error() string {
 		e.Field, e.Value, e.Reason)
 }
 
+// InvalidFieldValueError is returned when a string value is not valid for the certificate field.
+type InvalidFieldValueError struct {
+	// Field in which the value is defined.
+	Field string
+	// Value which is problematic.
+	Value string
+	// Reason why the value is not valid.
+	Reason string
+}
+
+// Error returns the string representation of the error.
+func (e InvalidFieldValueError) Error() string {
+	return fmt.Sprintf("invalid value: field '%s' contains value '%s', reason: '%s'", e.Field, e.Value, e.Reason)
+}
+
 // ParserValueInsufficientInputError is an error returned when a value list can't be fully parsed because there was no more input.
 type ParserValueInsufficientInputError struct {
 	// Consumed string at the expected field position.
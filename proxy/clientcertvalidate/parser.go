@@ -136,7 +136,7 @@ func (p *defaultSubjectParser) Parse() (ParsedSubject, error) {
 				return output, &ParserUnexpectedError{Unexpected: err}
 			}
 
-			if _, ok := validSubjectFields[field]; !ok {
+			if !validSubjectFields[field] && !certFields[field] {
 				return output, &ParserUnsupportedSubjectFieldError{Field: field}
 			}
 
@@ -175,6 +175,12 @@ func (p *defaultSubjectParser) Parse() (ParsedSubject, error) {
 				return output, &ParserUnexpectedError{Unexpected: err}
 			}
 
+			if output.Type() == ClientCertificateSubjectPrefixString && certFields[field] {
+				if values, err = normalizeCertFieldValues(field, values); err != nil {
+					return output, err
+				}
+			}
+
 			regexpKVs := []*regexp.Regexp{}
 
 			if output.Type() == ClientCertificateSubjectPrefixString {
@@ -0,0 +1,305 @@
+package clientcertvalidate
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	clientCertIssuer = "ISSUER"
+	clientCertDNS    = "DNS"
+	clientCertURI    = "URI"
+	clientCertEmail  = "EMAIL"
+	clientCertIP     = "IP"
+	clientCertEKU    = "EKU"
+	clientCertSPIFFE = "SPIFFE"
+
+	spiffeScheme = "spiffe"
+)
+
+var certFields = map[string]bool{
+	clientCertIssuer: true,
+	clientCertDNS:    true,
+	clientCertURI:    true,
+	clientCertEmail:  true,
+	clientCertIP:     true,
+	clientCertEKU:    true,
+	clientCertSPIFFE: true,
+}
+
+// identityFields are the fields whose matched value is the identity of the certificate, in the order of precedence.
+var identityFields = []string{clientCertSPIFFE, clientCertURI, clientCertDNS, clientCertEmail, clientCertIP}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "serverAuth",
+	x509.ExtKeyUsageClientAuth:      "clientAuth",
+	x509.ExtKeyUsageCodeSigning:     "codeSigning",
+	x509.ExtKeyUsageEmailProtection: "emailProtection",
+	x509.ExtKeyUsageTimeStamping:    "timeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+}
+
+// normalizeCertFieldValues validates the string values of the certificate fields. Values are unescaped, as issuer DNs contain commas.
+func normalizeCertFieldValues(field string, values []string) ([]string, error) {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = unescapeValue(value)
+		switch field {
+		case clientCertIP:
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, &InvalidFieldValueError{Field: field, Value: value, Reason: "not an IP address"}
+			}
+			value = ip.String()
+		case clientCertEKU:
+			if !isExtKeyUsageName(value) {
+				return nil, &InvalidFieldValueError{Field: field, Value: value, Reason: "unknown extended key usage"}
+			}
+		case clientCertSPIFFE:
+			if _, _, err := parseSpiffeIDRule(value); err != nil {
+				return nil, &InvalidFieldValueError{Field: field, Value: value, Reason: err.Error()}
+			}
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+func unescapeValue(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isExtKeyUsageName(name string) bool {
+	for _, v := range extKeyUsageNames {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// certFieldValues returns the values of the certificate field
+func certFieldValues(field string, cert *x509.Certificate) []string {
+	values := []string{}
+	switch field {
+	case clientCertIssuer:
+		values = append(values, cert.Issuer.String())
+	case clientCertDNS:
+		values = append(values, cert.DNSNames...)
+	case clientCertURI:
+		for _, uri := range cert.URIs {
+			values = append(values, uri.String())
+		}
+	case clientCertEmail:
+		values = append(values, cert.EmailAddresses...)
+	case clientCertIP:
+		for _, ip := range cert.IPAddresses {
+			values = append(values, ip.String())
+		}
+	case clientCertEKU:
+		for _, usage := range cert.ExtKeyUsage {
+			if name, ok := extKeyUsageNames[usage]; ok {
+				values = append(values, name)
+			}
+		}
+	}
+	return values
+}
+
+// certSpiffeID returns the SPIFFE ID of the certificate. An X509-SVID contains exactly one URI SAN with the spiffe scheme.
+func certSpiffeID(cert *x509.Certificate) (string, error) {
+	ids := []string{}
+	for _, uri := range cert.URIs {
+		if strings.EqualFold(uri.Scheme, spiffeScheme) {
+			ids = append(ids, uri.String())
+		}
+	}
+	if len(ids) != 1 {
+		return "", fmt.Errorf("%s: expected one SPIFFE ID in certificate but found %d", clientCertSPIFFE, len(ids))
+	}
+	if _, _, err := parseSpiffeID(ids[0]); err != nil {
+		return "", fmt.Errorf("%s: invalid SPIFFE ID %s in certificate: %v", clientCertSPIFFE, ids[0], err)
+	}
+	return ids[0], nil
+}
+
+// parseSpiffeID returns the trust domain and the path of the SPIFFE ID. The trust domain and the path segments are validated
+// according to the SPIFFE ID specification, so that dot segments cannot escape the matched path.
+func parseSpiffeID(id string) (string, string, error) {
+	if strings.Contains(id, "%") {
+		return "", "", fmt.Errorf("percent-encoded characters are not allowed")
+	}
+	u, err := url.Parse(id)
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.EqualFold(u.Scheme, spiffeScheme) {
+		return "", "", fmt.Errorf("scheme must be %s", spiffeScheme)
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("trust domain is missing")
+	}
+	if u.User != nil || u.Port() != "" || u.RawQuery != "" || u.ForceQuery || u.Fragment != "" {
+		return "", "", fmt.Errorf("user info, port, query and fragment are not allowed")
+	}
+	trustDomain := strings.ToLower(u.Host)
+	for _, r := range trustDomain {
+		if !isSpiffeTrustDomainChar(r) {
+			return "", "", fmt.Errorf("trust domain contains invalid character %q", r)
+		}
+	}
+	if u.Path == "" {
+		return trustDomain, "", nil
+	}
+	for _, segment := range strings.Split(u.Path, "/")[1:] {
+		switch segment {
+		case "":
+			return "", "", fmt.Errorf("path must not contain empty segments")
+		case ".", "..":
+			return "", "", fmt.Errorf("path must not contain dot segments")
+		}
+		for _, r := range segment {
+			if !isSpiffePathChar(r) {
+				return "", "", fmt.Errorf("path contains invalid character %q", r)
+			}
+		}
+	}
+	return trustDomain, u.Path, nil
+}
+
+// parseSpiffeIDRule returns the trust domain and the path of the SPIFFE ID rule, the path may end with /*
+func parseSpiffeIDRule(rule string) (string, string, error) {
+	if prefix := strings.TrimSuffix(rule, "/*"); prefix != rule {
+		trustDomain, path, err := parseSpiffeID(prefix)
+		if err != nil {
+			return "", "", err
+		}
+		return trustDomain, path + "/*", nil
+	}
+	return parseSpiffeID(rule)
+}
+
+func isSpiffeTrustDomainChar(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '_'
+}
+
+func isSpiffePathChar(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '_'
+}
+
+// matchSpiffeID matches the trust domain and the path of the SPIFFE ID. Without path any workload of the trust domain matches,
+// the path ending with /* matches the workloads below the path.
+func matchSpiffeID(expected, id string) bool {
+	expectedTrustDomain, expectedPath, err := parseSpiffeIDRule(expected)
+	if err != nil {
+		return false
+	}
+	trustDomain, path, err := parseSpiffeID(id)
+	if err != nil || trustDomain != expectedTrustDomain {
+		return false
+	}
+	if expectedPath == "" {
+		return true
+	}
+	if prefix := strings.TrimSuffix(expectedPath, "/*"); prefix != expectedPath {
+		return strings.HasPrefix(path, prefix+"/")
+	}
+	return path == expectedPath
+}
+
+// testCertValuesString validates the certificate field with the string values and returns the matched certificate value
+func testCertValuesString(field string, expected []string, cert *x509.Certificate) (string, error) {
+	if field == clientCertSPIFFE {
+		id, err := certSpiffeID(cert)
+		if err != nil {
+			return "", err
+		}
+		for _, value := range expected {
+			if matchSpiffeID(value, id) {
+				return id, nil
+			}
+		}
+		return "", ClientCertificateRejectedError{Field: field, Expected: expected, Received: id}
+	}
+	certValues := certFieldValues(field, cert)
+	if field == clientCertEKU {
+		for _, value := range expected {
+			if !containsString(certValues, value) {
+				return "", ClientCertificateRejectedError{Field: field, Expected: expected, Received: certValues}
+			}
+		}
+		return "", nil
+	}
+	for _, value := range expected {
+		for _, certValue := range certValues {
+			if value == certValue || (field == clientCertDNS && strings.EqualFold(value, certValue)) {
+				return certValue, nil
+			}
+		}
+	}
+	return "", ClientCertificateRejectedError{Field: field, Expected: expected, Received: certValues}
+}
+
+// testCertValuesRegexp validates the certificate field with the patterns and returns the matched certificate value
+func testCertValuesRegexp(field string, expected []*regexp.Regexp, cert *x509.Certificate) (string, error) {
+	var certValues []string
+	if field == clientCertSPIFFE {
+		id, err := certSpiffeID(cert)
+		if err != nil {
+			return "", err
+		}
+		certValues = []string{id}
+	} else {
+		certValues = certFieldValues(field, cert)
+	}
+	if field == clientCertEKU {
+		for _, pattern := range expected {
+			if matchAny(pattern, certValues) == "" {
+				return "", ClientCertificateRejectedError{Field: field, Expected: pattern, Received: certValues}
+			}
+		}
+		return "", nil
+	}
+	for _, pattern := range expected {
+		if matched := matchAny(pattern, certValues); matched != "" {
+			return matched, nil
+		}
+	}
+	return "", ClientCertificateRejectedError{Field: field, Expected: expected, Received: certValues}
+}
+
+func matchAny(pattern *regexp.Regexp, values []string) string {
+	for _, value := range values {
+		if pattern.MatchString(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
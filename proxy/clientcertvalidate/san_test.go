@@ -0,0 +1,156 @@
+package clientcertvalidate
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newSANCertificate(t *testing.T, uris ...string) *x509.Certificate {
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "workload", Organization: []string{"org"}},
+		Issuer:         pkix.Name{CommonName: "issuing-ca", Organization: []string{"org"}},
+		DNSNames:       []string{"broker-client.example.com", "client.internal"},
+		EmailAddresses: []string{"client@example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("2001:db8::1")},
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert.URIs = append(cert.URIs, u)
+	}
+	return cert
+}
+
+func parseSubject(t *testing.T, input string) ParsedSubject {
+	parsed, err := NewSubjectParser(input).Parse()
+	if err != nil {
+		t.Fatalf("expected %s to parse but got %v", input, err)
+	}
+	return parsed
+}
+
+func TestCertFieldsIdentity(t *testing.T) {
+	cert := newSANCertificate(t, "spiffe://example.org/ns/kafka/sa/producer", "https://example.org/client")
+
+	tests := []struct {
+		input    string
+		identity string
+	}{
+		{"s:/SPIFFE=[spiffe://example.org/ns/kafka/sa/producer]", "spiffe://example.org/ns/kafka/sa/producer"},
+		{"s:/SPIFFE=[spiffe://example.org]", "spiffe://example.org/ns/kafka/sa/producer"},
+		{"s:/SPIFFE=[spiffe://other.org,spiffe://EXAMPLE.org/ns/kafka/*]", "spiffe://example.org/ns/kafka/sa/producer"},
+		{"s:/URI=[https://example.org/client]", "https://example.org/client"},
+		{"s:/DNS=[CLIENT.internal]", "client.internal"},
+		{"s:/EMAIL=[client@example.com]/CN=[workload]", "client@example.com"},
+		{"s:/IP=[2001:0db8::0001]", "2001:db8::1"},
+		{"s:/DNS=[client.internal]/SPIFFE=[spiffe://example.org]/EKU=[clientAuth]", "spiffe://example.org/ns/kafka/sa/producer"},
+		{"s:/ISSUER=[CN=issuing-ca\\,O=org]", "CN=workload,O=org"},
+		{"s:/EKU=[clientAuth,serverAuth]", "CN=workload,O=org"},
+		{"r:/DNS=[^.*\\.example\\.com$]", "broker-client.example.com"},
+		{"r:/URI=[^spiffe://example\\.org/ns/kafka/.*$]", "spiffe://example.org/ns/kafka/sa/producer"},
+		{"r:/ISSUER=[^CN=issuing-ca,.*$]/EKU=[^client.*$]", "CN=workload,O=org"},
+	}
+	for _, tc := range tests {
+		identity, err := parseSubject(t, tc.input).X509Identity(cert)
+		if err != nil {
+			t.Fatalf("%s: expected certificate to be accepted but got %v", tc.input, err)
+		}
+		if identity != tc.identity {
+			t.Fatalf("%s: expected identity %s but got %s", tc.input, tc.identity, identity)
+		}
+	}
+}
+
+func TestCertFieldsRejected(t *testing.T) {
+	cert := newSANCertificate(t, "spiffe://example.org/ns/kafka/sa/producer")
+
+	tests := []string{
+		"s:/SPIFFE=[spiffe://other.org]",
+		"s:/SPIFFE=[spiffe://example.org/ns/kafka]",
+		"s:/SPIFFE=[spiffe://example.org/ns/kafka/sa/producer/*]",
+		"s:/URI=[https://example.org/client]",
+		"s:/DNS=[example.com]",
+		"s:/EMAIL=[other@example.com]",
+		"s:/IP=[10.0.0.2]",
+		"s:/ISSUER=[CN=other-ca]",
+		"s:/EKU=[clientAuth,codeSigning]",
+		"s:/DNS=[client.internal]/CN=[other]",
+		"r:/DNS=[^.*\\.example\\.org$]",
+		"r:/EKU=[^codeSigning$]",
+	}
+	for _, input := range tests {
+		if _, err := parseSubject(t, input).X509Identity(cert); err == nil {
+			t.Fatalf("%s: expected certificate to be rejected", input)
+		}
+		if err := parseSubject(t, input).X509Validate(cert); err == nil {
+			t.Fatalf("%s: expected certificate to be rejected", input)
+		}
+	}
+}
+
+func TestCertWithoutOneSpiffeIDRejected(t *testing.T) {
+	for _, cert := range []*x509.Certificate{
+		newSANCertificate(t),
+		newSANCertificate(t, "spiffe://example.org/a", "spiffe://example.org/b"),
+	} {
+		_, err := parseSubject(t, "s:/SPIFFE=[spiffe://example.org]").X509Identity(cert)
+		if err == nil {
+			t.Fatal("expected certificate to be rejected")
+		}
+		if !strings.HasPrefix(err.Error(), "SPIFFE: expected one SPIFFE ID in certificate") {
+			t.Fatalf("expected different error %v", err)
+		}
+	}
+}
+
+func TestCertWithInvalidSpiffeIDRejected(t *testing.T) {
+	for _, id := range []string{
+		"spiffe://example.org/ns/prod/../dev",
+		"spiffe://example.org/ns/prod/./dev",
+		"spiffe://example.org/ns/prod//dev",
+		"spiffe://example.org/ns/prod/dev/",
+		"spiffe://example.org/ns/prod/d@v",
+		"spiffe://example.org/ns/prod/d%65v",
+		"spiffe://exa+mple.org/ns/prod/dev",
+	} {
+		cert := newSANCertificate(t, id)
+		for _, input := range []string{"s:/SPIFFE=[spiffe://example.org/ns/prod/*]", "s:/SPIFFE=[spiffe://example.org]", "r:/SPIFFE=[^spiffe://.*$]"} {
+			if _, err := parseSubject(t, input).X509Identity(cert); err == nil {
+				t.Fatalf("%s: expected certificate with SPIFFE ID %s to be rejected", input, id)
+			}
+		}
+	}
+}
+
+func TestInvalidCertFieldValueParser(t *testing.T) {
+	tests := []string{
+		"s:/IP=[10.0.0.256]",
+		"s:/EKU=[clientAuthentication]",
+		"s:/SPIFFE=[https://example.org/workload]",
+		"s:/SPIFFE=[spiffe:///workload]",
+		"s:/SPIFFE=[spiffe://example.org:8443/workload]",
+		"s:/SPIFFE=[spiffe://example.org/ns/../workload]",
+		"s:/SPIFFE=[spiffe://example.org/ns/./workload]",
+		"s:/SPIFFE=[spiffe://example.org/ns//workload]",
+		"s:/SPIFFE=[spiffe://example.org/ns/workload/]",
+		"s:/SPIFFE=[spiffe://example.org/ns/work@load]",
+		"s:/SPIFFE=[spiffe://example.org/ns/%2E%2E/*]",
+		"s:/SPIFFE=[spiffe://exa+mple.org/workload]",
+	}
+	for _, input := range tests {
+		_, parseErr := NewSubjectParser(input).Parse()
+		if parseErr == nil {
+			t.Fatalf("expected %s not to parse but it parsed", input)
+		}
+		if !strings.HasPrefix(parseErr.Error(), "invalid value: field") {
+			t.Fatalf("expected different error type %v", parseErr)
+		}
+	}
+}
@@ -48,6 +48,7 @@ type ParsedSubject interface {
 	Type() ClientCertificateSubjectPrefixType
 	WithType(ClientCertificateSubjectPrefixType)
 	X509Validate(*x509.Certificate) error
+	X509Identity(*x509.Certificate) (string, error)
 }
 
 type defaultParsedSubject struct {
@@ -92,6 +93,50 @@ func (ccs *defaultParsedSubject) WithRegexpKVs(field string, values []*regexp.Re
 }
 
 func (ccs *defaultParsedSubject) X509Validate(cert *x509.Certificate) error {
+	_, err := ccs.X509Identity(cert)
+	return err
+}
+
+// X509Identity validates the certificate and returns its identity: the value matched by the SPIFFE, URI, DNS, EMAIL or IP field
+// in this order of precedence, the subject DN when none of these fields is defined.
+func (ccs *defaultParsedSubject) X509Identity(cert *x509.Certificate) (string, error) {
+	if err := ccs.x509ValidateSubject(cert); err != nil {
+		return "", err
+	}
+	matched := make(map[string]string)
+	switch ccs.inputValuesType {
+	case ClientCertificateSubjectPrefixString:
+		for k, v := range ccs.kvs {
+			if !certFields[k] {
+				continue
+			}
+			value, err := testCertValuesString(k, v, cert)
+			if err != nil {
+				return "", err
+			}
+			matched[k] = value
+		}
+	case ClientCertificateSubjectPrefixPattern:
+		for k, v := range ccs.regexpkvs {
+			if !certFields[k] {
+				continue
+			}
+			value, err := testCertValuesRegexp(k, v, cert)
+			if err != nil {
+				return "", err
+			}
+			matched[k] = value
+		}
+	}
+	for _, field := range identityFields {
+		if value, ok := matched[field]; ok {
+			return value, nil
+		}
+	}
+	return cert.Subject.String(), nil
+}
+
+func (ccs *defaultParsedSubject) x509ValidateSubject(cert *x509.Certificate) error {
 	switch ccs.inputValuesType {
 	case ClientCertificateSubjectPrefixString:
 		for k, v := range ccs.kvs {
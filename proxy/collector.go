@@ -8,33 +8,102 @@ var (
 	proxyConnectionsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{Name: "proxy_connections_total",
 			Help: "Total number of created connections"},
-		[]string{"broker"})
+		[]string{"broker", "cluster"})
 
 	proxyRequestsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{Name: "proxy_requests_total",
 			Help: "Total number of requests sent"},
-		[]string{"broker", "api_key", "api_version"})
+		[]string{"broker", "api_key", "api_version", "cluster"})
 
 	proxyRequestsBytes = prometheus.NewCounterVec(
 		prometheus.CounterOpts{Name: "proxy_requests_bytes",
 			Help: "Size of outgoing requests"},
-		[]string{"broker"})
+		[]string{"broker", "cluster"})
 
 	proxyResponsesBytes = prometheus.NewCounterVec(
 		prometheus.CounterOpts{Name: "proxy_responses_bytes",
 			Help: "Size of incoming responses"},
-		[]string{"broker"})
+		[]string{"broker", "cluster"})
 
 	proxyOpenedConnections = prometheus.NewDesc(
 		"proxy_opened_connections",
 		"Number of opened connections",
-		[]string{"broker"}, nil,
+		[]string{"broker", "cluster"}, nil,
 	)
 
 	proxyLocalAuthTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{Name: "proxy_local_auth_total",
 			Help: "Total number of local auth requests sent"},
 		[]string{"success", "status"})
+
+	proxyLocalAuthAuthenticatedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_local_auth_authenticated_total",
+			Help: "Total number of successful local SASL authentications per mechanism and listener profile"},
+		[]string{"mechanism", "profile"})
+
+	proxyLocalAuthSessionExpiredTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "proxy_local_auth_session_expired_total",
+			Help: "Total number of connections closed because the local SASL session expired without re-authentication"})
+
+	proxyLocalAuthLockoutsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_local_auth_lockouts_total",
+			Help: "Total number of usernames and client IPs locked after too many failed local authentications"},
+		[]string{"kind"})
+
+	proxyLocalAuthLockedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_local_auth_locked_total",
+			Help: "Total number of local authentications rejected because the username or client IP is locked"},
+		[]string{"kind"})
+
+	proxyBrokerReauthTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_broker_reauth_total",
+			Help: "Total number of SASL re-authentications to the brokers"},
+		[]string{"broker", "cluster", "success"})
+
+	proxyBrokerCredentialsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_broker_credentials_total",
+			Help: "Total number of per-client broker credentials lookups by result"},
+		[]string{"cluster", "result"})
+
+	proxyBrokerTokenRelayTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_broker_token_relay_total",
+			Help: "Total number of client tokens relayed or exchanged for broker authentication by result"},
+		[]string{"cluster", "mode", "result"})
+
+	proxyBrokerClientCertsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_broker_client_certs_total",
+			Help: "Total number of per-client TLS certificates of broker connections by result"},
+		[]string{"cluster", "result"})
+
+	proxyListenerConnectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_listener_connections_total",
+			Help: "Total number of connections accepted by listeners with TLS mode both"},
+		[]string{"listener", "transport"})
+
+	proxyListenerPlaintextConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "proxy_listener_plaintext_connections",
+			Help: "Number of opened plaintext connections on listeners with TLS mode both"},
+		[]string{"listener"})
+
+	proxyProtocolConnectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_protocol_connections_total",
+			Help: "Total number of connections accepted by listeners with PROXY protocol enabled"},
+		[]string{"listener", "result"})
+
+	proxyAuthChainStageTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_auth_chain_stage_total",
+			Help: "Total number of decisions of the authentication chain stages"},
+		[]string{"chain", "stage", "decision"})
+
+	proxyAuthCacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_auth_cache_requests_total",
+			Help: "Total number of authentications looked up in the auth cache"},
+		[]string{"cache", "result"})
+
+	proxyAuthCacheEvictionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "proxy_auth_cache_evictions_total",
+			Help: "Total number of authentication results evicted from the full auth cache"},
+		[]string{"cache"})
 )
 
 func init() {
@@ -43,14 +112,28 @@ func init() {
 	prometheus.MustRegister(proxyRequestsBytes)
 	prometheus.MustRegister(proxyResponsesBytes)
 	prometheus.MustRegister(proxyLocalAuthTotal)
+	prometheus.MustRegister(proxyLocalAuthAuthenticatedTotal)
+	prometheus.MustRegister(proxyLocalAuthSessionExpiredTotal)
+	prometheus.MustRegister(proxyLocalAuthLockoutsTotal)
+	prometheus.MustRegister(proxyLocalAuthLockedTotal)
+	prometheus.MustRegister(proxyBrokerReauthTotal)
+	prometheus.MustRegister(proxyBrokerCredentialsTotal)
+	prometheus.MustRegister(proxyBrokerTokenRelayTotal)
+	prometheus.MustRegister(proxyBrokerClientCertsTotal)
+	prometheus.MustRegister(proxyListenerConnectionsTotal)
+	prometheus.MustRegister(proxyListenerPlaintextConnections)
+	prometheus.MustRegister(proxyProtocolConnectionsTotal)
+	prometheus.MustRegister(proxyAuthChainStageTotal)
+	prometheus.MustRegister(proxyAuthCacheRequestsTotal)
+	prometheus.MustRegister(proxyAuthCacheEvictionsTotal)
 }
 
 type proxyCollector struct {
-	connSet *ConnSet
+	connSets []*ConnSet
 }
 
-func NewCollector(connSet *ConnSet) prometheus.Collector {
-	return &proxyCollector{connSet: connSet}
+func NewCollector(connSets ...*ConnSet) prometheus.Collector {
+	return &proxyCollector{connSets: connSets}
 }
 
 func (p *proxyCollector) Describe(ch chan<- *prometheus.Desc) {
@@ -59,8 +142,10 @@ func (p *proxyCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func (p *proxyCollector) Collect(ch chan<- prometheus.Metric) {
 
-	brokerToCount := p.connSet.Count()
-	for broker, count := range brokerToCount {
-		ch <- prometheus.MustNewConstMetric(proxyOpenedConnections, prometheus.GaugeValue, float64(count), broker)
+	for _, connSet := range p.connSets {
+		brokerToCount := connSet.Count()
+		for broker, count := range brokerToCount {
+			ch <- prometheus.MustNewConstMetric(proxyOpenedConnections, prometheus.GaugeValue, float64(count), broker, connSet.cluster)
+		}
 	}
 }
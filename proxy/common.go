@@ -151,13 +151,20 @@ func copyThenClose(cfg ProcessorConfig, remote, local DeadlineReadWriteCloser, b
 
 // NewConnSet initializes a new ConnSet and returns it.
 func NewConnSet() *ConnSet {
-	return &ConnSet{m: make(map[string][]net.Conn)}
+	return NewClusterConnSet("")
+}
+
+// NewClusterConnSet initializes a new ConnSet tracking connections to the upstream cluster and returns it.
+func NewClusterConnSet(cluster string) *ConnSet {
+	return &ConnSet{m: make(map[string][]net.Conn), cluster: cluster}
 }
 
 // A ConnSet tracks net.Conns associated with a provided ID.
 type ConnSet struct {
 	sync.RWMutex
 	m map[string][]net.Conn
+	// metrics
+	cluster string
 }
 
 // String returns a debug string for the ConnSet.
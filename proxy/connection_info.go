@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/proxy/proxyprotocol"
+)
+
+// localConnectionInfo describes the client connection for the auth plugins. It is called after the TLS handshake, as the TLS state is read from the connection.
+// The client certificate identity function is nil when the listener has no client certificate rules.
+func localConnectionInfo(conn Conn, clientCertIdentityFunc func(*x509.Certificate) string) apis.ConnectionInfo {
+	info := apis.ConnectionInfo{
+		ListenerAddress: conn.ListenerAddress,
+		ListenerProfile: conn.ListenerProfile,
+	}
+	if conn.LocalConnection == nil {
+		return info
+	}
+	if remoteAddr := conn.LocalConnection.RemoteAddr(); remoteAddr != nil {
+		info.ClientAddress = remoteAddr.String()
+	}
+	if header := conn.ProxyProtocolHeader; header != nil {
+		// server name of the TLS terminated by the load balancer, replaced by the server name of the TLS terminated by the proxy
+		info.TLSServerName = header.Authority()
+		if ssl, err := header.SSL(); err == nil && ssl != nil {
+			info.ProxySSL = ssl.Client&proxyprotocol.ClientSSL != 0
+			info.ProxySSLClientCert = ssl.Client&(proxyprotocol.ClientCertConn|proxyprotocol.ClientCertSess) != 0
+			info.ProxySSLClientCertVerified = ssl.ClientCertVerified()
+			info.ProxySSLVersion = ssl.Version()
+			info.ProxySSLClientCN = ssl.CommonName()
+		}
+	}
+	if state, ok := tlsConnectionState(conn.LocalConnection); ok {
+		info.TLSServerName = state.ServerName
+		if len(state.VerifiedChains) != 0 {
+			if clientCert := filterClientCertificate(state.PeerCertificates); clientCert != nil {
+				info.TLSClientSubject = clientCert.Subject.String()
+				info.TLSClientPrincipal = info.TLSClientSubject
+				if clientCertIdentityFunc != nil {
+					info.TLSClientPrincipal = clientCertIdentityFunc(clientCert)
+				}
+			}
+		}
+	}
+	return info
+}
+
+// tlsConnectionState returns the state of the completed TLS handshake, listeners pass TLS connections unwrapped
+func tlsConnectionState(conn net.Conn) (tls.ConnectionState, bool) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return tls.ConnectionState{}, false
+	}
+	state := tlsConn.ConnectionState()
+	return state, state.HandshakeComplete
+}
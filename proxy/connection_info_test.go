@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalConnectionInfo(t *testing.T) {
+	a := assert.New(t)
+
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+
+	_, c, connSrc := newBothModeListeners(t, bundle)
+	c.Proxy.TLS.ListenerCAChainCertFile = bundle.CACert.Name()
+	listeners, err := NewListeners(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := FromListenerConfig(c.Proxy.BootstrapServers[0])
+	l, err := listenInstance(connSrc, cfg, listeners.tcpConnOptions, listeners.listenFunc, listeners.acceptFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	caCertPEM, err := os.ReadFile(bundle.CACert.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caCertPEM) {
+		t.Fatal("failed to append CA certificate")
+	}
+	clientCert, err := tls.LoadX509KeyPair(bundle.ClientCert.Name(), bundle.ClientKey.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: []tls.Certificate{clientCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	server := receiveConn(t, connSrc)
+	defer server.LocalConnection.Close()
+
+	info := localConnectionInfo(server, nil)
+	a.Equal(client.LocalAddr().String(), info.ClientAddress)
+	a.Equal("127.0.0.1:0", info.ListenerAddress)
+	a.Equal("localhost", info.TLSServerName)
+	a.Equal(clientCert.Leaf.Subject.String(), info.TLSClientSubject)
+	a.NotEmpty(info.TLSClientSubject)
+	a.Equal(info.TLSClientSubject, info.TLSClientPrincipal)
+
+	info = localConnectionInfo(server, func(cert *x509.Certificate) string { return "principal-of-" + cert.Subject.CommonName })
+	a.Equal("principal-of-"+clientCert.Leaf.Subject.CommonName, info.TLSClientPrincipal)
+	a.Equal(clientCert.Leaf.Subject.String(), info.TLSClientSubject)
+}
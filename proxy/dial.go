@@ -74,6 +74,22 @@ type tlsDialer struct {
 	configFunc TLSConfigFunc
 }
 
+// withClientCertificate returns the dialer of a single client connection presenting the client certificate instead of the configured one
+func (d tlsDialer) withClientCertificate(cert *tls.Certificate) tlsDialer {
+	configFunc := d.configFunc
+	d.configFunc = func() *tls.Config {
+		config := configFunc()
+		if config == nil {
+			return nil
+		}
+		c := config.Clone()
+		c.Certificates = []tls.Certificate{*cert}
+		c.GetClientCertificate = nil
+		return c
+	}
+	return d
+}
+
 // see tls.DialWithDialer
 func (d tlsDialer) Dial(network, addr string) (net.Conn, error) {
 	config := d.configFunc()
@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// kerberosRuleParser matches the Kafka sasl.kerberos.principal.to.local.rules syntax
+var kerberosRuleParser = regexp.MustCompile(`^(DEFAULT|RULE:\[(\d+):([^\]]*)](\(([^)]*)\))?(s/([^/]*)/([^/]*)/(g)?)?/?(L|U)?)$`)
+
+var kerberosRuleParameter = regexp.MustCompile(`\$(\d+)`)
+
+// kerberosPrincipalRule translates a Kerberos principal to a short name, e.g. RULE:[1:$1@$0](.*@EXAMPLE\.COM)s/@.*//
+type kerberosPrincipalRule struct {
+	isDefault     bool
+	numComponents int
+	format        string
+	match         *regexp.Regexp
+	from          *regexp.Regexp
+	to            string
+	repeat        bool
+	toLowerCase   bool
+	toUpperCase   bool
+}
+
+type kerberosPrincipalRules []kerberosPrincipalRule
+
+// parseKerberosPrincipalRules parses the principal to local name rules, DEFAULT is used when no rule is given
+func parseKerberosPrincipalRules(rules []string) (kerberosPrincipalRules, error) {
+	if len(rules) == 0 {
+		rules = []string{"DEFAULT"}
+	}
+	result := make(kerberosPrincipalRules, 0, len(rules))
+	for _, rule := range rules {
+		matches := kerberosRuleParser.FindStringSubmatch(strings.TrimSpace(rule))
+		if matches == nil {
+			return nil, errors.Errorf("invalid principal to local rule '%s'", rule)
+		}
+		if matches[1] == "DEFAULT" {
+			result = append(result, kerberosPrincipalRule{isDefault: true})
+			continue
+		}
+		numComponents, err := strconv.Atoi(matches[2])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid number of components in principal to local rule '%s'", rule)
+		}
+		r := kerberosPrincipalRule{
+			numComponents: numComponents,
+			format:        matches[3],
+			to:            matches[8],
+			repeat:        matches[9] == "g",
+			toLowerCase:   matches[10] == "L",
+			toUpperCase:   matches[10] == "U",
+		}
+		if matches[4] != "" {
+			if r.match, err = regexp.Compile("^(?:" + matches[5] + ")$"); err != nil {
+				return nil, errors.Wrapf(err, "invalid match in principal to local rule '%s'", rule)
+			}
+		}
+		if matches[6] != "" {
+			if r.from, err = regexp.Compile(matches[7]); err != nil {
+				return nil, errors.Wrapf(err, "invalid substitution in principal to local rule '%s'", rule)
+			}
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// shortName returns the local name of the principal given by its name components and realm
+func (rules kerberosPrincipalRules) shortName(defaultRealm string, components []string, realm string) (string, error) {
+	for _, rule := range rules {
+		name, ok, err := rule.apply(defaultRealm, components, realm)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return name, nil
+		}
+	}
+	return "", errors.Errorf("no principal to local rules apply to %s@%s", strings.Join(components, "/"), realm)
+}
+
+func (r kerberosPrincipalRule) apply(defaultRealm string, components []string, realm string) (string, bool, error) {
+	var result string
+	if r.isDefault {
+		if realm != defaultRealm || len(components) == 0 {
+			return "", false, nil
+		}
+		result = components[0]
+	} else {
+		if len(components) != r.numComponents {
+			return "", false, nil
+		}
+		base, err := r.replaceParameters(components, realm)
+		if err != nil {
+			return "", false, err
+		}
+		if r.match != nil && !r.match.MatchString(base) {
+			return "", false, nil
+		}
+		result = r.replaceSubstitution(base)
+	}
+	if r.toLowerCase {
+		result = strings.ToLower(result)
+	} else if r.toUpperCase {
+		result = strings.ToUpper(result)
+	}
+	return result, true, nil
+}
+
+// replaceParameters replaces $0 with the realm and $n with the n-th name component
+func (r kerberosPrincipalRule) replaceParameters(components []string, realm string) (string, error) {
+	params := append([]string{realm}, components...)
+	var err error
+	result := kerberosRuleParameter.ReplaceAllStringFunc(r.format, func(s string) string {
+		idx, _ := strconv.Atoi(s[1:])
+		if idx >= len(params) {
+			err = fmt.Errorf("index %d from %s is outside of the valid range 0 to %d", idx, r.format, len(params)-1)
+			return s
+		}
+		return params[idx]
+	})
+	return result, err
+}
+
+func (r kerberosPrincipalRule) replaceSubstitution(base string) string {
+	if r.from == nil {
+		return base
+	}
+	if r.repeat {
+		return r.from.ReplaceAllString(base, r.to)
+	}
+	loc := r.from.FindStringSubmatchIndex(base)
+	if loc == nil {
+		return base
+	}
+	return base[:loc[0]] + string(r.from.ExpandString(nil, r.to, base, loc)) + base[loc[1]:]
+}
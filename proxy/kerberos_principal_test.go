@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKerberosPrincipalRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      []string
+		components []string
+		realm      string
+		shortName  string
+		errorMsg   string
+	}{
+		{
+			name:       "default rule, default realm",
+			components: []string{"alice"},
+			realm:      "EXAMPLE.COM",
+			shortName:  "alice",
+		},
+		{
+			name:       "default rule, service principal",
+			components: []string{"kafka", "host.example.com"},
+			realm:      "EXAMPLE.COM",
+			shortName:  "kafka",
+		},
+		{
+			name:       "default rule, other realm",
+			components: []string{"alice"},
+			realm:      "OTHER.COM",
+			errorMsg:   "no principal to local rules apply to alice@OTHER.COM",
+		},
+		{
+			name:       "strip realm",
+			rules:      []string{`RULE:[1:$1@$0](.*@OTHER\.COM)s/@.*//`, "DEFAULT"},
+			components: []string{"alice"},
+			realm:      "OTHER.COM",
+			shortName:  "alice",
+		},
+		{
+			name:       "service principal to lower case",
+			rules:      []string{"RULE:[2:$1](Kafka)/L"},
+			components: []string{"Kafka", "host.example.com"},
+			realm:      "OTHER.COM",
+			shortName:  "kafka",
+		},
+		{
+			name:       "replace all",
+			rules:      []string{"RULE:[1:$1]s/a/x/g"},
+			components: []string{"banana"},
+			realm:      "EXAMPLE.COM",
+			shortName:  "bxnxnx",
+		},
+		{
+			name:       "replace first to upper case",
+			rules:      []string{"RULE:[1:$1]s/a/x//U"},
+			components: []string{"banana"},
+			realm:      "EXAMPLE.COM",
+			shortName:  "BXNANA",
+		},
+		{
+			name:       "components do not match",
+			rules:      []string{"RULE:[2:$1@$0]"},
+			components: []string{"alice"},
+			realm:      "EXAMPLE.COM",
+			errorMsg:   "no principal to local rules apply to alice@EXAMPLE.COM",
+		},
+		{
+			name:       "parameter out of range",
+			rules:      []string{"RULE:[1:$2]"},
+			components: []string{"alice"},
+			realm:      "EXAMPLE.COM",
+			errorMsg:   "index 2 from $2 is outside of the valid range 0 to 1",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			rules, err := parseKerberosPrincipalRules(tc.rules)
+			a.Nil(err)
+			shortName, err := rules.shortName("EXAMPLE.COM", tc.components, tc.realm)
+			if tc.errorMsg == "" {
+				a.Nil(err)
+				a.Equal(tc.shortName, shortName)
+			} else if a.NotNil(err) {
+				a.Equal(tc.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseKerberosPrincipalRulesInvalid(t *testing.T) {
+	for _, rule := range []string{"RULE:1:$1", "RULE:[1:$1](", "RULE:[1:$1]s/(/x/", "DEFAULTS"} {
+		_, err := parseKerberosPrincipalRules([]string{rule})
+		assert.NotNil(t, err, rule)
+	}
+}
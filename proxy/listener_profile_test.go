@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileListeners(t *testing.T) {
+	a := assert.New(t)
+
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+
+	internalPort, externalPort := freePort(t), freePort(t)
+	c := config.NewConfig()
+	a.Nil(c.InitBootstrapServers([]string{
+		"192.168.99.100:32400,127.0.0.1:" + internalPort,
+		"192.168.99.100:32400,127.0.0.1:" + externalPort,
+	}))
+	profile := config.ListenerProfile{Name: "external", Listeners: []string{"127.0.0.1:" + externalPort}}
+	profile.TLS.Enable = true
+	profile.TLS.ListenerCertFile = bundle.ServerCert.Name()
+	profile.TLS.ListenerKeyFile = bundle.ServerKey.Name()
+	profile.TLS.DetectTimeout = c.Proxy.TLS.DetectTimeout
+	c.Proxy.ListenerProfiles = []config.ListenerProfile{profile}
+
+	listeners, err := NewListeners(c)
+	a.Nil(err)
+	a.Len(listeners.brokerToListenerConfig, 1)
+	a.Equal("127.0.0.1:"+internalPort, listeners.brokerToListenerConfig["192.168.99.100:32400"].ListenerAddress)
+
+	profileListeners, err := listeners.NewProfileListeners(c, profile)
+	a.Nil(err)
+	a.Len(profileListeners.brokerToListenerConfig, 1)
+	a.Equal("127.0.0.1:"+externalPort, profileListeners.brokerToListenerConfig["192.168.99.100:32400"].ListenerAddress)
+
+	connSrc, err := listeners.ListenInstances(c.Proxy.BootstrapServers)
+	a.Nil(err)
+	_, err = profileListeners.ListenInstances(c.Proxy.BootstrapServers)
+	a.Nil(err)
+
+	internalClient, err := net.Dial("tcp", "127.0.0.1:"+internalPort)
+	a.Nil(err)
+	defer internalClient.Close()
+	internalConn := receiveConn(t, connSrc)
+	defer internalConn.LocalConnection.Close()
+	a.Equal(config.DefaultListenerProfileName, internalConn.ListenerProfile)
+	_, ok := internalConn.LocalConnection.(*tls.Conn)
+	a.False(ok)
+
+	rawConn, err := net.Dial("tcp", "127.0.0.1:"+externalPort)
+	a.Nil(err)
+	externalClient := tls.Client(rawConn, &tls.Config{InsecureSkipVerify: true})
+	defer externalClient.Close()
+	externalConn := receiveConn(t, connSrc)
+	defer externalConn.LocalConnection.Close()
+	a.Equal("external", externalConn.ListenerProfile)
+	_, ok = externalConn.LocalConnection.(*tls.Conn)
+	a.True(ok)
+	assertEcho(t, externalClient, externalConn.LocalConnection)
+}
+
+func TestClientProfileProcessorConfig(t *testing.T) {
+	a := assert.New(t)
+
+	c := config.NewConfig()
+	c.Kafka.ForbiddenApiKeys = []int{20}
+	client, err := NewClient(NewConnSet(), c, nil, nil, nil, nil, nil, nil, nil)
+	a.Nil(err)
+
+	profile := config.ListenerProfile{Name: "internal", ForbiddenApiKeys: []int{37}}
+	a.Nil(client.AddListenerProfile(profile, nil, nil))
+	a.NotNil(client.AddListenerProfile(profile, nil, nil))
+
+	processorConfig, err := client.getProcessorConfig(config.DefaultListenerProfileName)
+	a.Nil(err)
+	a.Equal(map[int16]struct{}{20: {}}, processorConfig.ForbiddenApiKeys)
+
+	processorConfig, err = client.getProcessorConfig("internal")
+	a.Nil(err)
+	a.Equal(map[int16]struct{}{37: {}}, processorConfig.ForbiddenApiKeys)
+	a.False(processorConfig.LocalSasl.enabled)
+
+	_, err = client.getProcessorConfig("unknown")
+	a.NotNil(err)
+
+	profile = config.ListenerProfile{Name: "external"}
+	profile.LocalAuth.Enable = true
+	a.NotNil(client.AddListenerProfile(profile, nil, nil))
+}
+
+func TestClientProfileSameClientCert(t *testing.T) {
+	a := assert.New(t)
+
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+
+	c := config.NewConfig()
+	c.Kafka.TLS.Enable = true
+	c.Kafka.TLS.CAChainCertFile = bundle.CACert.Name()
+	c.Kafka.TLS.ClientCertFile = bundle.ClientCert.Name()
+	c.Kafka.TLS.ClientKeyFile = bundle.ClientKey.Name()
+	c.Kafka.TLS.SameClientCertEnable = true
+	client, err := NewClient(NewConnSet(), c, nil, nil, nil, nil, nil, nil, nil)
+	a.Nil(err)
+
+	plaintext := config.ListenerProfile{Name: "plaintext"}
+	a.Nil(client.AddListenerProfile(plaintext, nil, nil))
+	secure := config.ListenerProfile{Name: "secure"}
+	secure.TLS.Enable = true
+	a.Nil(client.AddListenerProfile(secure, nil, nil))
+
+	processorConfig, err := client.getProcessorConfig(config.DefaultListenerProfileName)
+	a.Nil(err)
+	a.NotNil(processorConfig.SameClientCert)
+
+	processorConfig, err = client.getProcessorConfig("plaintext")
+	a.Nil(err)
+	a.Nil(processorConfig.SameClientCert)
+
+	processorConfig, err = client.getProcessorConfig("secure")
+	a.Nil(err)
+	a.NotNil(processorConfig.SameClientCert)
+}
+
+func freePort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
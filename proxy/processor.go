@@ -1,9 +1,12 @@
 package proxy
 
 import (
+	"crypto/x509"
 	"errors"
 	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
 	"github.com/grepplabs/kafka-proxy/proxy/protocol"
+	"sync"
 	"time"
 )
 
@@ -42,6 +45,20 @@ type ProcessorConfig struct {
 	AuthServer            *AuthServer
 	ForbiddenApiKeys      map[int16]struct{}
 	ProducerAcks0Disabled bool
+	// BrokerSaslSession re-authenticates the broker connection, nil when the proxy does not re-authenticate
+	BrokerSaslSession *BrokerSaslSession
+	// BrokerCredentialsSession authenticates the broker connection after the local authentication, nil without per-client broker credentials
+	BrokerCredentialsSession *BrokerCredentialsSession
+	// BrokerClientCertSession replaces the broker connection after the local authentication, nil without client certificates issued for the principal
+	BrokerClientCertSession *BrokerClientCertSession
+	// LocalConnectionInfoFunc describes the client connection for the local auth plugins
+	LocalConnectionInfoFunc func() apis.ConnectionInfo
+	// ClientCertIdentityFunc returns the identity of the verified client certificate matched by the client certificate rules of the listener
+	ClientCertIdentityFunc func(*x509.Certificate) string
+	// SameClientCert is the client certificate of the broker connections which the client must present, nil when it is not required
+	SameClientCert *x509.Certificate
+	// name of the upstream cluster used in metrics
+	Cluster string
 }
 
 type processor struct {
@@ -57,10 +74,18 @@ type processor struct {
 
 	localSasl  *LocalSasl
 	authServer *AuthServer
+	// localWriteLock serializes the responses to the client, local SASL re-authentication and broker responses share the connection
+	localWriteLock *sync.Mutex
+
+	brokerSaslSession        *BrokerSaslSession
+	brokerCredentialsSession *BrokerCredentialsSession
+	brokerClientCertSession  *BrokerClientCertSession
+	localConnectionInfoFunc  func() apis.ConnectionInfo
 
 	forbiddenApiKeys map[int16]struct{}
 	// metrics
 	brokerAddress string
+	cluster       string
 	// producer will never send request with acks=0
 	producerAcks0Disabled bool
 }
@@ -103,8 +128,14 @@ func newProcessor(cfg ProcessorConfig, brokerAddress string) *processor {
 		readTimeout:                readTimeout,
 		writeTimeout:               writeTimeout,
 		brokerAddress:              brokerAddress,
+		cluster:                    cfg.Cluster,
 		localSasl:                  cfg.LocalSasl,
 		authServer:                 cfg.AuthServer,
+		localWriteLock:             &sync.Mutex{},
+		brokerSaslSession:          cfg.BrokerSaslSession,
+		brokerCredentialsSession:   cfg.BrokerCredentialsSession,
+		brokerClientCertSession:    cfg.BrokerClientCertSession,
+		localConnectionInfoFunc:    cfg.LocalConnectionInfoFunc,
 		forbiddenApiKeys:           cfg.ForbiddenApiKeys,
 		producerAcks0Disabled:      cfg.ProducerAcks0Disabled,
 	}
@@ -127,10 +158,16 @@ func (p *processor) RequestsLoop(dst DeadlineWriter, src DeadlineReaderWriter) (
 		nextResponseHandlerChannel: p.nextResponseHandlerChannel,
 		timeout:                    p.writeTimeout,
 		brokerAddress:              p.brokerAddress,
+		cluster:                    p.cluster,
 		forbiddenApiKeys:           p.forbiddenApiKeys,
 		buf:                        make([]byte, p.requestBufferSize),
 		localSasl:                  p.localSasl,
 		localSaslDone:              false, // sequential processing - mutex is required
+		localWriteLock:             p.localWriteLock,
+		brokerSaslSession:          p.brokerSaslSession,
+		brokerCredentialsSession:   p.brokerCredentialsSession,
+		brokerClientCertSession:    p.brokerClientCertSession,
+		localConnectionInfoFunc:    p.localConnectionInfoFunc,
 		producerAcks0Disabled:      p.producerAcks0Disabled,
 	}
 
@@ -144,11 +181,25 @@ type RequestsLoopContext struct {
 
 	timeout          time.Duration
 	brokerAddress    string
+	cluster          string
 	forbiddenApiKeys map[int16]struct{}
 	buf              []byte // bufSize
 
 	localSasl     *LocalSasl
 	localSaslDone bool
+	// localSaslExpiry is the end of the local SASL session, zero when the session does not expire
+	localSaslExpiry time.Time
+	// localSaslPrincipal is the principal authenticated by the local SASL, empty before authentication or for mechanisms without principal
+	localSaslPrincipal apis.Principal
+	// localConnectionInfoFunc describes the client connection for the local auth plugins
+	localConnectionInfoFunc func() apis.ConnectionInfo
+	localWriteLock          *sync.Mutex
+
+	brokerSaslSession *BrokerSaslSession
+	// brokerCredentialsSession authenticates the broker connection after the local authentication, nil without per-client broker credentials
+	brokerCredentialsSession *BrokerCredentialsSession
+	// brokerClientCertSession replaces the broker connection after the local authentication, nil without client certificates issued for the principal
+	brokerClientCertSession *BrokerClientCertSession
 
 	producerAcks0Disabled bool
 }
@@ -171,6 +222,11 @@ func (ctx *RequestsLoopContext) putNextHandlers(nextRequestHandler RequestHandle
 	default:
 		return errors.New("next request handler channel is full")
 	}
+	return ctx.putNextResponseHandler(nextResponseHandler)
+}
+
+// used by requests sent by the proxy itself
+func (ctx *RequestsLoopContext) putNextResponseHandler(nextResponseHandler ResponseHandler) error {
 
 	select {
 	case ctx.nextResponseHandlerChannel <- nextResponseHandler:
@@ -219,7 +275,14 @@ func (p *processor) ResponsesLoop(dst DeadlineWriter, src DeadlineReader) (readE
 		netAddressMappingFunc:      p.netAddressMappingFunc,
 		timeout:                    p.readTimeout,
 		brokerAddress:              p.brokerAddress,
+		cluster:                    p.cluster,
 		buf:                        make([]byte, p.responseBufferSize),
+		localWriteLock:             p.localWriteLock,
+	}
+	if p.brokerSaslSession != nil {
+		ctx.brokerSaslResponses = p.brokerSaslSession.responses
+	} else if p.brokerCredentialsSession != nil {
+		ctx.brokerSaslResponses = p.brokerCredentialsSession.responses
 	}
 	return ctx.responsesLoop(dst, src)
 }
@@ -230,7 +293,11 @@ type ResponsesLoopContext struct {
 	netAddressMappingFunc      config.NetAddressMappingFunc
 	timeout                    time.Duration
 	brokerAddress              string
+	cluster                    string
 	buf                        []byte // bufSize
+	localWriteLock             *sync.Mutex
+	// brokerSaslResponses receives the responses to the SASL authentication of the proxy in the requests loop, nil when the proxy does not authenticate there
+	brokerSaslResponses chan<- []byte
 }
 
 type ResponseHandler interface {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
 	"github.com/grepplabs/kafka-proxy/proxy/protocol"
 	"github.com/sirupsen/logrus"
 	"io"
@@ -44,43 +45,67 @@ func (handler *DefaultRequestHandler) handleRequest(dst DeadlineWriter, src Dead
 		return true, fmt.Errorf("api key %d is invalid, possible cause: using plain connection instead of TLS", requestKeyVersion.ApiKey)
 	}
 
-	proxyRequestsTotal.WithLabelValues(ctx.brokerAddress, strconv.Itoa(int(requestKeyVersion.ApiKey)), strconv.Itoa(int(requestKeyVersion.ApiVersion))).Inc()
-	proxyRequestsBytes.WithLabelValues(ctx.brokerAddress).Add(float64(requestKeyVersion.Length + 4))
+	proxyRequestsTotal.WithLabelValues(ctx.brokerAddress, strconv.Itoa(int(requestKeyVersion.ApiKey)), strconv.Itoa(int(requestKeyVersion.ApiVersion)), ctx.cluster).Inc()
+	proxyRequestsBytes.WithLabelValues(ctx.brokerAddress, ctx.cluster).Add(float64(requestKeyVersion.Length + 4))
 
 	if _, ok := ctx.forbiddenApiKeys[requestKeyVersion.ApiKey]; ok {
 		return true, fmt.Errorf("api key %d is forbidden", requestKeyVersion.ApiKey)
 	}
 
 	if ctx.localSasl.enabled {
-		if ctx.localSaslDone {
-			if requestKeyVersion.ApiKey == apiKeySaslHandshake {
-				return false, errors.New("SASL Auth was already done")
+		switch {
+		case requestKeyVersion.ApiKey == apiKeySaslHandshake:
+			if ctx.localSaslDone && requestKeyVersion.ApiVersion != 1 {
+				return false, errors.New("SASL re-authentication requires SaslHandshake version 1")
 			}
-		} else {
-			switch requestKeyVersion.ApiKey {
-			case apiKeySaslHandshake:
-				switch requestKeyVersion.ApiVersion {
-				case 0:
-					if err = ctx.localSasl.receiveAndSendSASLAuthV0(src, keyVersionBuf); err != nil {
-						return true, err
-					}
-				case 1:
-					if err = ctx.localSasl.receiveAndSendSASLAuthV1(src, keyVersionBuf); err != nil {
-						return true, err
-					}
-				default:
-					return true, fmt.Errorf("only saslHandshake version 0 and 1 are supported, got version %d", requestKeyVersion.ApiVersion)
+			var session localSaslSession
+			if session, err = ctx.localSaslAuthenticate(src, keyVersionBuf, requestKeyVersion.ApiVersion); err != nil {
+				return true, err
+			}
+			// the broker connection presenting the client certificate of the principal replaces the connection used before the local authentication
+			if ctx.brokerClientCertSession != nil {
+				if err = ctx.brokerClientCertSession.reconnect(ctx, session); err != nil {
+					return false, err
 				}
-				ctx.localSaslDone = true
-				if err = src.SetDeadline(time.Time{}); err != nil {
+			}
+			// the broker connection is authenticated with the client credentials after the local write lock is released
+			if ctx.brokerCredentialsSession != nil {
+				if err = ctx.brokerCredentialsSession.authenticate(dst, ctx, session); err != nil {
 					return false, err
 				}
-				// defaultRequestHandler was consumed but due to local handling enqueued defaultResponseHandler will not be.
-				return false, ctx.putNextRequestHandler(defaultRequestHandler)
-			case apiKeyApiApiVersions:
-				// continue processing
-			default:
-				return false, errors.New("SASL Auth is required. Only SaslHandshake or ApiVersions requests are allowed")
+			}
+			// defaultRequestHandler was consumed but due to local handling enqueued defaultResponseHandler will not be.
+			return false, ctx.putNextRequestHandler(defaultRequestHandler)
+		case ctx.localSaslDone:
+			if !ctx.localSaslExpiry.IsZero() && time.Now().After(ctx.localSaslExpiry) {
+				proxyLocalAuthSessionExpiredTotal.Inc()
+				return false, errors.New("SASL session expired, re-authentication is required")
+			}
+		case requestKeyVersion.ApiKey != apiKeyApiApiVersions:
+			return false, errors.New("SASL Auth is required. Only SaslHandshake or ApiVersions requests are allowed")
+		}
+	}
+
+	if ctx.brokerCredentialsSession != nil {
+		if requestKeyVersion.ApiKey == apiKeySaslAuthenticate {
+			return true, errors.New("SASL requests are not forwarded, proxy authenticates the broker connection")
+		}
+		// the broker connection is opened after the local authentication
+		if !ctx.brokerCredentialsSession.authenticated && requestKeyVersion.ApiKey == apiKeyApiApiVersions {
+			if err = ctx.receiveAndSendApiVersions(src, requestKeyVersion, ctx.brokerCredentialsSession.apiVersions); err != nil {
+				return true, err
+			}
+			// defaultRequestHandler was consumed but due to local handling enqueued defaultResponseHandler will not be.
+			return false, ctx.putNextRequestHandler(defaultRequestHandler)
+		}
+	}
+	if ctx.brokerSaslSession != nil {
+		if requestKeyVersion.ApiKey == apiKeySaslHandshake || requestKeyVersion.ApiKey == apiKeySaslAuthenticate {
+			return true, errors.New("SASL requests are not forwarded, proxy re-authenticates the broker connection")
+		}
+		if ctx.brokerSaslSession.reauthenticationDue() {
+			if err = ctx.brokerSaslSession.reauthenticate(dst, ctx); err != nil {
+				return false, err
 			}
 		}
 	}
@@ -133,6 +158,41 @@ func (handler *DefaultRequestHandler) handleRequest(dst DeadlineWriter, src Dead
 	}
 }
 
+// localSaslAuthenticate runs the local SASL exchange, KIP-368 re-authentication replaces the session of the connection
+func (ctx *RequestsLoopContext) localSaslAuthenticate(src DeadlineReaderWriter, keyVersionBuf []byte, apiVersion int16) (session localSaslSession, err error) {
+	// responses to the in-flight requests must not interleave with the SASL responses
+	ctx.localWriteLock.Lock()
+	defer ctx.localWriteLock.Unlock()
+
+	var connection apis.ConnectionInfo
+	if ctx.localConnectionInfoFunc != nil {
+		connection = ctx.localConnectionInfoFunc()
+	}
+	switch apiVersion {
+	case 0:
+		if session, err = ctx.localSasl.receiveAndSendSASLAuthV0(src, keyVersionBuf, connection); err != nil {
+			return localSaslSession{}, err
+		}
+	case 1:
+		if session, err = ctx.localSasl.receiveAndSendSASLAuthV1(src, keyVersionBuf, connection); err != nil {
+			return localSaslSession{}, err
+		}
+	default:
+		return localSaslSession{}, fmt.Errorf("only saslHandshake version 0 and 1 are supported, got version %d", apiVersion)
+	}
+	if session.principal.Name != "" {
+		logrus.Infof("Local SASL %s authenticated principal %s groups %v from %s", session.mechanism, session.principal.Name, session.principal.Groups, connection.ClientAddress)
+		proxyLocalAuthAuthenticatedTotal.WithLabelValues(session.mechanism, connection.ListenerProfile).Inc()
+	}
+	ctx.localSaslDone = true
+	ctx.localSaslPrincipal = session.principal
+	ctx.localSaslExpiry = time.Time{}
+	if session.lifetime > 0 {
+		ctx.localSaslExpiry = time.Now().Add(session.lifetime)
+	}
+	return session, src.SetDeadline(time.Time{})
+}
+
 func (handler *DefaultRequestHandler) mustReply(requestKeyVersion *protocol.RequestKeyVersion, src io.Reader, ctx *RequestsLoopContext) (bool, []byte, error) {
 	if requestKeyVersion.ApiKey == apiKeyProduce {
 		if ctx.producerAcks0Disabled {
@@ -196,12 +256,19 @@ func (handler *DefaultResponseHandler) handleResponse(dst DeadlineWriter, src De
 		return true, err
 	}
 
+	// the response is written as a whole, local SASL re-authentication waits for it
+	ctx.localWriteLock.Lock()
+	defer ctx.localWriteLock.Unlock()
+
 	// Read the inFlightRequests channel after header is read. Otherwise the channel would block and socket EOF from remote would not be received.
 	requestKeyVersion, err := receiveRequestKeyVersion(ctx.openRequestsChannel, openRequestReceiveTimeout)
 	if err != nil {
 		return true, err
 	}
-	proxyResponsesBytes.WithLabelValues(ctx.brokerAddress).Add(float64(responseHeader.Length + 4))
+	proxyResponsesBytes.WithLabelValues(ctx.brokerAddress, ctx.cluster).Add(float64(responseHeader.Length + 4))
+	if ctx.brokerSaslResponses != nil && (requestKeyVersion.ApiKey == apiKeySaslHandshake || requestKeyVersion.ApiKey == apiKeySaslAuthenticate) {
+		return ctx.passBrokerSaslResponse(src, responseHeaderBuf, responseHeader.Length)
+	}
 	logrus.Debugf("Kafka response key %v, version %v, length %v", requestKeyVersion.ApiKey, requestKeyVersion.ApiVersion, responseHeader.Length)
 
 	responseDeadline := time.Now().Add(ctx.timeout)
@@ -269,6 +336,22 @@ func (handler *DefaultResponseHandler) handleResponse(dst DeadlineWriter, src De
 	return false, nil // continue nextResponse
 }
 
+// passBrokerSaslResponse passes the response to the SASL re-authentication of the proxy instead of the client
+func (ctx *ResponsesLoopContext) passBrokerSaslResponse(src DeadlineReader, responseHeaderBuf []byte, length int32) (readErr bool, err error) {
+	if length > protocol.MaxResponseSize {
+		return true, protocol.PacketDecodingError{Info: fmt.Sprintf("SASL response of length %d too large", length)}
+	}
+	if err = src.SetReadDeadline(time.Now().Add(ctx.timeout)); err != nil {
+		return true, err
+	}
+	response := make([]byte, len(responseHeaderBuf)+int(length)-4)
+	copy(response, responseHeaderBuf)
+	if _, err = io.ReadFull(src, response[len(responseHeaderBuf):]); err != nil {
+		return true, err
+	}
+	return false, sendBrokerSaslResponse(ctx.brokerSaslResponses, response, ctx.timeout)
+}
+
 func sendRequestKeyVersion(openRequestsChannel chan<- protocol.RequestKeyVersion, timeout time.Duration, request *protocol.RequestKeyVersion) error {
 	select {
 	case openRequestsChannel <- *request:
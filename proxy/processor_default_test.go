@@ -2,7 +2,9 @@ package proxy
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
+	"sync"
 	"testing"
 	"time"
 
@@ -263,7 +265,7 @@ func TestHandleResponse(t *testing.T) {
 		openRequestsChannel := make(chan protocol.RequestKeyVersion, 1)
 		openRequestsChannel <- protocol.RequestKeyVersion{ApiKey: tc.apiKey, ApiVersion: tc.apiVersion}
 
-		ctx := &ResponsesLoopContext{openRequestsChannel: openRequestsChannel, timeout: 1 * time.Second, buf: buf, netAddressMappingFunc: netAddressMappingFunc}
+		ctx := &ResponsesLoopContext{openRequestsChannel: openRequestsChannel, timeout: 1 * time.Second, buf: buf, netAddressMappingFunc: netAddressMappingFunc, localWriteLock: &sync.Mutex{}}
 
 		a := assert.New(t)
 		_, err = defaultResponseHandler.handleResponse(dst, src, ctx)
@@ -314,3 +316,99 @@ func (w *TestDeadlineReaderWriter) Read(p []byte) (n int, err error) {
 func (w *TestDeadlineReaderWriter) Write(p []byte) (n int, err error) {
 	return w.reader.Write(p)
 }
+
+func newTestLocalSaslRequest(t *testing.T, apiVersion int16, mechanism string) []byte {
+	reqBuf, err := protocol.Encode(&protocol.Request{CorrelationID: 1, ClientID: "test-client", Body: &protocol.SaslHandshakeRequestV0orV1{Version: apiVersion, Mechanism: mechanism}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizeBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(sizeBuf, uint32(len(reqBuf)))
+	// SaslAuthenticate v1 with PLAIN my-test-user / my-test-password
+	authBuf, err := hex.DecodeString("00000040002400010000000200144b61666b614578616d706c6550726f64756365720000001e006d792d746573742d75736572006d792d746573742d70617373776f7264")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Join([][]byte{sizeBuf, reqBuf, authBuf}, nil)
+}
+
+func TestHandleRequestLocalSaslReauthentication(t *testing.T) {
+	a := assert.New(t)
+
+	conn := &fakeDeadlineReaderWriter{
+		reader: bytes.NewBuffer(newTestLocalSaslRequest(t, 1, SASLPlain)),
+		writer: new(bytes.Buffer),
+	}
+	ctx := &RequestsLoopContext{
+		nextRequestHandlerChannel: make(chan RequestHandler, 1),
+		localSasl: NewLocalSasl(LocalSaslParams{
+			enabled:             true,
+			timeout:             5 * time.Second,
+			localAuthenticators: map[string]LocalSaslAuth{SASLPlain: NewLocalSaslPlain(&fakePasswordAuthenticator{Username: "my-test-user", Password: "my-test-password"})},
+			maxSessionLifetime:  time.Hour,
+		}),
+		localSaslDone: true,
+		// re-authentication is accepted after the session expiry
+		localSaslExpiry: time.Now().Add(-time.Second),
+		localWriteLock:  &sync.Mutex{},
+	}
+	_, err := defaultRequestHandler.handleRequest(&TestDeadlineWriter{Buffer: new(bytes.Buffer)}, conn, ctx)
+	a.Nil(err)
+	a.Empty(conn.reader.Bytes())
+	a.WithinDuration(time.Now().Add(time.Hour), ctx.localSaslExpiry, time.Minute)
+
+	// skip SaslHandshake response
+	written := conn.writer.Bytes()
+	written = written[4+binary.BigEndian.Uint32(written[:4]):]
+	res := &protocol.SaslAuthenticateResponseV1{}
+	a.Nil(protocol.Decode(written[8:], res))
+	a.Equal(protocol.ErrNoError, res.Err)
+	a.InDelta(time.Hour.Milliseconds(), res.SessionLifetimeMs, float64(time.Minute.Milliseconds()))
+}
+
+func TestHandleRequestLocalSaslSession(t *testing.T) {
+	metadataRequest, err := hex.DecodeString("000000190003000000000001000b746573742d636c69656e74ffffffff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		request  []byte
+		expiry   time.Time
+		errorMsg string
+	}{
+		{
+			name:     "expired session",
+			request:  metadataRequest,
+			expiry:   time.Now().Add(-time.Second),
+			errorMsg: "SASL session expired, re-authentication is required",
+		},
+		{
+			name:     "re-authentication with SaslHandshake v0",
+			request:  newTestLocalSaslRequest(t, 0, SASLPlain),
+			expiry:   time.Now().Add(time.Hour),
+			errorMsg: "SASL re-authentication requires SaslHandshake version 1",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			conn := &fakeDeadlineReaderWriter{
+				reader: bytes.NewBuffer(tc.request),
+				writer: new(bytes.Buffer),
+			}
+			ctx := &RequestsLoopContext{
+				localSasl:       &LocalSasl{enabled: true},
+				localSaslDone:   true,
+				localSaslExpiry: tc.expiry,
+				localWriteLock:  &sync.Mutex{},
+			}
+			_, err := defaultRequestHandler.handleRequest(&TestDeadlineWriter{Buffer: new(bytes.Buffer)}, conn, ctx)
+			if a.NotNil(err) {
+				a.Equal(tc.errorMsg, err.Error())
+			}
+			a.Empty(conn.writer.Bytes())
+		})
+	}
+}
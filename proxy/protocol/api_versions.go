@@ -0,0 +1,121 @@
+package protocol
+
+import "github.com/pkg/errors"
+
+// ApiVersionsMaxVersion is the highest ApiVersions version which can be encoded, version 3 is the first flexible version
+const ApiVersionsMaxVersion = int16(3)
+
+type ApiVersionsRequestV0 struct {
+}
+
+func (r *ApiVersionsRequestV0) encode(pe packetEncoder) error {
+	return nil
+}
+
+func (r *ApiVersionsRequestV0) decode(pd packetDecoder) error {
+	return nil
+}
+
+func (r *ApiVersionsRequestV0) key() int16 {
+	return 18
+}
+
+func (r *ApiVersionsRequestV0) version() int16 {
+	return 0
+}
+
+// ApiVersion is the version range of the api key supported by the broker
+type ApiVersion struct {
+	ApiKey     int16
+	MinVersion int16
+	MaxVersion int16
+}
+
+// ApiVersionsResponse is the ApiVersions response of versions 0 to 3, the tagged fields of version 3 (supported features) are not encoded
+type ApiVersionsResponse struct {
+	Version        int16 // not encoded / decoded
+	Err            KError
+	ApiKeys        []ApiVersion
+	ThrottleTimeMs int32
+}
+
+func (r *ApiVersionsResponse) encode(pe packetEncoder) (err error) {
+	if r.Version < 0 || r.Version > ApiVersionsMaxVersion {
+		return errors.Errorf("ApiVersionsResponse expects version 0 to %d", ApiVersionsMaxVersion)
+	}
+	pe.putInt16(int16(r.Err))
+	if r.Version >= 3 {
+		err = pe.putCompactArrayLength(len(r.ApiKeys))
+	} else {
+		err = pe.putArrayLength(len(r.ApiKeys))
+	}
+	if err != nil {
+		return err
+	}
+	for _, apiKey := range r.ApiKeys {
+		pe.putInt16(apiKey.ApiKey)
+		pe.putInt16(apiKey.MinVersion)
+		pe.putInt16(apiKey.MaxVersion)
+		if r.Version >= 3 {
+			if err = (&TaggedFields{}).encode(pe); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Version >= 1 {
+		pe.putInt32(r.ThrottleTimeMs)
+	}
+	if r.Version >= 3 {
+		return (&TaggedFields{}).encode(pe)
+	}
+	return nil
+}
+
+func (r *ApiVersionsResponse) decode(pd packetDecoder) (err error) {
+	if r.Version < 0 || r.Version > ApiVersionsMaxVersion {
+		return errors.Errorf("ApiVersionsResponse expects version 0 to %d", ApiVersionsMaxVersion)
+	}
+	kerr, err := pd.getInt16()
+	if err != nil {
+		return err
+	}
+	r.Err = KError(kerr)
+
+	var n int
+	if r.Version >= 3 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
+	if err != nil {
+		return err
+	}
+	r.ApiKeys = make([]ApiVersion, 0, n)
+	for i := 0; i < n; i++ {
+		var apiKey ApiVersion
+		if apiKey.ApiKey, err = pd.getInt16(); err != nil {
+			return err
+		}
+		if apiKey.MinVersion, err = pd.getInt16(); err != nil {
+			return err
+		}
+		if apiKey.MaxVersion, err = pd.getInt16(); err != nil {
+			return err
+		}
+		if r.Version >= 3 {
+			if err = (&TaggedFields{}).decode(pd); err != nil {
+				return err
+			}
+		}
+		r.ApiKeys = append(r.ApiKeys, apiKey)
+	}
+	if r.Version >= 1 {
+		if r.ThrottleTimeMs, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
+	if r.Version >= 3 {
+		return (&TaggedFields{}).decode(pd)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package protocol
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncodeDecodeApiVersionsResponse(t *testing.T) {
+	tests := []struct {
+		version int16
+		hex     string
+	}{
+		{version: 0, hex: "000000000001001200000003"},
+		{version: 1, hex: "00000000000100120000000300000000"},
+		{version: 2, hex: "00000000000100120000000300000000"},
+		{version: 3, hex: "000002001200000003000000000000"},
+	}
+	for _, tt := range tests {
+		a := assert.New(t)
+		response := &ApiVersionsResponse{Version: tt.version, ApiKeys: []ApiVersion{{ApiKey: 18, MinVersion: 0, MaxVersion: 3}}}
+
+		encoded, err := Encode(response)
+		a.Nil(err)
+		a.Equal(tt.hex, hex.EncodeToString(encoded), "version %d", tt.version)
+
+		decoded := &ApiVersionsResponse{Version: tt.version}
+		a.Nil(Decode(encoded, decoded))
+		a.Equal(response, decoded)
+	}
+}
+
+func TestEncodeApiVersionsResponseUnsupportedVersion(t *testing.T) {
+	_, err := Encode(&ApiVersionsResponse{Version: 4})
+	assert.NotNil(t, err)
+}
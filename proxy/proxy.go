@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/grepplabs/kafka-proxy/config"
 	"github.com/grepplabs/kafka-proxy/pkg/libs/util"
@@ -16,9 +17,16 @@ import (
 
 type ListenFunc func(cfg *ListenerConfig) (l net.Listener, err error)
 
+// AcceptFunc prepares the accepted connection before it is passed to the proxy client
+type AcceptFunc func(cfg *ListenerConfig, conn net.Conn) (c net.Conn, err error)
+
 type Listeners struct {
 	// Source of new connections to Kafka broker.
 	connSrc chan Conn
+	// name of the listener profile
+	profile string
+	// returns name of the listener profile the bootstrap or external server listener is assigned to
+	listenerProfileFunc func(listenerAddress string) string
 	// listen IP for dynamically start
 	defaultListenerIP string
 	// advertised address for dynamic listeners
@@ -27,6 +35,7 @@ type Listeners struct {
 	tcpConnOptions TCPConnOptions
 
 	listenFunc ListenFunc
+	acceptFunc AcceptFunc
 
 	deterministicListeners    bool
 	disableDynamicListeners   bool
@@ -39,6 +48,15 @@ type Listeners struct {
 }
 
 func NewListeners(cfg *config.Config) (*Listeners, error) {
+	return newListeners(cfg, cfg.GetDefaultListenerProfile(), make(chan Conn, 1))
+}
+
+// NewProfileListeners creates listeners of the listener profile, new connections are sent to the source of p
+func (p *Listeners) NewProfileListeners(cfg *config.Config, profile config.ListenerProfile) (*Listeners, error) {
+	return newListeners(cfg, profile, p.connSrc)
+}
+
+func newListeners(cfg *config.Config, profile config.ListenerProfile, connSrc chan Conn) (*Listeners, error) {
 	tcpConnOptions := TCPConnOptions{
 		KeepAlive:       cfg.Proxy.ListenerKeepAlive,
 		ReadBufferSize:  cfg.Proxy.ListenerReadBufferSize,
@@ -46,45 +64,86 @@ func NewListeners(cfg *config.Config) (*Listeners, error) {
 	}
 
 	var tlsConfig *tls.Config
-	if cfg.Proxy.TLS.Enable {
+	if profile.TLS.Enable {
+		var err error
+		tlsConfig, err = newTLSListenerConfig(&profile.TLS)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var trustedNets []*net.IPNet
+	proxyProtocol := cfg.Proxy.ProxyProtocol.Enable
+	if proxyProtocol {
 		var err error
-		tlsConfig, err = newTLSListenerConfig(cfg)
+		trustedNets, err = parseTrustedCIDRs(cfg.Proxy.ProxyProtocol.TrustedCIDRs)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	listenFunc := func(cfg *ListenerConfig) (net.Listener, error) {
-		if tlsConfig != nil {
-			return tls.Listen("tcp", cfg.ListenerAddress, tlsConfig)
+	listenFunc := func(lc *ListenerConfig) (net.Listener, error) {
+		// PROXY protocol header precedes the TLS handshake, TLS server connection is created after the header is read
+		if tlsConfig != nil && !proxyProtocol && profile.TLS.GetListenerMode(lc.ListenerAddress) == config.ListenerTLSModeTLS {
+			return tls.Listen("tcp", lc.ListenerAddress, tlsConfig)
+		}
+		return net.Listen("tcp", lc.ListenerAddress)
+	}
+
+	var acceptFunc AcceptFunc
+	if tlsConfig != nil || proxyProtocol {
+		acceptFunc = func(lc *ListenerConfig, conn net.Conn) (net.Conn, error) {
+			if proxyProtocol {
+				var err error
+				if conn, err = acceptProxyProtocol(lc, conn, trustedNets, cfg.Proxy.ProxyProtocol.HeaderTimeout); err != nil {
+					return nil, err
+				}
+			}
+			if tlsConfig == nil {
+				return conn, nil
+			}
+			switch profile.TLS.GetListenerMode(lc.ListenerAddress) {
+			case config.ListenerTLSModeBoth:
+				return acceptTLSOrPlaintext(lc, conn, tlsConfig, profile.TLS.DetectTimeout)
+			case config.ListenerTLSModeTLS:
+				if proxyProtocol {
+					return tls.Server(conn, tlsConfig), nil
+				}
+			}
+			return conn, nil
 		}
-		return net.Listen("tcp", cfg.ListenerAddress)
 	}
 
-	brokerToListenerConfig, err := getBrokerToListenerConfig(cfg)
+	brokerToListenerConfig, err := getBrokerToListenerConfig(cfg, profile.Name)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Listeners{
 		defaultListenerIP:         cfg.Proxy.DefaultListenerIP,
-		dynamicAdvertisedListener: cfg.Proxy.DynamicAdvertisedListener,
-		connSrc:                   make(chan Conn, 1),
+		dynamicAdvertisedListener: profile.DynamicAdvertisedListener,
+		connSrc:                   connSrc,
+		profile:                   profile.Name,
+		listenerProfileFunc:       cfg.GetListenerProfileName,
 		brokerToListenerConfig:    brokerToListenerConfig,
 		tcpConnOptions:            tcpConnOptions,
 		listenFunc:                listenFunc,
+		acceptFunc:                acceptFunc,
 		deterministicListeners:    cfg.Proxy.DeterministicListeners,
 		disableDynamicListeners:   cfg.Proxy.DisableDynamicListeners,
-		dynamicSequentialMinPort:  cfg.Proxy.DynamicSequentialMinPort,
+		dynamicSequentialMinPort:  profile.DynamicSequentialMinPort,
 		currentDynamicPortCounter: 0,
-		dynamicSequentialMaxPorts: cfg.Proxy.DynamicSequentialMaxPorts,
+		dynamicSequentialMaxPorts: profile.DynamicSequentialMaxPorts,
 	}, nil
 }
 
-func getBrokerToListenerConfig(cfg *config.Config) (map[string]*ListenerConfig, error) {
+func getBrokerToListenerConfig(cfg *config.Config, profile string) (map[string]*ListenerConfig, error) {
 	brokerToListenerConfig := make(map[string]*ListenerConfig)
 
 	for _, v := range cfg.Proxy.BootstrapServers {
+		if cfg.GetListenerProfileName(v.ListenerAddress) != profile {
+			continue
+		}
 		if lc, ok := brokerToListenerConfig[v.BrokerAddress]; ok {
 			if lc.ListenerAddress != v.ListenerAddress || lc.AdvertisedAddress != v.AdvertisedAddress {
 				return nil, fmt.Errorf("bootstrap server mapping %s configured twice: %v and %v", v.BrokerAddress, v, lc.ToListenerConfig())
@@ -92,11 +151,16 @@ func getBrokerToListenerConfig(cfg *config.Config) (map[string]*ListenerConfig,
 			continue
 		}
 		logrus.Infof("Bootstrap server %s advertised as %s", v.BrokerAddress, v.AdvertisedAddress)
-		brokerToListenerConfig[v.BrokerAddress] = FromListenerConfig(v)
+		lc := FromListenerConfig(v)
+		lc.Profile = profile
+		brokerToListenerConfig[v.BrokerAddress] = lc
 	}
 
 	externalToListenerConfig := make(map[string]config.ListenerConfig)
 	for _, v := range cfg.Proxy.ExternalServers {
+		if cfg.GetListenerProfileName(v.ListenerAddress) != profile {
+			continue
+		}
 		if lc, ok := externalToListenerConfig[v.BrokerAddress]; ok {
 			if lc.ListenerAddress != v.ListenerAddress {
 				return nil, fmt.Errorf("external server mapping %s configured twice: %s and %v", v.BrokerAddress, v.ListenerAddress, lc)
@@ -117,7 +181,9 @@ func getBrokerToListenerConfig(cfg *config.Config) (map[string]*ListenerConfig,
 			continue
 		}
 		logrus.Infof("External server %s advertised as %s", v.BrokerAddress, v.AdvertisedAddress)
-		brokerToListenerConfig[v.BrokerAddress] = FromListenerConfig(v)
+		lc := FromListenerConfig(v)
+		lc.Profile = profile
+		brokerToListenerConfig[v.BrokerAddress] = lc
 	}
 	return brokerToListenerConfig, nil
 }
@@ -210,7 +276,8 @@ func (p *Listeners) ListenDynamicInstance(brokerAddress string, brokerId int32)
 		}
 	}
 	cfg := NewListenerConfig(brokerAddress, listenerAddress, "", brokerId)
-	l, err := listenInstance(p.connSrc, cfg, p.tcpConnOptions, p.listenFunc)
+	cfg.Profile = p.profile
+	l, err := listenInstance(p.connSrc, cfg, p.tcpConnOptions, p.listenFunc, p.acceptFunc)
 	if err != nil {
 		return "", 0, err
 	}
@@ -231,32 +298,36 @@ func (p *Listeners) ListenDynamicInstance(brokerAddress string, brokerId int32)
 }
 
 func (p *Listeners) getDynamicAdvertisedAddress(brokerID int32, port int) (string, int, error) {
-	dynamicAdvertisedListener := p.dynamicAdvertisedListener
-	if dynamicAdvertisedListener == "" {
+	if p.dynamicAdvertisedListener == "" {
 		return p.defaultListenerIP, port, nil
 	}
-	dynamicAdvertisedListener, err := p.templateDynamicAdvertisedAddress(brokerID)
+	return getAdvertisedAddress(p.dynamicAdvertisedListener, brokerID, port)
+}
+
+// getAdvertisedAddress executes the advertised listener template. If the template has no port, the provided port is advertised.
+func getAdvertisedAddress(advertisedListener string, brokerID int32, port int) (string, int, error) {
+	advertisedListener, err := templateAdvertisedAddress(advertisedListener, brokerID)
 	if err != nil {
 		return "", 0, err
 	}
 	var (
-		dynamicAdvertisedHost = dynamicAdvertisedListener
-		dynamicAdvertisedPort = port
+		advertisedHost = advertisedListener
+		advertisedPort = port
 	)
-	advHost, advPortStr, err := net.SplitHostPort(dynamicAdvertisedListener)
+	advHost, advPortStr, err := net.SplitHostPort(advertisedListener)
 	if err == nil {
 		if advPort, err := strconv.Atoi(advPortStr); err == nil {
-			dynamicAdvertisedHost = advHost
-			dynamicAdvertisedPort = advPort
+			advertisedHost = advHost
+			advertisedPort = advPort
 		}
 	}
-	return dynamicAdvertisedHost, dynamicAdvertisedPort, nil
+	return advertisedHost, advertisedPort, nil
 }
 
-func (p *Listeners) templateDynamicAdvertisedAddress(brokerID int32) (string, error) {
-	tmpl, err := template.New("dynamicAdvertisedHost").Option("missingkey=error").Parse(p.dynamicAdvertisedListener)
+func templateAdvertisedAddress(advertisedListener string, brokerID int32) (string, error) {
+	tmpl, err := template.New("dynamicAdvertisedHost").Option("missingkey=error").Parse(advertisedListener)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse host template '%s': %w", p.dynamicAdvertisedListener, err)
+		return "", fmt.Errorf("failed to parse host template '%s': %w", advertisedListener, err)
 	}
 	var buf bytes.Buffer
 	data := map[string]any{
@@ -265,7 +336,7 @@ func (p *Listeners) templateDynamicAdvertisedAddress(brokerID int32) (string, er
 	}
 	err = tmpl.Execute(&buf, data)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute host template '%s': %w", p.dynamicAdvertisedListener, err)
+		return "", fmt.Errorf("failed to execute host template '%s': %w", advertisedListener, err)
 	}
 	return buf.String(), nil
 }
@@ -276,8 +347,12 @@ func (p *Listeners) ListenInstances(cfgs []config.ListenerConfig) (<-chan Conn,
 
 	// allows multiple local addresses to point to the remote
 	for _, v := range cfgs {
+		if p.listenerProfileFunc(v.ListenerAddress) != p.profile {
+			continue
+		}
 		cfg := FromListenerConfig(v)
-		_, err := listenInstance(p.connSrc, cfg, p.tcpConnOptions, p.listenFunc)
+		cfg.Profile = p.profile
+		_, err := listenInstance(p.connSrc, cfg, p.tcpConnOptions, p.listenFunc, p.acceptFunc)
 		if err != nil {
 			return nil, err
 		}
@@ -285,7 +360,7 @@ func (p *Listeners) ListenInstances(cfgs []config.ListenerConfig) (<-chan Conn,
 	return p.connSrc, nil
 }
 
-func listenInstance(dst chan<- Conn, cfg *ListenerConfig, opts TCPConnOptions, listenFunc ListenFunc) (net.Listener, error) {
+func listenInstance(dst chan<- Conn, cfg *ListenerConfig, opts TCPConnOptions, listenFunc ListenFunc, acceptFunc AcceptFunc) (net.Listener, error) {
 	l, err := listenFunc(cfg)
 	if err != nil {
 		return nil, err
@@ -303,13 +378,20 @@ func listenInstance(dst chan<- Conn, cfg *ListenerConfig, opts TCPConnOptions, l
 					logrus.Infof("WARNING: Error while setting TCP options for accepted connection %q on %v: %v", cfg.ToListenerConfig(), l.Addr().String(), err)
 				}
 			}
-			brokerAddress := cfg.GetBrokerAddress()
-			if cfg.BrokerID != UnknownBrokerID {
-				logrus.Infof("New connection for %s brokerId %d", brokerAddress, cfg.BrokerID)
-			} else {
-				logrus.Infof("New connection for %s", brokerAddress)
+			if acceptFunc == nil {
+				sendConn(dst, cfg, c)
+				continue
 			}
-			dst <- Conn{BrokerAddress: brokerAddress, LocalConnection: c}
+			// accept preparation reads from the connection, it must not block the accept loop
+			go withRecover(func() {
+				conn, err := acceptFunc(cfg, c)
+				if err != nil {
+					logrus.Infof("Error while accepting connection from %v on %v: %v", c.RemoteAddr(), cfg.ListenerAddress, err)
+					_ = c.Close()
+					return
+				}
+				sendConn(dst, cfg, conn)
+			})
 		}
 	})
 	if cfg.BrokerID != UnknownBrokerID {
@@ -319,3 +401,29 @@ func listenInstance(dst chan<- Conn, cfg *ListenerConfig, opts TCPConnOptions, l
 	}
 	return l, nil
 }
+
+func sendConn(dst chan<- Conn, cfg *ListenerConfig, c net.Conn) {
+	brokerAddress := cfg.GetBrokerAddress()
+	if cfg.BrokerID != UnknownBrokerID {
+		logrus.Infof("New connection from %v for %s brokerId %d", c.RemoteAddr(), brokerAddress, cfg.BrokerID)
+	} else {
+		logrus.Infof("New connection from %v for %s", c.RemoteAddr(), brokerAddress)
+	}
+	dst <- Conn{BrokerAddress: brokerAddress, LocalConnection: c, ListenerAddress: cfg.ListenerAddress, ListenerProfile: cfg.Profile, ProxyProtocolHeader: proxyProtocolHeader(c)}
+}
+
+// acceptTLSOrPlaintext detects whether the client speaks TLS or plaintext on the listener accepting both
+func acceptTLSOrPlaintext(cfg *ListenerConfig, conn net.Conn, tlsConfig *tls.Config, timeout time.Duration) (net.Conn, error) {
+	detected, transport, err := detectTLS(conn, tlsConfig, timeout)
+	if err != nil {
+		return nil, err
+	}
+	proxyListenerConnectionsTotal.WithLabelValues(cfg.ListenerAddress, transport).Inc()
+	if transport != transportPlaintext {
+		return detected, nil
+	}
+	logrus.Debugf("Plaintext connection from %v on %s", conn.RemoteAddr(), cfg.ListenerAddress)
+	plaintextConnections := proxyListenerPlaintextConnections.WithLabelValues(cfg.ListenerAddress)
+	plaintextConnections.Inc()
+	return &closeNotifyConn{Conn: detected, onClose: plaintextConnections.Dec}, nil
+}
@@ -12,6 +12,8 @@ type ListenerConfig struct {
 	ListenerAddress   string
 	AdvertisedAddress string
 	BrokerID          int32
+	// name of the listener profile
+	Profile string
 }
 
 func FromListenerConfig(listenerConfig config.ListenerConfig) *ListenerConfig {
@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/proxy/proxyprotocol"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func parseTrustedCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	trustedNets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid PROXY protocol trusted CIDR %s", cidr)
+		}
+		trustedNets = append(trustedNets, ipNet)
+	}
+	return trustedNets, nil
+}
+
+// isTrustedSource returns true if the PROXY protocol header is accepted from the peer. Empty list trusts no peers.
+func isTrustedSource(addr net.Addr, trustedNets []*net.IPNet) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, ipNet := range trustedNets {
+		if ipNet.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyProtocolHeader returns the PROXY protocol header of the accepted connection, nil if the header was not read
+func proxyProtocolHeader(conn net.Conn) *proxyprotocol.Header {
+	for {
+		switch c := conn.(type) {
+		case *proxyprotocol.Conn:
+			return c.Header()
+		case *tls.Conn:
+			conn = c.NetConn()
+		case *peekedConn:
+			conn = c.Conn
+		case *closeNotifyConn:
+			conn = c.Conn
+		default:
+			return nil
+		}
+	}
+}
+
+// acceptProxyProtocol reads the PROXY protocol header sent by a trusted peer e.g. load balancer.
+// Connections from untrusted peers are passed unchanged, the header is not interpreted.
+func acceptProxyProtocol(cfg *ListenerConfig, conn net.Conn, trustedNets []*net.IPNet, timeout time.Duration) (net.Conn, error) {
+	if !isTrustedSource(conn.RemoteAddr(), trustedNets) {
+		logrus.Debugf("PROXY protocol header is not accepted from untrusted %v on %s", conn.RemoteAddr(), cfg.ListenerAddress)
+		proxyProtocolConnectionsTotal.WithLabelValues(cfg.ListenerAddress, "untrusted").Inc()
+		return conn, nil
+	}
+	ppConn, err := proxyprotocol.NewConn(conn, timeout)
+	if err != nil {
+		proxyProtocolConnectionsTotal.WithLabelValues(cfg.ListenerAddress, "error").Inc()
+		return nil, err
+	}
+	header := ppConn.Header()
+	proxyProtocolConnectionsTotal.WithLabelValues(cfg.ListenerAddress, "v"+strconv.Itoa(header.Version)).Inc()
+	if authority := header.Authority(); authority != "" {
+		logrus.Debugf("PROXY protocol v%d connection from %v via %v on %s, authority %s", header.Version, ppConn.RemoteAddr(), conn.RemoteAddr(), cfg.ListenerAddress, authority)
+	} else {
+		logrus.Debugf("PROXY protocol v%d connection from %v via %v on %s", header.Version, ppConn.RemoteAddr(), conn.RemoteAddr(), cfg.ListenerAddress)
+	}
+	return ppConn, nil
+}
@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/proxy/proxyprotocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func listenWithProxyProtocol(t *testing.T, c *config.Config) (net.Listener, chan Conn) {
+	c.Proxy.ProxyProtocol.Enable = true
+	c.Proxy.ProxyProtocol.HeaderTimeout = 2 * time.Second
+	if len(c.Proxy.ProxyProtocol.TrustedCIDRs) == 0 {
+		c.Proxy.ProxyProtocol.TrustedCIDRs = []string{"127.0.0.0/8"}
+	}
+	c.Proxy.BootstrapServers = []config.ListenerConfig{{BrokerAddress: "127.0.0.1:9092", ListenerAddress: "127.0.0.1:0"}}
+
+	listeners, err := NewListeners(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connSrc := make(chan Conn, 1)
+	l, err := listenInstance(connSrc, FromListenerConfig(c.Proxy.BootstrapServers[0]), listeners.tcpConnOptions, listeners.listenFunc, listeners.acceptFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, connSrc
+}
+
+func TestProxyProtocolClientAddress(t *testing.T) {
+	a := assert.New(t)
+
+	l, connSrc := listenWithProxyProtocol(t, config.NewConfig())
+	defer l.Close()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	a.Nil(err)
+	defer client.Close()
+	_, err = client.Write([]byte("PROXY TCP4 192.168.0.1 10.0.0.1 56324 32400\r\nping"))
+	a.Nil(err)
+
+	server := receiveConn(t, connSrc)
+	defer server.LocalConnection.Close()
+	a.Equal("192.168.0.1:56324", server.LocalConnection.RemoteAddr().String())
+	a.Equal("10.0.0.1:32400", server.LocalConnection.LocalAddr().String())
+}
+
+func proxyProtocolV2TLV(tlvType byte, value []byte) []byte {
+	buf := []byte{tlvType}
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(value)))
+	return append(buf, value...)
+}
+
+func TestProxyProtocolV2ConnectionInfo(t *testing.T) {
+	a := assert.New(t)
+
+	l, connSrc := listenWithProxyProtocol(t, config.NewConfig())
+	defer l.Close()
+
+	ssl := []byte{proxyprotocol.ClientSSL | proxyprotocol.ClientCertConn, 0, 0, 0, 0}
+	ssl = append(ssl, proxyProtocolV2TLV(proxyprotocol.TLVSubtypeSSLVersion, []byte("TLSv1.3"))...)
+	ssl = append(ssl, proxyProtocolV2TLV(proxyprotocol.TLVSubtypeSSLCN, []byte("alice"))...)
+	payload := append(net.ParseIP("192.168.0.1").To4(), net.ParseIP("10.0.0.1").To4()...)
+	payload = binary.BigEndian.AppendUint16(payload, 56324)
+	payload = binary.BigEndian.AppendUint16(payload, 32400)
+	payload = append(payload, proxyProtocolV2TLV(proxyprotocol.TLVTypeAuthority, []byte("kafka.example.com"))...)
+	payload = append(payload, proxyProtocolV2TLV(proxyprotocol.TLVTypeSSL, ssl)...)
+	header := append([]byte("\r\n\r\n\x00\r\nQUIT\n"), 0x21, 0x11)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	header = append(header, payload...)
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	a.Nil(err)
+	defer client.Close()
+	_, err = client.Write(append(header, []byte("ping")...))
+	a.Nil(err)
+
+	server := receiveConn(t, connSrc)
+	defer server.LocalConnection.Close()
+	a.NotNil(server.ProxyProtocolHeader)
+
+	info := localConnectionInfo(server, nil)
+	a.Equal("192.168.0.1:56324", info.ClientAddress)
+	a.Equal("kafka.example.com", info.TLSServerName)
+	a.True(info.ProxySSL)
+	a.True(info.ProxySSLClientCert)
+	a.True(info.ProxySSLClientCertVerified)
+	a.Equal("TLSv1.3", info.ProxySSLVersion)
+	a.Equal("alice", info.ProxySSLClientCN)
+
+	// the connection info of the PROXY protocol header reaches the auth plugin
+	passwordAuthenticator := &recordingPasswordAuthenticator{}
+	conversation := newLocalSaslConversation(NewLocalSaslPlain(passwordAuthenticator), info)
+	_, _, err = conversation.step([]byte("\x00alice\x00secret"))
+	a.Nil(err)
+	a.Equal("kafka.example.com", passwordAuthenticator.request.Connection.TLSServerName)
+	a.Equal("alice", passwordAuthenticator.request.Connection.ProxySSLClientCN)
+}
+
+func TestProxyProtocolBeforeTLSHandshake(t *testing.T) {
+	a := assert.New(t)
+
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+
+	c := config.NewConfig()
+	c.Proxy.TLS.Enable = true
+	c.Proxy.TLS.ListenerCertFile = bundle.ServerCert.Name()
+	c.Proxy.TLS.ListenerKeyFile = bundle.ServerKey.Name()
+	l, connSrc := listenWithProxyProtocol(t, c)
+	defer l.Close()
+
+	rawConn, err := net.Dial("tcp", l.Addr().String())
+	a.Nil(err)
+	_, err = rawConn.Write([]byte("PROXY TCP4 192.168.0.1 10.0.0.1 56324 32400\r\n"))
+	a.Nil(err)
+	client := tls.Client(rawConn, &tls.Config{InsecureSkipVerify: true})
+	defer client.Close()
+
+	server := receiveConn(t, connSrc)
+	defer server.LocalConnection.Close()
+	_, ok := server.LocalConnection.(*tls.Conn)
+	a.True(ok)
+	a.Equal("192.168.0.1:56324", server.LocalConnection.RemoteAddr().String())
+	assertEcho(t, client, server.LocalConnection)
+}
+
+func TestProxyProtocolUntrustedSource(t *testing.T) {
+	a := assert.New(t)
+
+	c := config.NewConfig()
+	c.Proxy.ProxyProtocol.TrustedCIDRs = []string{"10.0.0.0/8"}
+	l, connSrc := listenWithProxyProtocol(t, c)
+	defer l.Close()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	a.Nil(err)
+	defer client.Close()
+
+	server := receiveConn(t, connSrc)
+	defer server.LocalConnection.Close()
+	a.Equal(client.LocalAddr().String(), server.LocalConnection.RemoteAddr().String())
+}
+
+func TestProxyProtocolMissingHeader(t *testing.T) {
+	a := assert.New(t)
+
+	l, connSrc := listenWithProxyProtocol(t, config.NewConfig())
+	defer l.Close()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	a.Nil(err)
+	defer client.Close()
+	_, err = client.Write([]byte{0, 0, 0, 20, 0, 18, 0, 3})
+	a.Nil(err)
+
+	// connection is closed by the listener
+	a.Nil(client.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	_, err = client.Read(make([]byte, 1))
+	a.NotNil(err)
+	a.Equal(0, len(connSrc))
+}
+
+func TestIsTrustedSource(t *testing.T) {
+	a := assert.New(t)
+
+	trustedNets, err := parseTrustedCIDRs([]string{"10.0.0.0/8", "2001:db8::/32"})
+	a.Nil(err)
+	a.True(isTrustedSource(&net.TCPAddr{IP: net.ParseIP("10.1.2.3")}, trustedNets))
+	a.True(isTrustedSource(&net.TCPAddr{IP: net.ParseIP("2001:db8::1")}, trustedNets))
+	a.False(isTrustedSource(&net.TCPAddr{IP: net.ParseIP("192.168.0.1")}, trustedNets))
+	a.False(isTrustedSource(&net.TCPAddr{IP: net.ParseIP("192.168.0.1")}, nil))
+
+	_, err = parseTrustedCIDRs([]string{"10.0.0.1"})
+	a.NotNil(err)
+}
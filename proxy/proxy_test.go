@@ -2,9 +2,13 @@ package proxy
 
 import (
 	"fmt"
+	"net"
+	"strconv"
 	"testing"
 
 	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -269,7 +273,7 @@ func TestGetBrokerToListenerConfig(t *testing.T) {
 		c := &config.Config{}
 		c.Proxy.BootstrapServers = tt.bootstrapServers
 		c.Proxy.ExternalServers = tt.externalServers
-		brokerToListenerConfig, err := getBrokerToListenerConfig(c)
+		brokerToListenerConfig, err := getBrokerToListenerConfig(c, config.DefaultListenerProfileName)
 		a.Equal(tt.err, err)
 
 		mapping := make(map[string]config.ListenerConfig)
@@ -482,3 +486,56 @@ func TestNextDynamicPort(t *testing.T) {
 		})
 	}
 }
+
+func TestUpstreamClusterDynamicListeners(t *testing.T) {
+	a := assert.New(t)
+
+	newClusterListeners := func(cluster string) (*Listeners, uint16) {
+		port, err := strconv.Atoi(freePort(t))
+		a.Nil(err)
+		c := config.NewConfig()
+		c.Cluster = cluster
+		c.Proxy.DefaultListenerIP = "127.0.0.1"
+		c.Proxy.DeterministicListeners = true
+		c.Proxy.DynamicSequentialMinPort = uint16(port)
+		c.Proxy.DynamicSequentialMaxPorts = 1
+		listeners, err := NewListeners(c)
+		a.Nil(err)
+		return listeners, uint16(port)
+	}
+	listenersA, portA := newClusterListeners("")
+	listenersB, portB := newClusterListeners("cluster-b")
+
+	// the same broker id in both clusters is served by the listeners of own cluster
+	_, advertisedPortA, err := listenersA.ListenDynamicInstance("kafka-0:9092", 0)
+	a.Nil(err)
+	a.Equal(int32(portA), advertisedPortA)
+	_, advertisedPortB, err := listenersB.ListenDynamicInstance("kafka-0:9092", 0)
+	a.Nil(err)
+	a.Equal(int32(portB), advertisedPortB)
+}
+
+func TestCollectorClusterLabel(t *testing.T) {
+	a := assert.New(t)
+
+	connSetA, connSetB := NewConnSet(), NewClusterConnSet("cluster-b")
+	connSetA.Add("kafka-0:9092", &net.TCPConn{})
+	connSetB.Add("kafka-0:9092", &net.TCPConn{})
+	connSetB.Add("kafka-0:9092", &net.TCPConn{})
+
+	ch := make(chan prometheus.Metric, 10)
+	NewCollector(connSetA, connSetB).Collect(ch)
+	close(ch)
+	counts := make(map[string]float64)
+	for metric := range ch {
+		var m dto.Metric
+		a.Nil(metric.Write(&m))
+		labels := make(map[string]string)
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		a.Equal("kafka-0:9092", labels["broker"])
+		counts[labels["cluster"]] = m.GetGauge().GetValue()
+	}
+	a.Equal(map[string]float64{"": 1, "cluster-b": 2}, counts)
+}
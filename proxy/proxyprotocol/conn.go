@@ -0,0 +1,57 @@
+package proxyprotocol
+
+import (
+	"bufio"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Conn is a net.Conn which reports the addresses from the PROXY protocol header
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader
+	header *Header
+}
+
+// NewConn reads the PROXY protocol header from the connection within the timeout.
+func NewConn(conn net.Conn, timeout time.Duration) (*Conn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	header, err := ReadHeader(reader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read PROXY protocol header from %v", conn.RemoteAddr())
+	}
+	if err = conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn, reader: reader, header: header}, nil
+}
+
+// Header returns the PROXY protocol header
+func (c *Conn) Header() *Header {
+	return c.header
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// RemoteAddr returns the client address sent in the header or the address of the peer for LOCAL connections
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.header.Command == CommandProxy && c.header.SourceAddr != nil {
+		return c.header.SourceAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address sent in the header or the local address for LOCAL connections
+func (c *Conn) LocalAddr() net.Addr {
+	if c.header.Command == CommandProxy && c.header.DestinationAddr != nil {
+		return c.header.DestinationAddr
+	}
+	return c.Conn.LocalAddr()
+}
@@ -0,0 +1,337 @@
+package proxyprotocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Version 1 (human-readable) and version 2 (binary) header signatures.
+var (
+	signatureV1 = []byte("PROXY ")
+	signatureV2 = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+)
+
+const (
+	// maximal length of the version 1 header including CRLF
+	maxHeaderV1Length = 107
+
+	headerV2Length = 16
+
+	// address families and transport protocols of the version 2 header
+	familyUnspec = 0x0
+	familyInet   = 0x1
+	familyInet6  = 0x2
+	familyUnix   = 0x3
+
+	protocolStream = 0x1
+)
+
+// Command of the PROXY protocol header
+type Command byte
+
+const (
+	// CommandLocal connection was established by the proxy itself e.g. health check, the original addresses must be used
+	CommandLocal Command = 0x0
+	// CommandProxy connection was relayed on behalf of another node
+	CommandProxy Command = 0x1
+)
+
+// TLV types defined by the PROXY protocol specification
+const (
+	TLVTypeALPN      byte = 0x01
+	TLVTypeAuthority byte = 0x02
+	TLVTypeCRC32C    byte = 0x03
+	TLVTypeNoop      byte = 0x04
+	TLVTypeUniqueID  byte = 0x05
+	TLVTypeSSL       byte = 0x20
+	TLVTypeNetNS     byte = 0x30
+
+	TLVSubtypeSSLVersion byte = 0x21
+	TLVSubtypeSSLCN      byte = 0x22
+	TLVSubtypeSSLCipher  byte = 0x23
+	TLVSubtypeSSLSigAlg  byte = 0x24
+	TLVSubtypeSSLKeyAlg  byte = 0x25
+
+	// TLVTypeAWS is sent by AWS NLB with the VPC endpoint ID as subtype 0x01
+	TLVTypeAWS byte = 0xEA
+)
+
+// Client flags of the SSL TLV
+const (
+	ClientSSL      byte = 0x01
+	ClientCertConn byte = 0x02
+	ClientCertSess byte = 0x04
+)
+
+var (
+	// ErrNoProxyHeader is returned when the connection does not start with PROXY protocol signature
+	ErrNoProxyHeader = errors.New("PROXY protocol header not found")
+)
+
+// TLV is a type-length-value vector of the version 2 header
+type TLV struct {
+	Type  byte
+	Value []byte
+}
+
+// SSLInfo is the content of the SSL TLV
+type SSLInfo struct {
+	Client byte
+	// Verify is zero when the client presented a certificate which was successfully verified
+	Verify uint32
+	TLVs   []TLV
+}
+
+// Header is the parsed PROXY protocol header
+type Header struct {
+	Version         int
+	Command         Command
+	SourceAddr      net.Addr
+	DestinationAddr net.Addr
+	TLVs            []TLV
+}
+
+// ReadHeader reads the PROXY protocol version 1 or 2 header. ErrNoProxyHeader is returned and nothing is consumed
+// if the stream does not start with the header signature.
+func ReadHeader(r *bufio.Reader) (*Header, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case signatureV1[0]:
+		sig, err := r.Peek(len(signatureV1))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(sig, signatureV1) {
+			return nil, ErrNoProxyHeader
+		}
+		return readHeaderV1(r)
+	case signatureV2[0]:
+		sig, err := r.Peek(len(signatureV2))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(sig, signatureV2) {
+			return nil, ErrNoProxyHeader
+		}
+		return readHeaderV2(r)
+	default:
+		return nil, ErrNoProxyHeader
+	}
+}
+
+func readHeaderV1(r *bufio.Reader) (*Header, error) {
+	line := make([]byte, 0, maxHeaderV1Length)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) == maxHeaderV1Length {
+			return nil, errors.New("PROXY protocol v1 header is too long")
+		}
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errors.New("PROXY protocol v1 header must end with CRLF")
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) < 2 {
+		return nil, errors.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+	header := &Header{Version: 1, Command: CommandProxy}
+	switch fields[1] {
+	case "UNKNOWN":
+		// the receiver must ignore the rest of the line and use the real connection addresses
+		header.Command = CommandLocal
+		return header, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, errors.Errorf("unsupported PROXY protocol v1 transport %q", fields[1])
+	}
+	if len(fields) != 6 {
+		return nil, errors.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+	srcIP, dstIP := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	if srcIP == nil || dstIP == nil {
+		return nil, errors.Errorf("invalid PROXY protocol v1 addresses %q and %q", fields[2], fields[3])
+	}
+	if (fields[1] == "TCP4") != (srcIP.To4() != nil && dstIP.To4() != nil) {
+		return nil, errors.Errorf("PROXY protocol v1 addresses %q and %q do not match transport %s", fields[2], fields[3], fields[1])
+	}
+	srcPort, err := parsePortV1(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	dstPort, err := parsePortV1(fields[5])
+	if err != nil {
+		return nil, err
+	}
+	header.SourceAddr = &net.TCPAddr{IP: srcIP, Port: srcPort}
+	header.DestinationAddr = &net.TCPAddr{IP: dstIP, Port: dstPort}
+	return header, nil
+}
+
+func parsePortV1(s string) (int, error) {
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || (len(s) > 1 && s[0] == '0') {
+		return 0, errors.Errorf("invalid PROXY protocol v1 port %q", s)
+	}
+	return int(port), nil
+}
+
+func readHeaderV2(r *bufio.Reader) (*Header, error) {
+	fixed := make([]byte, headerV2Length)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+	if version := fixed[12] >> 4; version != 2 {
+		return nil, errors.Errorf("unsupported PROXY protocol version %d", version)
+	}
+	header := &Header{Version: 2, Command: Command(fixed[12] & 0x0F)}
+	if header.Command != CommandLocal && header.Command != CommandProxy {
+		return nil, errors.Errorf("unsupported PROXY protocol v2 command %d", header.Command)
+	}
+	family, protocol := fixed[13]>>4, fixed[13]&0x0F
+	payload := make([]byte, binary.BigEndian.Uint16(fixed[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	var addressLength int
+	switch family {
+	case familyUnspec:
+		addressLength = 0
+	case familyInet:
+		addressLength = 12
+	case familyInet6:
+		addressLength = 36
+	case familyUnix:
+		addressLength = 216
+	default:
+		return nil, errors.Errorf("unsupported PROXY protocol v2 address family %d", family)
+	}
+	if len(payload) < addressLength {
+		return nil, errors.Errorf("PROXY protocol v2 header is too short for address family %d", family)
+	}
+	tlvs, err := parseTLVs(payload[addressLength:])
+	if err != nil {
+		return nil, err
+	}
+	header.TLVs = tlvs
+
+	if header.Command == CommandLocal {
+		return header, nil
+	}
+	addresses := payload[:addressLength]
+	switch family {
+	case familyInet, familyInet6:
+		if protocol != protocolStream {
+			return nil, errors.Errorf("unsupported PROXY protocol v2 transport protocol %d", protocol)
+		}
+		ipLength := (addressLength - 4) / 2
+		header.SourceAddr = &net.TCPAddr{
+			IP:   net.IP(addresses[:ipLength]),
+			Port: int(binary.BigEndian.Uint16(addresses[2*ipLength:])),
+		}
+		header.DestinationAddr = &net.TCPAddr{
+			IP:   net.IP(addresses[ipLength : 2*ipLength]),
+			Port: int(binary.BigEndian.Uint16(addresses[2*ipLength+2:])),
+		}
+	case familyUnix:
+		header.SourceAddr = &net.UnixAddr{Net: "unix", Name: string(bytes.TrimRight(addresses[:108], "\x00"))}
+		header.DestinationAddr = &net.UnixAddr{Net: "unix", Name: string(bytes.TrimRight(addresses[108:], "\x00"))}
+	}
+	return header, nil
+}
+
+func parseTLVs(buf []byte) ([]TLV, error) {
+	tlvs := make([]TLV, 0)
+	for len(buf) > 0 {
+		if len(buf) < 3 {
+			return nil, errors.New("truncated PROXY protocol v2 TLV")
+		}
+		length := int(binary.BigEndian.Uint16(buf[1:3]))
+		if len(buf) < 3+length {
+			return nil, errors.Errorf("truncated PROXY protocol v2 TLV of type 0x%02x", buf[0])
+		}
+		tlvs = append(tlvs, TLV{Type: buf[0], Value: buf[3 : 3+length]})
+		buf = buf[3+length:]
+	}
+	return tlvs, nil
+}
+
+// TLV returns the value of the first TLV with the given type
+func (h *Header) TLV(tlvType byte) ([]byte, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == tlvType {
+			return tlv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Authority returns the host name sent by the client (SNI), if any
+func (h *Header) Authority() string {
+	value, _ := h.TLV(TLVTypeAuthority)
+	return string(value)
+}
+
+// SSL returns the TLS information of the client connection terminated by the sender, if any
+func (h *Header) SSL() (*SSLInfo, error) {
+	value, ok := h.TLV(TLVTypeSSL)
+	if !ok {
+		return nil, nil
+	}
+	if len(value) < 5 {
+		return nil, errors.New("truncated PROXY protocol v2 SSL TLV")
+	}
+	tlvs, err := parseTLVs(value[5:])
+	if err != nil {
+		return nil, err
+	}
+	return &SSLInfo{
+		Client: value[0],
+		Verify: binary.BigEndian.Uint32(value[1:5]),
+		TLVs:   tlvs,
+	}, nil
+}
+
+// TLV returns the value of the first SSL sub-TLV with the given type
+func (s *SSLInfo) TLV(tlvType byte) (string, bool) {
+	for _, tlv := range s.TLVs {
+		if tlv.Type == tlvType {
+			return string(tlv.Value), true
+		}
+	}
+	return "", false
+}
+
+// Version returns the TLS version used by the client e.g. TLSv1.3
+func (s *SSLInfo) Version() string {
+	value, _ := s.TLV(TLVSubtypeSSLVersion)
+	return value
+}
+
+// CommonName returns the common name of the client certificate
+func (s *SSLInfo) CommonName() string {
+	value, _ := s.TLV(TLVSubtypeSSLCN)
+	return value
+}
+
+// ClientCertVerified returns true if the client presented a certificate which was successfully verified
+func (s *SSLInfo) ClientCertVerified() bool {
+	return s.Client&ClientSSL != 0 && s.Client&(ClientCertConn|ClientCertSess) != 0 && s.Verify == 0
+}
@@ -0,0 +1,182 @@
+package proxyprotocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func headerV2(command byte, family byte, addresses []byte, tlvs ...TLV) []byte {
+	payload := append([]byte{}, addresses...)
+	for _, tlv := range tlvs {
+		payload = append(payload, tlvBytes(tlv)...)
+	}
+	buf := append([]byte{}, signatureV2...)
+	buf = append(buf, 0x20|command, family<<4|protocolStream)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(payload)))
+	return append(buf, payload...)
+}
+
+func tlvBytes(tlv TLV) []byte {
+	buf := []byte{tlv.Type}
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(tlv.Value)))
+	return append(buf, tlv.Value...)
+}
+
+func inetAddresses(src, dst string, srcPort, dstPort uint16) []byte {
+	buf := append([]byte{}, net.ParseIP(src).To4()...)
+	buf = append(buf, net.ParseIP(dst).To4()...)
+	buf = binary.BigEndian.AppendUint16(buf, srcPort)
+	return binary.BigEndian.AppendUint16(buf, dstPort)
+}
+
+func readHeader(input []byte) (*Header, []byte, error) {
+	r := bufio.NewReader(bytes.NewReader(input))
+	header, err := ReadHeader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	rest, _ := io.ReadAll(r)
+	return header, rest, nil
+}
+
+func TestReadHeaderV1(t *testing.T) {
+	a := assert.New(t)
+
+	header, rest, err := readHeader([]byte("PROXY TCP4 192.168.0.1 10.0.0.1 56324 32400\r\nkafka"))
+	a.Nil(err)
+	a.Equal(1, header.Version)
+	a.Equal(CommandProxy, header.Command)
+	a.Equal("192.168.0.1:56324", header.SourceAddr.String())
+	a.Equal("10.0.0.1:32400", header.DestinationAddr.String())
+	a.Equal("kafka", string(rest))
+
+	header, _, err = readHeader([]byte("PROXY TCP6 2001:db8::1 2001:db8::2 56324 32400\r\n"))
+	a.Nil(err)
+	a.Equal("[2001:db8::1]:56324", header.SourceAddr.String())
+
+	header, rest, err = readHeader([]byte("PROXY UNKNOWN ffff::1 ffff::2 1 2\r\nkafka"))
+	a.Nil(err)
+	a.Equal(CommandLocal, header.Command)
+	a.Nil(header.SourceAddr)
+	a.Equal("kafka", string(rest))
+}
+
+func TestReadHeaderV1Invalid(t *testing.T) {
+	a := assert.New(t)
+
+	for _, input := range []string{
+		"PROXY TCP4 192.168.0.1 10.0.0.1 56324\r\n",
+		"PROXY TCP4 192.168.0.1 10.0.0.1 56324 32400\n",
+		"PROXY TCP4 2001:db8::1 10.0.0.1 56324 32400\r\n",
+		"PROXY TCP4 192.168.0.1 10.0.0.1 056324 32400\r\n",
+		"PROXY TCP4 192.168.0.1 10.0.0.1 65536 32400\r\n",
+		"PROXY UDP4 192.168.0.1 10.0.0.1 56324 32400\r\n",
+		"PROXY TCP4 192.168.0.1 10.0.0.1 56324 32400 " + string(bytes.Repeat([]byte("x"), 100)) + "\r\n",
+	} {
+		_, _, err := readHeader([]byte(input))
+		a.NotNil(err, input)
+	}
+}
+
+func TestReadHeaderV2(t *testing.T) {
+	a := assert.New(t)
+
+	ssl := []byte{ClientSSL | ClientCertConn, 0, 0, 0, 0}
+	ssl = append(ssl, tlvBytes(TLV{Type: TLVSubtypeSSLVersion, Value: []byte("TLSv1.3")})...)
+	ssl = append(ssl, tlvBytes(TLV{Type: TLVSubtypeSSLCN, Value: []byte("client")})...)
+
+	input := headerV2(byte(CommandProxy), familyInet, inetAddresses("192.168.0.1", "10.0.0.1", 56324, 32400),
+		TLV{Type: TLVTypeAuthority, Value: []byte("kafka.example.com")},
+		TLV{Type: TLVTypeSSL, Value: ssl},
+	)
+	header, rest, err := readHeader(append(input, []byte("kafka")...))
+	a.Nil(err)
+	a.Equal(2, header.Version)
+	a.Equal(CommandProxy, header.Command)
+	a.Equal("192.168.0.1:56324", header.SourceAddr.String())
+	a.Equal("10.0.0.1:32400", header.DestinationAddr.String())
+	a.Equal("kafka.example.com", header.Authority())
+	a.Equal("kafka", string(rest))
+
+	sslInfo, err := header.SSL()
+	a.Nil(err)
+	a.Equal("TLSv1.3", sslInfo.Version())
+	a.Equal("client", sslInfo.CommonName())
+	a.True(sslInfo.ClientCertVerified())
+}
+
+func TestReadHeaderV2Local(t *testing.T) {
+	a := assert.New(t)
+
+	header, rest, err := readHeader(append(headerV2(byte(CommandLocal), familyUnspec, nil), []byte("kafka")...))
+	a.Nil(err)
+	a.Equal(CommandLocal, header.Command)
+	a.Nil(header.SourceAddr)
+	a.Equal("kafka", string(rest))
+}
+
+func TestReadHeaderV2Invalid(t *testing.T) {
+	a := assert.New(t)
+
+	truncatedTLV := headerV2(byte(CommandProxy), familyInet, inetAddresses("192.168.0.1", "10.0.0.1", 1, 2), TLV{Type: TLVTypeAuthority, Value: []byte("host")})
+	truncatedTLV[15] -= 2
+	_, _, err := readHeader(truncatedTLV[:len(truncatedTLV)-2])
+	a.NotNil(err)
+
+	shortAddresses := headerV2(byte(CommandProxy), familyInet6, inetAddresses("192.168.0.1", "10.0.0.1", 1, 2))
+	_, _, err = readHeader(shortAddresses)
+	a.NotNil(err)
+
+	badVersion := headerV2(byte(CommandProxy), familyInet, inetAddresses("192.168.0.1", "10.0.0.1", 1, 2))
+	badVersion[12] = 0x11
+	_, _, err = readHeader(badVersion)
+	a.NotNil(err)
+}
+
+func TestReadHeaderNotFound(t *testing.T) {
+	a := assert.New(t)
+
+	input := []byte{0, 0, 0, 20, 0, 18, 0, 3}
+	r := bufio.NewReader(bytes.NewReader(input))
+	_, err := ReadHeader(r)
+	a.Equal(ErrNoProxyHeader, err)
+	rest, _ := io.ReadAll(r)
+	a.Equal(input, rest)
+}
+
+func TestConnAddresses(t *testing.T) {
+	a := assert.New(t)
+
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	go func() {
+		_, _ = client.Write([]byte("PROXY TCP4 192.168.0.1 10.0.0.1 56324 32400\r\nkafka"))
+	}()
+	conn, err := NewConn(server, time.Second)
+	a.Nil(err)
+	a.Equal("192.168.0.1:56324", conn.RemoteAddr().String())
+	a.Equal("10.0.0.1:32400", conn.LocalAddr().String())
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	a.Nil(err)
+	a.Equal("kafka", string(buf))
+}
+
+func TestConnTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	_, err := NewConn(server, 50*time.Millisecond)
+	assert.NotNil(t, err)
+}
@@ -73,15 +73,26 @@ func NewAwsMSKIamAuth(
 
 // sendAndReceiveSASLAuth handles the entire SASL authentication process
 func (a *AwsMSKIamAuth) sendAndReceiveSASLAuth(conn DeadlineReaderWriter, brokerString string) error {
+	_, err := a.authenticate(conn, brokerString, 0)
+	return err
+}
+
+// sendAndReceiveSASLSessionAuth authenticates and returns the session lifetime announced by the broker
+func (a *AwsMSKIamAuth) sendAndReceiveSASLSessionAuth(conn DeadlineReaderWriter, brokerString string) (time.Duration, error) {
+	return a.authenticate(conn, brokerString, 1)
+}
+
+func (a *AwsMSKIamAuth) authenticate(conn DeadlineReaderWriter, brokerString string, authenticateVersion int16) (time.Duration, error) {
 	if err := a.saslHandshake(conn); err != nil {
-		return fmt.Errorf("handshake failed: %w", err)
+		return 0, fmt.Errorf("handshake failed: %w", err)
 	}
 
-	if err := a.saslAuthenticate(conn, brokerString); err != nil {
-		return fmt.Errorf("authenticate failed: %w", err)
+	sessionLifetime, err := a.saslAuthenticate(conn, brokerString, authenticateVersion)
+	if err != nil {
+		return 0, fmt.Errorf("authenticate failed: %w", err)
 	}
 
-	return nil
+	return sessionLifetime, nil
 }
 
 func (a *AwsMSKIamAuth) saslHandshake(conn DeadlineReaderWriter) error {
@@ -114,41 +125,38 @@ func (a *AwsMSKIamAuth) saslHandshake(conn DeadlineReaderWriter) error {
 	return nil
 }
 
-func (a *AwsMSKIamAuth) saslAuthenticate(conn DeadlineReaderWriter, brokerString string) error {
+func (a *AwsMSKIamAuth) saslAuthenticate(conn DeadlineReaderWriter, brokerString string, version int16) (time.Duration, error) {
 	host, _, err := net.SplitHostPort(brokerString)
 	if err != nil {
-		return fmt.Errorf("failed to parse host/port: %v", err)
+		return 0, fmt.Errorf("failed to parse host/port: %v", err)
 	}
 
 	authBytes, err := a.signer.SASLToken(context.Background(), host)
 	if err != nil {
-		return fmt.Errorf("failed to generate SASL token %v", err)
+		return 0, fmt.Errorf("failed to generate SASL token %v", err)
 	}
 
-	saslAuthReqV0 := &protocol.SaslAuthenticateRequestV0{SaslAuthBytes: authBytes}
-
 	req := &protocol.Request{
 		ClientID: a.clientID,
-		Body:     saslAuthReqV0,
+		Body:     newSaslAuthenticateRequest(version, authBytes),
 	}
 	if err := a.write(conn, req); err != nil {
-		return fmt.Errorf("writing SASL authentication request: %w", err)
+		return 0, fmt.Errorf("writing SASL authentication request: %w", err)
 	}
 
 	payload, err := a.read(conn)
 	if err != nil {
-		return fmt.Errorf("reading SASL authentication response: %w", err)
+		return 0, fmt.Errorf("reading SASL authentication response: %w", err)
 	}
 
-	res := &protocol.SaslAuthenticateResponseV0{}
-	err = protocol.Decode(payload, res)
+	res, err := decodeSaslAuthenticateResponse(version, payload)
 	if err != nil {
-		return fmt.Errorf("parsing SASL authentication response: %w", err)
+		return 0, fmt.Errorf("parsing SASL authentication response: %w", err)
 	}
 	if !errors.Is(res.Err, protocol.ErrNoError) {
-		return fmt.Errorf("sasl authentication protocol error: %w", res.Err)
+		return 0, fmt.Errorf("sasl authentication protocol error: %w", res.Err)
 	}
-	return nil
+	return time.Duration(res.SessionLifetimeMs) * time.Millisecond, nil
 }
 
 func (a *AwsMSKIamAuth) write(conn DeadlineReaderWriter, req *protocol.Request) error {
@@ -55,6 +55,13 @@ type SASLAuthByProxy interface {
 	sendAndReceiveSASLAuth(conn DeadlineReaderWriter, brokerAddress string) error
 }
 
+// SASLSessionAuthByProxy is implemented by the mechanisms which support re-authentication to the brokers (KIP-368)
+type SASLSessionAuthByProxy interface {
+	SASLAuthByProxy
+	// sendAndReceiveSASLSessionAuth authenticates with SaslAuthenticate v1 and returns the session lifetime announced by the broker, 0 when the session does not expire
+	sendAndReceiveSASLSessionAuth(conn DeadlineReaderWriter, brokerAddress string) (time.Duration, error)
+}
+
 // In SASL Plain, Kafka expects the auth header to be in the following format
 // Message format (from https://tools.ietf.org/html/rfc4616):
 //
@@ -86,6 +93,28 @@ func (b *SASLPlainAuth) sendAndReceiveSASLAuth(conn DeadlineReaderWriter, _ stri
 	return b.sendSaslAuthenticateRequest(conn)
 }
 
+// sendAndReceiveSASLAuthenticate authenticates with the SaslAuthenticate request instead of the opaque SASL/PLAIN packet,
+// so the responses can be told apart from the responses to the client requests sent on the same broker connection
+func (b *SASLPlainAuth) sendAndReceiveSASLAuthenticate(conn DeadlineReaderWriter) error {
+	saslHandshake := &SASLHandshake{
+		clientID:     b.clientID,
+		version:      1,
+		mechanism:    SASLPlain,
+		writeTimeout: b.writeTimeout,
+		readTimeout:  b.readTimeout,
+	}
+	if err := saslHandshake.sendAndReceiveHandshake(conn); err != nil {
+		return err
+	}
+	logrus.Debugf("Sending SaslAuthenticateRequest, mechanism PLAIN")
+
+	authBytes := []byte("\x00" + b.username + "\x00" + b.password)
+	if _, err := sendAndReceiveSaslAuthenticate(conn, b.clientID, authBytes, 0, b.writeTimeout, b.readTimeout); err != nil {
+		return fmt.Errorf("SASL/PLAIN auth for user %s failed: %w", b.username, err)
+	}
+	return nil
+}
+
 func (b *SASLPlainAuth) sendSaslAuthenticateRequest(conn DeadlineReaderWriter) error {
 	logrus.Debugf("Sending authentication opaque packets, mechanism PLAIN")
 
@@ -173,25 +202,37 @@ func (b *SASLHandshake) sendAndReceiveHandshake(conn DeadlineReaderWriter) error
 	return nil
 }
 
-func (b *SASLOAuthBearerAuth) getOAuthBearerToken() (string, error) {
+// getOAuthBearerToken returns the token with the authzid and the SASL extensions of the client initial response
+func (b *SASLOAuthBearerAuth) getOAuthBearerToken() (apis.TokenResponse, error) {
 	resp, err := b.tokenProvider.GetToken(context.Background(), apis.TokenRequest{})
 	if err != nil {
-		return "", err
+		return apis.TokenResponse{}, err
 	}
 	if !resp.Success {
-		return "", fmt.Errorf("get sasl token failed with status: %d", resp.Status)
+		return apis.TokenResponse{}, fmt.Errorf("get sasl token failed with status: %d", resp.Status)
 	}
 	if resp.Token == "" {
-		return "", errors.New("get sasl token returned empty token")
+		return apis.TokenResponse{}, errors.New("get sasl token returned empty token")
 	}
-	return resp.Token, nil
+	if err = (SaslOAuthBearer{}).ValidateClientInitialResponse(resp.AuthzID, resp.Extensions); err != nil {
+		return apis.TokenResponse{}, fmt.Errorf("get sasl token returned invalid client initial response: %w", err)
+	}
+	return resp, nil
 }
 
 func (b *SASLOAuthBearerAuth) sendAndReceiveSASLAuth(conn DeadlineReaderWriter, _ string) error {
+	_, err := b.authenticate(conn, 0)
+	return err
+}
 
+func (b *SASLOAuthBearerAuth) sendAndReceiveSASLSessionAuth(conn DeadlineReaderWriter, _ string) (time.Duration, error) {
+	return b.authenticate(conn, 1)
+}
+
+func (b *SASLOAuthBearerAuth) authenticate(conn DeadlineReaderWriter, authenticateVersion int16) (time.Duration, error) {
 	token, err := b.getOAuthBearerToken()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	saslHandshake := &SASLHandshake{
 		clientID:     b.clientID,
@@ -202,62 +243,89 @@ func (b *SASLOAuthBearerAuth) sendAndReceiveSASLAuth(conn DeadlineReaderWriter,
 	}
 	handshakeErr := saslHandshake.sendAndReceiveHandshake(conn)
 	if handshakeErr != nil {
-		return handshakeErr
+		return 0, handshakeErr
 	}
-	return b.sendSaslAuthenticateRequest(token, conn)
+	return b.sendSaslAuthenticateRequest(token, conn, authenticateVersion)
 }
 
-func (b *SASLOAuthBearerAuth) sendSaslAuthenticateRequest(token string, conn DeadlineReaderWriter) error {
+func (b *SASLOAuthBearerAuth) sendSaslAuthenticateRequest(token apis.TokenResponse, conn DeadlineReaderWriter, version int16) (time.Duration, error) {
 	logrus.Debugf("Sending SaslAuthenticateRequest, mechanism OAUTHBEARER")
 
-	saslAuthReqV0 := protocol.SaslAuthenticateRequestV0{SaslAuthBytes: SaslOAuthBearer{}.ToBytes(token, "", make(map[string]string, 0))}
+	authBytes := SaslOAuthBearer{}.ToBytes(token.Token, token.AuthzID, token.Extensions)
+	return sendAndReceiveSaslAuthenticate(conn, b.clientID, authBytes, version, b.writeTimeout, b.readTimeout)
+}
 
+// sendAndReceiveSaslAuthenticate sends the SaslAuthenticate request and returns the session lifetime announced by the broker
+func sendAndReceiveSaslAuthenticate(conn DeadlineReaderWriter, clientID string, authBytes []byte, version int16, writeTimeout time.Duration, readTimeout time.Duration) (time.Duration, error) {
 	req := &protocol.Request{
-		ClientID: b.clientID,
-		Body:     &saslAuthReqV0,
+		ClientID: clientID,
+		Body:     newSaslAuthenticateRequest(version, authBytes),
 	}
 	reqBuf, err := protocol.Encode(req)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	sizeBuf := make([]byte, 4)
 	binary.BigEndian.PutUint32(sizeBuf, uint32(len(reqBuf)))
 
-	err = conn.SetWriteDeadline(time.Now().Add(b.writeTimeout))
+	err = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	_, err = conn.Write(bytes.Join([][]byte{sizeBuf, reqBuf}, nil))
 	if err != nil {
-		return fmt.Errorf("failed to send SASL auth request: %w", err)
+		return 0, fmt.Errorf("failed to send SASL auth request: %w", err)
 	}
 
-	err = conn.SetReadDeadline(time.Now().Add(b.readTimeout))
+	err = conn.SetReadDeadline(time.Now().Add(readTimeout))
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	//wait for the response
 	header := make([]byte, 8) // response header
 	_, err = io.ReadFull(conn, header)
 	if err != nil {
-		return fmt.Errorf("failed to read SASL auth header: %w", err)
+		return 0, fmt.Errorf("failed to read SASL auth header: %w", err)
 	}
 	length := binary.BigEndian.Uint32(header[:4])
 	payload := make([]byte, length-4)
 	_, err = io.ReadFull(conn, payload)
 	if err != nil {
-		return fmt.Errorf("failed to read SASL auth payload: %w", err)
+		return 0, fmt.Errorf("failed to read SASL auth payload: %w", err)
 	}
 
-	res := &protocol.SaslAuthenticateResponseV0{}
-	err = protocol.Decode(payload, res)
+	res, err := decodeSaslAuthenticateResponse(version, payload)
 	if err != nil {
-		return fmt.Errorf("failed to parse SASL auth response: %w", err)
+		return 0, fmt.Errorf("failed to parse SASL auth response: %w", err)
 	}
 	if !errors.Is(res.Err, protocol.ErrNoError) {
-		return fmt.Errorf("SASL authentication failed, error message is '%v'", res.ErrMsg)
+		return 0, fmt.Errorf("SASL authentication failed, error message is '%v'", res.ErrMsg)
 	}
-	return nil
+	return time.Duration(res.SessionLifetimeMs) * time.Millisecond, nil
+}
+
+// newSaslAuthenticateRequest creates the SaslAuthenticate request, the version 1 response announces the session lifetime (KIP-368)
+func newSaslAuthenticateRequest(version int16, authBytes []byte) protocol.ProtocolBody {
+	if version == 1 {
+		return &protocol.SaslAuthenticateRequestV1{SaslAuthBytes: authBytes}
+	}
+	return &protocol.SaslAuthenticateRequestV0{SaslAuthBytes: authBytes}
+}
+
+// decodeSaslAuthenticateResponse decodes the SaslAuthenticate response, the session lifetime of version 0 is always 0
+func decodeSaslAuthenticateResponse(version int16, payload []byte) (*protocol.SaslAuthenticateResponseV1, error) {
+	if version == 1 {
+		res := &protocol.SaslAuthenticateResponseV1{}
+		if err := protocol.Decode(payload, res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	res := &protocol.SaslAuthenticateResponseV0{}
+	if err := protocol.Decode(payload, res); err != nil {
+		return nil, err
+	}
+	return &protocol.SaslAuthenticateResponseV1{Err: res.Err, ErrMsg: res.ErrMsg, SaslAuthBytes: res.SaslAuthBytes}, nil
 }
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSASLOAuthBearerAuthSendsTokenProviderExtensions(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  apis.TokenResponse
+		authBytes string
+		errorMsg  string
+	}{
+		{
+			name:      "token",
+			response:  apis.TokenResponse{Success: true, Token: "123"},
+			authBytes: "n,,\u0001auth=Bearer 123\u0001\u0001",
+		},
+		{
+			name:      "token with authzid and extensions",
+			response:  apis.TokenResponse{Success: true, Token: "123", AuthzID: "alice", Extensions: map[string]string{"logicalCluster": "lkc-1"}},
+			authBytes: "n,a=alice,\u0001auth=Bearer 123\u0001logicalCluster=lkc-1\u0001\u0001",
+		},
+		{
+			name:     "invalid extension",
+			response: apis.TokenResponse{Success: true, Token: "123", Extensions: map[string]string{"auth": "Bearer 345"}},
+			errorMsg: "get sasl token returned invalid client initial response: OAUTHBEARER extension key 'auth' is reserved",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			auth := &SASLOAuthBearerAuth{tokenProvider: &testTokenProvider{response: tc.response}}
+
+			conn := &fakeDeadlineReaderWriter{reader: bytes.NewBuffer(newTestBrokerSaslResponses(t, 0)), writer: new(bytes.Buffer)}
+			err := auth.sendAndReceiveSASLAuth(conn, "")
+			if tc.errorMsg != "" {
+				a.EqualError(err, tc.errorMsg)
+				a.Empty(conn.writer.Bytes())
+				return
+			}
+			a.Nil(err)
+			version, authBytes := readTestSaslAuthenticateRequest(t, conn.writer.Bytes())
+			a.Equal(int16(0), version)
+			a.Contains(authBytes, tc.authBytes)
+		})
+	}
+}
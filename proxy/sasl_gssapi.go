@@ -25,6 +25,7 @@ import (
 
 const (
 	TOK_ID_KRB_AP_REQ   = 256
+	TOK_ID_KRB_AP_REP   = 512
 	GSS_API_GENERIC_TAG = 0x60
 )
 
@@ -186,24 +187,51 @@ func (g *SASLGSSAPIAuth) createApReq(
 	if err != nil {
 		return nil, err
 	}
-	aprBytes := make([]byte, 2)
-	binary.BigEndian.PutUint16(aprBytes, TOK_ID_KRB_AP_REQ)
 	tb, err := APReq.Marshal()
 	if err != nil {
 		return nil, err
 	}
-	aprBytes = append(aprBytes, tb...)
+	return marshalGSSAPIToken(TOK_ID_KRB_AP_REQ, tb)
+}
+
+// marshalGSSAPIToken frames the Kerberos message as a context establishment token (RFC 1964 section 1.1)
+func marshalGSSAPIToken(tokID uint16, krb5Message []byte) ([]byte, error) {
+	tokBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(tokBytes, tokID)
+	tokBytes = append(tokBytes, krb5Message...)
 
 	oidBytes, err := asn1.Marshal(gssapi.OIDKRB5.OID())
 	if err != nil {
 		return nil, err
 	}
-	tkoLengthBytes := asn1tools.MarshalLengthBytes(len(oidBytes) + len(aprBytes))
+	tkoLengthBytes := asn1tools.MarshalLengthBytes(len(oidBytes) + len(tokBytes))
 	gssHeader := append([]byte{GSS_API_GENERIC_TAG}, tkoLengthBytes...)
 	gssHeader = append(gssHeader, oidBytes...)
-	gssPackage := append(gssHeader, aprBytes...)
+	gssPackage := append(gssHeader, tokBytes...)
 	return gssPackage, nil
+}
 
+// unmarshalGSSAPIToken returns the Kerberos message of the context establishment token
+func unmarshalGSSAPIToken(tokID uint16, b []byte) ([]byte, error) {
+	if len(b) == 0 || b[0] != GSS_API_GENERIC_TAG {
+		return nil, errors.New("GSSAPI token does not start with the generic token tag")
+	}
+	var token asn1.RawValue
+	if _, err := asn1.Unmarshal(b, &token); err != nil {
+		return nil, fmt.Errorf("invalid GSSAPI token: %v", err)
+	}
+	var oid asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(token.Bytes, &oid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GSSAPI token mechanism: %v", err)
+	}
+	if !oid.Equal(gssapi.OIDKRB5.OID()) {
+		return nil, fmt.Errorf("unsupported GSSAPI token mechanism %v", oid)
+	}
+	if len(rest) < 2 || binary.BigEndian.Uint16(rest[:2]) != tokID {
+		return nil, fmt.Errorf("GSSAPI token id %d is expected", tokID)
+	}
+	return rest[2:], nil
 }
 
 type KerberosClient interface {
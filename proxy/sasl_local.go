@@ -8,6 +8,7 @@ import (
 	"github.com/grepplabs/kafka-proxy/proxy/protocol"
 	"github.com/pkg/errors"
 	"io"
+	"sort"
 	"time"
 )
 
@@ -15,144 +16,234 @@ type LocalSasl struct {
 	enabled             bool
 	timeout             time.Duration
 	localAuthenticators map[string]LocalSaslAuth
+	// maxSessionLifetime is the maximum time before the client must re-authenticate (KIP-368), 0 disables session expiry
+	maxSessionLifetime time.Duration
+	// lockout rejects clients after too many failed authentications, nil disables the brute-force protection
+	lockout *AuthLockout
 }
 
 type LocalSaslParams struct {
-	enabled               bool
-	timeout               time.Duration
-	passwordAuthenticator apis.PasswordAuthenticator
-	tokenAuthenticator    apis.TokenInfo
+	enabled             bool
+	timeout             time.Duration
+	localAuthenticators map[string]LocalSaslAuth
+	maxSessionLifetime  time.Duration
+	lockout             *AuthLockout
 }
 
 func NewLocalSasl(params LocalSaslParams) *LocalSasl {
 	localAuthenticators := make(map[string]LocalSaslAuth)
-	if params.passwordAuthenticator != nil {
-		localAuthenticators[SASLPlain] = NewLocalSaslPlain(params.passwordAuthenticator)
-	}
-
-	if params.tokenAuthenticator != nil {
-		localAuthenticators[SASLOAuthBearer] = NewLocalSaslOauth(params.tokenAuthenticator)
+	for mechanism, localAuthenticator := range params.localAuthenticators {
+		if localAuthenticator != nil {
+			localAuthenticators[mechanism] = localAuthenticator
+		}
 	}
 	return &LocalSasl{
 		enabled:             params.enabled,
 		timeout:             params.timeout,
 		localAuthenticators: localAuthenticators,
+		maxSessionLifetime:  params.maxSessionLifetime,
+		lockout:             params.lockout,
 	}
 }
 
-func (p *LocalSasl) receiveAndSendSASLAuthV1(conn DeadlineReaderWriter, readKeyVersionBuf []byte) (err error) {
-	var localSaslAuth LocalSaslAuth
-	if localSaslAuth, err = p.receiveAndSendSaslV0orV1(conn, readKeyVersionBuf, 1); err != nil {
-		return err
+// newConversation returns the conversation of the connection guarded by the lockout
+func (p *LocalSasl) newConversation(localSaslAuth LocalSaslAuth, connection apis.ConnectionInfo) localSaslConversation {
+	conversation := newLocalSaslConversation(localSaslAuth, connection)
+	if p.lockout != nil {
+		return newLockoutConversation(conversation, p.lockout, connection)
 	}
-	if err = p.receiveAndSendAuthV1(conn, localSaslAuth); err != nil {
-		return err
+	return conversation
+}
+
+// localSaslSession is the result of the local SASL authentication of the connection
+type localSaslSession struct {
+	mechanism string
+	principal apis.Principal
+	// lifetime is the time before the client must re-authenticate, 0 when the session does not expire
+	lifetime time.Duration
+	// clientCredentials are the SASL/PLAIN credentials of the client, nil for other mechanisms
+	clientCredentials *apis.BrokerCredentials
+	// clientToken is the verified OAUTHBEARER token of the client, nil for other mechanisms
+	clientToken *oauthBearerToken
+}
+
+// newSession returns the session authenticated by the conversation
+func (p *LocalSasl) newSession(conversation localSaslConversation, sessionLifetime time.Duration) localSaslSession {
+	session := localSaslSession{lifetime: sessionLifetime}
+	if authenticated, ok := conversation.(localSaslAuthenticatedPrincipal); ok {
+		session.principal = authenticated.authenticatedPrincipal()
+	}
+	if client, ok := conversation.(localSaslClientCredentials); ok {
+		if credentials, ok := client.clientCredentials(); ok {
+			session.clientCredentials = &credentials
+		}
 	}
-	return nil
+	if client, ok := conversation.(localSaslClientToken); ok {
+		if token, ok := client.clientToken(); ok {
+			session.clientToken = &token
+		}
+	}
+	return session
+}
+
+// sessionLifetime returns the lifetime of the session authenticated by the conversation, 0 when the session does not expire
+func (p *LocalSasl) sessionLifetime(conversation localSaslConversation) time.Duration {
+	if p.maxSessionLifetime <= 0 {
+		return 0
+	}
+	lifetime := p.maxSessionLifetime
+	if credential, ok := conversation.(localSaslCredentialExpiry); ok {
+		if expiry := credential.credentialExpiry(); !expiry.IsZero() && time.Until(expiry) < lifetime {
+			lifetime = time.Until(expiry)
+		}
+	}
+	if lifetime < time.Millisecond {
+		// credential has just expired, the next request must re-authenticate
+		lifetime = time.Millisecond
+	}
+	return lifetime
+}
+
+// enabledMechanisms returns sorted mechanisms of the local authenticators
+func (p *LocalSasl) enabledMechanisms() []string {
+	mechanisms := make([]string, 0, len(p.localAuthenticators))
+	for mechanism := range p.localAuthenticators {
+		mechanisms = append(mechanisms, mechanism)
+	}
+	sort.Strings(mechanisms)
+	return mechanisms
 }
 
-func (p *LocalSasl) receiveAndSendSASLAuthV0(conn DeadlineReaderWriter, readKeyVersionBuf []byte) (err error) {
+// receiveAndSendSASLAuthV1 authenticates the client and returns the authenticated session
+func (p *LocalSasl) receiveAndSendSASLAuthV1(conn DeadlineReaderWriter, readKeyVersionBuf []byte, connection apis.ConnectionInfo) (session localSaslSession, err error) {
 	var localSaslAuth LocalSaslAuth
-	if localSaslAuth, err = p.receiveAndSendSaslV0orV1(conn, readKeyVersionBuf, 0); err != nil {
-		return err
+	var mechanism string
+	if localSaslAuth, mechanism, err = p.receiveAndSendSaslV0orV1(conn, readKeyVersionBuf, 1); err != nil {
+		return localSaslSession{}, err
 	}
-	if err = p.receiveAndSendAuthV0(conn, localSaslAuth); err != nil {
-		return err
+	session, err = p.receiveAndSendAuthV1(conn, localSaslAuth, connection)
+	session.mechanism = mechanism
+	return session, err
+}
+
+// receiveAndSendSASLAuthV0 authenticates the client and returns the authenticated session
+func (p *LocalSasl) receiveAndSendSASLAuthV0(conn DeadlineReaderWriter, readKeyVersionBuf []byte, connection apis.ConnectionInfo) (session localSaslSession, err error) {
+	var localSaslAuth LocalSaslAuth
+	var mechanism string
+	if localSaslAuth, mechanism, err = p.receiveAndSendSaslV0orV1(conn, readKeyVersionBuf, 0); err != nil {
+		return localSaslSession{}, err
 	}
-	return nil
+	session, err = p.receiveAndSendAuthV0(conn, localSaslAuth, connection)
+	session.mechanism = mechanism
+	return session, err
 }
 
-func (p *LocalSasl) receiveAndSendSaslV0orV1(conn DeadlineReaderWriter, keyVersionBuf []byte, version int16) (localSaslAuth LocalSaslAuth, err error) {
+func (p *LocalSasl) receiveAndSendSaslV0orV1(conn DeadlineReaderWriter, keyVersionBuf []byte, version int16) (localSaslAuth LocalSaslAuth, mechanism string, err error) {
 	requestDeadline := time.Now().Add(p.timeout)
 	err = conn.SetDeadline(requestDeadline)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if len(keyVersionBuf) != 8 {
-		return nil, errors.New("length of keyVersionBuf should be 8")
+		return nil, "", errors.New("length of keyVersionBuf should be 8")
 	}
 	// keyVersionBuf has already been read from connection
 	requestKeyVersion := &protocol.RequestKeyVersion{}
 	if err = protocol.Decode(keyVersionBuf, requestKeyVersion); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if !(requestKeyVersion.ApiKey == 17 && requestKeyVersion.ApiVersion == version) {
-		return nil, fmt.Errorf("SaslHandshake version %d is expected, but got %d", version, requestKeyVersion.ApiVersion)
+		return nil, "", fmt.Errorf("SaslHandshake version %d is expected, but got %d", version, requestKeyVersion.ApiVersion)
 	}
 
 	if int32(requestKeyVersion.Length) > protocol.MaxRequestSize {
-		return nil, protocol.PacketDecodingError{Info: fmt.Sprintf("sasl handshake message of length %d too large", requestKeyVersion.Length)}
+		return nil, "", protocol.PacketDecodingError{Info: fmt.Sprintf("sasl handshake message of length %d too large", requestKeyVersion.Length)}
 	}
 
 	resp := make([]byte, int(requestKeyVersion.Length-4))
 	if _, err = io.ReadFull(conn, resp); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	payload := bytes.Join([][]byte{keyVersionBuf[4:], resp}, nil)
 
 	saslReqV0orV1 := &protocol.SaslHandshakeRequestV0orV1{Version: version}
 	req := &protocol.Request{Body: saslReqV0orV1}
 	if err = protocol.Decode(payload, req); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var saslResult error
 	saslErr := protocol.ErrNoError
+	enabledMechanisms := p.enabledMechanisms()
 	localSaslAuth = p.localAuthenticators[saslReqV0orV1.Mechanism]
 	if localSaslAuth == nil {
-		mechanisms := make([]string, 0)
-		for mechanism := range p.localAuthenticators {
-			mechanisms = append(mechanisms, mechanism)
-		}
-		saslResult = fmt.Errorf("PLAIN or OAUTHBEARER mechanism expected, %v are configured, but got %s", mechanisms, saslReqV0orV1.Mechanism)
+		saslResult = fmt.Errorf("%s mechanism is not enabled, %v are configured", saslReqV0orV1.Mechanism, enabledMechanisms)
 		saslErr = protocol.ErrUnsupportedSASLMechanism
 	}
 
-	saslResV0 := &protocol.SaslHandshakeResponseV0orV1{Err: saslErr, EnabledMechanisms: []string{saslReqV0orV1.Mechanism}}
+	saslResV0 := &protocol.SaslHandshakeResponseV0orV1{Err: saslErr, EnabledMechanisms: enabledMechanisms}
 	newResponseBuf, err := protocol.Encode(saslResV0)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	newHeaderBuf, err := protocol.Encode(&protocol.ResponseHeader{Length: int32(len(newResponseBuf) + 4), CorrelationID: req.CorrelationID})
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if _, err := conn.Write(newHeaderBuf); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if _, err := conn.Write(newResponseBuf); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return localSaslAuth, saslResult
+	return localSaslAuth, saslReqV0orV1.Mechanism, saslResult
 }
 
-func (p *LocalSasl) receiveAndSendAuthV1(conn DeadlineReaderWriter, localSaslAuth LocalSaslAuth) (err error) {
+func (p *LocalSasl) receiveAndSendAuthV1(conn DeadlineReaderWriter, localSaslAuth LocalSaslAuth, connection apis.ConnectionInfo) (session localSaslSession, err error) {
+	if localSaslAuth == nil {
+		return localSaslSession{}, errors.New("localSaslAuth is nil")
+	}
+	conversation := p.newConversation(localSaslAuth, connection)
+	for {
+		var done bool
+		var sessionLifetime time.Duration
+		if done, sessionLifetime, err = p.receiveAndSendAuthStepV1(conn, conversation); err != nil {
+			return localSaslSession{}, err
+		}
+		if done {
+			return p.newSession(conversation, sessionLifetime), nil
+		}
+	}
+}
+
+// receiveAndSendAuthStepV1 handles a single SaslAuthenticate request of the SASL exchange
+func (p *LocalSasl) receiveAndSendAuthStepV1(conn DeadlineReaderWriter, conversation localSaslConversation) (done bool, sessionLifetime time.Duration, err error) {
 	requestDeadline := time.Now().Add(p.timeout)
 	err = conn.SetDeadline(requestDeadline)
 	if err != nil {
-		return err
+		return false, 0, err
 	}
 
 	keyVersionBuf := make([]byte, 8) // Size => int32 + ApiKey => int16 + ApiVersion => int16
 	if _, err = io.ReadFull(conn, keyVersionBuf); err != nil {
-		return err
+		return false, 0, err
 	}
 	requestKeyVersion := &protocol.RequestKeyVersion{}
 	if err = protocol.Decode(keyVersionBuf, requestKeyVersion); err != nil {
-		return err
+		return false, 0, err
 	}
 	if requestKeyVersion.ApiKey != 36 {
-		return errors.Errorf("SaslAuthenticate is expected, but got apiKey %d", requestKeyVersion.ApiKey)
+		return false, 0, errors.Errorf("SaslAuthenticate is expected, but got apiKey %d", requestKeyVersion.ApiKey)
 	}
 
 	if requestKeyVersion.Length > protocol.MaxRequestSize {
-		return protocol.PacketDecodingError{Info: fmt.Sprintf("sasl authenticate message of length %d too large", requestKeyVersion.Length)}
+		return false, 0, protocol.PacketDecodingError{Info: fmt.Sprintf("sasl authenticate message of length %d too large", requestKeyVersion.Length)}
 	}
 
 	resp := make([]byte, int(requestKeyVersion.Length-4))
 	if _, err = io.ReadFull(conn, resp); err != nil {
-		return err
+		return false, 0, err
 	}
 	payload := bytes.Join([][]byte{keyVersionBuf[4:], resp}, nil)
 
@@ -161,141 +252,176 @@ func (p *LocalSasl) receiveAndSendAuthV1(conn DeadlineReaderWriter, localSaslAut
 		saslAuthReqV0 := &protocol.SaslAuthenticateRequestV0{}
 		req := &protocol.Request{Body: saslAuthReqV0}
 		if err = protocol.Decode(payload, req); err != nil {
-			return err
+			return false, 0, err
 		}
 
-		authErr := localSaslAuth.doLocalAuth(saslAuthReqV0.SaslAuthBytes)
+		challenge, done, authErr := stepSaslAuthenticate(conversation, saslAuthReqV0.SaslAuthBytes)
+		if done && authErr == nil {
+			sessionLifetime = p.sessionLifetime(conversation)
+		}
 
 		var saslAuthResV0 *protocol.SaslAuthenticateResponseV0
 		if authErr == nil {
-			// Length of SaslAuthBytes !=0 for OAUTHBEARER causes that java SaslClientAuthenticator in INTERMEDIATE state will sent SaslAuthenticate(36) second time
-			saslAuthResV0 = &protocol.SaslAuthenticateResponseV0{Err: protocol.ErrNoError, SaslAuthBytes: make([]byte, 0)}
+			saslAuthResV0 = &protocol.SaslAuthenticateResponseV0{Err: protocol.ErrNoError, SaslAuthBytes: challenge}
 		} else {
 			errMsg := authErr.Error()
 			saslAuthResV0 = &protocol.SaslAuthenticateResponseV0{Err: protocol.ErrSASLAuthenticationFailed, ErrMsg: &errMsg, SaslAuthBytes: make([]byte, 0)}
 		}
 		newResponseBuf, err := protocol.Encode(saslAuthResV0)
 		if err != nil {
-			return err
+			return false, 0, err
 		}
 
 		newHeaderBuf, err := protocol.Encode(&protocol.ResponseHeader{Length: int32(len(newResponseBuf) + 4), CorrelationID: req.CorrelationID})
 		if err != nil {
-			return err
+			return false, 0, err
 		}
 		if _, err := conn.Write(newHeaderBuf); err != nil {
-			return err
+			return false, 0, err
 		}
 		if _, err := conn.Write(newResponseBuf); err != nil {
-			return err
+			return false, 0, err
 		}
-		return authErr
+		return done, sessionLifetime, authErr
 	case 1:
 		saslAuthReqV1 := &protocol.SaslAuthenticateRequestV1{}
 		req := &protocol.Request{Body: saslAuthReqV1}
 		if err = protocol.Decode(payload, req); err != nil {
-			return err
+			return false, 0, err
 		}
 
-		authErr := localSaslAuth.doLocalAuth(saslAuthReqV1.SaslAuthBytes)
+		challenge, done, authErr := stepSaslAuthenticate(conversation, saslAuthReqV1.SaslAuthBytes)
+		if done && authErr == nil {
+			sessionLifetime = p.sessionLifetime(conversation)
+		}
 
 		var saslAuthResV1 *protocol.SaslAuthenticateResponseV1
 		if authErr == nil {
-			// Length of SaslAuthBytes !=0 for OAUTHBEARER causes that java SaslClientAuthenticator in INTERMEDIATE state will sent SaslAuthenticate(36) second time
-			saslAuthResV1 = &protocol.SaslAuthenticateResponseV1{Err: protocol.ErrNoError, SaslAuthBytes: make([]byte, 0), SessionLifetimeMs: 0}
+			saslAuthResV1 = &protocol.SaslAuthenticateResponseV1{Err: protocol.ErrNoError, SaslAuthBytes: challenge, SessionLifetimeMs: sessionLifetime.Milliseconds()}
 		} else {
 			errMsg := authErr.Error()
 			saslAuthResV1 = &protocol.SaslAuthenticateResponseV1{Err: protocol.ErrSASLAuthenticationFailed, ErrMsg: &errMsg, SaslAuthBytes: make([]byte, 0), SessionLifetimeMs: 0}
 		}
 		newResponseBuf, err := protocol.Encode(saslAuthResV1)
 		if err != nil {
-			return err
+			return false, 0, err
 		}
 
 		newHeaderBuf, err := protocol.Encode(&protocol.ResponseHeader{Length: int32(len(newResponseBuf) + 4), CorrelationID: req.CorrelationID})
 		if err != nil {
-			return err
+			return false, 0, err
 		}
 		if _, err := conn.Write(newHeaderBuf); err != nil {
-			return err
+			return false, 0, err
 		}
 		if _, err := conn.Write(newResponseBuf); err != nil {
-			return err
+			return false, 0, err
 		}
-		return authErr
+		return done, sessionLifetime, authErr
 	case 2:
 		saslAuthReqV2 := &protocol.SaslAuthenticateRequestV2{}
 		req := &protocol.RequestV2{Body: saslAuthReqV2}
 		if err = protocol.Decode(payload, req); err != nil {
-			return err
+			return false, 0, err
 		}
 
-		authErr := localSaslAuth.doLocalAuth(saslAuthReqV2.SaslAuthBytes)
+		challenge, done, authErr := stepSaslAuthenticate(conversation, saslAuthReqV2.SaslAuthBytes)
+		if done && authErr == nil {
+			sessionLifetime = p.sessionLifetime(conversation)
+		}
 
 		var saslAuthResV2 *protocol.SaslAuthenticateResponseV2
 		if authErr == nil {
-			// Length of SaslAuthBytes !=0 for OAUTHBEARER causes that java SaslClientAuthenticator in INTERMEDIATE state will sent SaslAuthenticate(36) second time
-			saslAuthResV2 = &protocol.SaslAuthenticateResponseV2{Err: protocol.ErrNoError, SaslAuthBytes: make([]byte, 0), SessionLifetimeMs: 0}
+			saslAuthResV2 = &protocol.SaslAuthenticateResponseV2{Err: protocol.ErrNoError, SaslAuthBytes: challenge, SessionLifetimeMs: sessionLifetime.Milliseconds()}
 		} else {
 			errMsg := authErr.Error()
 			saslAuthResV2 = &protocol.SaslAuthenticateResponseV2{Err: protocol.ErrSASLAuthenticationFailed, ErrMsg: &errMsg, SaslAuthBytes: make([]byte, 0), SessionLifetimeMs: 0}
 		}
 		newResponseBuf, err := protocol.Encode(saslAuthResV2)
 		if err != nil {
-			return err
+			return false, 0, err
 		}
 		// 2 (Length) + 2 (CorrelationID) + 1 (empty TaggedFields)
 		newHeaderBuf, err := protocol.Encode(&protocol.ResponseHeaderV1{Length: int32(len(newResponseBuf) + 5), CorrelationID: req.CorrelationID})
 		if err != nil {
-			return err
+			return false, 0, err
 		}
 		if _, err := conn.Write(newHeaderBuf); err != nil {
-			return err
+			return false, 0, err
 		}
 		if _, err := conn.Write(newResponseBuf); err != nil {
-			return err
+			return false, 0, err
 		}
-		return authErr
+		return done, sessionLifetime, authErr
 	default:
-		return errors.Errorf("SaslAuthenticate version 0,1 or 2 is expected, apiVersion %d", requestKeyVersion.ApiVersion)
+		return false, 0, errors.Errorf("SaslAuthenticate version 0,1 or 2 is expected, apiVersion %d", requestKeyVersion.ApiVersion)
 	}
 }
 
-func (p *LocalSasl) receiveAndSendAuthV0(conn DeadlineReaderWriter, localSaslAuth LocalSaslAuth) (err error) {
+// stepSaslAuthenticate advances the conversation, SaslAuthenticate responses always carry the challenge bytes
+func stepSaslAuthenticate(conversation localSaslConversation, saslAuthBytes []byte) (challenge []byte, done bool, err error) {
+	challenge, done, err = conversation.step(saslAuthBytes)
+	if challenge == nil {
+		challenge = make([]byte, 0)
+	}
+	return challenge, done, err
+}
+
+func (p *LocalSasl) receiveAndSendAuthV0(conn DeadlineReaderWriter, localSaslAuth LocalSaslAuth, connection apis.ConnectionInfo) (session localSaslSession, err error) {
+	if localSaslAuth == nil {
+		return localSaslSession{}, errors.New("localSaslAuth is nil")
+	}
+	conversation := p.newConversation(localSaslAuth, connection)
+	for {
+		var done bool
+		if done, err = p.receiveAndSendAuthStepV0(conn, conversation); err != nil {
+			return localSaslSession{}, err
+		}
+		if done {
+			// size delimited tokens cannot carry the session lifetime, the session expires without notice to the client
+			return p.newSession(conversation, p.sessionLifetime(conversation)), nil
+		}
+	}
+}
+
+// receiveAndSendAuthStepV0 handles a single size delimited SASL token of the SASL exchange
+func (p *LocalSasl) receiveAndSendAuthStepV0(conn DeadlineReaderWriter, conversation localSaslConversation) (done bool, err error) {
 	requestDeadline := time.Now().Add(p.timeout)
 	err = conn.SetDeadline(requestDeadline)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	sizeBuf := make([]byte, 4) // Size => int32
 	if _, err = io.ReadFull(conn, sizeBuf); err != nil {
-		return err
+		return false, err
 	}
 
 	length := binary.BigEndian.Uint32(sizeBuf)
 	if int32(length) > protocol.MaxRequestSize {
-		return protocol.PacketDecodingError{Info: fmt.Sprintf("auth message of length %d too large", length)}
+		return false, protocol.PacketDecodingError{Info: fmt.Sprintf("auth message of length %d too large", length)}
 	}
 
 	saslAuthBytes := make([]byte, length)
 	_, err = io.ReadFull(conn, saslAuthBytes)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	if localSaslAuth == nil {
-		return errors.New("localSaslAuth is nil")
+	challenge, done, err := conversation.step(saslAuthBytes)
+	if err != nil {
+		return false, err
 	}
-
-	if err = localSaslAuth.doLocalAuth(saslAuthBytes); err != nil {
-		return err
+	if challenge == nil {
+		// the mechanism has no token to send e.g. after the final GSSAPI message
+		return done, nil
 	}
-	// If the credentials are valid, we would write a 4 byte response filled with null characters.
+	// If the credentials are valid, we would write the size delimited challenge i.e. a 4 byte response filled with null characters for single step mechanisms.
 	// Otherwise, the closes the connection i.e. return error
 	header := make([]byte, 4)
-	if _, err := conn.Write(header); err != nil {
-		return err
+	binary.BigEndian.PutUint32(header, uint32(len(challenge)))
+	if _, err := conn.Write(bytes.Join([][]byte{header, challenge}, nil)); err != nil {
+		return false, err
 	}
-	return nil
+	return done, nil
 }
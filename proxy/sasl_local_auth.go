@@ -2,11 +2,14 @@ package proxy
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"github.com/grepplabs/kafka-proxy/pkg/apis"
 	"github.com/grepplabs/kafka-proxy/proxy/protocol"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type errLocalAuthFailed struct {
@@ -17,6 +20,14 @@ func (e errLocalAuthFailed) Error() string {
 	return fmt.Sprintf("user %s authentication failed", e.user)
 }
 
+type errLocalTokenRejected struct {
+	status int32
+}
+
+func (e errLocalTokenRejected) Error() string {
+	return fmt.Sprintf("local oauth verify token failed with status: %d", e.status)
+}
+
 type LocalSaslAuth interface {
 	doLocalAuth(saslAuthBytes []byte) (err error)
 }
@@ -33,28 +44,88 @@ func NewLocalSaslPlain(localAuthenticator apis.PasswordAuthenticator) *LocalSasl
 
 // implements LocalSaslAuth
 func (p *LocalSaslPlain) doLocalAuth(saslAuthBytes []byte) (err error) {
+	_, err = p.authenticate(saslAuthBytes, apis.ConnectionInfo{})
+	return err
+}
+
+// authenticate returns the principal of the user, plugins without principal support authenticate the username.
+// The connection is passed only to authenticators supporting PasswordAuthenticatorV2
+func (p *LocalSaslPlain) authenticate(saslAuthBytes []byte, connection apis.ConnectionInfo) (apis.Principal, error) {
 	tokens := strings.Split(string(saslAuthBytes), "\x00")
 	if len(tokens) != 3 {
-		return fmt.Errorf("invalid SASL/PLAIN request: expected 3 tokens, got %d", len(tokens))
+		return apis.Principal{}, fmt.Errorf("invalid SASL/PLAIN request: expected 3 tokens, got %d", len(tokens))
 	}
 	if p.localAuthenticator == nil {
-		return protocol.PacketDecodingError{Info: "Listener authenticator is not set"}
+		return apis.Principal{}, protocol.PacketDecodingError{Info: "Listener authenticator is not set"}
 	}
 
 	// logrus.Infof("user: %s , password: %s", tokens[1], tokens[2])
-	ok, status, err := p.localAuthenticator.Authenticate(tokens[1], tokens[2])
+	var resp apis.AuthenticateResponse
+	var err error
+	if authenticatorV2, ok := p.localAuthenticator.(apis.PasswordAuthenticatorV2); ok {
+		resp, err = authenticatorV2.AuthenticatePrincipal(context.Background(), apis.AuthenticateRequest{Username: tokens[1], Password: tokens[2], Connection: connection})
+	} else {
+		resp.Authenticated, resp.Status, err = p.localAuthenticator.Authenticate(tokens[1], tokens[2])
+	}
 	if err != nil {
 		proxyLocalAuthTotal.WithLabelValues("error", "1").Inc()
-		return err
+		return apis.Principal{}, err
 	}
-	proxyLocalAuthTotal.WithLabelValues(strconv.FormatBool(ok), strconv.Itoa(int(status))).Inc()
+	proxyLocalAuthTotal.WithLabelValues(strconv.FormatBool(resp.Authenticated), strconv.Itoa(int(resp.Status))).Inc()
 
-	if !ok {
-		return errLocalAuthFailed{
+	if !resp.Authenticated {
+		return apis.Principal{}, errLocalAuthFailed{
 			user: tokens[1],
 		}
 	}
-	return nil
+	if resp.Principal.Name == "" {
+		resp.Principal.Name = tokens[1]
+	}
+	return resp.Principal, nil
+}
+
+// implements localSaslMultiStepAuth
+func (p *LocalSaslPlain) newConversation(connection apis.ConnectionInfo) localSaslConversation {
+	return &localSaslPlainConversation{localSaslPlain: p, connection: connection}
+}
+
+type localSaslPlainConversation struct {
+	localSaslPlain *LocalSaslPlain
+	connection     apis.ConnectionInfo
+	principal      apis.Principal
+	// credentials are the authenticated username and password of the client
+	credentials *apis.BrokerCredentials
+}
+
+func (c *localSaslPlainConversation) step(saslAuthBytes []byte) (challenge []byte, done bool, err error) {
+	c.principal, err = c.localSaslPlain.authenticate(saslAuthBytes, c.connection)
+	if err == nil {
+		tokens := strings.Split(string(saslAuthBytes), "\x00")
+		c.credentials = &apis.BrokerCredentials{Username: tokens[1], Password: tokens[2]}
+	}
+	return make([]byte, 0), true, err
+}
+
+// implements localSaslClientCredentials
+func (c *localSaslPlainConversation) clientCredentials() (apis.BrokerCredentials, bool) {
+	if c.credentials == nil {
+		return apis.BrokerCredentials{}, false
+	}
+	return *c.credentials, true
+}
+
+// implements localSaslClaimedUsername
+func (c *localSaslPlainConversation) claimedUsername(saslAuthBytes []byte) string {
+	tokens := strings.Split(string(saslAuthBytes), "\x00")
+	if len(tokens) != 3 {
+		return ""
+	}
+	return tokens[1]
+}
+
+// implements localSaslAuthenticatedPrincipal
+func (c *localSaslPlainConversation) authenticatedPrincipal() apis.Principal {
+	return c.principal
 }
 
 type LocalSaslOauth struct {
@@ -71,16 +142,148 @@ func NewLocalSaslOauth(tokenAuthenticator apis.TokenInfo) *LocalSaslOauth {
 
 // implements LocalSaslAuth
 func (p *LocalSaslOauth) doLocalAuth(saslAuthBytes []byte) (err error) {
-	token, _, _, err := p.saslOAuthBearer.GetClientInitialResponse(saslAuthBytes)
+	_, _, err = p.verifyToken(saslAuthBytes, apis.ConnectionInfo{})
+	return err
+}
+
+// verifyToken returns the token of the client initial response and its principal when it is valid
+func (p *LocalSaslOauth) verifyToken(saslAuthBytes []byte, connection apis.ConnectionInfo) (string, apis.Principal, error) {
+	token, authzid, extensions, err := p.saslOAuthBearer.GetClientInitialResponse(saslAuthBytes)
 	if err != nil {
-		return err
+		return "", apis.Principal{}, err
 	}
-	resp, err := p.tokenAuthenticator.VerifyToken(context.Background(), apis.VerifyRequest{Token: token})
+	resp, err := p.tokenAuthenticator.VerifyToken(context.Background(), apis.VerifyRequest{Token: token, Connection: connection, AuthzID: authzid, Extensions: extensions})
 	if err != nil {
-		return err
+		return "", apis.Principal{}, err
 	}
 	if !resp.Success {
-		return fmt.Errorf("local oauth verify token failed with status: %d", resp.Status)
+		return "", apis.Principal{}, errLocalTokenRejected{status: resp.Status}
+	}
+	if resp.Principal.Name == "" {
+		// token infos without principal support, the subject of the JWT is the principal
+		resp.Principal.Name = parseJWTClaims(token).Sub
+	}
+	return token, resp.Principal, nil
+}
+
+// implements localSaslMultiStepAuth
+func (p *LocalSaslOauth) newConversation(connection apis.ConnectionInfo) localSaslConversation {
+	return &localSaslOauthConversation{localSaslOauth: p, connection: connection}
+}
+
+type localSaslOauthConversation struct {
+	localSaslOauth *LocalSaslOauth
+	connection     apis.ConnectionInfo
+	tokenExpiry    time.Time
+	principal      apis.Principal
+	// token is the verified client initial response
+	token *oauthBearerToken
+}
+
+func (c *localSaslOauthConversation) step(saslAuthBytes []byte) (challenge []byte, done bool, err error) {
+	token, principal, err := c.localSaslOauth.verifyToken(saslAuthBytes, c.connection)
+	if err == nil {
+		c.tokenExpiry = jwtExpiry(token)
+		c.principal = principal
+		_, authzid, extensions, _ := c.localSaslOauth.saslOAuthBearer.GetClientInitialResponse(saslAuthBytes)
+		c.token = &oauthBearerToken{token: token, authzid: authzid, extensions: extensions}
+	}
+	// Length of SaslAuthBytes !=0 for OAUTHBEARER causes that java SaslClientAuthenticator in INTERMEDIATE state will sent SaslAuthenticate(36) second time
+	return make([]byte, 0), true, err
+}
+
+// implements localSaslCredentialExpiry
+func (c *localSaslOauthConversation) credentialExpiry() time.Time {
+	return c.tokenExpiry
+}
+
+// implements localSaslAuthenticatedPrincipal
+func (c *localSaslOauthConversation) authenticatedPrincipal() apis.Principal {
+	return c.principal
+}
+
+// implements localSaslClientToken
+func (c *localSaslOauthConversation) clientToken() (oauthBearerToken, bool) {
+	if c.token == nil {
+		return oauthBearerToken{}, false
+	}
+	return *c.token, true
+}
+
+// jwtClaims are the claims of the verified token used by the proxy
+type jwtClaims struct {
+	Sub string  `json:"sub"`
+	Exp float64 `json:"exp"`
+}
+
+// parseJWTClaims returns the claims of the verified token, empty claims for opaque tokens
+func parseJWTClaims(token string) jwtClaims {
+	var claims jwtClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return jwtClaims{}
+	}
+	return claims
+}
+
+// jwtExpiry returns the exp claim of the verified token, zero time for opaque tokens or tokens without expiry
+func jwtExpiry(token string) time.Time {
+	claims := parseJWTClaims(token)
+	if claims.Exp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(claims.Exp), 0)
+}
+
+// localSaslConversation is the SASL exchange of a single client connection. A nil challenge means there is no token to send
+type localSaslConversation interface {
+	step(saslAuthBytes []byte) (challenge []byte, done bool, err error)
+}
+
+// localSaslCredentialExpiry is implemented by conversations authenticating a credential with limited lifetime e.g. OAUTHBEARER token
+type localSaslCredentialExpiry interface {
+	credentialExpiry() time.Time
+}
+
+// localSaslAuthenticatedPrincipal is implemented by conversations knowing the principal of the authenticated client
+type localSaslAuthenticatedPrincipal interface {
+	authenticatedPrincipal() apis.Principal
+}
+
+// localSaslClientCredentials is implemented by conversations receiving the password of the client e.g. PLAIN
+type localSaslClientCredentials interface {
+	clientCredentials() (credentials apis.BrokerCredentials, ok bool)
+}
+
+// localSaslClientToken is implemented by conversations receiving the token of the client e.g. OAUTHBEARER
+type localSaslClientToken interface {
+	clientToken() (token oauthBearerToken, ok bool)
+}
+
+// localSaslMultiStepAuth is implemented by mechanisms requiring more than one SaslAuthenticate round trip
+type localSaslMultiStepAuth interface {
+	newConversation(connection apis.ConnectionInfo) localSaslConversation
+}
+
+type localSaslSingleStepConversation struct {
+	localSaslAuth LocalSaslAuth
+}
+
+func (c *localSaslSingleStepConversation) step(saslAuthBytes []byte) (challenge []byte, done bool, err error) {
+	// Length of SaslAuthBytes !=0 for OAUTHBEARER causes that java SaslClientAuthenticator in INTERMEDIATE state will sent SaslAuthenticate(36) second time
+	return make([]byte, 0), true, c.localSaslAuth.doLocalAuth(saslAuthBytes)
+}
+
+func newLocalSaslConversation(localSaslAuth LocalSaslAuth, connection apis.ConnectionInfo) localSaslConversation {
+	if multiStepAuth, ok := localSaslAuth.(localSaslMultiStepAuth); ok {
+		return multiStepAuth.newConversation(connection)
 	}
-	return nil
+	return &localSaslSingleStepConversation{localSaslAuth: localSaslAuth}
 }
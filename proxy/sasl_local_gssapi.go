@@ -0,0 +1,328 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1tools"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana"
+	"github.com/jcmturner/gokrb5/v8/iana/asnAppTag"
+	"github.com/jcmturner/gokrb5/v8/iana/chksumtype"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// gssapiSecurityLayerNone is the only SASL security layer supported, Kafka does not wrap the messages after authentication
+	gssapiSecurityLayerNone = 0x01
+	// gssapiWrapTokenSentByAcceptor is the wrap token flag set by the acceptor (RFC 4121 section 4.2.2)
+	gssapiWrapTokenSentByAcceptor = 0x01
+)
+
+type LocalSaslGSSAPIOptions struct {
+	// Keytab holds the keys of the service principals the proxy accepts tickets for
+	Keytab *keytab.Keytab
+	// ServicePrincipal restricts the accepted tickets to a single service principal e.g. kafka/proxy.example.com@EXAMPLE.COM
+	ServicePrincipal string
+	// DefaultRealm is the realm of the DEFAULT principal to local rule, the realm of the service ticket is used when empty
+	DefaultRealm string
+	// PrincipalToLocalRules map the client principal to a local name (sasl.kerberos.principal.to.local.rules syntax)
+	PrincipalToLocalRules []string
+	MaxClockSkew          time.Duration
+}
+
+// LocalSaslGSSAPI is the Kerberos acceptor of the SASL/GSSAPI exchange (RFC 4752)
+type LocalSaslGSSAPI struct {
+	keytab           *keytab.Keytab
+	servicePrincipal *types.PrincipalName
+	serviceRealm     string
+	defaultRealm     string
+	principalRules   kerberosPrincipalRules
+	maxClockSkew     time.Duration
+	replayCache      *krb5ReplayCache
+}
+
+func NewLocalSaslGSSAPI(opts LocalSaslGSSAPIOptions) (*LocalSaslGSSAPI, error) {
+	if opts.Keytab == nil {
+		return nil, errors.New("GSSAPI keytab is required")
+	}
+	if opts.MaxClockSkew <= 0 {
+		return nil, errors.New("GSSAPI max clock skew must be greater than 0")
+	}
+	principalRules, err := parseKerberosPrincipalRules(opts.PrincipalToLocalRules)
+	if err != nil {
+		return nil, err
+	}
+	p := &LocalSaslGSSAPI{
+		keytab:         opts.Keytab,
+		defaultRealm:   opts.DefaultRealm,
+		principalRules: principalRules,
+		maxClockSkew:   opts.MaxClockSkew,
+		replayCache:    newKrb5ReplayCache(opts.MaxClockSkew),
+	}
+	if opts.ServicePrincipal != "" {
+		servicePrincipal, serviceRealm := types.ParseSPNString(opts.ServicePrincipal)
+		p.servicePrincipal = &servicePrincipal
+		p.serviceRealm = serviceRealm
+		if p.defaultRealm == "" {
+			p.defaultRealm = serviceRealm
+		}
+	}
+	return p, nil
+}
+
+// implements LocalSaslAuth
+func (p *LocalSaslGSSAPI) doLocalAuth(_ []byte) (err error) {
+	return fmt.Errorf("%s requires a multi-step SASL exchange", SASLSGSSAPI)
+}
+
+// implements localSaslMultiStepAuth
+func (p *LocalSaslGSSAPI) newConversation(_ apis.ConnectionInfo) localSaslConversation {
+	return &localSaslGSSAPIConversation{acceptor: p}
+}
+
+// acceptSecContext verifies the AP_REQ of the initial context token with the service keytab
+func (p *LocalSaslGSSAPI) acceptSecContext(token []byte) (*messages.APReq, error) {
+	apReqBytes, err := unmarshalGSSAPIToken(TOK_ID_KRB_AP_REQ, token)
+	if err != nil {
+		return nil, err
+	}
+	apReq := &messages.APReq{}
+	if err = apReq.Unmarshal(apReqBytes); err != nil {
+		return nil, err
+	}
+	if p.servicePrincipal != nil && (!apReq.Ticket.SName.Equal(*p.servicePrincipal) || apReq.Ticket.Realm != p.serviceRealm) {
+		return nil, errors.Errorf("service ticket for %s@%s is not accepted", apReq.Ticket.SName.PrincipalNameString(), apReq.Ticket.Realm)
+	}
+	ok, err := apReq.Verify(p.keytab, p.maxClockSkew, types.HostAddress{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("AP_REQ verification failed")
+	}
+	if p.replayCache.isReplay(apReq) {
+		return nil, errors.New("replayed AP_REQ")
+	}
+	return apReq, nil
+}
+
+// localName maps the client principal to the local name
+func (p *LocalSaslGSSAPI) localName(apReq *messages.APReq) (string, error) {
+	defaultRealm := p.defaultRealm
+	if defaultRealm == "" {
+		defaultRealm = apReq.Ticket.Realm
+	}
+	return p.principalRules.shortName(defaultRealm, apReq.Ticket.DecryptedEncPart.CName.NameString, apReq.Ticket.DecryptedEncPart.CRealm)
+}
+
+type localSaslGSSAPIConversation struct {
+	acceptor *LocalSaslGSSAPI
+	// established is true when the security context was established and the security layer was not negotiated yet
+	established bool
+	// negotiating is true when the security layer was offered to the client
+	negotiating bool
+	key         types.EncryptionKey
+	seqNumber   uint64
+	principal   string
+	localName   string
+	ticketEnd   time.Time
+}
+
+func (c *localSaslGSSAPIConversation) step(saslAuthBytes []byte) (challenge []byte, done bool, err error) {
+	switch {
+	case c.negotiating:
+		if err = c.verifySecurityLayer(saslAuthBytes); err != nil {
+			proxyLocalAuthTotal.WithLabelValues("false", "0").Inc()
+			logrus.Debugf("GSSAPI security layer negotiation for %s failed: %v", c.principal, err)
+			return nil, true, errLocalAuthFailed{user: c.principal}
+		}
+		proxyLocalAuthTotal.WithLabelValues("true", "0").Inc()
+		logrus.Debugf("GSSAPI principal %s authenticated as %s", c.principal, c.localName)
+		// the exchange is complete, there is no token to send
+		return nil, true, nil
+	case c.established:
+		if len(saslAuthBytes) != 0 {
+			return nil, true, errors.New("empty GSSAPI token is expected after AP_REP")
+		}
+		return c.offerSecurityLayer()
+	default:
+		apReq, err := c.acceptor.acceptSecContext(saslAuthBytes)
+		if err != nil {
+			proxyLocalAuthTotal.WithLabelValues("false", "0").Inc()
+			return nil, true, errors.Wrap(err, "GSSAPI authentication failed")
+		}
+		c.ticketEnd = apReq.Ticket.DecryptedEncPart.EndTime
+		c.principal = fmt.Sprintf("%s@%s", apReq.Ticket.DecryptedEncPart.CName.PrincipalNameString(), apReq.Ticket.DecryptedEncPart.CRealm)
+		if c.localName, err = c.acceptor.localName(apReq); err != nil {
+			proxyLocalAuthTotal.WithLabelValues("false", "0").Inc()
+			logrus.Debugf("GSSAPI principal %s is not mapped: %v", c.principal, err)
+			return nil, true, errLocalAuthFailed{user: c.principal}
+		}
+		flags, err := gssapiChecksumFlags(apReq.Authenticator.Cksum)
+		if err != nil {
+			return nil, true, err
+		}
+		// initiator subkey is used for the wrap tokens, as the acceptor does not assert a subkey
+		c.key = apReq.Ticket.DecryptedEncPart.Key
+		if len(apReq.Authenticator.SubKey.KeyValue) != 0 {
+			c.key = apReq.Authenticator.SubKey
+		}
+		if flags&gssapi.ContextFlagMutual == 0 {
+			c.seqNumber = uint64(apReq.Authenticator.SeqNumber)
+			return c.offerSecurityLayer()
+		}
+		apRep, err := c.newAPRep(apReq)
+		if err != nil {
+			return nil, true, err
+		}
+		c.established = true
+		return apRep, false, nil
+	}
+}
+
+// implements localSaslCredentialExpiry
+func (c *localSaslGSSAPIConversation) credentialExpiry() time.Time {
+	return c.ticketEnd
+}
+
+// implements localSaslAuthenticatedPrincipal
+func (c *localSaslGSSAPIConversation) authenticatedPrincipal() apis.Principal {
+	return apis.Principal{Name: c.localName, Attributes: map[string]string{"kerberos_principal": c.principal}}
+}
+
+// newAPRep creates the AP_REP token of the mutual authentication
+func (c *localSaslGSSAPIConversation) newAPRep(apReq *messages.APReq) ([]byte, error) {
+	seq, err := rand.Int(rand.Reader, big.NewInt(math.MaxUint32))
+	if err != nil {
+		return nil, err
+	}
+	c.seqNumber = uint64(seq.Int64() & 0x3fffffff)
+
+	encPart, err := asn1.Marshal(messages.EncAPRepPart{
+		CTime:          apReq.Authenticator.CTime,
+		Cusec:          apReq.Authenticator.Cusec,
+		SequenceNumber: int64(c.seqNumber),
+	})
+	if err != nil {
+		return nil, err
+	}
+	encPart = asn1tools.AddASNAppTag(encPart, asnAppTag.EncAPRepPart)
+	encryptedPart, err := crypto.GetEncryptedData(encPart, apReq.Ticket.DecryptedEncPart.Key, keyusage.AP_REP_ENCPART, 0)
+	if err != nil {
+		return nil, err
+	}
+	apRep, err := asn1.Marshal(messages.APRep{
+		PVNO:    iana.PVNO,
+		MsgType: msgtype.KRB_AP_REP,
+		EncPart: encryptedPart,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marshalGSSAPIToken(TOK_ID_KRB_AP_REP, asn1tools.AddASNAppTag(apRep, asnAppTag.APREP))
+}
+
+// offerSecurityLayer sends the wrapped security layers and max buffer size supported by the server
+func (c *localSaslGSSAPIConversation) offerSecurityLayer() ([]byte, bool, error) {
+	encType, err := crypto.GetEtype(c.key.KeyType)
+	if err != nil {
+		return nil, true, err
+	}
+	token := gssapi.WrapToken{
+		Flags:     gssapiWrapTokenSentByAcceptor,
+		EC:        uint16(encType.GetHMACBitLength() / 8),
+		RRC:       0,
+		SndSeqNum: c.seqNumber,
+		// no security layer, so the max buffer size must be 0
+		Payload: []byte{gssapiSecurityLayerNone, 0, 0, 0},
+	}
+	if err = token.SetCheckSum(c.key, keyusage.GSSAPI_ACCEPTOR_SEAL); err != nil {
+		return nil, true, err
+	}
+	challenge, err := token.Marshal()
+	if err != nil {
+		return nil, true, err
+	}
+	c.established = false
+	c.negotiating = true
+	return challenge, false, nil
+}
+
+// verifySecurityLayer checks the wrapped security layer chosen by the client and its authorization identity
+func (c *localSaslGSSAPIConversation) verifySecurityLayer(saslAuthBytes []byte) error {
+	token := gssapi.WrapToken{}
+	if err := token.Unmarshal(saslAuthBytes, false); err != nil {
+		return err
+	}
+	if ok, err := token.Verify(c.key, keyusage.GSSAPI_INITIATOR_SEAL); !ok {
+		return err
+	}
+	if len(token.Payload) < 4 {
+		return errors.New("security layer message is too short")
+	}
+	if token.Payload[0]&gssapiSecurityLayerNone == 0 {
+		return errors.Errorf("unsupported security layer 0x%02x", token.Payload[0])
+	}
+	if authzID := string(token.Payload[4:]); authzID != "" && authzID != c.principal {
+		return errors.Errorf("authorization id %s differs from the authenticated principal", authzID)
+	}
+	return nil
+}
+
+// gssapiChecksumFlags returns the context flags of the authenticator checksum (RFC 4121 section 4.1.1)
+func gssapiChecksumFlags(cksum types.Checksum) (uint32, error) {
+	if cksum.CksumType != chksumtype.GSSAPI || len(cksum.Checksum) < 24 {
+		return 0, errors.New("authenticator checksum is not a GSSAPI checksum")
+	}
+	return binary.LittleEndian.Uint32(cksum.Checksum[20:24]), nil
+}
+
+// krb5ReplayCache remembers the authenticators seen within the clock skew
+type krb5ReplayCache struct {
+	sync.Mutex
+	maxClockSkew time.Duration
+	entries      map[string]time.Time
+}
+
+func newKrb5ReplayCache(maxClockSkew time.Duration) *krb5ReplayCache {
+	return &krb5ReplayCache{
+		maxClockSkew: maxClockSkew,
+		entries:      make(map[string]time.Time),
+	}
+}
+
+func (c *krb5ReplayCache) isReplay(apReq *messages.APReq) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	for key, expiry := range c.entries {
+		if now.After(expiry) {
+			delete(c.entries, key)
+		}
+	}
+	key := fmt.Sprintf("%s@%s|%s@%s|%d|%d",
+		apReq.Authenticator.CName.PrincipalNameString(), apReq.Authenticator.CRealm,
+		apReq.Ticket.SName.PrincipalNameString(), apReq.Ticket.Realm,
+		apReq.Authenticator.CTime.Unix(), apReq.Authenticator.Cusec)
+	if _, ok := c.entries[key]; ok {
+		return true
+	}
+	c.entries[key] = apReq.Authenticator.CTime.Add(c.maxClockSkew)
+	return false
+}
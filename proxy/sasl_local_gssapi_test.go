@@ -0,0 +1,272 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/chksumtype"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testServicePrincipal = "kafka/proxy.example.com"
+	testRealm            = "EXAMPLE.COM"
+)
+
+// testKDC issues service tickets encrypted with the keys of the service keytab
+type testKDC struct {
+	keytab *keytab.Keytab
+}
+
+func newTestKDC(t *testing.T, servicePassword string) *testKDC {
+	kt := keytab.New()
+	if err := kt.AddEntry(testServicePrincipal, testRealm, servicePassword, time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatal(err)
+	}
+	return &testKDC{keytab: kt}
+}
+
+// testServiceTicket is a service ticket with the session key issued to the client
+type testServiceTicket struct {
+	ticket     messages.Ticket
+	sessionKey types.EncryptionKey
+	cname      types.PrincipalName
+	crealm     string
+}
+
+func (k *testKDC) serviceTicket(t *testing.T, username, realm string) testServiceTicket {
+	now := time.Now().UTC()
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, username)
+	ticket, sessionKey, err := messages.NewTicket(
+		cname, realm,
+		types.NewPrincipalName(nametype.KRB_NT_SRV_INST, testServicePrincipal), testRealm,
+		types.NewKrbFlags(), k.keytab, etypeID.AES256_CTS_HMAC_SHA1_96, 1, now, now, now.Add(time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testServiceTicket{ticket: ticket, sessionKey: sessionKey, cname: cname, crealm: realm}
+}
+
+// initialContextToken creates the AP_REQ token of the client, flags are the requested GSSAPI context flags
+func (st testServiceTicket) initialContextToken(t *testing.T, flags uint32) []byte {
+	auth, err := types.NewAuthenticator(st.crealm, st.cname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := make([]byte, 24)
+	binary.LittleEndian.PutUint32(checksum[:4], 16)
+	binary.LittleEndian.PutUint32(checksum[20:24], flags)
+	auth.Cksum = types.Checksum{CksumType: chksumtype.GSSAPI, Checksum: checksum}
+
+	apReq, err := messages.NewAPReq(st.ticket, st.sessionKey, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := apReq.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := marshalGSSAPIToken(TOK_ID_KRB_AP_REQ, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func newTestLocalSaslGSSAPI(t *testing.T, kdc *testKDC, rules ...string) *LocalSaslGSSAPI {
+	acceptor, err := NewLocalSaslGSSAPI(LocalSaslGSSAPIOptions{
+		Keytab:                kdc.keytab,
+		ServicePrincipal:      testServicePrincipal + "@" + testRealm,
+		PrincipalToLocalRules: rules,
+		MaxClockSkew:          5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acceptor
+}
+
+// verifySecurityLayerOffer checks the server wrap token and returns the client one
+func verifySecurityLayerOffer(t *testing.T, challenge []byte, key types.EncryptionKey, seqNumber uint64) []byte {
+	a := assert.New(t)
+
+	offer := gssapi.WrapToken{}
+	a.Nil(offer.Unmarshal(challenge, true))
+	ok, err := offer.Verify(key, keyusage.GSSAPI_ACCEPTOR_SEAL)
+	a.True(ok)
+	a.Nil(err)
+	a.Equal([]byte{gssapiSecurityLayerNone, 0, 0, 0}, offer.Payload)
+	a.Equal(seqNumber, offer.SndSeqNum)
+
+	response, err := gssapi.NewInitiatorWrapToken(offer.Payload, key)
+	a.Nil(err)
+	b, err := response.Marshal()
+	a.Nil(err)
+	return b
+}
+
+func TestLocalSaslGSSAPI(t *testing.T) {
+	a := assert.New(t)
+
+	kdc := newTestKDC(t, "service-password")
+	st := kdc.serviceTicket(t, "alice", testRealm)
+	token := st.initialContextToken(t, gssapi.ContextFlagInteg|gssapi.ContextFlagConf)
+
+	conversation := newTestLocalSaslGSSAPI(t, kdc).newConversation(apis.ConnectionInfo{}).(*localSaslGSSAPIConversation)
+	challenge, done, err := conversation.step(token)
+	a.Nil(err)
+	a.False(done)
+	a.Equal("alice@EXAMPLE.COM", conversation.principal)
+	a.Equal("alice", conversation.localName)
+	a.Equal(apis.Principal{Name: "alice", Attributes: map[string]string{"kerberos_principal": "alice@EXAMPLE.COM"}}, conversation.authenticatedPrincipal())
+
+	response := verifySecurityLayerOffer(t, challenge, st.sessionKey, conversation.seqNumber)
+	challenge, done, err = conversation.step(response)
+	a.Nil(err)
+	a.True(done)
+	a.Nil(challenge)
+}
+
+func TestLocalSaslGSSAPIMutualAuthentication(t *testing.T) {
+	a := assert.New(t)
+
+	kdc := newTestKDC(t, "service-password")
+	st := kdc.serviceTicket(t, "alice", testRealm)
+	token := st.initialContextToken(t, gssapi.ContextFlagMutual|gssapi.ContextFlagInteg)
+
+	conversation := newTestLocalSaslGSSAPI(t, kdc).newConversation(apis.ConnectionInfo{})
+	challenge, done, err := conversation.step(token)
+	a.Nil(err)
+	a.False(done)
+
+	apRepBytes, err := unmarshalGSSAPIToken(TOK_ID_KRB_AP_REP, challenge)
+	a.Nil(err)
+	apRep := messages.APRep{}
+	a.Nil(apRep.Unmarshal(apRepBytes))
+	encPartBytes, err := crypto.DecryptEncPart(apRep.EncPart, st.sessionKey, keyusage.AP_REP_ENCPART)
+	a.Nil(err)
+	encPart := messages.EncAPRepPart{}
+	a.Nil(encPart.Unmarshal(encPartBytes))
+
+	challenge, done, err = conversation.step(make([]byte, 0))
+	a.Nil(err)
+	a.False(done)
+
+	response := verifySecurityLayerOffer(t, challenge, st.sessionKey, uint64(encPart.SequenceNumber))
+	challenge, done, err = conversation.step(response)
+	a.Nil(err)
+	a.True(done)
+	a.Nil(challenge)
+}
+
+func TestLocalSaslGSSAPIRejected(t *testing.T) {
+	kdc := newTestKDC(t, "service-password")
+	st := kdc.serviceTicket(t, "alice", testRealm)
+	token := st.initialContextToken(t, gssapi.ContextFlagInteg)
+
+	otherKDC := newTestKDC(t, "other-service-password")
+	otherSt := otherKDC.serviceTicket(t, "alice", testRealm)
+
+	foreignSt := kdc.serviceTicket(t, "bob", "OTHER.COM")
+
+	tests := []struct {
+		name     string
+		token    func() []byte
+		rules    []string
+		errorMsg string
+	}{
+		{
+			name:     "malformed token",
+			token:    func() []byte { return []byte("alice") },
+			errorMsg: "GSSAPI authentication failed: GSSAPI token does not start with the generic token tag",
+		},
+		{
+			name: "unknown service key",
+			token: func() []byte {
+				return otherSt.initialContextToken(t, gssapi.ContextFlagInteg)
+			},
+		},
+		{
+			name:     "principal is not mapped",
+			token:    func() []byte { return foreignSt.initialContextToken(t, gssapi.ContextFlagInteg) },
+			errorMsg: "user bob@OTHER.COM authentication failed",
+		},
+		{
+			name:     "no rule for principal",
+			token:    func() []byte { return token },
+			rules:    []string{"RULE:[2:$1@$0](.*@EXAMPLE.COM)s/@.*//"},
+			errorMsg: "user alice@EXAMPLE.COM authentication failed",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			conversation := newTestLocalSaslGSSAPI(t, kdc, tc.rules...).newConversation(apis.ConnectionInfo{})
+			challenge, done, err := conversation.step(tc.token())
+			a.Nil(challenge)
+			a.True(done)
+			if a.NotNil(err) && tc.errorMsg != "" {
+				a.Equal(tc.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestLocalSaslGSSAPIReplay(t *testing.T) {
+	a := assert.New(t)
+
+	kdc := newTestKDC(t, "service-password")
+	st := kdc.serviceTicket(t, "alice", testRealm)
+	token := st.initialContextToken(t, gssapi.ContextFlagInteg)
+
+	acceptor := newTestLocalSaslGSSAPI(t, kdc)
+	_, _, err := acceptor.newConversation(apis.ConnectionInfo{}).step(token)
+	a.Nil(err)
+	_, _, err = acceptor.newConversation(apis.ConnectionInfo{}).step(token)
+	if a.NotNil(err) {
+		a.Equal("GSSAPI authentication failed: replayed AP_REQ", err.Error())
+	}
+}
+
+func TestLocalSaslGSSAPIReceiveAndSendAuthV0(t *testing.T) {
+	a := assert.New(t)
+
+	kdc := newTestKDC(t, "service-password")
+	st := kdc.serviceTicket(t, "alice", testRealm)
+	token := st.initialContextToken(t, gssapi.ContextFlagInteg)
+	response, err := gssapi.NewInitiatorWrapToken([]byte{gssapiSecurityLayerNone, 0, 0, 0}, st.sessionKey)
+	a.Nil(err)
+	responseBytes, err := response.Marshal()
+	a.Nil(err)
+
+	reader := new(bytes.Buffer)
+	for _, b := range [][]byte{token, responseBytes} {
+		a.Nil(binary.Write(reader, binary.BigEndian, uint32(len(b))))
+		reader.Write(b)
+	}
+	conn := &fakeDeadlineReaderWriter{
+		reader: reader,
+		writer: new(bytes.Buffer),
+	}
+	localSasl := &LocalSasl{timeout: 5 * time.Second}
+	_, err = localSasl.receiveAndSendAuthV0(conn, newTestLocalSaslGSSAPI(t, kdc), apis.ConnectionInfo{})
+	a.Nil(err)
+
+	// only the security layer offer is sent, the final client message has no response
+	written := conn.writer.Bytes()
+	a.Equal(int(binary.BigEndian.Uint32(written[:4])), len(written)-4)
+	offer := gssapi.WrapToken{}
+	a.Nil(offer.Unmarshal(written[4:], true))
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/pkg/errors"
+	"github.com/xdg-go/scram"
+)
+
+type LocalSaslScram struct {
+	mechanism       string
+	credentialStore apis.ScramCredentialStore
+	server          *scram.Server
+}
+
+func NewLocalSaslScram(mechanism string, credentialStore apis.ScramCredentialStore) (*LocalSaslScram, error) {
+	var hashGenerator scram.HashGeneratorFcn
+	switch mechanism {
+	case SASLSCRAM256:
+		hashGenerator = SHA256
+	case SASLSCRAM512:
+		hashGenerator = SHA512
+	default:
+		return nil, errors.Errorf("unsupported SCRAM mechanism '%s'", mechanism)
+	}
+	p := &LocalSaslScram{
+		mechanism:       mechanism,
+		credentialStore: credentialStore,
+	}
+	server, err := hashGenerator.NewServer(p.lookupCredentials)
+	if err != nil {
+		return nil, err
+	}
+	p.server = server
+	return p, nil
+}
+
+func (p *LocalSaslScram) lookupCredentials(username string) (scram.StoredCredentials, error) {
+	credentials, ok, err := p.credentialStore.GetCredentials(p.mechanism, username)
+	if err != nil {
+		proxyLocalAuthTotal.WithLabelValues("error", "1").Inc()
+		return scram.StoredCredentials{}, err
+	}
+	if !ok {
+		proxyLocalAuthTotal.WithLabelValues("false", "0").Inc()
+		return scram.StoredCredentials{}, errLocalAuthFailed{user: username}
+	}
+	return scram.StoredCredentials{
+		KeyFactors: scram.KeyFactors{Salt: string(credentials.Salt), Iters: credentials.Iterations},
+		StoredKey:  credentials.StoredKey,
+		ServerKey:  credentials.ServerKey,
+	}, nil
+}
+
+// implements LocalSaslAuth
+func (p *LocalSaslScram) doLocalAuth(_ []byte) (err error) {
+	return fmt.Errorf("%s requires a multi-step SASL exchange", p.mechanism)
+}
+
+// implements localSaslMultiStepAuth
+func (p *LocalSaslScram) newConversation(_ apis.ConnectionInfo) localSaslConversation {
+	return &localSaslScramConversation{conversation: p.server.NewConversation()}
+}
+
+type localSaslScramConversation struct {
+	conversation *scram.ServerConversation
+	started      bool
+}
+
+func (c *localSaslScramConversation) step(saslAuthBytes []byte) (challenge []byte, done bool, err error) {
+	clientFirst := !c.started
+	c.started = true
+
+	response, err := c.conversation.Step(string(saslAuthBytes))
+	if err != nil {
+		if clientFirst {
+			// unknown user, credential store or client-first message error
+			return nil, true, err
+		}
+		proxyLocalAuthTotal.WithLabelValues("false", "0").Inc()
+		return nil, true, errLocalAuthFailed{user: c.conversation.Username()}
+	}
+	if !c.conversation.Done() {
+		return []byte(response), false, nil
+	}
+	proxyLocalAuthTotal.WithLabelValues(strconv.FormatBool(c.conversation.Valid()), "0").Inc()
+	return []byte(response), true, nil
+}
+
+// implements localSaslClaimedUsername
+func (c *localSaslScramConversation) claimedUsername(saslAuthBytes []byte) string {
+	if c.started {
+		return ""
+	}
+	// client-first message: gs2-header,n=username,r=nonce
+	fields := strings.Split(string(saslAuthBytes), ",")
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "n=") {
+		return ""
+	}
+	return strings.NewReplacer("=2C", ",", "=3D", "=").Replace(strings.TrimPrefix(fields[2], "n="))
+}
+
+// implements localSaslAuthenticatedPrincipal
+func (c *localSaslScramConversation) authenticatedPrincipal() apis.Principal {
+	return apis.Principal{Name: c.conversation.Username()}
+}
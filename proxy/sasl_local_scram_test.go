@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	scramfile "github.com/grepplabs/kafka-proxy/pkg/libs/scram-file"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeScramCredentialStore struct {
+	credentials map[string]apis.ScramCredentials
+}
+
+func (s *fakeScramCredentialStore) GetCredentials(mechanism, username string) (apis.ScramCredentials, bool, error) {
+	credentials, ok := s.credentials[mechanism+" "+username]
+	return credentials, ok, nil
+}
+
+func TestLocalSaslScram(t *testing.T) {
+	store := &fakeScramCredentialStore{credentials: make(map[string]apis.ScramCredentials)}
+	for _, mechanism := range []string{SASLSCRAM256, SASLSCRAM512} {
+		credentials, err := scramfile.NewCredentials(mechanism, "my-test-password", scramfile.DefaultIterations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.credentials[mechanism+" my-test-user"] = credentials
+	}
+
+	tests := []struct {
+		name            string
+		clientMechanism string
+		serverMechanism string
+		username        string
+		password        string
+		authError       error
+	}{
+		{
+			name:            "SCRAM-SHA-256 success",
+			clientMechanism: SASLSCRAM256,
+			serverMechanism: SASLSCRAM256,
+			username:        "my-test-user",
+			password:        "my-test-password",
+		},
+		{
+			name:            "SCRAM-SHA-512 success",
+			clientMechanism: SASLSCRAM512,
+			serverMechanism: SASLSCRAM512,
+			username:        "my-test-user",
+			password:        "my-test-password",
+		},
+		{
+			name:            "SCRAM-SHA-512 invalid password",
+			clientMechanism: SASLSCRAM512,
+			serverMechanism: SASLSCRAM512,
+			username:        "my-test-user",
+			password:        "bad-password",
+			authError:       errLocalAuthFailed{user: "my-test-user"},
+		},
+		{
+			name:            "SCRAM-SHA-256 unknown user",
+			clientMechanism: SASLSCRAM256,
+			serverMechanism: SASLSCRAM256,
+			username:        "unknown-user",
+			password:        "my-test-password",
+			authError:       errLocalAuthFailed{user: "unknown-user"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			localSaslScram, err := NewLocalSaslScram(tc.serverMechanism, store)
+			a.Nil(err)
+			localSasl := NewLocalSasl(LocalSaslParams{
+				enabled:             true,
+				timeout:             5 * time.Second,
+				localAuthenticators: map[string]LocalSaslAuth{tc.serverMechanism: localSaslScram},
+			})
+
+			clientConn, serverConn := net.Pipe()
+			defer clientConn.Close()
+			defer serverConn.Close()
+
+			serverResult := make(chan error, 1)
+			go func() {
+				keyVersionBuf := make([]byte, 8)
+				if _, err := io.ReadFull(serverConn, keyVersionBuf); err != nil {
+					serverResult <- err
+					return
+				}
+				_, err := localSasl.receiveAndSendSASLAuthV1(serverConn, keyVersionBuf, apis.ConnectionInfo{})
+				serverResult <- err
+			}()
+
+			client := &SASLSCRAMAuth{
+				clientID:     "test-client",
+				writeTimeout: 5 * time.Second,
+				readTimeout:  5 * time.Second,
+				username:     tc.username,
+				password:     tc.password,
+				mechanism:    tc.clientMechanism,
+			}
+			clientErr := client.sendAndReceiveSASLAuth(clientConn, "")
+			a.Equal(tc.authError, <-serverResult)
+			if tc.authError == nil {
+				a.Nil(clientErr)
+			} else {
+				a.NotNil(clientErr)
+			}
+		})
+	}
+}
+
+func TestLocalSaslScramMechanismNotEnabled(t *testing.T) {
+	localSaslScram, err := NewLocalSaslScram(SASLSCRAM256, &fakeScramCredentialStore{})
+	assert.Nil(t, err)
+	localSasl := NewLocalSasl(LocalSaslParams{
+		enabled:             true,
+		timeout:             5 * time.Second,
+		localAuthenticators: map[string]LocalSaslAuth{SASLSCRAM256: localSaslScram},
+	})
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	serverResult := make(chan error, 1)
+	go func() {
+		keyVersionBuf := make([]byte, 8)
+		if _, err := io.ReadFull(serverConn, keyVersionBuf); err != nil {
+			serverResult <- err
+			return
+		}
+		_, err := localSasl.receiveAndSendSASLAuthV1(serverConn, keyVersionBuf, apis.ConnectionInfo{})
+		serverResult <- err
+	}()
+
+	client := &SASLSCRAMAuth{writeTimeout: 5 * time.Second, readTimeout: 5 * time.Second, username: "my-test-user", password: "my-test-password", mechanism: SASLSCRAM512}
+	assert.NotNil(t, client.sendAndReceiveSASLAuth(clientConn, ""))
+	err = <-serverResult
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "SCRAM-SHA-512 mechanism is not enabled, [SCRAM-SHA-256] are configured", err.Error())
+	}
+}
@@ -2,7 +2,13 @@ package proxy
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
+	"fmt"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/proxy/protocol"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -82,7 +88,7 @@ func TestLocalSaslReceiveAndSendAuthV1(t *testing.T) {
 				Password: tc.password,
 			})
 			localSasl := &LocalSasl{}
-			err = localSasl.receiveAndSendAuthV1(conn, localSaslAuth)
+			_, err = localSasl.receiveAndSendAuthV1(conn, localSaslAuth, apis.ConnectionInfo{})
 			a.Equal(tc.authError, err)
 
 			written := conn.writer.Bytes()
@@ -100,6 +106,131 @@ func (pa fakePasswordAuthenticator) Authenticate(username, password string) (boo
 	return username == pa.Username && password == pa.Password, 0, nil
 }
 
+type fakePasswordAuthenticatorV2 struct {
+	fakePasswordAuthenticator
+	principal apis.Principal
+}
+
+func (pa fakePasswordAuthenticatorV2) AuthenticatePrincipal(_ context.Context, request apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	ok, status, err := pa.Authenticate(request.Username, request.Password)
+	return apis.AuthenticateResponse{Authenticated: ok, Status: status, Principal: pa.principal}, err
+}
+
+type fakeTokenInfoV2 struct {
+	principal apis.Principal
+}
+
+func (p fakeTokenInfoV2) VerifyToken(_ context.Context, _ apis.VerifyRequest) (apis.VerifyResponse, error) {
+	return apis.VerifyResponse{Success: true, Principal: p.principal}, nil
+}
+
+func TestLocalSaslPrincipal(t *testing.T) {
+	plainAuthBytes := []byte("\x00alice\x00secret")
+	oauthAuthBytes := []byte("n,,\x01auth=Bearer " + newTestJWT(`{"sub":"alice-sub"}`) + "\x01\x01")
+	tests := []struct {
+		name          string
+		localSaslAuth LocalSaslAuth
+		saslAuthBytes []byte
+		principal     apis.Principal
+	}{
+		{
+			name:          "PLAIN authenticator without principal",
+			localSaslAuth: NewLocalSaslPlain(&fakePasswordAuthenticator{Username: "alice", Password: "secret"}),
+			saslAuthBytes: plainAuthBytes,
+			principal:     apis.Principal{Name: "alice"},
+		},
+		{
+			name: "PLAIN authenticator with principal",
+			localSaslAuth: NewLocalSaslPlain(&fakePasswordAuthenticatorV2{
+				fakePasswordAuthenticator: fakePasswordAuthenticator{Username: "alice", Password: "secret"},
+				principal:                 apis.Principal{Name: "User:alice", Groups: []string{"admins"}, Attributes: map[string]string{"department": "ops"}},
+			}),
+			saslAuthBytes: plainAuthBytes,
+			principal:     apis.Principal{Name: "User:alice", Groups: []string{"admins"}, Attributes: map[string]string{"department": "ops"}},
+		},
+		{
+			name: "PLAIN authenticator with groups only",
+			localSaslAuth: NewLocalSaslPlain(&fakePasswordAuthenticatorV2{
+				fakePasswordAuthenticator: fakePasswordAuthenticator{Username: "alice", Password: "secret"},
+				principal:                 apis.Principal{Groups: []string{"admins"}},
+			}),
+			saslAuthBytes: plainAuthBytes,
+			principal:     apis.Principal{Name: "alice", Groups: []string{"admins"}},
+		},
+		{
+			name:          "OAUTHBEARER token info without principal",
+			localSaslAuth: NewLocalSaslOauth(&fakeTokenInfoV2{}),
+			saslAuthBytes: oauthAuthBytes,
+			principal:     apis.Principal{Name: "alice-sub"},
+		},
+		{
+			name:          "OAUTHBEARER token info with principal",
+			localSaslAuth: NewLocalSaslOauth(&fakeTokenInfoV2{principal: apis.Principal{Name: "alice", Groups: []string{"readers"}}}),
+			saslAuthBytes: oauthAuthBytes,
+			principal:     apis.Principal{Name: "alice", Groups: []string{"readers"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			localSasl := NewLocalSasl(LocalSaslParams{enabled: true, timeout: 5 * time.Second})
+			conversation := newLocalSaslConversation(tc.localSaslAuth, apis.ConnectionInfo{})
+			_, done, err := conversation.step(tc.saslAuthBytes)
+			a.Nil(err)
+			a.True(done)
+			a.Equal(tc.principal, localSasl.newSession(conversation, 0).principal)
+		})
+	}
+}
+
+type recordingTokenInfo struct {
+	request apis.VerifyRequest
+}
+
+func (p *recordingTokenInfo) VerifyToken(_ context.Context, request apis.VerifyRequest) (apis.VerifyResponse, error) {
+	p.request = request
+	return apis.VerifyResponse{Success: true}, nil
+}
+
+type recordingPasswordAuthenticator struct {
+	request apis.AuthenticateRequest
+}
+
+func (pa *recordingPasswordAuthenticator) Authenticate(_, _ string) (bool, int32, error) {
+	return false, 0, nil
+}
+
+func (pa *recordingPasswordAuthenticator) AuthenticatePrincipal(_ context.Context, request apis.AuthenticateRequest) (apis.AuthenticateResponse, error) {
+	pa.request = request
+	return apis.AuthenticateResponse{Authenticated: true}, nil
+}
+
+func TestLocalSaslConnectionInfo(t *testing.T) {
+	a := assert.New(t)
+
+	connection := apis.ConnectionInfo{
+		ClientAddress:      "10.0.0.1:50000",
+		ListenerAddress:    "0.0.0.0:32400",
+		ListenerProfile:    "external",
+		TLSClientSubject:   "CN=client",
+		TLSServerName:      "kafka.example.com",
+		TLSClientPrincipal: "spiffe://example.org/client",
+	}
+
+	tokenInfo := &recordingTokenInfo{}
+	conversation := newLocalSaslConversation(NewLocalSaslOauth(tokenInfo), connection)
+	_, _, err := conversation.step([]byte("n,a=alice,\x01auth=Bearer token\x01traceId=123\x01\x01"))
+	a.Nil(err)
+	a.Equal(apis.VerifyRequest{Token: "token", Connection: connection, AuthzID: "alice", Extensions: map[string]string{"traceId": "123"}}, tokenInfo.request)
+
+	passwordAuthenticator := &recordingPasswordAuthenticator{}
+	conversation = newLocalSaslConversation(NewLocalSaslPlain(passwordAuthenticator), connection)
+	_, _, err = conversation.step([]byte("\x00alice\x00secret"))
+	a.Nil(err)
+	a.Equal(apis.AuthenticateRequest{Username: "alice", Password: "secret", Connection: connection}, passwordAuthenticator.request)
+}
+
 type fakeDeadlineReaderWriter struct {
 	reader *bytes.Buffer
 	writer *bytes.Buffer
@@ -124,3 +255,122 @@ func (r *fakeDeadlineReaderWriter) SetReadDeadline(t time.Time) error {
 func (r *fakeDeadlineReaderWriter) SetWriteDeadline(t time.Time) error {
 	return nil
 }
+
+func TestLocalSaslHandshakeEnabledMechanisms(t *testing.T) {
+	localSasl := NewLocalSasl(LocalSaslParams{
+		enabled: true,
+		timeout: 5 * time.Second,
+		localAuthenticators: map[string]LocalSaslAuth{
+			SASLPlain:       NewLocalSaslPlain(&fakePasswordAuthenticator{}),
+			SASLOAuthBearer: NewLocalSaslOauth(nil),
+			SASLSCRAM512:    nil,
+		},
+	})
+	tests := []struct {
+		name      string
+		mechanism string
+		saslErr   protocol.KError
+		errorMsg  string
+	}{
+		{
+			name:      "enabled mechanism",
+			mechanism: SASLOAuthBearer,
+			saslErr:   protocol.ErrNoError,
+		},
+		{
+			name:      "not enabled mechanism",
+			mechanism: SASLSCRAM512,
+			saslErr:   protocol.ErrUnsupportedSASLMechanism,
+			errorMsg:  "SCRAM-SHA-512 mechanism is not enabled, [OAUTHBEARER PLAIN] are configured",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			reqBuf, err := protocol.Encode(&protocol.Request{CorrelationID: 7, ClientID: "test-client", Body: &protocol.SaslHandshakeRequestV0orV1{Version: 1, Mechanism: tc.mechanism}})
+			a.Nil(err)
+			sizeBuf := make([]byte, 4)
+			binary.BigEndian.PutUint32(sizeBuf, uint32(len(reqBuf)))
+			reqBytes := bytes.Join([][]byte{sizeBuf, reqBuf}, nil)
+
+			conn := &fakeDeadlineReaderWriter{
+				reader: bytes.NewBuffer(reqBytes[8:]),
+				writer: new(bytes.Buffer),
+			}
+			localSaslAuth, mechanism, err := localSasl.receiveAndSendSaslV0orV1(conn, reqBytes[:8], 1)
+			if tc.errorMsg == "" {
+				a.Nil(err)
+				a.NotNil(localSaslAuth)
+				a.Equal(tc.mechanism, mechanism)
+			} else if a.NotNil(err) {
+				a.Equal(tc.errorMsg, err.Error())
+			}
+
+			written := conn.writer.Bytes()
+			a.Equal(uint32(7), binary.BigEndian.Uint32(written[4:8]))
+			res := &protocol.SaslHandshakeResponseV0orV1{}
+			a.Nil(protocol.Decode(written[8:], res))
+			a.Equal(tc.saslErr, res.Err)
+			a.Equal([]string{SASLOAuthBearer, SASLPlain}, res.EnabledMechanisms)
+		})
+	}
+}
+
+func newTestJWT(claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"none"}`)) + "." + encode([]byte(claims)) + "." + encode([]byte("signature"))
+}
+
+func TestLocalSaslSessionLifetime(t *testing.T) {
+	expiry := time.Now().Add(10 * time.Minute)
+	tests := []struct {
+		name               string
+		token              string
+		maxSessionLifetime time.Duration
+		sessionLifetime    time.Duration
+	}{
+		{
+			name:               "session expiry disabled",
+			token:              newTestJWT(fmt.Sprintf(`{"sub":"alice","exp":%d}`, expiry.Unix())),
+			maxSessionLifetime: 0,
+			sessionLifetime:    0,
+		},
+		{
+			name:               "token expires before max session lifetime",
+			token:              newTestJWT(fmt.Sprintf(`{"sub":"alice","exp":%d}`, expiry.Unix())),
+			maxSessionLifetime: time.Hour,
+			sessionLifetime:    10 * time.Minute,
+		},
+		{
+			name:               "token expires after max session lifetime",
+			token:              newTestJWT(fmt.Sprintf(`{"sub":"alice","exp":%d}`, expiry.Unix())),
+			maxSessionLifetime: time.Minute,
+			sessionLifetime:    time.Minute,
+		},
+		{
+			name:               "token without expiry",
+			token:              newTestJWT(`{"sub":"alice"}`),
+			maxSessionLifetime: time.Hour,
+			sessionLifetime:    time.Hour,
+		},
+		{
+			name:               "opaque token",
+			token:              "opaque-token",
+			maxSessionLifetime: time.Hour,
+			sessionLifetime:    time.Hour,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			localSasl := NewLocalSasl(LocalSaslParams{enabled: true, timeout: 5 * time.Second, maxSessionLifetime: tc.maxSessionLifetime})
+			conversation := newLocalSaslConversation(NewLocalSaslOauth(&testTokenInfo{token: tc.token}), apis.ConnectionInfo{})
+			_, done, err := conversation.step([]byte("n,,\x01auth=Bearer " + tc.token + "\x01\x01"))
+			a.Nil(err)
+			a.True(done)
+			a.InDelta(tc.sessionLifetime.Milliseconds(), localSasl.sessionLifetime(conversation).Milliseconds(), float64(time.Second.Milliseconds()))
+		})
+	}
+}
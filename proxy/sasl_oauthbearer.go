@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -22,6 +23,9 @@ var (
 	saslOauthKVPairs                      = fmt.Sprintf("(%s=%s%s)*", saslOauthKey, saslOauthValue, saslOauthSeparator)
 	saslOauthAuthPattern                  = regexp.MustCompile(`(?P<scheme>\w+)[ ]+(?P<token>[-_.a-zA-Z0-9]+)`)
 	saslOauthClientInitialResponsePattern = regexp.MustCompile(fmt.Sprintf("n,(a=(?P<authzid>%s))?,%s(?P<kvpairs>%s)%s", saslOauthSaslName, saslOauthSeparator, saslOauthKVPairs, saslOauthSeparator))
+	saslOauthSaslNamePattern              = regexp.MustCompile(fmt.Sprintf("^%s$", saslOauthSaslName))
+	saslOauthKeyPattern                   = regexp.MustCompile(fmt.Sprintf("^%s$", saslOauthKey))
+	saslOauthValuePattern                 = regexp.MustCompile(fmt.Sprintf("^%s$", saslOauthValue))
 )
 
 type SaslOAuthBearer struct{}
@@ -93,9 +97,14 @@ func (SaslOAuthBearer) mkString(mapValues map[string]string, keyValueSeparator s
 	if len(mapValues) == 0 {
 		return ""
 	}
+	keys := make([]string, 0, len(mapValues))
+	for k := range mapValues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	elements := make([]string, 0, len(mapValues))
-	for k, v := range mapValues {
-		elements = append(elements, strings.Join([]string{k, v}, keyValueSeparator))
+	for _, k := range keys {
+		elements = append(elements, strings.Join([]string{k, mapValues[k]}, keyValueSeparator))
 	}
 	return strings.Join(elements, elementSeparator)
 }
@@ -113,3 +122,22 @@ func (p SaslOAuthBearer) ToBytes(tokenValue string, authorizationId string, sasl
 		saslOauthSeparator, tokenValue, extensions, saslOauthSeparator, saslOauthSeparator)
 	return []byte(message)
 }
+
+// ValidateClientInitialResponse validates the authzid (RFC 5801 saslname) and the SASL extensions (RFC 7628 key and value) sent with the token
+func (SaslOAuthBearer) ValidateClientInitialResponse(authorizationId string, saslExtensions map[string]string) error {
+	if authorizationId != "" && !saslOauthSaslNamePattern.MatchString(authorizationId) {
+		return fmt.Errorf("invalid OAUTHBEARER authzid '%s'", authorizationId)
+	}
+	for key, value := range saslExtensions {
+		if key == saslOauthAuthKey {
+			return fmt.Errorf("OAUTHBEARER extension key '%s' is reserved", key)
+		}
+		if !saslOauthKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid OAUTHBEARER extension key '%s'", key)
+		}
+		if !saslOauthValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid OAUTHBEARER extension value of the key '%s'", key)
+		}
+	}
+	return nil
+}
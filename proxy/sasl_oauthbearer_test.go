@@ -70,3 +70,35 @@ func TestSaslOAuthBearerNoExtensionsFromByteArray(t *testing.T) {
 	a.Equal("user@example.com", authzid)
 	a.Empty(extensions)
 }
+
+func TestSaslOAuthBearerValidateClientInitialResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		authzid    string
+		extensions map[string]string
+		errorMsg   string
+	}{
+		{name: "empty"},
+		{name: "authzid and extensions", authzid: "user@example.com", extensions: map[string]string{"traceId": "abc", "logicalCluster": "lkc-1, lkc-2"}},
+		{name: "invalid authzid", authzid: "a,b", errorMsg: "invalid OAUTHBEARER authzid 'a,b'"},
+		{name: "reserved key", extensions: map[string]string{"auth": "Bearer 123"}, errorMsg: "OAUTHBEARER extension key 'auth' is reserved"},
+		{name: "invalid key", extensions: map[string]string{"trace-id": "abc"}, errorMsg: "invalid OAUTHBEARER extension key 'trace-id'"},
+		{name: "invalid value", extensions: map[string]string{"traceId": "a\u0001b"}, errorMsg: "invalid OAUTHBEARER extension value of the key 'traceId'"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := SaslOAuthBearer{}.ValidateClientInitialResponse(tc.authzid, tc.extensions)
+			if tc.errorMsg != "" {
+				assert.EqualError(t, err, tc.errorMsg)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestSaslOAuthBearerToBytesSortsExtensions(t *testing.T) {
+	a := assert.New(t)
+	authBytes := SaslOAuthBearer{}.ToBytes("123", "alice", map[string]string{"propB": "valueB", "propA": "valueA"})
+	a.Equal("n,a=alice,\u0001auth=Bearer 123\u0001propA=valueA\u0001propB=valueB\u0001\u0001", string(authBytes))
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/proxy/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+const apiKeySaslAuthenticate = int16(36)
+
+// BrokerSaslSession re-authenticates the connection to the broker before the SASL session expires (KIP-368).
+// Re-authentication is done by the requests loop between client requests, the SASL responses are passed by the responses loop.
+type BrokerSaslSession struct {
+	auth          SASLSessionAuthByProxy
+	brokerAddress string
+	cluster       string
+	// reauthTime is the time after which the session is re-authenticated, zero when the session does not expire
+	reauthTime time.Time
+	responses  chan []byte
+}
+
+func newBrokerSaslSession(auth SASLSessionAuthByProxy, brokerAddress string, cluster string, sessionLifetime time.Duration) *BrokerSaslSession {
+	session := &BrokerSaslSession{
+		auth:          auth,
+		brokerAddress: brokerAddress,
+		cluster:       cluster,
+		responses:     make(chan []byte, 1),
+	}
+	session.setSessionLifetime(sessionLifetime)
+	return session
+}
+
+// setSessionLifetime schedules the re-authentication between 85% and 95% of the session lifetime like Kafka clients do
+func (s *BrokerSaslSession) setSessionLifetime(sessionLifetime time.Duration) {
+	if sessionLifetime <= 0 {
+		s.reauthTime = time.Time{}
+		return
+	}
+	s.reauthTime = time.Now().Add(time.Duration(float64(sessionLifetime) * (0.85 + 0.1*rand.Float64())))
+}
+
+func (s *BrokerSaslSession) reauthenticationDue() bool {
+	return !s.reauthTime.IsZero() && time.Now().After(s.reauthTime)
+}
+
+// reauthenticate runs the SASL exchange on the broker connection, responses to the in-flight requests are passed to the client before the SASL responses
+func (s *BrokerSaslSession) reauthenticate(dst DeadlineWriter, ctx *RequestsLoopContext) error {
+	logrus.Debugf("Re-authenticating SASL session to broker %s", s.brokerAddress)
+
+	conn := &brokerSaslConn{dst: dst, ctx: ctx, responses: s.responses}
+	sessionLifetime, err := s.auth.sendAndReceiveSASLSessionAuth(conn, s.brokerAddress)
+	if err != nil {
+		proxyBrokerReauthTotal.WithLabelValues(s.brokerAddress, s.cluster, "false").Inc()
+		return fmt.Errorf("SASL re-authentication to broker %s failed: %w", s.brokerAddress, err)
+	}
+	proxyBrokerReauthTotal.WithLabelValues(s.brokerAddress, s.cluster, "true").Inc()
+	s.setSessionLifetime(sessionLifetime)
+	return nil
+}
+
+// brokerSaslConn writes the SASL requests to the broker and reads the SASL responses passed by the responses loop
+type brokerSaslConn struct {
+	dst          DeadlineWriter
+	ctx          *RequestsLoopContext
+	responses    <-chan []byte
+	response     bytes.Reader
+	readDeadline time.Time
+}
+
+// Write sends a single request, SASL mechanisms write the size and the request at once
+func (c *brokerSaslConn) Write(p []byte) (int, error) {
+	if len(p) < 8 {
+		return 0, errors.New("SASL request is too short")
+	}
+	requestKeyVersion := &protocol.RequestKeyVersion{}
+	if err := protocol.Decode(p[:8], requestKeyVersion); err != nil {
+		return 0, err
+	}
+	if requestKeyVersion.ApiKey != apiKeySaslHandshake && requestKeyVersion.ApiKey != apiKeySaslAuthenticate {
+		return 0, fmt.Errorf("unexpected SASL request api key %d", requestKeyVersion.ApiKey)
+	}
+	if err := sendRequestKeyVersion(c.ctx.openRequestsChannel, openRequestSendTimeout, requestKeyVersion); err != nil {
+		return 0, err
+	}
+	if err := c.ctx.putNextResponseHandler(defaultResponseHandler); err != nil {
+		return 0, err
+	}
+	return c.dst.Write(p)
+}
+
+func (c *brokerSaslConn) Read(p []byte) (int, error) {
+	if c.response.Len() == 0 {
+		var deadline <-chan time.Time
+		if !c.readDeadline.IsZero() {
+			timer := time.NewTimer(time.Until(c.readDeadline))
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		select {
+		case response := <-c.responses:
+			c.response.Reset(response)
+		case <-deadline:
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+	return c.response.Read(p)
+}
+
+func (c *brokerSaslConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline = t
+	return nil
+}
+
+func (c *brokerSaslConn) SetWriteDeadline(t time.Time) error {
+	return c.dst.SetWriteDeadline(t)
+}
+
+func (c *brokerSaslConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+// sendBrokerSaslResponse passes the SASL response to the re-authentication waiting in the requests loop
+func sendBrokerSaslResponse(responses chan<- []byte, response []byte, timeout time.Duration) error {
+	select {
+	case responses <- response:
+	default:
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case responses <- response:
+		case <-timer.C:
+			return errors.New("SASL re-authentication is not waiting for the response")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/proxy/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBroker answers requests of the proxy connection, SASL requests are recorded
+type testBroker struct {
+	conn         net.Conn
+	apiKeys      []int16
+	saslMessages [][]byte
+}
+
+func (b *testBroker) readRequest(t *testing.T) (apiKey int16, correlationID int32, body []byte) {
+	sizeBuf := make([]byte, 4)
+	if _, err := io.ReadFull(b.conn, sizeBuf); err != nil {
+		t.Fatal(err)
+	}
+	request := make([]byte, binary.BigEndian.Uint32(sizeBuf))
+	if _, err := io.ReadFull(b.conn, request); err != nil {
+		t.Fatal(err)
+	}
+	apiKey = int16(binary.BigEndian.Uint16(request[0:2]))
+	correlationID = int32(binary.BigEndian.Uint32(request[4:8]))
+	b.apiKeys = append(b.apiKeys, apiKey)
+	return apiKey, correlationID, request
+}
+
+func (b *testBroker) writeResponse(t *testing.T, correlationID int32, body []byte) {
+	header, err := protocol.Encode(&protocol.ResponseHeader{Length: int32(len(body) + 4), CorrelationID: correlationID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.conn.Write(append(header, body...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestApiVersionsRequest(t *testing.T, conn net.Conn, correlationID int32) {
+	request := make([]byte, 14)
+	binary.BigEndian.PutUint32(request[0:4], 10)
+	binary.BigEndian.PutUint16(request[4:6], uint16(apiKeyApiApiVersions))
+	binary.BigEndian.PutUint32(request[8:12], uint32(correlationID))
+	binary.BigEndian.PutUint16(request[12:14], 0xffff)
+	if _, err := conn.Write(request); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestResponseCorrelationID(t *testing.T, conn net.Conn) int32 {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, binary.BigEndian.Uint32(header[0:4])-4)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		t.Fatal(err)
+	}
+	return int32(binary.BigEndian.Uint32(header[4:8]))
+}
+
+func TestBrokerSaslSessionReauthentication(t *testing.T) {
+	a := assert.New(t)
+
+	client, proxyLocal := net.Pipe()
+	proxyRemote, brokerConn := net.Pipe()
+	defer client.Close()
+	defer brokerConn.Close()
+
+	auth := &SASLOAuthBearerAuth{
+		clientID:      "kafka-proxy",
+		writeTimeout:  5 * time.Second,
+		readTimeout:   5 * time.Second,
+		tokenProvider: &testTokenProvider{response: apis.TokenResponse{Success: true, Token: "new-token"}},
+	}
+	session := newBrokerSaslSession(auth, "broker:9092", "", 100*time.Millisecond)
+	processor := newProcessor(ProcessorConfig{LocalSasl: &LocalSasl{}, AuthServer: &AuthServer{}, BrokerSaslSession: session}, "broker:9092")
+	go func() {
+		_, _ = processor.RequestsLoop(proxyRemote, proxyLocal)
+		_ = proxyRemote.Close()
+	}()
+	go func() {
+		_, _ = processor.ResponsesLoop(proxyLocal, proxyRemote)
+		_ = proxyLocal.Close()
+	}()
+
+	broker := &testBroker{conn: brokerConn}
+	brokerDone := make(chan struct{})
+	go func() {
+		defer close(brokerDone)
+		// the first request is forwarded before the session is due for re-authentication
+		_, firstCorrelationID, _ := broker.readRequest(t)
+		// re-authentication starts before the second request, the first response is still in-flight
+		apiKey, correlationID, _ := broker.readRequest(t)
+		if apiKey != apiKeySaslHandshake {
+			t.Errorf("SaslHandshake expected, got api key %d", apiKey)
+			return
+		}
+		broker.writeResponse(t, firstCorrelationID, []byte{0, 0, 0, 0, 0, 0})
+		body, _ := protocol.Encode(&protocol.SaslHandshakeResponseV0orV1{Err: protocol.ErrNoError, EnabledMechanisms: []string{SASLOAuthBearer}})
+		broker.writeResponse(t, correlationID, body)
+
+		apiKey, correlationID, request := broker.readRequest(t)
+		if apiKey != apiKeySaslAuthenticate {
+			t.Errorf("SaslAuthenticate expected, got api key %d", apiKey)
+			return
+		}
+		broker.saslMessages = append(broker.saslMessages, request)
+		body, _ = protocol.Encode(&protocol.SaslAuthenticateResponseV1{Err: protocol.ErrNoError, SaslAuthBytes: []byte{}, SessionLifetimeMs: time.Hour.Milliseconds()})
+		broker.writeResponse(t, correlationID, body)
+
+		_, correlationID, _ = broker.readRequest(t)
+		broker.writeResponse(t, correlationID, []byte{0, 0, 0, 0, 0, 0})
+	}()
+
+	// pipes are not buffered, responses are read while the requests are written
+	go func() {
+		writeTestApiVersionsRequest(t, client, 1)
+		time.Sleep(200 * time.Millisecond)
+		writeTestApiVersionsRequest(t, client, 2)
+	}()
+
+	// SASL responses are not passed to the client
+	a.Equal(int32(1), readTestResponseCorrelationID(t, client))
+	a.Equal(int32(2), readTestResponseCorrelationID(t, client))
+	<-brokerDone
+
+	a.Equal([]int16{apiKeyApiApiVersions, apiKeySaslHandshake, apiKeySaslAuthenticate, apiKeyApiApiVersions}, broker.apiKeys)
+	if a.Len(broker.saslMessages, 1) {
+		a.Contains(string(broker.saslMessages[0]), "auth=Bearer new-token")
+	}
+	a.True(session.reauthTime.After(time.Now().Add(50 * time.Minute)))
+	a.False(session.reauthenticationDue())
+}
+
+func TestBrokerSaslSessionRejectsClientSasl(t *testing.T) {
+	a := assert.New(t)
+
+	client, proxyLocal := net.Pipe()
+	proxyRemote, brokerConn := net.Pipe()
+	defer client.Close()
+	defer brokerConn.Close()
+
+	session := newBrokerSaslSession(&SASLOAuthBearerAuth{}, "broker:9092", "", time.Hour)
+	processor := newProcessor(ProcessorConfig{LocalSasl: &LocalSasl{}, AuthServer: &AuthServer{}, BrokerSaslSession: session}, "broker:9092")
+	result := make(chan error, 1)
+	go func() {
+		_, err := processor.RequestsLoop(proxyRemote, proxyLocal)
+		result <- err
+	}()
+
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint16(request[4:6], uint16(apiKeySaslHandshake))
+	binary.BigEndian.PutUint16(request[6:8], 1)
+	_, err := client.Write(request)
+	a.Nil(err)
+
+	err = <-result
+	if a.NotNil(err) {
+		a.Equal("SASL requests are not forwarded, proxy re-authenticates the broker connection", err.Error())
+	}
+}
+
+func TestBrokerSaslSessionLifetime(t *testing.T) {
+	a := assert.New(t)
+
+	session := newBrokerSaslSession(&SASLOAuthBearerAuth{}, "broker:9092", "", 0)
+	a.True(session.reauthTime.IsZero())
+	a.False(session.reauthenticationDue())
+
+	session.setSessionLifetime(time.Hour)
+	a.True(session.reauthTime.After(time.Now().Add(50 * time.Minute)))
+	a.True(session.reauthTime.Before(time.Now().Add(58 * time.Minute)))
+	a.False(session.reauthenticationDue())
+
+	session.setSessionLifetime(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	a.True(session.reauthenticationDue())
+}
@@ -56,9 +56,7 @@ var (
 	zeroTime = time.Time{}
 )
 
-func newTLSListenerConfig(conf *config.Config) (*tls.Config, error) {
-	opts := conf.Proxy.TLS
-
+func newTLSListenerConfig(opts *config.ListenerTLSConfig) (*tls.Config, error) {
 	cipherSuites, err := getCipherSuites(opts.ListenerCipherSuites)
 	if err != nil {
 		return nil, err
@@ -67,7 +65,7 @@ func newTLSListenerConfig(conf *config.Config) (*tls.Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	tlsValidateFunc, err := tlsClientCertVerificationFunc(conf)
+	tlsValidateFunc, err := tlsClientCertVerificationFunc(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -185,6 +183,26 @@ func handshakeAsTLSAndValidateClientCert(conn net.Conn, expectedCert *x509.Certi
 	return result
 }
 
+// handshakeAsTLSAndGetVerifiedClientCert returns the client certificate verified by the TLS handshake of the client connection
+func handshakeAsTLSAndGetVerifiedClientCert(conn net.Conn, handshakeTimeout time.Duration) (*x509.Certificate, error) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil, errors.New("Unable to cast connection to TLS when getting client cert")
+	}
+	if err := handshakeTLSConn(tlsConn, handshakeTimeout); err != nil {
+		return nil, err
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.VerifiedChains) == 0 {
+		return nil, errors.New("Client cert is not verified")
+	}
+	clientCert := filterClientCertificate(state.PeerCertificates)
+	if clientCert == nil {
+		return nil, errors.New("Client cert not found")
+	}
+	return clientCert, nil
+}
+
 func handshakeTLSConn(tlsConn *tls.Conn, timeout time.Duration) error {
 	err := tlsConn.SetDeadline(time.Now().Add(timeout))
 	if err != nil {
@@ -0,0 +1,225 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/pkg/errors"
+)
+
+var clientCertIssuerNow = time.Now
+
+// clientCertIssuer issues short-lived client certificates of the broker connections signed by the local intermediate CA.
+// Certificates are cached per subject and reused for the first half of their validity.
+type clientCertIssuer struct {
+	caCert *x509.Certificate
+	caKey  crypto.Signer
+	// caChain is sent after the issued certificate, so the brokers can build the chain up to their trusted root
+	caChain    [][]byte
+	validity   time.Duration
+	maxEntries int
+	cluster    string
+
+	mu      sync.Mutex
+	entries map[string]clientCertEntry
+}
+
+type clientCertEntry struct {
+	cert *tls.Certificate
+	// renew is the time after which a new certificate is issued for the subject
+	renew time.Time
+}
+
+func newClientCertIssuer(c *config.Config) (*clientCertIssuer, error) {
+	issuerConfig := c.Kafka.TLS.ClientCertIssuer
+	caChain, err := readCertificateChain(issuerConfig.CACertFile)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := x509.ParseCertificate(caChain[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse CA certificate file from location '%s'", issuerConfig.CACertFile)
+	}
+	if !caCert.IsCA {
+		return nil, errors.Errorf("certificate '%s' is not a CA certificate", issuerConfig.CACertFile)
+	}
+	caKey, err := readSignerKey(issuerConfig.CAKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if !publicKeysEqual(caKey.Public(), caCert.PublicKey) {
+		return nil, errors.Errorf("CA private key '%s' does not match the CA certificate '%s'", issuerConfig.CAKeyFile, issuerConfig.CACertFile)
+	}
+	return &clientCertIssuer{
+		caCert:     caCert,
+		caKey:      caKey,
+		caChain:    caChain,
+		validity:   issuerConfig.Validity,
+		maxEntries: issuerConfig.CacheMaxEntries,
+		cluster:    c.Cluster,
+		entries:    make(map[string]clientCertEntry),
+	}, nil
+}
+
+// principalCertSubject returns the DER encoded subject with the principal as common name
+func principalCertSubject(principal string) ([]byte, error) {
+	return asn1.Marshal(pkix.Name{CommonName: principal}.ToRDNSequence())
+}
+
+// certificate returns the client certificate with the DER encoded subject
+func (i *clientCertIssuer) certificate(rawSubject []byte) (*tls.Certificate, error) {
+	key := string(rawSubject)
+	now := clientCertIssuerNow()
+
+	i.mu.Lock()
+	entry, ok := i.entries[key]
+	i.mu.Unlock()
+	if ok && now.Before(entry.renew) {
+		proxyBrokerClientCertsTotal.WithLabelValues(i.cluster, "cached").Inc()
+		return entry.cert, nil
+	}
+
+	cert, err := i.issue(rawSubject, now)
+	if err != nil {
+		proxyBrokerClientCertsTotal.WithLabelValues(i.cluster, "error").Inc()
+		return nil, err
+	}
+	proxyBrokerClientCertsTotal.WithLabelValues(i.cluster, "issued").Inc()
+	// validity of the certificate is capped by the CA certificate
+	i.put(key, clientCertEntry{cert: cert, renew: now.Add(cert.Leaf.NotAfter.Sub(now) / 2)}, now)
+	return cert, nil
+}
+
+func (i *clientCertIssuer) issue(rawSubject []byte, now time.Time) (*tls.Certificate, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate client certificate key")
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate client certificate serial number")
+	}
+	notAfter := now.Add(i.validity)
+	if notAfter.After(i.caCert.NotAfter) {
+		notAfter = i.caCert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		RawSubject:   rawSubject,
+		// tolerate clock skew between the proxy and the brokers
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, i.caCert, privateKey.Public(), i.caKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to issue client certificate")
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse issued client certificate")
+	}
+	return &tls.Certificate{
+		Certificate: append([][]byte{der}, i.caChain...),
+		PrivateKey:  privateKey,
+		Leaf:        leaf,
+	}, nil
+}
+
+func (i *clientCertIssuer) put(key string, entry clientCertEntry, now time.Time) {
+	if i.maxEntries <= 0 {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.entries) >= i.maxEntries {
+		for k, v := range i.entries {
+			if !now.Before(v.renew) {
+				delete(i.entries, k)
+			}
+		}
+		if len(i.entries) >= i.maxEntries {
+			return
+		}
+	}
+	i.entries[key] = entry
+}
+
+// readCertificateChain returns the DER encoded certificates of the PEM file, the first one is the issuing certificate
+func readCertificateChain(certFile string) ([][]byte, error) {
+	content, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, errors.Errorf("Failed to read file from location '%s'", certFile)
+	}
+	var chain [][]byte
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			chain = append(chain, block.Bytes)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, errors.Errorf("no certificate found in file from location '%s'", certFile)
+	}
+	return chain, nil
+}
+
+// readSignerKey returns the unencrypted PKCS #8, PKCS #1 or SEC 1 private key of the PEM file
+func readSignerKey(keyFile string) (crypto.Signer, error) {
+	content, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Errorf("Failed to read file from location '%s'", keyFile)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.Errorf("no private key found in file from location '%s'", keyFile)
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse private key file from location '%s'", keyFile)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf("unsupported private key type in file from location '%s'", keyFile)
+	}
+	return signer, nil
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	aDer, err := x509.MarshalPKIXPublicKey(a)
+	if err != nil {
+		return false
+	}
+	bDer, err := x509.MarshalPKIXPublicKey(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aDer, bDer)
+}
@@ -0,0 +1,315 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/proxy/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClientCertIssuer(t *testing.T, bundle *CertsBundle) *clientCertIssuer {
+	c := config.NewConfig()
+	c.Kafka.TLS.ClientCertIssuer = config.ClientCertIssuerConfig{
+		Enable:          true,
+		CACertFile:      bundle.CACert.Name(),
+		CAKeyFile:       bundle.CAKey.Name(),
+		SubjectSource:   config.ClientCertSubjectSourcePrincipal,
+		Validity:        time.Hour,
+		CacheMaxEntries: 10,
+	}
+	issuer, err := newClientCertIssuer(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issuer
+}
+
+func TestClientCertIssuerCertificate(t *testing.T) {
+	a := assert.New(t)
+
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+	issuer := newTestClientCertIssuer(t, bundle)
+
+	now := time.Now()
+	clientCertIssuerNow = func() time.Time { return now }
+	defer func() { clientCertIssuerNow = time.Now }()
+
+	subject, err := principalCertSubject("alice")
+	a.Nil(err)
+	cert, err := issuer.certificate(subject)
+	a.Nil(err)
+	a.Equal("CN=alice", cert.Leaf.Subject.String())
+	a.Equal(now.Add(time.Hour).Truncate(time.Second).UTC(), cert.Leaf.NotAfter)
+	a.Len(cert.Certificate, 2)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(issuer.caCert)
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: now, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	a.Nil(err)
+
+	// reused for the first half of the validity
+	now = now.Add(29 * time.Minute)
+	cached, err := issuer.certificate(subject)
+	a.Nil(err)
+	a.True(cert == cached)
+
+	now = now.Add(time.Minute)
+	renewed, err := issuer.certificate(subject)
+	a.Nil(err)
+	a.False(cert == renewed)
+
+	// the subject of the client certificate is preserved
+	clientCert, err := parseCertificate(bundle.ClientCert.Name())
+	a.Nil(err)
+	other, err := issuer.certificate(clientCert.RawSubject)
+	a.Nil(err)
+	a.Equal(clientCert.Subject.String(), other.Leaf.Subject.String())
+}
+
+func TestClientCertIssuerCertificateCappedByCA(t *testing.T) {
+	a := assert.New(t)
+
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+	issuer := newTestClientCertIssuer(t, bundle)
+
+	now := time.Now()
+	clientCertIssuerNow = func() time.Time { return now }
+	defer func() { clientCertIssuerNow = time.Now }()
+
+	remaining := issuer.caCert.NotAfter.Sub(now)
+	issuer.validity = 4 * remaining
+
+	subject, err := principalCertSubject("alice")
+	a.Nil(err)
+	cert, err := issuer.certificate(subject)
+	a.Nil(err)
+	a.Equal(issuer.caCert.NotAfter, cert.Leaf.NotAfter)
+
+	// renewed after the first half of the capped validity
+	now = now.Add(remaining/2 + time.Minute)
+	renewed, err := issuer.certificate(subject)
+	a.Nil(err)
+	a.False(cert == renewed)
+}
+
+func TestNewClientCertIssuerErrors(t *testing.T) {
+	a := assert.New(t)
+
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+
+	c := config.NewConfig()
+	c.Kafka.TLS.ClientCertIssuer = config.ClientCertIssuerConfig{Enable: true, CACertFile: bundle.CACert.Name(), CAKeyFile: bundle.ServerKey.Name(), Validity: time.Hour}
+	_, err := newClientCertIssuer(c)
+	a.EqualError(err, "CA private key '"+bundle.ServerKey.Name()+"' does not match the CA certificate '"+bundle.CACert.Name()+"'")
+
+	c.Kafka.TLS.ClientCertIssuer.CACertFile = bundle.ServerCert.Name()
+	_, err = newClientCertIssuer(c)
+	a.EqualError(err, "certificate '"+bundle.ServerCert.Name()+"' is not a CA certificate")
+}
+
+func TestTLSDialerWithIssuedClientCertificate(t *testing.T) {
+	a := assert.New(t)
+
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+	issuer := newTestClientCertIssuer(t, bundle)
+
+	c := new(config.Config)
+	c.Proxy.TLS.ListenerCertFile = bundle.ServerCert.Name()
+	c.Proxy.TLS.ListenerKeyFile = bundle.ServerKey.Name()
+	c.Proxy.TLS.ListenerCAChainCertFile = bundle.CACert.Name()
+	c.Kafka.TLS.CAChainCertFile = bundle.CACert.Name()
+	c.Kafka.TLS.ClientCertFile = bundle.ClientCert.Name()
+	c.Kafka.TLS.ClientKeyFile = bundle.ClientKey.Name()
+
+	serverConfig, err := newTLSListenerConfig(&c.Proxy.TLS)
+	a.Nil(err)
+	clientConfig, err := newTLSClientConfig(c)
+	a.Nil(err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	a.Nil(err)
+	defer listener.Close()
+
+	subjects := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			subjects <- err.Error()
+			return
+		}
+		defer conn.Close()
+		tlsConn := conn.(*tls.Conn)
+		if err := tlsConn.Handshake(); err != nil {
+			subjects <- err.Error()
+			return
+		}
+		subjects <- tlsConn.ConnectionState().PeerCertificates[0].Subject.String()
+	}()
+
+	subject, err := principalCertSubject("alice")
+	a.Nil(err)
+	cert, err := issuer.certificate(subject)
+	a.Nil(err)
+	dialer := tlsDialer{
+		timeout:    3 * time.Second,
+		rawDialer:  directDialer{dialTimeout: 3 * time.Second, keepAlive: 60 * time.Second},
+		configFunc: clientConfig,
+	}.withClientCertificate(cert)
+	conn, err := dialer.Dial("tcp", listener.Addr().String())
+	a.Nil(err)
+	defer conn.Close()
+	a.Equal("CN=alice", <-subjects)
+}
+
+func TestReconnectableConnReplace(t *testing.T) {
+	a := assert.New(t)
+
+	oldConn, oldBroker := net.Pipe()
+	newConn, newBroker := net.Pipe()
+	defer oldBroker.Close()
+	defer newBroker.Close()
+
+	conn := newReconnectableConn(oldConn)
+	read := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			read <- err.Error()
+			return
+		}
+		read <- string(buf)
+	}()
+	// the pending read continues with the new connection
+	time.Sleep(50 * time.Millisecond)
+	a.Nil(conn.replace(newConn))
+	go func() { _, _ = newBroker.Write([]byte("pong")) }()
+	a.Equal("pong", <-read)
+
+	a.Nil(conn.Close())
+	another, _ := net.Pipe()
+	a.EqualError(conn.replace(another), "broker connection is closed")
+}
+
+func TestReconnectableConnReplaceKeepsDeadlines(t *testing.T) {
+	a := assert.New(t)
+
+	oldConn, oldBroker := net.Pipe()
+	newConn, newBroker := net.Pipe()
+	defer oldBroker.Close()
+	defer newBroker.Close()
+
+	conn := newReconnectableConn(oldConn)
+	a.Nil(conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond)))
+	a.Nil(conn.replace(newConn))
+
+	// the deadline of the replaced connection applies to the new connection
+	_, err := conn.Read(make([]byte, 4))
+	if a.NotNil(err) {
+		a.True(errors.Is(err, os.ErrDeadlineExceeded))
+	}
+	a.Nil(conn.Close())
+}
+
+func TestPendingConn(t *testing.T) {
+	a := assert.New(t)
+
+	brokerConn, broker := net.Pipe()
+	defer broker.Close()
+
+	conn := newPendingConn()
+	_, err := conn.Write([]byte("ping"))
+	a.EqualError(err, "broker connection is not open")
+	a.Nil(conn.SetDeadline(time.Time{}))
+
+	read := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			read <- err.Error()
+			return
+		}
+		read <- string(buf)
+	}()
+	// the pending read waits until the connection is opened
+	time.Sleep(50 * time.Millisecond)
+	a.Nil(conn.replace(brokerConn))
+	go func() { _, _ = broker.Write([]byte("pong")) }()
+	a.Equal("pong", <-read)
+	a.Nil(conn.Close())
+
+	// the pending read ends when the connection is closed before it is opened
+	conn = newPendingConn()
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 4))
+		readErr <- err
+	}()
+	a.Nil(conn.Close())
+	a.True(errors.Is(<-readErr, net.ErrClosed))
+	another, _ := net.Pipe()
+	a.EqualError(conn.replace(another), "broker connection is closed")
+}
+
+func TestPrincipalCertSubject(t *testing.T) {
+	a := assert.New(t)
+
+	subject, err := principalCertSubject("CN=bob,O=x")
+	a.Nil(err)
+	var rdn pkix.RDNSequence
+	_, err = asn1.Unmarshal(subject, &rdn)
+	a.Nil(err)
+	var name pkix.Name
+	name.FillFromRDNSequence(&rdn)
+	a.Equal("CN=bob,O=x", name.CommonName)
+}
+
+func TestBrokerClientCertSessionReconnect(t *testing.T) {
+	a := assert.New(t)
+
+	oldConn, _ := net.Pipe()
+	var dialed []string
+	session := newBrokerClientCertSession(oldConn, "broker:9092", func(rawSubject []byte) (net.Conn, time.Duration, error) {
+		var rdn pkix.RDNSequence
+		if _, err := asn1.Unmarshal(rawSubject, &rdn); err != nil {
+			return nil, 0, err
+		}
+		dialed = append(dialed, rdn.String())
+		conn, _ := net.Pipe()
+		return conn, time.Hour, nil
+	})
+	openRequests := make(chan protocol.RequestKeyVersion, 1)
+	// the session lifetime of the replaced connection is unknown
+	brokerSaslSession := newBrokerSaslSession(nil, "broker:9092", "", 0)
+	ctx := &RequestsLoopContext{openRequestsChannel: openRequests, brokerSaslSession: brokerSaslSession}
+
+	openRequests <- protocol.RequestKeyVersion{}
+	err := session.reconnect(ctx, localSaslSession{mechanism: SASLPlain, principal: apis.Principal{Name: "alice"}})
+	a.EqualError(err, "broker connection to broker:9092 cannot be replaced with 1 requests in flight")
+	<-openRequests
+
+	a.Nil(session.reconnect(ctx, localSaslSession{mechanism: SASLPlain, principal: apis.Principal{Name: "alice"}}))
+	a.False(session.conn.current() == oldConn)
+	// the re-authentication is scheduled by the session lifetime of the new connection
+	a.False(brokerSaslSession.reauthTime.IsZero())
+	a.True(brokerSaslSession.reauthTime.Before(time.Now().Add(time.Hour)))
+	// re-authentication keeps the connection
+	a.Nil(session.reconnect(ctx, localSaslSession{mechanism: SASLPlain, principal: apis.Principal{Name: "alice"}}))
+	a.Equal([]string{"CN=alice"}, dialed)
+
+	err = session.reconnect(ctx, localSaslSession{mechanism: SASLPlain, principal: apis.Principal{Name: "bob"}})
+	a.EqualError(err, "SASL re-authentication must not change the principal alice to bob")
+}
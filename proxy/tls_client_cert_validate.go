@@ -8,8 +8,8 @@ import (
 	"github.com/grepplabs/kafka-proxy/proxy/clientcertvalidate"
 )
 
-func tlsClientCertVerificationFunc(conf *config.Config) (func([][]byte, [][]*x509.Certificate) error, error) {
-	parsedSubjects, parserErr := getParsedSubjects(conf)
+func tlsClientCertVerificationFunc(opts *config.ListenerTLSConfig) (func([][]byte, [][]*x509.Certificate) error, error) {
+	parsedSubjects, parserErr := getParsedSubjects(opts)
 	if parserErr != nil {
 		return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error { return nil }, parserErr
 	}
@@ -34,9 +34,26 @@ func tlsClientCertVerificationFunc(conf *config.Config) (func([][]byte, [][]*x50
 	}, nil
 }
 
-func getParsedSubjects(conf *config.Config) ([]clientcertvalidate.ParsedSubject, error) {
+// tlsClientCertIdentityFunc returns the identity of the verified client certificate matched by the first validating client subject.
+// The identity is the subject DN when no client subject is defined or matches.
+func tlsClientCertIdentityFunc(opts *config.ListenerTLSConfig) (func(*x509.Certificate) string, error) {
+	parsedSubjects, err := getParsedSubjects(opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(cert *x509.Certificate) string {
+		for _, parsedSubject := range parsedSubjects {
+			if identity, err := parsedSubject.X509Identity(cert); err == nil {
+				return identity
+			}
+		}
+		return cert.Subject.String()
+	}, nil
+}
+
+func getParsedSubjects(opts *config.ListenerTLSConfig) ([]clientcertvalidate.ParsedSubject, error) {
 	parsedSubjects := []clientcertvalidate.ParsedSubject{}
-	for _, subject := range conf.Proxy.TLS.ClientCert.Subjects {
+	for _, subject := range opts.ClientCert.Subjects {
 		parser := clientcertvalidate.NewSubjectParser(subject)
 		parsedSubject, parseErr := parser.Parse()
 		if parseErr != nil {
@@ -344,3 +344,54 @@ func TestClientCertMultiplePatternMatchingFieldsOrderDoesNotMatter(t *testing.T)
 
 	a.Nil(err)
 }
+
+func TestClientCertSANValidate(t *testing.T) {
+	a := assert.New(t)
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+	c := new(config.Config)
+	c.Proxy.TLS.ListenerCertFile = bundle.ServerCert.Name()
+	c.Proxy.TLS.ListenerKeyFile = bundle.ServerKey.Name()
+	c.Proxy.TLS.ListenerCAChainCertFile = bundle.CACert.Name()
+
+	c.Kafka.TLS.CAChainCertFile = bundle.CACert.Name()
+	c.Kafka.TLS.ClientCertFile = bundle.ClientCert.Name()
+	c.Kafka.TLS.ClientKeyFile = bundle.ClientKey.Name()
+
+	c.Proxy.TLS.ClientCert.Subjects = []string{"s:/DNS=[localhost]/EKU=[clientAuth]"}
+	_, _, _, err := makeTLSPipe(c, nil)
+	a.Nil(err)
+
+	c.Proxy.TLS.ClientCert.Subjects = []string{"s:/DNS=[client.example.com]"}
+	_, _, _, err = makeTLSPipe(c, nil)
+	a.NotNil(err)
+	a.Contains(err.Error(), "tls: no client certificate presented for any of the defined client subjects")
+}
+
+func TestClientCertIdentity(t *testing.T) {
+	a := assert.New(t)
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+	clientCert, err := parseCertificate(bundle.ClientCert.Name())
+	a.Nil(err)
+
+	c := new(config.Config)
+	identityFunc, err := tlsClientCertIdentityFunc(&c.Proxy.TLS)
+	a.Nil(err)
+	a.Equal(clientCert.Subject.String(), identityFunc(clientCert))
+
+	// the identity is taken from the first matching client subject
+	c.Proxy.TLS.ClientCert.Subjects = []string{"s:/DNS=[client.example.com]", "s:/CN=[" + clientCert.Subject.CommonName + "]", "r:/DNS=[^local.*$]"}
+	identityFunc, err = tlsClientCertIdentityFunc(&c.Proxy.TLS)
+	a.Nil(err)
+	a.Equal(clientCert.Subject.String(), identityFunc(clientCert))
+
+	c.Proxy.TLS.ClientCert.Subjects = []string{"s:/DNS=[client.example.com]", "r:/DNS=[^local.*$]"}
+	identityFunc, err = tlsClientCertIdentityFunc(&c.Proxy.TLS)
+	a.Nil(err)
+	a.Equal("localhost", identityFunc(clientCert))
+
+	c.Proxy.TLS.ClientCert.Subjects = []string{"s:/IP=[localhost]"}
+	_, err = tlsClientCertIdentityFunc(&c.Proxy.TLS)
+	a.NotNil(err)
+}
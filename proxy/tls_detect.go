@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// first byte of TLS record carrying ClientHello
+	tlsRecordTypeHandshake = 0x16
+
+	transportTLS       = "tls"
+	transportPlaintext = "plaintext"
+)
+
+// peekedConn is a net.Conn which serves already peeked bytes before reading from the underlying connection
+type peekedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func newPeekedConn(conn net.Conn) *peekedConn {
+	return &peekedConn{Conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// closeNotifyConn invokes onClose once, when the connection is closed
+type closeNotifyConn struct {
+	net.Conn
+	once    sync.Once
+	onClose func()
+}
+
+func (c *closeNotifyConn) Close() error {
+	c.once.Do(c.onClose)
+	return c.Conn.Close()
+}
+
+// detectTLS peeks the first byte sent by the client and performs the TLS handshake if the client starts with a TLS record.
+// Otherwise the connection is returned as plaintext with the peeked bytes preserved.
+func detectTLS(conn net.Conn, tlsConfig *tls.Config, timeout time.Duration) (net.Conn, string, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, "", err
+	}
+	peeked := newPeekedConn(conn)
+	first, err := peeked.reader.Peek(1)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to read first bytes while detecting TLS")
+	}
+	if err = conn.SetReadDeadline(zeroTime); err != nil {
+		return nil, "", err
+	}
+	if first[0] != tlsRecordTypeHandshake {
+		return peeked, transportPlaintext, nil
+	}
+	tlsConn := tls.Server(peeked, tlsConfig)
+	if err = handshakeTLSConn(tlsConn, timeout); err != nil {
+		return nil, "", err
+	}
+	return tlsConn, transportTLS, nil
+}
@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBothModeListeners(t *testing.T, bundle *CertsBundle) (*Listeners, *config.Config, chan Conn) {
+	c := config.NewConfig()
+	c.Proxy.TLS.Enable = true
+	c.Proxy.TLS.ListenerMode = config.ListenerTLSModeBoth
+	c.Proxy.TLS.ListenerCertFile = bundle.ServerCert.Name()
+	c.Proxy.TLS.ListenerKeyFile = bundle.ServerKey.Name()
+	c.Proxy.TLS.DetectTimeout = 2 * time.Second
+	c.Proxy.BootstrapServers = []config.ListenerConfig{{BrokerAddress: "127.0.0.1:9092", ListenerAddress: "127.0.0.1:0"}}
+
+	connSrc := make(chan Conn, 1)
+	listeners, err := NewListeners(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listeners.connSrc = connSrc
+	return listeners, c, connSrc
+}
+
+func TestDetectTLSOnListenerWithBothModes(t *testing.T) {
+	bundle := NewCertsBundle()
+	defer bundle.Close()
+
+	listeners, c, connSrc := newBothModeListeners(t, bundle)
+	cfg := FromListenerConfig(c.Proxy.BootstrapServers[0])
+	l, err := listenInstance(connSrc, cfg, listeners.tcpConnOptions, listeners.listenFunc, listeners.acceptFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	caCertPEM, err := os.ReadFile(bundle.CACert.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caCertPEM) {
+		t.Fatal("failed to append CA certificate")
+	}
+
+	t.Run("tls", func(t *testing.T) {
+		client, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: roots, ServerName: "localhost"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+
+		server := receiveConn(t, connSrc)
+		defer server.LocalConnection.Close()
+		_, ok := server.LocalConnection.(*tls.Conn)
+		assert.True(t, ok)
+		assertEcho(t, client, server.LocalConnection)
+	})
+	t.Run("plaintext", func(t *testing.T) {
+		client, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		_, err = client.Write([]byte("ping"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		server := receiveConn(t, connSrc)
+		defer server.LocalConnection.Close()
+		_, ok := server.LocalConnection.(*closeNotifyConn)
+		assert.True(t, ok)
+
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(server.LocalConnection, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "ping", string(buf))
+	})
+}
+
+func TestDetectTLSTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	_, _, err := detectTLS(server, &tls.Config{}, 50*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func receiveConn(t *testing.T, connSrc <-chan Conn) Conn {
+	select {
+	case conn := <-connSrc:
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection was not accepted")
+	}
+	return Conn{}
+}
+
+func assertEcho(t *testing.T, client net.Conn, server net.Conn) {
+	go func() {
+		_, _ = client.Write([]byte("ping"))
+	}()
+	buf := make([]byte, 4)
+	_, err := io.ReadFull(server, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "ping", string(buf))
+}
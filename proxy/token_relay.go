@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	tokenexchange "github.com/grepplabs/kafka-proxy/pkg/libs/token-exchange"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// oauthBearerToken is the verified OAUTHBEARER client initial response of the locally authenticated client
+type oauthBearerToken struct {
+	token      string
+	authzid    string
+	extensions map[string]string
+}
+
+// tokenExchanger exchanges the token of the client for the token sent to the brokers
+type tokenExchanger interface {
+	ExchangeToken(ctx context.Context, subjectToken string) (string, error)
+}
+
+// BrokerTokenRelay authenticates the broker connections with the OAUTHBEARER token of the locally authenticated client.
+// The token is relayed as it is or exchanged at the token endpoint (RFC 8693), SASL extensions and authzid of the client are relayed in both modes.
+type BrokerTokenRelay struct {
+	mode string
+	// exchanger is nil in the relay mode
+	exchanger tokenExchanger
+	timeout   time.Duration
+	// reauthentication re-authenticates the broker connection with the new token of the client on local re-authentication (KIP-368)
+	reauthentication bool
+	cluster          string
+
+	clientID     string
+	writeTimeout time.Duration
+	readTimeout  time.Duration
+}
+
+func NewBrokerTokenRelay(c *config.Config) (*BrokerTokenRelay, error) {
+	tokenRelay := c.Kafka.SASL.TokenRelay
+	relay := &BrokerTokenRelay{
+		mode:             tokenRelay.Mode,
+		timeout:          tokenRelay.Exchange.Timeout,
+		reauthentication: c.Kafka.SASL.ReauthenticationEnable,
+		cluster:          c.Cluster,
+		clientID:         c.Kafka.ClientID,
+		writeTimeout:     c.Kafka.WriteTimeout,
+		readTimeout:      c.Kafka.ReadTimeout,
+	}
+	switch tokenRelay.Mode {
+	case config.TokenRelayModeRelay:
+	case config.TokenRelayModeExchange:
+		exchanger, err := tokenexchange.NewTokenExchanger(tokenexchange.TokenExchangerOptions{
+			Timeout:            tokenRelay.Exchange.Timeout,
+			TokenURL:           tokenRelay.Exchange.TokenURL,
+			ClientID:           tokenRelay.Exchange.ClientID,
+			ClientSecret:       tokenRelay.Exchange.ClientSecret,
+			ClientSecretFile:   tokenRelay.Exchange.ClientSecretFile,
+			Audience:           tokenRelay.Exchange.Audience,
+			Resource:           tokenRelay.Exchange.Resource,
+			Scopes:             tokenRelay.Exchange.Scopes,
+			SubjectTokenType:   tokenRelay.Exchange.SubjectTokenType,
+			RequestedTokenType: tokenRelay.Exchange.RequestedTokenType,
+			CacheMaxEntries:    tokenRelay.Exchange.CacheMaxEntries,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "token exchange configuration")
+		}
+		relay.exchanger = exchanger
+	default:
+		return nil, errors.Errorf("unsupported token relay mode '%s'", tokenRelay.Mode)
+	}
+	return relay, nil
+}
+
+// token returns the token sent to the broker for the locally authenticated session
+func (r *BrokerTokenRelay) token(session localSaslSession) (string, error) {
+	if session.clientToken == nil {
+		proxyBrokerTokenRelayTotal.WithLabelValues(r.cluster, r.mode, "missing").Inc()
+		return "", errors.Errorf("no OAUTHBEARER token of principal %s (%s)", session.principal.Name, session.mechanism)
+	}
+	if r.exchanger == nil {
+		proxyBrokerTokenRelayTotal.WithLabelValues(r.cluster, r.mode, "success").Inc()
+		return session.clientToken.token, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	token, err := r.exchanger.ExchangeToken(ctx, session.clientToken.token)
+	if err != nil {
+		proxyBrokerTokenRelayTotal.WithLabelValues(r.cluster, r.mode, "error").Inc()
+		return "", errors.Wrapf(err, "token exchange for principal %s failed", session.principal.Name)
+	}
+	proxyBrokerTokenRelayTotal.WithLabelValues(r.cluster, r.mode, "success").Inc()
+	return token, nil
+}
+
+// implements brokerClientAuth
+func (r *BrokerTokenRelay) authenticateClient(conn DeadlineReaderWriter, session localSaslSession, _ apis.ConnectionInfo) (string, error) {
+	token, err := r.token(session)
+	if err != nil {
+		return "", err
+	}
+	saslHandshake := &SASLHandshake{
+		clientID:     r.clientID,
+		version:      1,
+		mechanism:    SASLOAuthBearer,
+		writeTimeout: r.writeTimeout,
+		readTimeout:  r.readTimeout,
+	}
+	if err = saslHandshake.sendAndReceiveHandshake(conn); err != nil {
+		return "", err
+	}
+	// re-authentication of the broker connection requires SaslAuthenticate version 1
+	var version int16
+	if r.reauthentication {
+		version = 1
+	}
+	logrus.Debugf("Sending SaslAuthenticateRequest, mechanism OAUTHBEARER, token relay mode %s", r.mode)
+	authBytes := SaslOAuthBearer{}.ToBytes(token, session.clientToken.authzid, session.clientToken.extensions)
+	if _, err = sendAndReceiveSaslAuthenticate(conn, r.clientID, authBytes, version, r.writeTimeout, r.readTimeout); err != nil {
+		return "", fmt.Errorf("SASL/OAUTHBEARER auth with %s token failed: %w", r.mode, err)
+	}
+	return fmt.Sprintf("%s token", r.mode), nil
+}
+
+// implements brokerClientAuth
+func (r *BrokerTokenRelay) reauthenticates() bool {
+	return r.reauthentication
+}
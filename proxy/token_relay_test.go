@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/grepplabs/kafka-proxy/config"
+	"github.com/grepplabs/kafka-proxy/pkg/apis"
+	"github.com/grepplabs/kafka-proxy/proxy/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type testTokenExchanger struct {
+	tokens map[string]string
+}
+
+func (e *testTokenExchanger) ExchangeToken(_ context.Context, subjectToken string) (string, error) {
+	token, ok := e.tokens[subjectToken]
+	if !ok {
+		return "", errors.New("invalid_grant")
+	}
+	return token, nil
+}
+
+func newTestBrokerTokenRelay(t *testing.T, mode string, reauthentication bool) *BrokerTokenRelay {
+	c := config.NewConfig()
+	c.Kafka.SASL.ReauthenticationEnable = reauthentication
+	c.Kafka.SASL.TokenRelay = config.TokenRelayConfig{Enable: true, Mode: config.TokenRelayModeRelay}
+	relay, err := NewBrokerTokenRelay(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode == config.TokenRelayModeExchange {
+		relay.mode = mode
+		relay.exchanger = &testTokenExchanger{tokens: map[string]string{"client-token": "exchanged-token"}}
+	}
+	return relay
+}
+
+// newTestBrokerSaslResponses returns the SaslHandshake and SaslAuthenticate responses of the broker
+func newTestBrokerSaslResponses(t *testing.T, authenticateVersion int16) []byte {
+	handshake, err := protocol.Encode(&protocol.SaslHandshakeResponseV0orV1{Err: protocol.ErrNoError, EnabledMechanisms: []string{SASLOAuthBearer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var authenticate []byte
+	if authenticateVersion == 1 {
+		authenticate, err = protocol.Encode(&protocol.SaslAuthenticateResponseV1{Err: protocol.ErrNoError, SaslAuthBytes: []byte{}, SessionLifetimeMs: 60000})
+	} else {
+		authenticate, err = protocol.Encode(&protocol.SaslAuthenticateResponseV0{Err: protocol.ErrNoError, SaslAuthBytes: []byte{}})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	for correlationID, payload := range [][]byte{handshake, authenticate} {
+		header, err := protocol.Encode(&protocol.ResponseHeader{Length: int32(len(payload) + 4), CorrelationID: int32(correlationID)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(header)
+		buf.Write(payload)
+	}
+	return buf.Bytes()
+}
+
+// readTestSaslAuthenticateRequest returns the version and the auth bytes of the second request written to the broker
+func readTestSaslAuthenticateRequest(t *testing.T, written []byte) (int16, string) {
+	handshakeSize := binary.BigEndian.Uint32(written[:4])
+	request := written[4+handshakeSize:]
+	keyVersion := &protocol.RequestKeyVersion{}
+	if err := protocol.Decode(request[:8], keyVersion); err != nil {
+		t.Fatal(err)
+	}
+	if keyVersion.ApiKey != apiKeySaslAuthenticate {
+		t.Fatalf("SaslAuthenticate expected, got api key %d", keyVersion.ApiKey)
+	}
+	return keyVersion.ApiVersion, string(request[4:])
+}
+
+func TestBrokerTokenRelayAuthenticateClient(t *testing.T) {
+	clientToken := &oauthBearerToken{token: "client-token", authzid: "alice", extensions: map[string]string{"traceId": "abc"}}
+
+	tests := []struct {
+		name             string
+		mode             string
+		reauthentication bool
+		session          localSaslSession
+		token            string
+		version          int16
+		errorMsg         string
+	}{
+		{
+			name:    "relay",
+			mode:    config.TokenRelayModeRelay,
+			session: localSaslSession{mechanism: SASLOAuthBearer, principal: apis.Principal{Name: "alice"}, clientToken: clientToken},
+			token:   "client-token",
+		},
+		{
+			name:             "relay with re-authentication",
+			mode:             config.TokenRelayModeRelay,
+			reauthentication: true,
+			session:          localSaslSession{mechanism: SASLOAuthBearer, principal: apis.Principal{Name: "alice"}, clientToken: clientToken},
+			token:            "client-token",
+			version:          1,
+		},
+		{
+			name:    "exchange",
+			mode:    config.TokenRelayModeExchange,
+			session: localSaslSession{mechanism: SASLOAuthBearer, principal: apis.Principal{Name: "alice"}, clientToken: clientToken},
+			token:   "exchanged-token",
+		},
+		{
+			name:     "exchange rejected",
+			mode:     config.TokenRelayModeExchange,
+			session:  localSaslSession{mechanism: SASLOAuthBearer, principal: apis.Principal{Name: "bob"}, clientToken: &oauthBearerToken{token: "unknown"}},
+			errorMsg: "token exchange for principal bob failed: invalid_grant",
+		},
+		{
+			name:     "client without token",
+			mode:     config.TokenRelayModeRelay,
+			session:  localSaslSession{mechanism: SASLPlain, principal: apis.Principal{Name: "bob"}},
+			errorMsg: "no OAUTHBEARER token of principal bob (PLAIN)",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			relay := newTestBrokerTokenRelay(t, tc.mode, tc.reauthentication)
+			a.Equal(tc.reauthentication, relay.reauthenticates())
+
+			conn := &fakeDeadlineReaderWriter{reader: bytes.NewBuffer(newTestBrokerSaslResponses(t, tc.version)), writer: new(bytes.Buffer)}
+			_, err := relay.authenticateClient(conn, tc.session, apis.ConnectionInfo{})
+			if tc.errorMsg != "" {
+				a.EqualError(err, tc.errorMsg)
+				a.Empty(conn.writer.Bytes())
+				return
+			}
+			a.Nil(err)
+			version, authBytes := readTestSaslAuthenticateRequest(t, conn.writer.Bytes())
+			a.Equal(tc.version, version)
+			// token is sent with the authzid and the extensions of the client
+			a.Contains(authBytes, "n,a=alice,\x01auth=Bearer "+tc.token+"\x01traceId=abc\x01\x01")
+		})
+	}
+}
+
+func TestLocalSaslOauthConversationClientToken(t *testing.T) {
+	a := assert.New(t)
+
+	localSasl := NewLocalSasl(LocalSaslParams{enabled: true, localAuthenticators: map[string]LocalSaslAuth{SASLOAuthBearer: NewLocalSaslOauth(&countingTokenInfo{})}})
+	conversation := localSasl.newConversation(localSasl.localAuthenticators[SASLOAuthBearer], apis.ConnectionInfo{})
+	_, _, err := conversation.step(SaslOAuthBearer{}.ToBytes("client-token", "alice", map[string]string{"traceId": "abc"}))
+	a.Nil(err)
+
+	session := localSasl.newSession(conversation, 0)
+	if a.NotNil(session.clientToken) {
+		a.Equal(oauthBearerToken{token: "client-token", authzid: "alice", extensions: map[string]string{"traceId": "abc"}}, *session.clientToken)
+	}
+	a.Nil(session.clientCredentials)
+}
+
+func TestNewBrokerTokenRelay(t *testing.T) {
+	a := assert.New(t)
+
+	c := config.NewConfig()
+	c.Kafka.SASL.TokenRelay = config.TokenRelayConfig{Enable: true, Mode: config.TokenRelayModeExchange}
+	_, err := NewBrokerTokenRelay(c)
+	a.EqualError(err, "token exchange configuration: token exchange url is required")
+
+	c.Kafka.SASL.TokenRelay.Exchange.TokenURL = "https://idp.example.com/token"
+	relay, err := NewBrokerTokenRelay(c)
+	a.Nil(err)
+	a.NotNil(relay.exchanger)
+
+	c.Kafka.SASL.TokenRelay.Mode = "unknown"
+	_, err = NewBrokerTokenRelay(c)
+	a.EqualError(err, "unsupported token relay mode 'unknown'")
+}
@@ -87,7 +87,7 @@ func localPipe(listener net.Listener, dialer proxy.Dialer, timeout time.Duration
 func makeTLSPipe(conf *config.Config, expectedClientCert *x509.Certificate) (net.Conn, net.Conn, func(), error) {
 	stop := func() {}
 
-	serverConfig, err := newTLSListenerConfig(conf)
+	serverConfig, err := newTLSListenerConfig(&conf.Proxy.TLS)
 	if err != nil {
 		return nil, nil, stop, err
 	}
@@ -142,7 +142,7 @@ func makeTLSSocks5ProxyPipe(conf *config.Config, authenticator socks5.Authentica
 	if err != nil {
 		return nil, nil, stop, err
 	}
-	serverConfig, err := newTLSListenerConfig(conf)
+	serverConfig, err := newTLSListenerConfig(&conf.Proxy.TLS)
 	if err != nil {
 		return nil, nil, stop, err
 	}
@@ -215,7 +215,7 @@ func makeTLSHttpProxyPipe(conf *config.Config, proxyusername, proxypassword stri
 	if err != nil {
 		return nil, nil, stop, err
 	}
-	serverConfig, err := newTLSListenerConfig(conf)
+	serverConfig, err := newTLSListenerConfig(&conf.Proxy.TLS)
 	if err != nil {
 		return nil, nil, stop, err
 	}